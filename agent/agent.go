@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+)
+
+const (
+	opGet  = "get"
+	opAdd  = "add"
+	opList = "list"
+	opLock = "lock"
+
+	// requestTimeout - how long a client may take to send its request and
+	// read the response.
+	requestTimeout = 5 * time.Second
+)
+
+type (
+	// request - one request per connection, as JSON.
+	request struct {
+		Op  string `json:"op"`
+		ID  string `json:"id,omitempty"`
+		Key string `json:"key,omitempty"`
+	}
+
+	response struct {
+		Key   string   `json:"key,omitempty"`
+		IDs   []string `json:"ids,omitempty"`
+		Error string   `json:"error,omitempty"`
+	}
+
+	// Agent - caches container master keys by container ID in locked memory,
+	// each for ttl after it was added, until the agent is locked or stops.
+	Agent struct {
+		ttl  time.Duration
+		mu   sync.Mutex
+		keys map[string]*entry
+	}
+
+	entry struct {
+		key   *secret.Secret
+		timer *time.Timer
+	}
+)
+
+// New - returns an empty agent caching keys for ttl.
+func New(ttl time.Duration) *Agent {
+	return &Agent{ttl: ttl, keys: make(map[string]*entry)}
+}
+
+// Serve - answers requests on l until it is closed.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		go a.handle(conn)
+	}
+}
+
+// Lock - wipes every cached key.
+func (a *Agent) Lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, e := range a.keys {
+		e.wipe()
+		delete(a.keys, id)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	_ = json.NewEncoder(conn).Encode(a.answer(req))
+}
+
+func (a *Agent) answer(req request) response {
+	switch req.Op {
+	case opGet:
+		key, ok := a.get(req.ID)
+		if !ok {
+			return response{}
+		}
+		defer clear(key)
+
+		return response{Key: hex.EncodeToString(key)}
+	case opAdd:
+		key, err := hex.DecodeString(req.Key)
+		if err != nil || len(key) != lib.KeyLen || req.ID == "" {
+			return response{Error: lib.ErrAgentInvalidKey.Error()}
+		}
+		defer clear(key)
+
+		a.add(req.ID, key)
+		return response{}
+	case opList:
+		return response{IDs: a.ids()}
+	case opLock:
+		a.Lock()
+		return response{}
+	default:
+		return response{Error: lib.ErrAgentUnknownOp.Error()}
+	}
+}
+
+func (a *Agent) add(id string, key []byte) {
+	e := &entry{key: secret.New(len(key))}
+	copy(e.key.Bytes(), key)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if old, ok := a.keys[id]; ok {
+		old.wipe()
+	}
+	a.keys[id] = e
+	e.timer = time.AfterFunc(a.ttl, func() {
+		a.expire(id, e)
+	})
+}
+
+// expire - removes e once its TTL ends, unless it was replaced meanwhile.
+func (a *Agent) expire(id string, e *entry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.keys[id] == e {
+		e.wipe()
+		delete(a.keys, id)
+	}
+}
+
+func (a *Agent) get(id string) ([]byte, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.keys[id]
+	if !ok {
+		return nil, false
+	}
+
+	return slices.Clone(e.key.Bytes()), true
+}
+
+func (a *Agent) ids() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ids := make([]string, 0, len(a.keys))
+	for id := range a.keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// wipe - zeroes the key and releases its locked pages.
+func (e *entry) wipe() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.key.Wipe()
+}
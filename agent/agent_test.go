@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// serve - runs an agent on a socket in a short temporary path, since socket
+// paths are limited to about 100 bytes.
+func serve(t *testing.T, ttl time.Duration) (*Agent, *Client) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "tva")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := listen(socket)
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	a := New(ttl)
+	go func() { _ = a.Serve(l) }()
+
+	return a, NewClient(socket)
+}
+
+func TestAgent(t *testing.T) {
+	var key = bytes.Repeat([]byte{0x42}, 32)
+
+	t.Run("add_get_list", func(t *testing.T) {
+		_, client := serve(t, time.Minute)
+
+		if err := client.Add("c1", key); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+
+		got, ok := client.Get("c1")
+		if !ok || !bytes.Equal(got, key) {
+			t.Errorf("Get() = %x, %v, want %x, true", got, ok, key)
+		}
+
+		if _, ok = client.Get("c2"); ok {
+			t.Errorf("Get() of an unknown container found a key")
+		}
+
+		ids, err := client.List()
+		if err != nil || !slices.Equal(ids, []string{"c1"}) {
+			t.Errorf("List() = %v, %v, want [c1]", ids, err)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		_, client := serve(t, 50*time.Millisecond)
+
+		if err := client.Add("c1", key); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+
+		if _, ok := client.Get("c1"); ok {
+			t.Errorf("Get() found a key past its ttl")
+		}
+	})
+
+	t.Run("lock", func(t *testing.T) {
+		a, client := serve(t, time.Minute)
+
+		if err := client.Add("c1", key); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if err := client.Lock(); err != nil {
+			t.Fatalf("Lock() error = %v", err)
+		}
+
+		if _, ok := client.Get("c1"); ok {
+			t.Errorf("Get() found a key after Lock()")
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if len(a.keys) != 0 {
+			t.Errorf("agent holds %d keys after Lock()", len(a.keys))
+		}
+	})
+
+	t.Run("invalid_key", func(t *testing.T) {
+		_, client := serve(t, time.Minute)
+
+		if err := client.Add("", key); err == nil {
+			t.Errorf("Add() without a container id succeeded")
+		}
+	})
+
+	t.Run("not_running", func(t *testing.T) {
+		client := NewClient(filepath.Join(t.TempDir(), "none.sock"))
+
+		if client.Running() {
+			t.Errorf("Running() = true without an agent")
+		}
+		if _, ok := client.Get("c1"); ok {
+			t.Errorf("Get() found a key without an agent")
+		}
+		if NewClient("").Running() {
+			t.Errorf("Running() = true for an empty socket")
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EnvSocket - environment variable with the agent socket path.
+const EnvSocket = "TVAULT_AGENT_SOCK"
+
+// dialTimeout - how long a client waits for the agent to accept.
+const dialTimeout = time.Second
+
+// SocketPath - returns $TVAULT_AGENT_SOCK, or the default socket: in
+// $XDG_RUNTIME_DIR when it is set, else in a per-user directory of the
+// temporary directory, which agent start creates with mode 0700.
+func SocketPath() string {
+	if path := os.Getenv(EnvSocket); path != "" {
+		return path
+	}
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "tvault-agent.sock")
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("tvault-agent-%d", os.Getuid()), "agent.sock")
+}
+
+// Client - talks to the agent on a socket. A nil client, an empty socket path
+// and an agent that is not running all behave as an agent without keys, as
+// does a socket that another user could have created: see checkSocket and
+// checkPeer.
+type Client struct {
+	socket string
+}
+
+// NewClient - returns a client of the agent on socket, or nil when socket is
+// empty.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		return nil
+	}
+
+	return &Client{socket: socket}
+}
+
+// Get - returns the cached key of the container id.
+func (c *Client) Get(id string) ([]byte, bool) {
+	resp, err := c.do(request{Op: opGet, ID: id})
+	if err != nil || resp.Key == "" {
+		return nil, false
+	}
+
+	key, err := hex.DecodeString(resp.Key)
+	if err != nil {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// Add - caches key as the key of the container id.
+func (c *Client) Add(id string, key []byte) error {
+	_, err := c.do(request{Op: opAdd, ID: id, Key: hex.EncodeToString(key)})
+	return err
+}
+
+// List - returns the IDs of the containers whose keys are cached.
+func (c *Client) List() ([]string, error) {
+	resp, err := c.do(request{Op: opList})
+	return resp.IDs, err
+}
+
+// Lock - makes the agent wipe every cached key.
+func (c *Client) Lock() error {
+	_, err := c.do(request{Op: opLock})
+	return err
+}
+
+// Running - reports whether an agent answers on the socket.
+func (c *Client) Running() bool {
+	_, err := c.List()
+	return err == nil
+}
+
+func (c *Client) do(req request) (response, error) {
+	if c == nil {
+		return response{}, net.ErrClosed
+	}
+
+	if err := checkSocket(c.socket); err != nil {
+		return response{}, err
+	}
+
+	conn, err := net.DialTimeout("unix", c.socket, dialTimeout)
+	if err != nil {
+		return response{}, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err = checkPeer(conn); err != nil {
+		return response{}, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+
+	var resp response
+	if err = json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package agent
+
+import (
+	"net"
+	"os"
+)
+
+// listen - creates the socket and restricts it to the current user as far as
+// the platform allows.
+func listen(socket string) (net.Listener, error) {
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = os.Chmod(socket, 0o600)
+
+	return l, nil
+}
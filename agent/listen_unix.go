@@ -0,0 +1,17 @@
+//go:build unix
+
+package agent
+
+import (
+	"net"
+	"syscall"
+)
+
+// listen - creates the socket with mode 0600, so no other user can connect
+// even briefly.
+func listen(socket string) (net.Listener, error) {
+	umask := syscall.Umask(0o177)
+	defer syscall.Umask(umask)
+
+	return net.Listen("unix", socket)
+}
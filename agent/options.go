@@ -0,0 +1,37 @@
+package agent
+
+import "github.com/namelesscorp/tvault-core/lib"
+
+type Options struct {
+	Agent      *lib.Agent
+	InfoWriter *lib.Writer
+	LogWriter  *lib.Writer
+}
+
+func (o *Options) Validate() error {
+	if *o.Agent.TTL <= 0 {
+		return lib.ValidationErr(lib.CategoryAgent, lib.ErrAgentTTLInvalid)
+	}
+
+	if err := o.validateWriter(o.InfoWriter, lib.ErrInfoWriterTypeInvalid, lib.ErrInfoWriterPathRequired, lib.ErrInfoWriterFormatInvalid); err != nil {
+		return err
+	}
+
+	return o.validateWriter(o.LogWriter, lib.ErrLogWriterTypeInvalid, lib.ErrLogWriterPathRequired, lib.ErrLogWriterFormatInvalid)
+}
+
+func (o *Options) validateWriter(writer *lib.Writer, errType, errPath, errFormat error) error {
+	if _, ok := lib.WriterTypes[*writer.Type]; !ok {
+		return lib.ValidationErr(lib.CategoryAgent, errType)
+	}
+
+	if *writer.Type == lib.WriterTypeFile && *writer.Path == "" {
+		return lib.ValidationErr(lib.CategoryAgent, errPath)
+	}
+
+	if _, ok := lib.WriterFormats[*writer.Format]; !ok {
+		return lib.ValidationErr(lib.CategoryAgent, errFormat)
+	}
+
+	return nil
+}
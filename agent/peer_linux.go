@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// checkPeer - refuses an agent whose process runs as another user, as
+// SO_PEERCRED reports it, which also covers a socket swapped after
+// checkSocket.
+func checkPeer(conn net.Conn) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return lib.ErrAgentPeer
+	}
+
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var (
+		cred    *syscall.Ucred
+		credErr error
+	)
+	if err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED) // #nosec G115
+	}); err != nil {
+		return err
+	}
+	if credErr != nil {
+		return credErr
+	}
+
+	if int(cred.Uid) != os.Getuid() {
+		return lib.ErrAgentPeer
+	}
+
+	return nil
+}
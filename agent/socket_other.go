@@ -0,0 +1,24 @@
+//go:build !unix
+
+package agent
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// checkSocket - there is no file owner to check here; the socket must exist.
+func checkSocket(socket string) error {
+	_, err := os.Lstat(socket)
+	return err
+}
+
+// checkSocketDir - creates dir when it is missing.
+func checkSocketDir(dir string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package agent
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// checkSocket - refuses a path that is not a socket of the current user
+// closed to others, so a key is never sent to a socket another user created
+// at the path before the agent started.
+func checkSocket(socket string) error {
+	fi, err := os.Lstat(socket)
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode().Type() != fs.ModeSocket || !ownedByUser(fi) || fi.Mode().Perm()&0o077 != 0 {
+		return lib.ErrAgentSocket
+	}
+
+	return nil
+}
+
+// checkSocketDir - creates dir with mode 0700 when it is missing, and refuses
+// a symlink, a directory of another user or one others can write to, where
+// the socket could be replaced.
+func checkSocketDir(dir string) error {
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() || !ownedByUser(fi) || fi.Mode().Perm()&0o022 != 0 {
+		return lib.ErrAgentSocketDir
+	}
+
+	return nil
+}
+
+func ownedByUser(fi fs.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid()
+}
@@ -0,0 +1,59 @@
+//go:build unix
+
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestClientRefusesOpenSocket(t *testing.T) {
+	_, client := serve(t, time.Minute)
+	if !client.Running() {
+		t.Fatalf("Running() = false for a private socket")
+	}
+
+	if err := os.Chmod(client.socket, 0o666); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	if _, err := client.List(); !errors.Is(err, lib.ErrAgentSocket) {
+		t.Errorf("List() error = %v, want %v", err, lib.ErrAgentSocket)
+	}
+}
+
+func TestCheckSocketDir(t *testing.T) {
+	parent := t.TempDir()
+
+	missing := filepath.Join(parent, "missing")
+	if err := checkSocketDir(missing); err != nil {
+		t.Fatalf("checkSocketDir() of a missing directory error = %v", err)
+	}
+	if fi, err := os.Stat(missing); err != nil || fi.Mode().Perm() != 0o700 {
+		t.Errorf("checkSocketDir() created %v, %v, want mode 0700", fi.Mode().Perm(), err)
+	}
+
+	shared := filepath.Join(parent, "shared")
+	if err := os.Mkdir(shared, 0o700); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := os.Chmod(shared, 0o777); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	if err := checkSocketDir(shared); !errors.Is(err, lib.ErrAgentSocketDir) {
+		t.Errorf("checkSocketDir() of a shared directory error = %v, want %v", err, lib.ErrAgentSocketDir)
+	}
+
+	link := filepath.Join(parent, "link")
+	if err := os.Symlink(missing, link); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	if err := checkSocketDir(link); !errors.Is(err, lib.ErrAgentSocketDir) {
+		t.Errorf("checkSocketDir() of a symlink error = %v, want %v", err, lib.ErrAgentSocketDir)
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// Started - what agent start reports once it listens.
+type Started struct {
+	Socket string `json:"socket"`
+	TTL    string `json:"ttl"`
+}
+
+// Start - serves an agent on the socket until ctx is cancelled, then wipes
+// its keys. The socket is created with mode 0600 in a directory private to
+// the user, created with mode 0700 when missing; a stale socket left by an
+// agent that did not stop cleanly is replaced.
+func Start(ctx context.Context, opts Options) error {
+	socket := *opts.Agent.Socket
+	if err := checkSocketDir(filepath.Dir(socket)); err != nil {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentListenError, lib.ErrMessageAgentListenError, lib.SuggestionAgentListen, err)
+	}
+	if NewClient(socket).Running() {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentListenError, lib.ErrMessageAgentListenError, lib.SuggestionAgentListen, lib.ErrAgentRunning)
+	}
+	_ = os.Remove(socket)
+
+	l, err := listen(socket)
+	if err != nil {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentListenError, lib.ErrMessageAgentListenError, lib.SuggestionAgentListen, err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = l.Close()
+	})
+	defer stop()
+
+	if err = writeStarted(opts.InfoWriter, Started{Socket: socket, TTL: opts.Agent.TTL.String()}); err != nil {
+		_ = l.Close()
+		return err
+	}
+
+	a := New(*opts.Agent.TTL)
+	defer a.Lock()
+
+	if err = a.Serve(l); err != nil {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentListenError, lib.ErrMessageAgentListenError, "", err)
+	}
+
+	return nil
+}
+
+// Lock - makes the agent on the socket wipe every cached key.
+func Lock(opts Options) error {
+	if err := NewClient(*opts.Agent.Socket).Lock(); err != nil {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentRequestError, lib.ErrMessageAgentRequestError, lib.SuggestionAgentRequest, err)
+	}
+
+	return nil
+}
+
+func writeStarted(opts *lib.Writer, started Started) error {
+	writer, closer, err := lib.NewWriter(opts)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any = started
+	if *opts.Format == lib.WriterFormatPlaintext {
+		msg = fmt.Sprintf("%s=%s; export %s;\n", EnvSocket, started.Socket, EnvSocket)
+	}
+
+	if _, err = lib.WriteFormatted(writer, *opts.Format, msg); err != nil {
+		return lib.IOErr(lib.CategoryAgent, lib.ErrCodeAgentListenError, lib.ErrMessageAgentListenError, "", err)
+	}
+
+	return nil
+}
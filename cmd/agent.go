@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/agent"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+const (
+	usageAgentTemplate = "usage: tvault-core agent <subcommand> [options]\n" +
+		"available subcommands: [%s | %s]; with [%s | %s]"
+
+	defaultAgentTTL = time.Hour
+)
+
+func handleAgent(ctx context.Context, args []string) (*lib.Writer, error) {
+	var options = createDefaultAgentOptions()
+	if len(args) < 1 {
+		return options.LogWriter, fmt.Errorf(
+			usageAgentTemplate,
+			subStart, subLock,
+			subInfoWriter, subLogWriter,
+		)
+	}
+
+	if err := parseAgentSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	switch args[0] {
+	case subStart:
+		if err := agent.Start(ctx, options); err != nil {
+			return options.LogWriter, err
+		}
+	case subLock:
+		if err := agent.Lock(options); err != nil {
+			return options.LogWriter, err
+		}
+	default:
+		return options.LogWriter, fmt.Errorf(lib.ErrUnknownSubcommand, args[0])
+	}
+
+	return options.LogWriter, nil
+}
+
+func createDefaultAgentOptions() agent.Options {
+	var ttl = defaultAgentTTL
+
+	return agent.Options{
+		Agent: &lib.Agent{
+			Socket: lib.StringPtr(agent.SocketPath()),
+			TTL:    &ttl,
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+}
+
+func parseAgentSubcommands(args []string, options *agent.Options) error {
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		switch subcommand {
+		case subStart:
+			if err := processAgentStart(options.Agent, subcommandArgs); err != nil {
+				return err
+			}
+		case subLock:
+			if err := processAgentLock(options.Agent, subcommandArgs); err != nil {
+				return err
+			}
+		case subInfoWriter:
+			if err := processAgentInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return err
+			}
+		case subLogWriter:
+			if err := processAgentLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return nil
+}
+
+func processAgentStart(options *lib.Agent, args []string) error {
+	var flagSet = flag.NewFlagSet(subStart, flag.ExitOnError)
+
+	options.Socket = flagSet.String("socket", agent.SocketPath(), "path to agent socket; default: $"+agent.EnvSocket+" or the per-user socket")
+	options.TTL = flagSet.Duration("ttl", defaultAgentTTL, "how long a key is cached, e.g. 30m; default: 1h")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subStart, err)
+	}
+
+	return nil
+}
+
+func processAgentLock(options *lib.Agent, args []string) error {
+	var flagSet = flag.NewFlagSet(subLock, flag.ExitOnError)
+
+	options.Socket = flagSet.String("socket", agent.SocketPath(), "path to agent socket; default: $"+agent.EnvSocket+" or the per-user socket")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subLock, err)
+	}
+
+	return nil
+}
+
+func processAgentInfoWriter(options *lib.Writer, args []string) error {
+	var flagSet = flag.NewFlagSet(subInfoWriter, flag.ExitOnError)
+
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
+	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
+	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; plaintext prints a shell export line; default: json")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subInfoWriter, err)
+	}
+
+	return nil
+}
+
+func processAgentLogWriter(options *lib.Writer, args []string) error {
+	var flagSet = flag.NewFlagSet(subLogWriter, flag.ExitOnError)
+
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
+	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
+	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subLogWriter, err)
+	}
+
+	return nil
+}
+
+// processAgentSocket - parses the agent subcommand of unseal, reseal and
+// container ls, which names the socket of the agent consulted for keys.
+// Without it only $TVAULT_AGENT_SOCK enables the agent, so a key is never
+// handed to a socket the user did not ask for.
+func processAgentSocket(options *lib.Agent, args []string) error {
+	var flagSet = flag.NewFlagSet(subAgent, flag.ExitOnError)
+
+	options.Socket = flagSet.String("socket", agent.SocketPath(), "path to agent socket; empty disables the agent; default: $"+agent.EnvSocket+" or the per-user socket")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subAgent, err)
+	}
+
+	return nil
+}
@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
 
+	"github.com/namelesscorp/tvault-core/agent"
 	"github.com/namelesscorp/tvault-core/container"
 	"github.com/namelesscorp/tvault-core/lib"
 )
 
 const usageContainerTemplate = "usage: tvault-core container <subcommand> [options]\n" +
-	"available subcommands: [%s | %s | %s]"
+	"available subcommands: [%s | %s]; with [%s | %s | %s]"
 
-func handleContainer(args []string) (*lib.Writer, error) {
+func handleContainer(ctx context.Context, args []string) (*lib.Writer, error) {
 	var options = createDefaultContainerOptions()
 	if len(args) < 1 {
-		return options.LogWriter, fmt.Errorf(usageContainerTemplate, subInfo, subInfoWriter, subLogWriter)
+		return options.LogWriter, fmt.Errorf(usageContainerTemplate, subInfo, subLs, subAgent, subInfoWriter, subLogWriter)
+	}
+
+	if args[0] == subLs {
+		return handleContainerList(ctx, args)
 	}
 
 	var (
@@ -40,9 +47,30 @@ func handleContainer(args []string) (*lib.Writer, error) {
 	return options.LogWriter, nil
 }
 
+func handleContainerList(ctx context.Context, args []string) (*lib.Writer, error) {
+	var options = createDefaultContainerOptions()
+	if _, err := parseContainerSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := options.ValidateList(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := container.List(ctx, options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
 func createDefaultContainerOptions() container.Options {
 	return container.Options{
 		Path: lib.StringPtr(""),
+		Dir:  lib.StringPtr(""),
+		Agent: &lib.Agent{
+			Socket: lib.StringPtr(os.Getenv(agent.EnvSocket)),
+		},
 		InfoWriter: &lib.Writer{
 			Type:   lib.StringPtr(lib.WriterTypeStdout),
 			Path:   lib.StringPtr(""),
@@ -72,6 +100,14 @@ func parseContainerSubcommands(args []string, options *container.Options) (map[s
 			if err := processContainerInfo(options, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subLs:
+			if err := processContainerList(options, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subAgent:
+			if err := processAgentSocket(options.Agent, subcommandArgs); err != nil {
+				return nil, err
+			}
 		case subInfoWriter:
 			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
 				return nil, err
@@ -102,10 +138,22 @@ func processContainerInfo(options *container.Options, args []string) error {
 	return nil
 }
 
+func processContainerList(options *container.Options, args []string) error {
+	var flagSet = flag.NewFlagSet(subLs, flag.ExitOnError)
+
+	options.Dir = flagSet.String("dir", "", "directory searched for containers (required flag)")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subLs, err)
+	}
+
+	return nil
+}
+
 func processContainerInfoWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subInfoWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file)")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]")
 
@@ -119,7 +167,7 @@ func processContainerInfoWriter(options *lib.Writer, args []string) error {
 func processContainerLogWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subLogWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file)")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]")
 
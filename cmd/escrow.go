@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/reseal"
+	"github.com/namelesscorp/tvault-core/unseal"
+)
+
+const usageEscrowTemplate = "usage: tvault-core escrow <subcommand> [options]\n" +
+	"available subcommands: [%s | %s]; with [%s | %s]"
+
+func handleEscrow(ctx context.Context, args []string) (*lib.Writer, error) {
+	if len(args) < 1 {
+		return createDefaultUnsealOptions().LogWriter, fmt.Errorf(
+			usageEscrowTemplate,
+			subUnseal, subRewrap,
+			subInfoWriter, subLogWriter,
+		)
+	}
+
+	switch args[0] {
+	case subUnseal:
+		return handleEscrowUnseal(ctx, args)
+	case subRewrap:
+		return handleEscrowRewrap(args)
+	}
+
+	return createDefaultUnsealOptions().LogWriter, fmt.Errorf(lib.ErrUnknownSubcommand, args[0])
+}
+
+func handleEscrowUnseal(ctx context.Context, args []string) (*lib.Writer, error) {
+	var options = createDefaultUnsealOptions()
+	usedSubcommands, err := parseEscrowUnsealSubcommands(args, &options)
+	if err != nil {
+		return options.LogWriter, err
+	}
+	applyUnsealStreamDefaults(&options, usedSubcommands)
+
+	if *options.Container.EscrowKeyPath == "" {
+		return options.LogWriter, lib.ValidationErr(lib.CategoryUnseal, lib.ErrEscrowPrivateKeyPathRequired)
+	}
+
+	if err := options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := unseal.Unseal(ctx, options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
+func handleEscrowRewrap(args []string) (*lib.Writer, error) {
+	var options = createDefaultRewrapOptions()
+	if err := parseRewrapSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := reseal.RewrapEscrow(options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
+func parseEscrowUnsealSubcommands(args []string, options *unseal.Options) (map[string]bool, error) {
+	var usedSubcommands = make(map[string]bool)
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		usedSubcommands[subcommand] = true
+
+		switch subcommand {
+		case subUnseal:
+			if err := processEscrowUnseal(options.Container, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subInfoWriter:
+			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subLimits:
+			if err := processUnsealLimits(options.Limits, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subLogWriter:
+			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
+		default:
+			return usedSubcommands, fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return usedSubcommands, nil
+}
+
+func processEscrowUnseal(options *lib.Container, args []string) error {
+	var flagSet = flag.NewFlagSet(subUnseal, flag.ExitOnError)
+
+	options.CurrentPath = flagSet.String("current-path", "", "current path to container file (required); default: empty")
+	options.FolderPath = flagSet.String("folder-path", "", "path to folder for unseal (required without -stdout); default: empty")
+	options.Stdout = flagSet.Bool("stdout", false, "write the payload of a container sealed with -stdin to stdout (required for such containers); default: false")
+	options.EscrowKeyPath = flagSet.String("private-key-path", "", "path to file with the hex escrow X25519 private key (required); default: empty")
+	options.Resume = flagSet.Bool("resume", false, "continue an interrupted unseal to -folder-path, keeping the files it already extracted (not required); default: false")
+	options.OnConflict = flagSet.String("on-conflict", lib.ConflictFail, "what to do with files already in -folder-path [fail | skip | overwrite | rename | newer] (not required); default: fail")
+	options.DryRun = flagSet.Bool("dry-run", false, "list what would be written or overwritten in -folder-path without writing anything (not required); default: false")
+	options.Symlinks = flagSet.String("symlinks", lib.SymlinksContain, "what to do with symlinks [reject | skip | contain | allow]; contain refuses targets outside -folder-path (not required); default: contain")
+	options.Preserve = flagSet.String("preserve", lib.PreserveNone, "metadata recorded by seal -preserve=all to restore [none | all]: none drops ownership and setuid, setgid and sticky bits, all restores them, ownership only as root (not required); default: none")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subUnseal, err)
+	}
+
+	return nil
+}
+
+func createDefaultRewrapOptions() reseal.RewrapOptions {
+	return reseal.RewrapOptions{
+		Escrow: &lib.Escrow{
+			Dir:            lib.StringPtr(""),
+			PrivateKeyPath: lib.StringPtr(""),
+			NewPublicKey:   lib.StringPtr(""),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+}
+
+func parseRewrapSubcommands(args []string, options *reseal.RewrapOptions) error {
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		switch subcommand {
+		case subRewrap:
+			if err := processEscrowRewrap(options.Escrow, subcommandArgs); err != nil {
+				return err
+			}
+		case subInfoWriter:
+			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return err
+			}
+		case subLogWriter:
+			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return nil
+}
+
+func processEscrowRewrap(options *lib.Escrow, args []string) error {
+	var flagSet = flag.NewFlagSet(subRewrap, flag.ExitOnError)
+
+	options.Dir = flagSet.String("dir", "", "directory searched recursively for .tvlt containers (required); default: empty")
+	options.PrivateKeyPath = flagSet.String("private-key-path", "", "path to file with the current hex escrow X25519 private key (required); default: empty")
+	options.NewPublicKey = flagSet.String("new-public-key", "", "hex X25519 public key of the new escrow key (required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subRewrap, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/namelesscorp/tvault-core/kms"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// createDefaultKMSOptions - get default kms opts, read from the environment
+// variables of the Vault CLI.
+func createDefaultKMSOptions() *lib.KMS {
+	var timeout = kms.DefaultTimeout
+	return &lib.KMS{
+		Address:    lib.StringPtr(os.Getenv(kms.EnvAddress)),
+		Mount:      lib.StringPtr(""),
+		Key:        lib.StringPtr(""),
+		TokenPath:  lib.StringPtr(""),
+		CACert:     lib.StringPtr(os.Getenv(kms.EnvCACert)),
+		ClientCert: lib.StringPtr(os.Getenv(kms.EnvClientCert)),
+		ClientKey:  lib.StringPtr(os.Getenv(kms.EnvClientKey)),
+		Timeout:    &timeout,
+	}
+}
+
+// processKMS - parses the kms subcommand of seal, unseal and reseal. Only seal
+// chooses the transit key; unseal and reseal use the one in the container,
+// under the mount given here or else the recorded one.
+func processKMS(options *lib.KMS, args []string, chooseKey bool) error {
+	var flagSet = flag.NewFlagSet(subKMS, flag.ExitOnError)
+
+	options.Address = flagSet.String("address", os.Getenv(kms.EnvAddress), "transit server address, e.g. https://vault:8200 (required); default: $"+kms.EnvAddress)
+	if chooseKey {
+		options.Mount = flagSet.String("mount", kms.DefaultMount, "path the transit engine is mounted at (not required); default: "+kms.DefaultMount)
+		options.Key = flagSet.String("key", "", "transit key the container key is wrapped with (required for token -type=kms); default: empty")
+	} else {
+		options.Mount = flagSet.String("mount", "", "path the transit engine is mounted at, in place of the one recorded in the container (not required); default: the recorded mount")
+	}
+	options.TokenPath = flagSet.String("token-path", "", "file holding the Vault token (not required); default: $"+kms.EnvToken)
+	options.CACert = flagSet.String("ca-cert", os.Getenv(kms.EnvCACert), "PEM file of the CA that signs the server certificate (not required); default: $"+kms.EnvCACert)
+	options.ClientCert = flagSet.String("client-cert", os.Getenv(kms.EnvClientCert), "PEM client certificate for TLS authentication (not required); default: $"+kms.EnvClientCert)
+	options.ClientKey = flagSet.String("client-key", os.Getenv(kms.EnvClientKey), "PEM key of -client-cert (not required); default: $"+kms.EnvClientKey)
+	options.Timeout = flagSet.Duration("timeout", kms.DefaultTimeout, "limit of each request to the server (not required); default: "+kms.DefaultTimeout.String())
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subKMS, err)
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/namelesscorp/tvault-core/container"
 	"github.com/namelesscorp/tvault-core/debug"
@@ -19,6 +24,9 @@ const (
 	commandVersion   = "version"
 	commandInfo      = "info"
 	commandContainer = "container"
+	commandToken     = "token"
+	commandEscrow    = "escrow"
+	commandAgent     = "agent"
 
 	subContainer         = "container"
 	subInfo              = "info"
@@ -30,12 +38,42 @@ const (
 	subTokenWriter       = "token-writer"
 	subTokenReader       = "token-reader"
 	subLogWriter         = "log-writer"
+	subHolders           = "holders"
+	subVerify            = "verify"
+	subReshare           = "reshare"
+	subInspect           = "inspect"
+	subRecoveryWriter    = "recovery-writer"
+	subUnseal            = "unseal"
+	subRewrap            = "rewrap"
+	subAgent             = "agent"
+	subStart             = "start"
+	subLock              = "lock"
+	subLs                = "ls"
+	subKMS               = "kms"
+	subLimits            = "limits"
 
 	usageMessage = "usage: tvault-core <command> [subcommand] [options]\n" +
-		"available commands: [%s | %s | %s | %s | %s]"
+		"available commands: [%s]"
+
+	// exitCodeCanceled is returned when SIGINT or SIGTERM cancelled the
+	// command, following the shell convention of 128 + SIGINT.
+	exitCodeCanceled = 130
 )
 
 var (
+	// commands - the commands run accepts, in the order usage lists them.
+	commands = []string{
+		commandSeal,
+		commandUnseal,
+		commandReseal,
+		commandContainer,
+		commandToken,
+		commandEscrow,
+		commandAgent,
+		commandVersion,
+		commandInfo,
+	}
+
 	subcommands = map[string]bool{
 		subContainer:         true,
 		subToken:             true,
@@ -46,6 +84,19 @@ var (
 		subTokenReader:       true,
 		subLogWriter:         true,
 		subInfoWriter:        true,
+		subHolders:           true,
+		subVerify:            true,
+		subReshare:           true,
+		subInspect:           true,
+		subRecoveryWriter:    true,
+		subUnseal:            true,
+		subRewrap:            true,
+		subAgent:             true,
+		subStart:             true,
+		subLock:              true,
+		subLs:                true,
+		subKMS:               true,
+		subLimits:            true,
 	}
 )
 
@@ -54,9 +105,10 @@ func main() {
 }
 
 // run executes the CLI and returns the process exit code: 0 on success,
-// non-zero on any error (bad usage, command failure, or a recovered panic).
-// main wraps this so os.Exit still runs the deferred debug.Stop and panic
-// recovery below before the process terminates.
+// exitCodeCanceled when a signal cancelled the command, and 1 on any other
+// error (bad usage, command failure, or a recovered panic). main wraps this so
+// os.Exit still runs the deferred debug.Stop and panic recovery below before
+// the process terminates.
 func run() (exitCode int) {
 	defer func() {
 		debug.Stop()
@@ -68,31 +120,49 @@ func run() (exitCode int) {
 	}()
 
 	if len(os.Args) < 2 {
-		fmt.Printf(usageMessage, commandSeal, commandUnseal, commandReseal, commandVersion, commandInfo)
+		fmt.Printf(usageMessage, strings.Join(commands, " | "))
 		return 1
 	}
 
+	ctx, stop := notifyContext()
+	defer stop()
+
 	switch os.Args[1] {
 	case commandSeal:
-		if logWriter, err := handleSeal(os.Args[2:]); err != nil {
+		if logWriter, err := handleSeal(ctx, os.Args[2:]); err != nil {
 			lib.ErrorFormatted(logWriter, commandSeal, err)
-			return 1
+			return errorExitCode(err)
 		}
 	case commandUnseal:
-		if logWriter, err := handleUnseal(os.Args[2:]); err != nil {
+		if logWriter, err := handleUnseal(ctx, os.Args[2:]); err != nil {
 			lib.ErrorFormatted(logWriter, commandUnseal, err)
-			return 1
+			return errorExitCode(err)
 		}
 
 	case commandReseal:
-		if logWriter, err := handleReseal(os.Args[2:]); err != nil {
+		if logWriter, err := handleReseal(ctx, os.Args[2:]); err != nil {
 			lib.ErrorFormatted(logWriter, commandReseal, err)
-			return 1
+			return errorExitCode(err)
 		}
 	case commandContainer:
-		if logWriter, err := handleContainer(os.Args[2:]); err != nil {
+		if logWriter, err := handleContainer(ctx, os.Args[2:]); err != nil {
 			lib.ErrorFormatted(logWriter, commandContainer, err)
-			return 1
+			return errorExitCode(err)
+		}
+	case commandToken:
+		if logWriter, err := handleToken(ctx, os.Args[2:]); err != nil {
+			lib.ErrorFormatted(logWriter, commandToken, err)
+			return errorExitCode(err)
+		}
+	case commandEscrow:
+		if logWriter, err := handleEscrow(ctx, os.Args[2:]); err != nil {
+			lib.ErrorFormatted(logWriter, commandEscrow, err)
+			return errorExitCode(err)
+		}
+	case commandAgent:
+		if logWriter, err := handleAgent(ctx, os.Args[2:]); err != nil {
+			lib.ErrorFormatted(logWriter, commandAgent, err)
+			return errorExitCode(err)
 		}
 	case commandVersion:
 		fmt.Printf(
@@ -116,22 +186,35 @@ func run() (exitCode int) {
 				"created by trust vault team (nameless)\n",
 		)
 	default:
-		fmt.Printf(
-			"unknown command: %s; use [%s | %s | %s | %s | %s | %s]",
-			os.Args[1],
-			commandSeal,
-			commandUnseal,
-			commandReseal,
-			commandContainer,
-			commandVersion,
-			commandInfo,
-		)
+		fmt.Printf("unknown command: %s; use [%s]", os.Args[1], strings.Join(commands, " | "))
 		return 1
 	}
 
 	return 0
 }
 
+// notifyContext - returns a context cancelled by the first SIGINT or SIGTERM,
+// so every command stops and cleans up after itself. The signals
+// are released once it fires, and a second one terminates the process at once.
+func notifyContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// errorExitCode - returns the process exit code for a failed command.
+func errorExitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitCodeCanceled
+	}
+
+	return 1
+}
+
 func findNextSubcommand(args []string, startIdx int) int {
 	for i := startIdx; i < len(args); i++ {
 		if args[i][0] == '-' {
@@ -1,22 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
 
+	"github.com/namelesscorp/tvault-core/agent"
 	"github.com/namelesscorp/tvault-core/lib"
 	"github.com/namelesscorp/tvault-core/reseal"
 )
 
 const usageResealTemplate = "usage: tvault-core reseal <subcommand> [options]\n" +
-	"available subcommands: [%s | %s | %s | %s | %s]"
+	"available subcommands: [%s | %s | %s | %s | %s | %s | %s | %s | %s]"
 
-func handleReseal(args []string) (*lib.Writer, error) {
+func handleReseal(ctx context.Context, args []string) (*lib.Writer, error) {
 	var options = createDefaultResealOptions()
 	if len(args) < 1 {
 		return options.LogWriter, fmt.Errorf(
 			usageResealTemplate,
-			subContainer, subIntegrityProvider, subTokenReader, subTokenWriter, subLogWriter,
+			subContainer, subToken, subIntegrityProvider, subTokenReader, subTokenWriter, subLogWriter, subHolders, subAgent, subKMS,
 		)
 	}
 
@@ -36,7 +39,7 @@ func handleReseal(args []string) (*lib.Writer, error) {
 		return options.LogWriter, err
 	}
 
-	if err = reseal.Reseal(options); err != nil {
+	if err = reseal.Reseal(ctx, options); err != nil {
 		return options.LogWriter, err
 	}
 
@@ -46,13 +49,21 @@ func handleReseal(args []string) (*lib.Writer, error) {
 func createDefaultResealOptions() reseal.Options {
 	return reseal.Options{
 		Container: &lib.Container{
-			Name:        lib.StringPtr(""),
-			NewPath:     lib.StringPtr(""),
-			CurrentPath: lib.StringPtr(""),
-			FolderPath:  lib.StringPtr(""),
-			Passphrase:  lib.StringPtr(""),
-			Comment:     lib.StringPtr(""),
-			Tags:        lib.StringPtr(""),
+			Name:            lib.StringPtr(""),
+			NewPath:         lib.StringPtr(""),
+			CurrentPath:     lib.StringPtr(""),
+			FolderPath:      lib.StringPtr(""),
+			Passphrase:      lib.StringPtr(""),
+			Comment:         lib.StringPtr(""),
+			Tags:            lib.StringPtr(""),
+			RotateKey:       lib.BoolPtr(false),
+			EscrowPublicKey: lib.StringPtr(os.Getenv(lib.EnvEscrowPublicKey)),
+			Ignore:          &[]string{},
+			Preserve:        lib.StringPtr(""),
+		},
+		Token: &lib.Token{
+			NotBefore: lib.StringPtr(""),
+			ExpiresAt: lib.StringPtr(""),
 		},
 		IntegrityProvider: &lib.IntegrityProvider{
 			Type:              lib.StringPtr(""),
@@ -70,6 +81,13 @@ func createDefaultResealOptions() reseal.Options {
 			Path:   lib.StringPtr(""),
 			Format: lib.StringPtr(lib.WriterFormatJSON),
 		},
+		Holders: &lib.Holders{
+			Path: lib.StringPtr(""),
+		},
+		Agent: &lib.Agent{
+			Socket: lib.StringPtr(os.Getenv(agent.EnvSocket)),
+		},
+		KMS: createDefaultKMSOptions(),
 		LogWriter: &lib.Writer{
 			Type:   lib.StringPtr(lib.WriterTypeStdout),
 			Path:   lib.StringPtr(""),
@@ -94,6 +112,10 @@ func parseResealSubcommands(args []string, options *reseal.Options) (map[string]
 			if err := processResealContainer(options.Container, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subToken:
+			if err := processResealToken(options.Token, subcommandArgs); err != nil {
+				return nil, err
+			}
 		case subIntegrityProvider:
 			if err := processResealIntegrityProvider(options.IntegrityProvider, subcommandArgs); err != nil {
 				return nil, err
@@ -110,6 +132,18 @@ func parseResealSubcommands(args []string, options *reseal.Options) (map[string]
 			if err := processResealLogWriter(options.LogWriter, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subHolders:
+			if err := processResealHolders(options.Holders, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subAgent:
+			if err := processAgentSocket(options.Agent, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subKMS:
+			if err := processKMS(options.KMS, subcommandArgs, false); err != nil {
+				return nil, err
+			}
 		default:
 			return usedSubcommands, fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
 		}
@@ -130,6 +164,10 @@ func processResealContainer(options *lib.Container, args []string) error {
 	options.Passphrase = flagSet.String("passphrase", "", "passphrase to reseal container file (required for seal token -type=none); default: empty")
 	options.Comment = flagSet.String("comment", "", "container comment (not required); default: empty)")
 	options.Tags = flagSet.String("tags", "", "container tags, comma separated (not required); default: empty)")
+	options.EscrowPublicKey = flagSet.String("escrow-public-key", os.Getenv(lib.EnvEscrowPublicKey), "hex X25519 escrow public key the key is wrapped to, replacing the recorded one (not required); default: $"+lib.EnvEscrowPublicKey)
+	options.RotateKey = flagSet.Bool("rotate-key", false, "generate a new master key and revoke all previously issued tokens (not required); default: false")
+	options.Preserve = flagSet.String("preserve", "", "filesystem metadata to keep [none | all], as for seal (not required); default: the mode the container was sealed with")
+	options.Ignore = ignoreFlags(flagSet)
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subContainer, err)
@@ -138,6 +176,19 @@ func processResealContainer(options *lib.Container, args []string) error {
 	return nil
 }
 
+func processResealToken(options *lib.Token, args []string) error {
+	var flagSet = flag.NewFlagSet(subToken, flag.ExitOnError)
+
+	options.NotBefore = flagSet.String("token-not-before", "", "RFC 3339 time before which re-issued tokens are rejected (not required); default: empty")
+	options.ExpiresAt = flagSet.String("token-expires-at", "", "RFC 3339 time from which re-issued tokens are rejected (not required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subToken, err)
+	}
+
+	return nil
+}
+
 func processResealIntegrityProvider(options *lib.IntegrityProvider, args []string) error {
 	var flagSet = flag.NewFlagSet(subIntegrityProvider, flag.ExitOnError)
 
@@ -169,7 +220,7 @@ func processResealTokenReader(options *lib.Reader, args []string) error {
 func processResealTokenWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subTokenWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]; default: stdout")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
 
@@ -183,7 +234,7 @@ func processResealTokenWriter(options *lib.Writer, args []string) error {
 func processResealLogWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subLogWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]; default: stdout")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
 
@@ -193,3 +244,15 @@ func processResealLogWriter(options *lib.Writer, args []string) error {
 
 	return nil
 }
+
+func processResealHolders(options *lib.Holders, args []string) error {
+	var flagSet = flag.NewFlagSet(subHolders, flag.ExitOnError)
+
+	options.Path = flagSet.String("path", "", "path to holders file with holder passphrases or X25519 private keys; missing secrets are prompted for (not required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subHolders, err)
+	}
+
+	return nil
+}
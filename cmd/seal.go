@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/namelesscorp/tvault-core/compression"
+	"github.com/namelesscorp/tvault-core/compression/zip"
 	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/lib"
 	"github.com/namelesscorp/tvault-core/seal"
@@ -12,19 +15,19 @@ import (
 )
 
 const usageSealTemplate = "usage: tvault-core seal <subcommand> [options]\n" +
-	"available subcommands: [%s | %s | %s | %s | %s | %s | %s]"
+	"available subcommands: [%s | %s | %s | %s | %s | %s | %s | %s | %s | %s]"
 
 // handleSeal - processing "seal" subcommand
 // - parse args
 // - validate options
 // - seal
-func handleSeal(args []string) (*lib.Writer, error) {
+func handleSeal(ctx context.Context, args []string) (*lib.Writer, error) {
 	var options = createDefaultSealOptions()
 	if len(args) < 1 {
 		return options.LogWriter, fmt.Errorf(
 			usageSealTemplate,
 			subContainer, subToken, subCompression, subIntegrityProvider,
-			subShamir, subTokenWriter, subLogWriter,
+			subShamir, subTokenWriter, subRecoveryWriter, subLogWriter, subHolders, subKMS,
 		)
 	}
 
@@ -39,12 +42,13 @@ func handleSeal(args []string) (*lib.Writer, error) {
 	if !usedSubcommands[subContainer] {
 		return options.LogWriter, fmt.Errorf(lib.ErrSubcommandRequired, subContainer, commandSeal)
 	}
+	applySealStreamDefaults(&options, usedSubcommands)
 
 	if err = options.Validate(); err != nil {
 		return options.LogWriter, err
 	}
 
-	if err = seal.Seal(options); err != nil {
+	if err = seal.Seal(ctx, options); err != nil {
 		return options.LogWriter, err
 	}
 
@@ -55,16 +59,27 @@ func handleSeal(args []string) (*lib.Writer, error) {
 func createDefaultSealOptions() seal.Options {
 	return seal.Options{
 		Container: &lib.Container{
-			Name:        lib.StringPtr(""),
-			NewPath:     lib.StringPtr(""),
-			CurrentPath: lib.StringPtr(""),
-			FolderPath:  lib.StringPtr(""),
-			Passphrase:  lib.StringPtr(""),
-			Comment:     lib.StringPtr(""),
-			Tags:        lib.StringPtr(""),
+			Name:            lib.StringPtr(""),
+			NewPath:         lib.StringPtr(""),
+			CurrentPath:     lib.StringPtr(""),
+			FolderPath:      lib.StringPtr(""),
+			Passphrase:      lib.StringPtr(""),
+			Comment:         lib.StringPtr(""),
+			Tags:            lib.StringPtr(""),
+			Recovery:        lib.BoolPtr(false),
+			EscrowPublicKey: lib.StringPtr(os.Getenv(lib.EnvEscrowPublicKey)),
+			Overwrite:       lib.StringPtr(lib.OverwriteNever),
+			Resume:          lib.BoolPtr(false),
+			Ignore:          &[]string{},
+			Sources:         &[]string{},
+			Stdin:           lib.BoolPtr(false),
+			Preserve:        lib.StringPtr(lib.PreserveNone),
 		},
 		Token: &lib.Token{
-			Type: lib.StringPtr(token.TypeNameShare),
+			Type:      lib.StringPtr(token.TypeNameShare),
+			Plugin:    lib.StringPtr(""),
+			NotBefore: lib.StringPtr(""),
+			ExpiresAt: lib.StringPtr(""),
 		},
 		Compression: &lib.Compression{
 			Type: lib.StringPtr(compression.TypeNameZip),
@@ -75,15 +90,25 @@ func createDefaultSealOptions() seal.Options {
 			NewPassphrase:     lib.StringPtr(""),
 		},
 		Shamir: &lib.Shamir{
-			Shares:    lib.IntPtr(5),
-			Threshold: lib.IntPtr(3),
-			IsEnabled: lib.BoolPtr(true),
+			Shares:     lib.IntPtr(5),
+			Threshold:  lib.IntPtr(3),
+			IsEnabled:  lib.BoolPtr(true),
+			PolicyPath: lib.StringPtr(""),
 		},
 		TokenWriter: &lib.Writer{
 			Type:   lib.StringPtr(lib.WriterTypeStdout),
 			Path:   lib.StringPtr(""),
 			Format: lib.StringPtr(lib.WriterFormatJSON),
 		},
+		RecoveryWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{
+			Path: lib.StringPtr(""),
+		},
+		KMS: createDefaultKMSOptions(),
 		LogWriter: &lib.Writer{
 			Type:   lib.StringPtr(lib.WriterTypeStdout),
 			Path:   lib.StringPtr(""),
@@ -92,6 +117,29 @@ func createDefaultSealOptions() seal.Options {
 	}
 }
 
+// applySealStreamDefaults - container -stdin defaults the compression type to
+// raw, and a container written to stdout moves the writers left at their
+// defaults to stderr.
+func applySealStreamDefaults(options *seal.Options, usedSubcommands map[string]bool) {
+	if *options.Container.Stdin && !usedSubcommands[subCompression] {
+		*options.Compression.Type = compression.TypeNameRaw
+	}
+
+	if *options.Container.NewPath != lib.StdioPath {
+		return
+	}
+
+	for subcommand, writer := range map[string]*lib.Writer{
+		subTokenWriter:    options.TokenWriter,
+		subRecoveryWriter: options.RecoveryWriter,
+		subLogWriter:      options.LogWriter,
+	} {
+		if !usedSubcommands[subcommand] {
+			*writer.Type = lib.WriterTypeStderr
+		}
+	}
+}
+
 // parseSealSubcommands - parse "seal" args
 func parseSealSubcommands(args []string, options *seal.Options) (map[string]bool, error) {
 	var usedSubcommands = make(map[string]bool)
@@ -129,10 +177,22 @@ func parseSealSubcommands(args []string, options *seal.Options) (map[string]bool
 			if err := processSealTokenWriter(options.TokenWriter, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subRecoveryWriter:
+			if err := processSealRecoveryWriter(options.RecoveryWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
 		case subLogWriter:
 			if err := processSealLogWriter(options.LogWriter, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subHolders:
+			if err := processSealHolders(options.Holders, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subKMS:
+			if err := processKMS(options.KMS, subcommandArgs, true); err != nil {
+				return nil, err
+			}
 		default:
 			return usedSubcommands, fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
 		}
@@ -148,11 +208,25 @@ func processSealContainer(options *lib.Container, args []string) error {
 	var flagSet = flag.NewFlagSet(subContainer, flag.ExitOnError)
 
 	options.Name = flagSet.String("name", "", "container name (not required); default: container path name")
-	options.NewPath = flagSet.String("new-path", "", "new path to save container file (required); default: empty")
-	options.FolderPath = flagSet.String("folder-path", "", "path to folder for seal (required); default: empty")
+	options.NewPath = flagSet.String("new-path", "", "new path to save container file, or - to write it to stdout (required); default: empty")
+	options.FolderPath = flagSet.String("folder-path", "", "path to folder for seal, packed at the archive root (required without -source or -stdin); default: empty")
 	options.Passphrase = flagSet.String("passphrase", "", "container passphrase (required); default: empty")
 	options.Comment = flagSet.String("comment", "", "container comment (not required); default: created by trust vault core")
 	options.Tags = flagSet.String("tags", "", "container tags, comma separated (not required); default: empty)")
+	options.EscrowPublicKey = flagSet.String("escrow-public-key", os.Getenv(lib.EnvEscrowPublicKey), "hex X25519 escrow public key the key is also wrapped to (not required); default: $"+lib.EnvEscrowPublicKey)
+	options.Recovery = flagSet.Bool("recovery-code", false, "also wrap the key to a recovery code, shown once by recovery-writer (not required); default: false")
+	options.Overwrite = flagSet.String("overwrite", lib.OverwriteNever, "what to do when -new-path exists [never | always | backup]: never refuses, always replaces it, backup keeps the old container as <path>.bak (not required); default: never")
+	options.Resume = flagSet.Bool("resume", false, "continue an interrupted seal to -new-path from its journal; the folder and options must be unchanged (not required); default: false")
+	options.Stdin = flagSet.Bool("stdin", false, "seal stdin as a raw payload instead of files, e.g. a database dump; sets compression -type=raw (not required); default: false")
+	options.Preserve = flagSet.String("preserve", lib.PreserveNone, "filesystem metadata to keep [none | all]: none packs file content, symlinks and modes, all also keeps directories, modification times, ownership, extended attributes and hard links (not required); default: none")
+	options.Ignore = ignoreFlags(flagSet)
+
+	sources := []string{}
+	flagSet.Func("source", "file or folder to seal as <path>[:<prefix>], packed under the archive path prefix; repeatable (required without -folder-path or -stdin); default: empty", func(spec string) error {
+		sources = append(sources, spec)
+		return nil
+	})
+	options.Sources = &sources
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subContainer, err)
@@ -161,11 +235,31 @@ func processSealContainer(options *lib.Container, args []string) error {
 	return nil
 }
 
+// ignoreFlags - registers the repeatable -exclude and -include flags of seal
+// and reseal, which collect gitignore-syntax rules in command-line order.
+func ignoreFlags(flagSet *flag.FlagSet) *[]string {
+	rules := []string{}
+
+	flagSet.Func("exclude", "gitignore-syntax pattern of paths to leave out, after those of the folder's "+zip.IgnoreFile+"; repeatable (not required); default: empty", func(pattern string) error {
+		rules = append(rules, pattern)
+		return nil
+	})
+	flagSet.Func("include", "gitignore-syntax pattern of paths to keep despite an earlier exclude; repeatable (not required); default: empty", func(pattern string) error {
+		rules = append(rules, "!"+pattern)
+		return nil
+	})
+
+	return &rules
+}
+
 // processSealToken - parse "token" args
 func processSealToken(options *lib.Token, args []string) error {
 	var flagSet = flag.NewFlagSet(subToken, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", token.TypeNameShare, "type [none | share | master] (share required for shamir -is-enabled=true); default: share")
+	options.Type = flagSet.String("type", token.TypeNameShare, "type [none | share | master | plugin | kms] (share required for shamir -is-enabled=true); default: share")
+	options.Plugin = flagSet.String("plugin", "", "key provider run as tvault-keyprovider-<name> from PATH (required for -type=plugin); default: empty")
+	options.NotBefore = flagSet.String("token-not-before", "", "RFC 3339 time before which tokens are rejected (not required); default: empty")
+	options.ExpiresAt = flagSet.String("token-expires-at", "", "RFC 3339 time from which tokens are rejected (not required); default: empty")
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subToken, err)
@@ -178,7 +272,7 @@ func processSealToken(options *lib.Token, args []string) error {
 func processSealCompression(options *lib.Compression, args []string) error {
 	var flagSet = flag.NewFlagSet(subCompression, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", compression.TypeNameZip, "compression type [zip]; default: zip")
+	options.Type = flagSet.String("type", compression.TypeNameZip, "compression type [zip | none | raw], raw for container -stdin only; default: zip, or raw with container -stdin")
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subCompression, err)
@@ -191,7 +285,7 @@ func processSealCompression(options *lib.Compression, args []string) error {
 func processSealIntegrityProvider(options *lib.IntegrityProvider, args []string) error {
 	var flagSet = flag.NewFlagSet(subIntegrityProvider, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", integrity.TypeNameHMAC, "type [none | hmac]; default: hmac")
+	options.Type = flagSet.String("type", integrity.TypeNameHMAC, "type [none | hmac | feldman]; default: hmac")
 	options.NewPassphrase = flagSet.String("new-passphrase", "", "new passphrase (required for -type=hmac); default: empty")
 
 	if err := flagSet.Parse(args); err != nil {
@@ -208,6 +302,7 @@ func processSealShamir(options *lib.Shamir, args []string) error {
 	options.Shares = flagSet.Int("shares", 5, "number of shares (required for -is-enabled=true); default: 5")
 	options.Threshold = flagSet.Int("threshold", 3, "threshold of shares (required for -is-enabled=true); default: 3)")
 	options.IsEnabled = flagSet.Bool("is-enabled", true, "enable shamir (required for token -type=share); default: true)")
+	options.PolicyPath = flagSet.String("policy-path", "", "path to sharing policy JSON with groups, thresholds and weights; overrides -shares and -threshold (not required); default: empty")
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subShamir, err)
@@ -220,7 +315,7 @@ func processSealShamir(options *lib.Shamir, args []string) error {
 func processSealTokenWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subTokenWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]; default: stdout")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
 
@@ -231,11 +326,26 @@ func processSealTokenWriter(options *lib.Writer, args []string) error {
 	return nil
 }
 
+// processSealRecoveryWriter - parse "recovery-writer" args
+func processSealRecoveryWriter(options *lib.Writer, args []string) error {
+	var flagSet = flag.NewFlagSet(subRecoveryWriter, flag.ExitOnError)
+
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
+	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
+	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subRecoveryWriter, err)
+	}
+
+	return nil
+}
+
 // processSealLogWriter - parse "log-writer" args
 func processSealLogWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subLogWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]; default: stdout")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
 
@@ -245,3 +355,16 @@ func processSealLogWriter(options *lib.Writer, args []string) error {
 
 	return nil
 }
+
+// processSealHolders - parse "holders" args
+func processSealHolders(options *lib.Holders, args []string) error {
+	var flagSet = flag.NewFlagSet(subHolders, flag.ExitOnError)
+
+	options.Path = flagSet.String("path", "", "path to holders file, one holder per share, each with a passphrase or an X25519 public key (not required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subHolders, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/reseal"
+	"github.com/namelesscorp/tvault-core/verify"
+)
+
+const usageTokenTemplate = "usage: tvault-core token <subcommand> [options]\n" +
+	"available subcommands: [%s | %s | %s]; with [%s | %s | %s | %s | %s | %s]"
+
+func handleToken(ctx context.Context, args []string) (*lib.Writer, error) {
+	if len(args) < 1 {
+		return createDefaultTokenOptions().LogWriter, fmt.Errorf(
+			usageTokenTemplate,
+			subInspect, subVerify, subReshare,
+			subIntegrityProvider, subTokenReader, subTokenWriter, subHolders, subInfoWriter, subLogWriter,
+		)
+	}
+
+	switch args[0] {
+	case subInspect:
+		return handleTokenInspect(args)
+	case subReshare:
+		return handleTokenReshare(ctx, args)
+	}
+
+	return handleTokenVerify(args)
+}
+
+func handleTokenVerify(args []string) (*lib.Writer, error) {
+	var (
+		options         = createDefaultTokenOptions()
+		usedSubcommands map[string]bool
+		err             error
+	)
+	if usedSubcommands, err = parseTokenSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if !usedSubcommands[subVerify] {
+		return options.LogWriter, fmt.Errorf(lib.ErrSubcommandRequired, subVerify, commandToken)
+	}
+
+	if err = options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err = verify.Verify(options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
+func handleTokenInspect(args []string) (*lib.Writer, error) {
+	var options = createDefaultInspectOptions()
+	if err := parseInspectSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := verify.Inspect(options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
+func handleTokenReshare(ctx context.Context, args []string) (*lib.Writer, error) {
+	var options = createDefaultReshareOptions()
+	if err := parseReshareSubcommands(args, &options); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return options.LogWriter, err
+	}
+
+	if err := reseal.Reshare(ctx, options); err != nil {
+		return options.LogWriter, err
+	}
+
+	return options.LogWriter, nil
+}
+
+func createDefaultTokenOptions() verify.Options {
+	return verify.Options{
+		Container: &lib.Container{
+			CurrentPath: lib.StringPtr(""),
+		},
+		IntegrityProvider: &lib.IntegrityProvider{
+			CurrentPassphrase: lib.StringPtr(""),
+		},
+		TokenReader: &lib.Reader{
+			Type:   lib.StringPtr(lib.ReaderTypeFlag),
+			Path:   lib.StringPtr(""),
+			Flag:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{
+			Path: lib.StringPtr(""),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+}
+
+func parseTokenSubcommands(args []string, options *verify.Options) (map[string]bool, error) {
+	var usedSubcommands = make(map[string]bool)
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		usedSubcommands[subcommand] = true
+
+		switch subcommand {
+		case subVerify:
+			if err := processTokenVerify(options.Container, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subIntegrityProvider:
+			if err := processUnsealIntegrityProvider(options.IntegrityProvider, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subTokenReader:
+			if err := processUnsealTokenReader(options.TokenReader, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subHolders:
+			if err := processUnsealHolders(options.Holders, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subInfoWriter:
+			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subLogWriter:
+			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
+		default:
+			return usedSubcommands, fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return usedSubcommands, nil
+}
+
+func processTokenVerify(options *lib.Container, args []string) error {
+	var flagSet = flag.NewFlagSet(subVerify, flag.ExitOnError)
+
+	options.CurrentPath = flagSet.String("container", "", "path to container to check the shares against (required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subVerify, err)
+	}
+
+	return nil
+}
+
+func createDefaultInspectOptions() verify.InspectOptions {
+	return verify.InspectOptions{
+		Container: &lib.Container{
+			CurrentPath: lib.StringPtr(""),
+		},
+		IntegrityProvider: &lib.IntegrityProvider{
+			CurrentPassphrase: lib.StringPtr(""),
+		},
+		TokenReader: &lib.Reader{
+			Type:   lib.StringPtr(lib.ReaderTypeFlag),
+			Path:   lib.StringPtr(""),
+			Flag:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{
+			Path: lib.StringPtr(""),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+}
+
+func parseInspectSubcommands(args []string, options *verify.InspectOptions) error {
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		switch subcommand {
+		case subInspect:
+			if err := processTokenInspect(options.Container, subcommandArgs); err != nil {
+				return err
+			}
+		case subIntegrityProvider:
+			if err := processUnsealIntegrityProvider(options.IntegrityProvider, subcommandArgs); err != nil {
+				return err
+			}
+		case subTokenReader:
+			if err := processUnsealTokenReader(options.TokenReader, subcommandArgs); err != nil {
+				return err
+			}
+		case subHolders:
+			if err := processUnsealHolders(options.Holders, subcommandArgs); err != nil {
+				return err
+			}
+		case subInfoWriter:
+			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return err
+			}
+		case subLogWriter:
+			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return nil
+}
+
+func processTokenInspect(options *lib.Container, args []string) error {
+	var flagSet = flag.NewFlagSet(subInspect, flag.ExitOnError)
+
+	options.CurrentPath = flagSet.String("container", "", "path to container whose salt opens encrypted tokens (optional); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subInspect, err)
+	}
+
+	return nil
+}
+
+func createDefaultReshareOptions() reseal.ReshareOptions {
+	return reseal.ReshareOptions{
+		Container: &lib.Container{
+			CurrentPath: lib.StringPtr(""),
+		},
+		IntegrityProvider: &lib.IntegrityProvider{
+			CurrentPassphrase: lib.StringPtr(""),
+			NewPassphrase:     lib.StringPtr(""),
+		},
+		Shamir: &lib.Shamir{
+			Shares:    lib.IntPtr(0),
+			Threshold: lib.IntPtr(0),
+		},
+		TokenReader: &lib.Reader{
+			Type:   lib.StringPtr(lib.ReaderTypeFlag),
+			Path:   lib.StringPtr(""),
+			Flag:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		TokenWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{
+			Path:    lib.StringPtr(""),
+			NewPath: lib.StringPtr(""),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+}
+
+func parseReshareSubcommands(args []string, options *reseal.ReshareOptions) error {
+	for i := 0; i < len(args); {
+		var (
+			subcommand          = args[i]
+			nextSubcommandIndex = findNextSubcommand(args, i+1)
+			subcommandArgs      = args[i+1 : nextSubcommandIndex]
+		)
+
+		switch subcommand {
+		case subReshare:
+			if err := processTokenReshare(options, subcommandArgs); err != nil {
+				return err
+			}
+		case subIntegrityProvider:
+			if err := processResealIntegrityProvider(options.IntegrityProvider, subcommandArgs); err != nil {
+				return err
+			}
+		case subTokenReader:
+			if err := processUnsealTokenReader(options.TokenReader, subcommandArgs); err != nil {
+				return err
+			}
+		case subTokenWriter:
+			if err := processResealTokenWriter(options.TokenWriter, subcommandArgs); err != nil {
+				return err
+			}
+		case subHolders:
+			if err := processReshareHolders(options.Holders, subcommandArgs); err != nil {
+				return err
+			}
+		case subLogWriter:
+			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
+		}
+
+		i = nextSubcommandIndex
+	}
+
+	return nil
+}
+
+func processTokenReshare(options *reseal.ReshareOptions, args []string) error {
+	var flagSet = flag.NewFlagSet(subReshare, flag.ExitOnError)
+
+	options.Container.CurrentPath = flagSet.String("container", "", "path to container to reshare (required); default: empty")
+	options.Shamir.Shares = flagSet.Int("shares", 0, "number of new shares (required); default: 0")
+	options.Shamir.Threshold = flagSet.Int("threshold", 0, "number of new shares needed to unseal (required); default: 0")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subReshare, err)
+	}
+
+	return nil
+}
+
+func processReshareHolders(options *lib.Holders, args []string) error {
+	var flagSet = flag.NewFlagSet(subHolders, flag.ExitOnError)
+
+	options.Path = flagSet.String("path", "", "path to holders file with holder passphrases or X25519 private keys opening the current shares (not required); default: empty")
+	options.NewPath = flagSet.String("new-path", "", "path to holders file with one holder per new share (required when the current shares are sealed per holder); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subHolders, err)
+	}
+
+	return nil
+}
@@ -1,22 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
 
+	"github.com/namelesscorp/tvault-core/agent"
 	"github.com/namelesscorp/tvault-core/lib"
 	"github.com/namelesscorp/tvault-core/unseal"
 )
 
 const usageUnsealTemplate = "usage: tvault-core unseal <subcommand> [options]\n" +
-	"available subcommands: [%s | %s | %s | %s]"
+	"available subcommands: [%s | %s | %s | %s | %s | %s | %s | %s | %s]"
 
-func handleUnseal(args []string) (*lib.Writer, error) {
+func handleUnseal(ctx context.Context, args []string) (*lib.Writer, error) {
 	var options = createDefaultUnsealOptions()
 	if len(args) < 1 {
 		return options.LogWriter, fmt.Errorf(
 			usageUnsealTemplate,
-			subContainer, subIntegrityProvider, subTokenReader, subLogWriter,
+			subContainer, subIntegrityProvider, subTokenReader, subInfoWriter, subLogWriter, subHolders, subAgent, subKMS, subLimits,
 		)
 	}
 
@@ -30,12 +33,13 @@ func handleUnseal(args []string) (*lib.Writer, error) {
 	if !usedSubcommands[subContainer] {
 		return options.LogWriter, fmt.Errorf(lib.ErrSubcommandRequired, subContainer, commandUnseal)
 	}
+	applyUnsealStreamDefaults(&options, usedSubcommands)
 
 	if err = options.Validate(); err != nil {
 		return options.LogWriter, err
 	}
 
-	if err = unseal.Unseal(options); err != nil {
+	if err = unseal.Unseal(ctx, options); err != nil {
 		return options.LogWriter, err
 	}
 
@@ -45,10 +49,18 @@ func handleUnseal(args []string) (*lib.Writer, error) {
 func createDefaultUnsealOptions() unseal.Options {
 	return unseal.Options{
 		Container: &lib.Container{
-			NewPath:     lib.StringPtr(""),
-			CurrentPath: lib.StringPtr(""),
-			FolderPath:  lib.StringPtr(""),
-			Passphrase:  lib.StringPtr(""),
+			NewPath:       lib.StringPtr(""),
+			CurrentPath:   lib.StringPtr(""),
+			FolderPath:    lib.StringPtr(""),
+			Passphrase:    lib.StringPtr(""),
+			RecoveryCode:  lib.StringPtr(""),
+			EscrowKeyPath: lib.StringPtr(""),
+			Resume:        lib.BoolPtr(false),
+			Stdout:        lib.BoolPtr(false),
+			OnConflict:    lib.StringPtr(lib.ConflictFail),
+			DryRun:        lib.BoolPtr(false),
+			Symlinks:      lib.StringPtr(lib.SymlinksContain),
+			Preserve:      lib.StringPtr(lib.PreserveNone),
 		},
 		IntegrityProvider: &lib.IntegrityProvider{
 			Type:              lib.StringPtr(""),
@@ -61,6 +73,19 @@ func createDefaultUnsealOptions() unseal.Options {
 			Flag:   lib.StringPtr(""),
 			Format: lib.StringPtr(lib.WriterFormatJSON),
 		},
+		Holders: &lib.Holders{
+			Path: lib.StringPtr(""),
+		},
+		Agent: &lib.Agent{
+			Socket: lib.StringPtr(os.Getenv(agent.EnvSocket)),
+		},
+		KMS:    createDefaultKMSOptions(),
+		Limits: createDefaultLimitsOptions(),
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
 		LogWriter: &lib.Writer{
 			Type:   lib.StringPtr(lib.WriterTypeStdout),
 			Path:   lib.StringPtr(""),
@@ -69,6 +94,23 @@ func createDefaultUnsealOptions() unseal.Options {
 	}
 }
 
+func createDefaultLimitsOptions() *lib.Limits {
+	return &lib.Limits{
+		MaxFiles: lib.Int64Ptr(lib.DefaultMaxFiles),
+		MaxBytes: lib.Int64Ptr(0),
+		MaxRatio: lib.Int64Ptr(0),
+		MaxDepth: lib.IntPtr(lib.DefaultMaxDepth),
+	}
+}
+
+// applyUnsealStreamDefaults - container -stdout moves a log writer left at its
+// default to stderr, off the payload.
+func applyUnsealStreamDefaults(options *unseal.Options, usedSubcommands map[string]bool) {
+	if *options.Container.Stdout && !usedSubcommands[subLogWriter] {
+		*options.LogWriter.Type = lib.WriterTypeStderr
+	}
+}
+
 func parseUnsealSubcommands(args []string, options *unseal.Options) (map[string]bool, error) {
 	var usedSubcommands = make(map[string]bool)
 	for i := 0; i < len(args); {
@@ -93,10 +135,30 @@ func parseUnsealSubcommands(args []string, options *unseal.Options) (map[string]
 			if err := processUnsealTokenReader(options.TokenReader, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subInfoWriter:
+			if err := processContainerInfoWriter(options.InfoWriter, subcommandArgs); err != nil {
+				return nil, err
+			}
 		case subLogWriter:
 			if err := processUnsealLogWriter(options.LogWriter, subcommandArgs); err != nil {
 				return nil, err
 			}
+		case subHolders:
+			if err := processUnsealHolders(options.Holders, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subAgent:
+			if err := processAgentSocket(options.Agent, subcommandArgs); err != nil {
+				return nil, err
+			}
+		case subKMS:
+			if err := processKMS(options.KMS, subcommandArgs, false); err != nil {
+				return nil, err
+			}
+		case subLimits:
+			if err := processUnsealLimits(options.Limits, subcommandArgs); err != nil {
+				return nil, err
+			}
 		default:
 			return usedSubcommands, fmt.Errorf(lib.ErrUnknownSubcommand, subcommand)
 		}
@@ -111,8 +173,15 @@ func processUnsealContainer(options *lib.Container, args []string) error {
 	var flagSet = flag.NewFlagSet(subContainer, flag.ExitOnError)
 
 	options.CurrentPath = flagSet.String("current-path", "", "current path to container file (required); default: empty")
-	options.FolderPath = flagSet.String("folder-path", "", "path to folder for unseal (required); default: empty")
+	options.FolderPath = flagSet.String("folder-path", "", "path to folder for unseal (required without -stdout); default: empty")
+	options.Stdout = flagSet.Bool("stdout", false, "write the payload of a container sealed with -stdin to stdout (required for such containers); default: false")
 	options.Passphrase = flagSet.String("passphrase", "", "passphrase to decrypt container file (required for seal token -type=none); default: empty")
+	options.RecoveryCode = flagSet.String("recovery-code", "", "recovery code shown by seal container -recovery-code; replaces tokens and passphrase (not required); default: empty")
+	options.Resume = flagSet.Bool("resume", false, "continue an interrupted unseal to -folder-path, keeping the files it already extracted (not required); default: false")
+	options.OnConflict = flagSet.String("on-conflict", lib.ConflictFail, "what to do with files already in -folder-path [fail | skip | overwrite | rename | newer] (not required); default: fail")
+	options.DryRun = flagSet.Bool("dry-run", false, "list what would be written or overwritten in -folder-path without writing anything (not required); default: false")
+	options.Symlinks = flagSet.String("symlinks", lib.SymlinksContain, "what to do with symlinks [reject | skip | contain | allow]; contain refuses targets outside -folder-path (not required); default: contain")
+	options.Preserve = flagSet.String("preserve", lib.PreserveNone, "metadata recorded by seal -preserve=all to restore [none | all]: none drops ownership and setuid, setgid and sticky bits, all restores them, ownership only as root (not required); default: none")
 
 	if err := flagSet.Parse(args); err != nil {
 		return fmt.Errorf(lib.ErrFailedParseFlags, subContainer, err)
@@ -151,7 +220,7 @@ func processUnsealTokenReader(options *lib.Reader, args []string) error {
 func processUnsealLogWriter(options *lib.Writer, args []string) error {
 	var flagSet = flag.NewFlagSet(subLogWriter, flag.ExitOnError)
 
-	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout]; default: stdout")
+	options.Type = flagSet.String("type", lib.WriterTypeStdout, "type [file | stdout | stderr]; default: stdout")
 	options.Path = flagSet.String("path", "", "path to file (required for -type=file); default: empty")
 	options.Format = flagSet.String("format", lib.WriterFormatJSON, "format [plaintext | json]; default: json")
 
@@ -161,3 +230,32 @@ func processUnsealLogWriter(options *lib.Writer, args []string) error {
 
 	return nil
 }
+
+func processUnsealHolders(options *lib.Holders, args []string) error {
+	var flagSet = flag.NewFlagSet(subHolders, flag.ExitOnError)
+
+	options.Path = flagSet.String("path", "", "path to holders file with holder passphrases or X25519 private keys; missing secrets are prompted for (not required); default: empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subHolders, err)
+	}
+
+	return nil
+}
+
+// processUnsealLimits - parses the limits subcommand of unseal and escrow
+// unseal.
+func processUnsealLimits(options *lib.Limits, args []string) error {
+	var flagSet = flag.NewFlagSet(subLimits, flag.ExitOnError)
+
+	options.MaxFiles = flagSet.Int64("max-files", lib.DefaultMaxFiles, "most entries the container may extract, directories included; 0 for no limit (not required); default: 1000000")
+	options.MaxBytes = flagSet.Int64("max-bytes", 0, "most bytes the container may extract in total; 0 for no limit (not required); default: 0")
+	options.MaxRatio = flagSet.Int64("max-ratio", 0, "most uncompressed bytes per compressed byte of one entry; 0 for no limit (not required); default: 0")
+	options.MaxDepth = flagSet.Int("max-depth", lib.DefaultMaxDepth, "most path segments of an entry; 0 for no limit (not required); default: 256")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf(lib.ErrFailedParseFlags, subLimits, err)
+	}
+
+	return nil
+}
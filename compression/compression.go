@@ -1,18 +1,27 @@
 package compression
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 const (
 	TypeNone byte = 0x00
 	TypeZip  byte = 0x01
+	// TypeRaw - a single unnamed stream stored as is, without an archive. It
+	// is what seal container -stdin produces and has no Compression
+	// implementation.
+	TypeRaw byte = 0x02
 
 	TypeNameNone string = "none"
 	TypeNameZip  string = "zip"
+	TypeNameRaw  string = "raw"
 )
 
 var Types = map[string]struct{}{
 	TypeNameNone: {},
 	TypeNameZip:  {},
+	TypeNameRaw:  {},
 }
 
 // Compression bundles a folder into a single stream and back. Both supported
@@ -22,8 +31,8 @@ type Compression interface {
 	Pack(folder string) ([]byte, error)
 	Unpack(data []byte, targetDir string) error
 
-	PackTo(folder string, w io.Writer) error
-	UnpackFrom(r io.ReaderAt, size int64, targetDir string) error
+	PackTo(ctx context.Context, folder string, w io.Writer) error
+	UnpackFrom(ctx context.Context, r io.ReaderAt, size int64, targetDir string) error
 
 	ID() byte
 	GetUncompressedSize() int64
@@ -39,6 +48,8 @@ func ConvertIDToName(id byte) string {
 		return TypeNameNone
 	case TypeZip:
 		return TypeNameZip
+	case TypeRaw:
+		return TypeNameRaw
 	default:
 		return ""
 	}
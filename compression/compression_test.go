@@ -20,6 +20,11 @@ func TestConvertIDToName(t *testing.T) {
 			id:       TypeZip,
 			expected: TypeNameZip,
 		},
+		{
+			name:     "TypeRaw",
+			id:       TypeRaw,
+			expected: TypeNameRaw,
+		},
 		{
 			name:     "Unknown type",
 			id:       0xFF,
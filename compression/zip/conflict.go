@@ -0,0 +1,208 @@
+package zip
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// ExtractReport - what UnpackFrom extracted, or with a dry run would extract,
+// by archive path: the entries whose path was free, those whose path
+// already existed, and the symlinks lib.SymlinksSkip left out. Directories
+// are not listed.
+type ExtractReport struct {
+	Created         []string   `json:"created"`
+	Conflicts       []Conflict `json:"conflicts"`
+	SkippedSymlinks []string   `json:"skipped_symlinks"`
+}
+
+// Conflict - an entry whose path already existed, with the action taken:
+// lib.ConflictOverwrite, lib.ConflictSkip, lib.ConflictRename with the archive
+// path it was written to instead, or lib.ConflictFail.
+type Conflict struct {
+	Path      string `json:"path"`
+	Action    string `json:"action"`
+	RenamedTo string `json:"renamed_to,omitempty"`
+}
+
+// SetConflictPolicy registers what UnpackFrom does with an entry whose path
+// already exists in the target: lib.ConflictFail, lib.ConflictSkip,
+// lib.ConflictOverwrite (the default), lib.ConflictRename or
+// lib.ConflictNewer. With dryRun set UnpackFrom writes nothing and only fills
+// the report. Like SetProgress it is reached via a type assertion.
+func (z *zip) SetConflictPolicy(policy string, dryRun bool) {
+	z.onConflict = policy
+	z.dryRun = dryRun
+}
+
+// SetExtractReport registers the report UnpackFrom fills. Like SetProgress it
+// is reached via a type assertion.
+func (z *zip) SetExtractReport(report *ExtractReport) {
+	z.report = report
+}
+
+// planEntries - decides for each job and hard link what happens to a path
+// that already exists, as the conflict policy says, and records it in the
+// report. It returns the jobs and links to extract, with a renamed entry
+// pointed at its new path and a hard link at the final path of its file. With
+// lib.ConflictFail every conflict is listed before an error is returned, so
+// nothing has been written yet.
+func (z *zip) planEntries(targetDir string, jobs, links []unpackJob) (planned, plannedLinks []unpackJob, err error) {
+	var (
+		taken  = make(map[string]bool, len(jobs)+len(links))
+		final  = make(map[string]string, len(jobs))
+		failed []string
+	)
+	for _, j := range jobs {
+		taken[j.dst] = true
+	}
+	for _, j := range links {
+		taken[j.dst] = true
+	}
+
+	plan := func(j unpackJob) (unpackJob, bool, error) {
+		action, err := z.conflictAction(j)
+		if err != nil {
+			return j, false, err
+		}
+
+		switch action {
+		case "":
+			z.report.created(j.file.Name)
+			return j, true, nil
+		case lib.ConflictFail:
+			failed = append(failed, j.file.Name)
+			z.report.conflict(Conflict{Path: j.file.Name, Action: action})
+			return j, false, nil
+		case lib.ConflictSkip:
+			z.report.conflict(Conflict{Path: j.file.Name, Action: action})
+			if z.progress != nil {
+				z.progress(int64(j.file.UncompressedSize64)) // #nosec G115
+			}
+			return j, false, nil
+		case lib.ConflictRename:
+			j.dst = freePath(j.dst, taken)
+			taken[j.dst] = true
+
+			rel, err := filepath.Rel(targetDir, j.dst)
+			if err != nil {
+				return j, false, lib.InternalErr(lib.CategoryCompression, lib.ErrCodeGetFilePathRelative, lib.ErrMessageGetFilePathRelative, "", err)
+			}
+			z.report.conflict(Conflict{Path: j.file.Name, Action: action, RenamedTo: filepath.ToSlash(rel)})
+		default:
+			z.report.conflict(Conflict{Path: j.file.Name, Action: action})
+		}
+		j.replace = true
+
+		return j, true, nil
+	}
+
+	for _, j := range jobs {
+		j, extract, err := plan(j)
+		if err != nil {
+			return nil, nil, err
+		}
+		final[j.file.Name] = j.dst
+		if extract {
+			planned = append(planned, j)
+		}
+	}
+
+	for _, j := range links {
+		j, extract, err := plan(j)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dst, ok := final[j.meta.Link]; ok {
+			j.linkTo = dst
+		}
+		if extract {
+			plannedLinks = append(plannedLinks, j)
+		}
+	}
+
+	if len(failed) > 0 && !z.dryRun {
+		return nil, nil, lib.NewError(
+			lib.ErrorTypeValidation,
+			lib.CategoryCompression,
+			lib.ErrCodeExtractConflict,
+			lib.ErrExtractConflict.Error(),
+			strings.Join(failed, ", "),
+			lib.SuggestionExtractConflict,
+			lib.ErrExtractConflict,
+		)
+	}
+
+	return planned, plannedLinks, nil
+}
+
+// conflictAction - the action for an entry whose path exists, or empty when
+// it is free. An entry the extract log holds was written by the interrupted
+// extraction, so it is overwritten whatever the policy; lib.ConflictNewer
+// overwrites only with an entry modified after the file on disk.
+func (z *zip) conflictAction(j unpackJob) (string, error) {
+	existing, err := os.Lstat(j.dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+	}
+
+	if z.extractLog != nil {
+		if _, ok := z.extractLog.Done(j.file.Name); ok {
+			return lib.ConflictOverwrite, nil
+		}
+	}
+
+	switch z.onConflict {
+	case "":
+		return lib.ConflictOverwrite, nil
+	case lib.ConflictNewer:
+		if entryModTime(j).After(existing.ModTime()) {
+			return lib.ConflictOverwrite, nil
+		}
+		return lib.ConflictSkip, nil
+	}
+
+	return z.onConflict, nil
+}
+
+// entryModTime - the modification time of the entry: the recorded one of
+// lib.PreserveAll, or that of the zip header.
+func entryModTime(j unpackJob) time.Time {
+	if j.meta != nil {
+		return time.Unix(0, j.meta.ModTime)
+	}
+
+	return j.file.Modified
+}
+
+// freePath - the first of dst.1, dst.2, ... that neither exists nor is taken
+// by another entry.
+func freePath(dst string, taken map[string]bool) string {
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s.%d", dst, n)
+		if _, err := os.Lstat(candidate); errors.Is(err, fs.ErrNotExist) && !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+func (r *ExtractReport) created(name string) {
+	if r != nil {
+		r.Created = append(r.Created, name)
+	}
+}
+
+func (r *ExtractReport) conflict(c Conflict) {
+	if r != nil {
+		r.Conflicts = append(r.Conflicts, c)
+	}
+}
@@ -0,0 +1,182 @@
+package zip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestZipUnpackFromConflictPolicy(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, data := range map[string]string{"a.txt": "alpha", "b.txt": "bravo"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	packed, err := New().Pack(srcDir)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		policy    string
+		dryRun    bool
+		old       bool
+		wantErr   bool
+		wantA     string
+		wantFiles []string
+		want      ExtractReport
+	}{
+		{
+			name:      "fail",
+			policy:    lib.ConflictFail,
+			wantErr:   true,
+			wantA:     "local",
+			wantFiles: []string{"a.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictFail}}},
+		},
+		{
+			name:      "skip",
+			policy:    lib.ConflictSkip,
+			wantA:     "local",
+			wantFiles: []string{"a.txt", "b.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictSkip}}},
+		},
+		{
+			name:      "overwrite",
+			policy:    lib.ConflictOverwrite,
+			wantA:     "alpha",
+			wantFiles: []string{"a.txt", "b.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictOverwrite}}},
+		},
+		{
+			name:      "rename",
+			policy:    lib.ConflictRename,
+			wantA:     "local",
+			wantFiles: []string{"a.txt", "a.txt.1", "b.txt"},
+			want: ExtractReport{
+				Created:   []string{"b.txt"},
+				Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictRename, RenamedTo: "a.txt.1"}},
+			},
+		},
+		{
+			name:      "newer keeps a newer file",
+			policy:    lib.ConflictNewer,
+			wantA:     "local",
+			wantFiles: []string{"a.txt", "b.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictSkip}}},
+		},
+		{
+			name:      "newer replaces an older file",
+			policy:    lib.ConflictNewer,
+			old:       true,
+			wantA:     "alpha",
+			wantFiles: []string{"a.txt", "b.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictOverwrite}}},
+		},
+		{
+			name:      "dry run",
+			policy:    lib.ConflictFail,
+			dryRun:    true,
+			wantA:     "local",
+			wantFiles: []string{"a.txt"},
+			want:      ExtractReport{Created: []string{"b.txt"}, Conflicts: []Conflict{{Path: "a.txt", Action: lib.ConflictFail}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			aPath := filepath.Join(targetDir, "a.txt")
+			if err := os.WriteFile(aPath, []byte("local"), 0644); err != nil {
+				t.Fatalf("Failed to write local file: %v", err)
+			}
+
+			mtime := time.Now().Add(time.Hour)
+			if tt.old {
+				mtime = time.Unix(1_000_000_000, 0)
+			}
+			if err := os.Chtimes(aPath, mtime, mtime); err != nil {
+				t.Fatalf("Failed to set times: %v", err)
+			}
+
+			var report ExtractReport
+			z := New().(*zip)
+			z.SetConflictPolicy(tt.policy, tt.dryRun)
+			z.SetExtractReport(&report)
+
+			err := z.UnpackFrom(context.Background(), bytes.NewReader(packed), int64(len(packed)), targetDir)
+			if tt.wantErr {
+				if !errors.Is(err, lib.ErrExtractConflict) {
+					t.Fatalf("Expected ErrExtractConflict, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("UnpackFrom failed: %v", err)
+			}
+
+			if got, _ := os.ReadFile(aPath); string(got) != tt.wantA {
+				t.Errorf("Expected a.txt to hold %q, got %q", tt.wantA, got)
+			}
+
+			entries, err := os.ReadDir(targetDir)
+			if err != nil {
+				t.Fatalf("ReadDir failed: %v", err)
+			}
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("Expected files %v, got %v", tt.wantFiles, files)
+			}
+
+			if !reflect.DeepEqual(report, tt.want) {
+				t.Errorf("Expected report %+v, got %+v", tt.want, report)
+			}
+		})
+	}
+}
+
+func TestZipUnpackFromOverwriteReplacesSymlink(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("alpha"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	packed, err := New().Pack(srcDir)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	// Overwriting must replace the link, not write through it out of the target.
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err = os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatalf("Failed to write outside file: %v", err)
+	}
+	targetDir := t.TempDir()
+	if err = os.Symlink(outside, filepath.Join(targetDir, "a.txt")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	z := New().(*zip)
+	z.SetConflictPolicy(lib.ConflictOverwrite, false)
+	if err = z.UnpackFrom(context.Background(), bytes.NewReader(packed), int64(len(packed)), targetDir); err != nil {
+		t.Fatalf("UnpackFrom failed: %v", err)
+	}
+
+	if got, _ := os.ReadFile(outside); string(got) != "outside" {
+		t.Errorf("Expected the link target to be left alone, got %q", got)
+	}
+	if st, errStat := os.Lstat(filepath.Join(targetDir, "a.txt")); errStat != nil || !st.Mode().IsRegular() {
+		t.Errorf("Expected a.txt to be a regular file, stat %v, err %v", st, errStat)
+	}
+}
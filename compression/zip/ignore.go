@@ -0,0 +1,224 @@
+package zip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// IgnoreFile - the file in the root of a sealed folder whose gitignore-syntax
+// rules exclude paths from the archive.
+const IgnoreFile = ".tvaultignore"
+
+// Rules - an ordered list of gitignore-syntax patterns. A path is excluded
+// when the last pattern matching it is not negated with "!". A pattern with a
+// slash before its end is anchored to the folder root, one without matches at
+// any depth, and a trailing slash matches directories only. As in git, a file
+// in an excluded directory cannot be included again, because the directory is
+// not walked.
+type Rules struct {
+	patterns []rulePattern
+}
+
+type rulePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// LoadRules - returns the rules of the IgnoreFile in folder, if there is one,
+// followed by extra, so later extra rules override the file. It returns nil
+// when there are no rules at all.
+func LoadRules(folder string, extra []string) (*Rules, error) {
+	data, err := os.ReadFile(filepath.Join(folder, IgnoreFile)) // #nosec G304
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeIgnoreRulesError, lib.ErrMessageIgnoreRulesError, "", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeIgnoreRulesError, lib.ErrMessageIgnoreRulesError, "", err)
+	}
+
+	rules := ParseRules(append(lines, extra...))
+	if len(rules.patterns) == 0 {
+		return nil, nil
+	}
+
+	return rules, nil
+}
+
+// ParseRules - compiles gitignore-syntax lines, skipping blank lines and
+// comments.
+func ParseRules(lines []string) *Rules {
+	rules := &Rules{}
+	for _, line := range lines {
+		if p, ok := parseRule(line); ok {
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+
+	return rules
+}
+
+// Excluded - reports whether relPath, slash-separated and relative to the
+// folder root, is excluded. A nil Rules excludes nothing.
+func (r *Rules) Excluded(relPath string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+
+	excluded := false
+	for _, p := range r.patterns {
+		if (!p.dirOnly || isDir) && p.re.MatchString(relPath) {
+			excluded = !p.negate
+		}
+	}
+
+	return excluded
+}
+
+func parseRule(line string) (rulePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rulePattern{}, false
+	}
+
+	var p rulePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rulePattern{}, false
+	}
+
+	prefix := "^(?:.*/)?"
+	if strings.Contains(line, "/") {
+		prefix = "^"
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	// Like git, a pattern that does not compile (e.g. a reversed range) never
+	// matches, so it is dropped.
+	re, err := regexp.Compile(prefix + translateGlob(line) + "$")
+	if err != nil {
+		return rulePattern{}, false
+	}
+	p.re = re
+
+	return p, true
+}
+
+// trimTrailingSpaces - drops trailing spaces unless escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
+
+// translateGlob - turns a gitignore glob into a regular expression: "*" and
+// "?" stop at slashes, "**" spans directories when it is a whole path segment,
+// and an unterminated "[" is literal.
+func translateGlob(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case strings.HasPrefix(glob[i:], "**"):
+			start := i == 0 || glob[i-1] == '/'
+			end := i+2 == len(glob) || glob[i+2] == '/'
+			switch {
+			case start && end && i+2 < len(glob):
+				// "**/" matches zero or more directories.
+				b.WriteString("(?:.*/)?")
+				i += 2
+			case start && end:
+				// A trailing "/**" matches everything inside.
+				b.WriteString(".*")
+				i++
+			default:
+				b.WriteString("[^/]*")
+				i++
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			class, n := translateClass(glob[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	return b.String()
+}
+
+// translateClass - translates the bracket expression at the start of glob,
+// returning it and its length, or a zero length when it is not terminated.
+func translateClass(glob string) (string, int) {
+	i := 1
+	negate := i < len(glob) && (glob[i] == '!' || glob[i] == '^')
+	if negate {
+		i++
+	}
+
+	// A "]" right after the opening bracket is a member, not the end.
+	start := i
+	if i < len(glob) && glob[i] == ']' {
+		i++
+	}
+	end := strings.IndexByte(glob[i:], ']')
+	if end < 0 {
+		return "", 0
+	}
+	end += i
+
+	var b strings.Builder
+	b.WriteByte('[')
+	if negate {
+		b.WriteString("^/")
+	}
+	for j := start; j < end; j++ {
+		c := glob[j]
+		if c == '\\' && j+1 < end {
+			j++
+			c = glob[j]
+		}
+		if c == '\\' || c == '[' || c == ']' || c == '^' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(']')
+
+	return b.String(), end + 1
+}
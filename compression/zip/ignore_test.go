@@ -0,0 +1,98 @@
+package zip
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRulesExcluded(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		path    string
+		isDir   bool
+		exclude bool
+	}{
+		{"basename at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"star stops at slash", []string{"a/*.log"}, "a/b/debug.log", false, false},
+		{"anchored to the root", []string{"/build"}, "src/build", true, false},
+		{"anchored match", []string{"/build"}, "build", true, true},
+		{"directory only skips files", []string{"cache/"}, "cache", false, false},
+		{"directory only", []string{"cache/"}, "x/cache", true, true},
+		{"double star spans directories", []string{"a/**/z.txt"}, "a/b/c/z.txt", false, true},
+		{"double star matches no directory", []string{"a/**/z.txt"}, "a/z.txt", false, true},
+		{"trailing double star", []string{"a/**"}, "a/b/c", false, true},
+		{"negation wins when last", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"last match wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"character class", []string{"file[0-9].txt"}, "file7.txt", false, true},
+		{"negated class", []string{"file[!0-9].txt"}, "file7.txt", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"comment", []string{"# secret"}, "# secret", false, false},
+		{"unterminated class is literal", []string{"a[b"}, "a[b", false, true},
+		{"invalid range never matches", []string{"[z-a]"}, "m", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRules(tt.rules).Excluded(tt.path, tt.isDir); got != tt.exclude {
+				t.Errorf("Excluded(%q) with %q = %v, want %v", tt.path, tt.rules, got, tt.exclude)
+			}
+		})
+	}
+}
+
+func TestWalkFolderWithRules(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		IgnoreFile:                  "node_modules/\n*.log\n",
+		"main.go":                   "package main",
+		"debug.log":                 "noise",
+		"keep.log":                  "kept",
+		"node_modules/pkg/index.js": "module",
+		"build/out.bin":             "binary",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := LoadRules(tempDir, []string{"!keep.log", "build"})
+	if err != nil {
+		t.Fatalf("LoadRules failed: %v", err)
+	}
+
+	entries, _, count, excluded, names, err := WalkFolderWithRules(tempDir, rules)
+	if err != nil {
+		t.Fatalf("WalkFolderWithRules failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.RelPath)
+	}
+	want := []string{IgnoreFile, "keep.log", "main.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Packed entries = %q, want %q", got, want)
+	}
+	if count != 3 || len(names) != 3 {
+		t.Errorf("Expected 3 files, got count=%d names=%d", count, len(names))
+	}
+	// debug.log, build and node_modules; the content of an excluded directory
+	// is not counted.
+	if excluded != 3 {
+		t.Errorf("Expected 3 excluded paths, got %d", excluded)
+	}
+}
+
+func TestLoadRulesWithoutRules(t *testing.T) {
+	rules, err := LoadRules(t.TempDir(), nil)
+	if err != nil || rules != nil {
+		t.Errorf("LoadRules() = %v, %v, want nil rules", rules, err)
+	}
+}
@@ -0,0 +1,85 @@
+package zip
+
+import (
+	archiveZip "archive/zip"
+	"fmt"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// Limits - bounds UnpackFrom checks an archive against before it extracts
+// anything; a zero field disables its limit. The sizes are those of the
+// central directory, which archive/zip holds every entry to while reading, so
+// an archive within them cannot write more.
+type Limits struct {
+	MaxFiles int64 // entries, directories and links included
+	MaxBytes int64 // total uncompressed size
+	MaxRatio int64 // uncompressed bytes per compressed byte of one entry
+	MaxDepth int   // path segments of an entry name
+}
+
+// SetLimits registers the limits UnpackFrom checks the archive against. Like
+// SetProgress it is reached via a type assertion.
+func (z *zip) SetLimits(limits Limits) {
+	z.limits = limits
+}
+
+// CheckTotals - checks a count of entries and their total uncompressed size
+// against MaxFiles and MaxBytes, so a container can be rejected on its
+// recorded metadata before it is decrypted.
+func (l Limits) CheckTotals(files, bytes int64) error {
+	switch {
+	case l.MaxFiles > 0 && files > l.MaxFiles:
+		return limitErr(fmt.Sprintf("%d entries, limits -max-files=%d", files, l.MaxFiles))
+	case l.MaxBytes > 0 && bytes > l.MaxBytes:
+		return limitErr(fmt.Sprintf("%d bytes uncompressed, limits -max-bytes=%d", bytes, l.MaxBytes))
+	default:
+		return nil
+	}
+}
+
+// check - checks every entry of files against the limits, then their count
+// and total size.
+func (l Limits) check(files []*archiveZip.File) error {
+	var total uint64
+	for _, f := range files {
+		if l.MaxDepth > 0 {
+			if depth := strings.Count(strings.Trim(f.Name, "/"), "/") + 1; depth > l.MaxDepth {
+				return limitErr(fmt.Sprintf("%s is %d levels deep, limits -max-depth=%d", f.Name, depth, l.MaxDepth))
+			}
+		}
+
+		// uncompressed > compressed*ratio, without the product overflowing.
+		if l.MaxRatio > 0 && f.UncompressedSize64 > 0 {
+			if (f.UncompressedSize64-1)/uint64(l.MaxRatio) >= f.CompressedSize64 { // #nosec G115
+				return limitErr(fmt.Sprintf(
+					"%s inflates %d bytes to %d, limits -max-ratio=%d",
+					f.Name, f.CompressedSize64, f.UncompressedSize64, l.MaxRatio,
+				))
+			}
+		}
+
+		// The total never passes the limit, so a forged size cannot wrap it.
+		if l.MaxBytes > 0 {
+			if f.UncompressedSize64 > uint64(l.MaxBytes)-total { // #nosec G115
+				return limitErr(fmt.Sprintf("%s takes the uncompressed size past -max-bytes=%d", f.Name, l.MaxBytes))
+			}
+			total += f.UncompressedSize64
+		}
+	}
+
+	return l.CheckTotals(int64(len(files)), 0)
+}
+
+func limitErr(details string) error {
+	return lib.NewError(
+		lib.ErrorTypeValidation,
+		lib.CategoryCompression,
+		lib.ErrCodeExtractLimitExceeded,
+		lib.ErrExtractLimitExceeded.Error(),
+		details,
+		lib.SuggestionExtractLimitExceeded,
+		lib.ErrExtractLimitExceeded,
+	)
+}
@@ -0,0 +1,92 @@
+package zip
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestZipUnpackFromLimits(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "a", "b"), 0750); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "a", "b", "deep.txt"), []byte("deep"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "zeros"), make([]byte, 1<<20), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	packed, err := New().Pack(srcDir)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{name: "no limits", limits: Limits{}},
+		{name: "within", limits: Limits{MaxFiles: 2, MaxBytes: 1<<20 + 4, MaxRatio: 2000, MaxDepth: 3}},
+		{name: "files", limits: Limits{MaxFiles: 1}, wantErr: true},
+		{name: "bytes", limits: Limits{MaxBytes: 1 << 20}, wantErr: true},
+		{name: "ratio", limits: Limits{MaxRatio: 100}, wantErr: true},
+		{name: "depth", limits: Limits{MaxDepth: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+
+			z := New().(*zip)
+			z.SetLimits(tt.limits)
+			err := z.UnpackFrom(context.Background(), bytes.NewReader(packed), int64(len(packed)), targetDir)
+
+			entries, errRead := os.ReadDir(targetDir)
+			if errRead != nil {
+				t.Fatalf("ReadDir failed: %v", errRead)
+			}
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("UnpackFrom failed: %v", err)
+				}
+				if len(entries) != 2 {
+					t.Errorf("Expected 2 extracted entries, got %d", len(entries))
+				}
+				return
+			}
+
+			if !errors.Is(err, lib.ErrExtractLimitExceeded) {
+				t.Fatalf("Expected ErrExtractLimitExceeded, got %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected nothing extracted, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestLimitsCheckTotals(t *testing.T) {
+	limits := Limits{MaxFiles: 10, MaxBytes: 100}
+
+	if err := limits.CheckTotals(10, 100); err != nil {
+		t.Errorf("Expected totals at the limits to pass, got %v", err)
+	}
+	if err := limits.CheckTotals(11, 0); !errors.Is(err, lib.ErrExtractLimitExceeded) {
+		t.Errorf("Expected ErrExtractLimitExceeded for files, got %v", err)
+	}
+	if err := limits.CheckTotals(0, 101); !errors.Is(err, lib.ErrExtractLimitExceeded) {
+		t.Errorf("Expected ErrExtractLimitExceeded for bytes, got %v", err)
+	}
+	if err := (Limits{}).CheckTotals(1<<40, 1<<62); err != nil {
+		t.Errorf("Expected zero limits to pass, got %v", err)
+	}
+}
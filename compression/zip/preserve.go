@@ -0,0 +1,141 @@
+package zip
+
+import (
+	archiveZip "archive/zip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// metaExtraID - the header ID ("tv") of the extra field holding the metadata
+// of an entry packed with lib.PreserveAll.
+const metaExtraID = 0x7674
+
+// entryMeta - the metadata lib.PreserveAll records in the extra field of an
+// entry, beyond the mode the zip header already holds.
+type entryMeta struct {
+	ModTime int64             `json:"mtime"` // unix nanoseconds
+	UID     *int              `json:"uid,omitempty"`
+	GID     *int              `json:"gid,omitempty"`
+	Xattrs  map[string][]byte `json:"xattrs,omitempty"`
+	Link    string            `json:"link,omitempty"` // archive path of a hard link's file
+}
+
+// fileKey - identifies a file across its hard links.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// readEntryMeta - reads the metadata of the file at path for its entry:
+// modification time, ownership where the platform has it, and the extended
+// attributes of anything but a symlink.
+func readEntryMeta(path string, fi fs.FileInfo) (*entryMeta, error) {
+	meta := &entryMeta{ModTime: fi.ModTime().UnixNano()}
+
+	if uid, gid, ok := fileOwner(fi); ok {
+		meta.UID, meta.GID = &uid, &gid
+	}
+
+	if fi.Mode()&os.ModeSymlink == 0 {
+		xattrs, err := readXattrs(path)
+		if err != nil {
+			return nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeReadFileMetadataError, lib.ErrMessageReadFileMetadataError, "", err)
+		}
+		meta.Xattrs = xattrs
+	}
+
+	return meta, nil
+}
+
+// setEntryMeta - records the metadata of e, with its hard link, in the extra
+// field of h; an entry walked without lib.PreserveAll has none.
+func setEntryMeta(h *archiveZip.FileHeader, e *Entry) error {
+	if e.meta == nil {
+		return nil
+	}
+
+	meta := *e.meta
+	meta.Link = e.LinkOf
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return lib.InternalErr(lib.CategoryCompression, lib.ErrCodeCreateZipError, lib.ErrMessageCreateZipError, "", err)
+	}
+	if len(h.Extra)+4+len(data) > math.MaxUint16 {
+		return lib.IOErr(
+			lib.CategoryCompression,
+			lib.ErrCodeCreateZipError,
+			lib.ErrMessageCreateZipError,
+			"",
+			fmt.Errorf("metadata of %q does not fit a zip extra field", e.RelPath),
+		)
+	}
+
+	h.Extra = binary.LittleEndian.AppendUint16(h.Extra, metaExtraID)
+	h.Extra = binary.LittleEndian.AppendUint16(h.Extra, uint16(len(data))) // #nosec G115
+	h.Extra = append(h.Extra, data...)
+
+	return nil
+}
+
+// readFileMeta - the metadata recorded in the extra field of f, or nil when it
+// was packed without lib.PreserveAll.
+func readFileMeta(f *archiveZip.File) (*entryMeta, error) {
+	for extra := f.Extra; len(extra) >= 4; {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+
+		if id == metaExtraID {
+			meta := &entryMeta{}
+			if err := json.Unmarshal(extra[4:4+size], meta); err != nil {
+				return nil, lib.FormatErr(lib.CategoryCompression, lib.ErrCodeReadFileMetadataError, lib.ErrMessageReadFileMetadataError, "", err)
+			}
+
+			return meta, nil
+		}
+		extra = extra[4+size:]
+	}
+
+	return nil, nil
+}
+
+// restoreEntryMeta - applies meta to the extracted entry at path: extended
+// attributes, ownership when owner is set and running as root, then mode and
+// modification time, which a chown could reset. A symlink gets its ownership
+// only. A nil meta restores nothing.
+func restoreEntryMeta(path string, mode fs.FileMode, meta *entryMeta, owner bool) error {
+	if meta == nil {
+		return nil
+	}
+
+	var (
+		isSymlink = mode&os.ModeSymlink != 0
+		err       error
+	)
+	if !isSymlink {
+		err = writeXattrs(path, meta.Xattrs)
+	}
+	if err == nil && owner && meta.UID != nil && meta.GID != nil && os.Geteuid() == 0 {
+		err = os.Lchown(path, *meta.UID, *meta.GID)
+	}
+	if err == nil && !isSymlink {
+		err = os.Chmod(path, mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+	}
+	if err == nil && !isSymlink {
+		err = os.Chtimes(path, time.Time{}, time.Unix(0, meta.ModTime))
+	}
+	if err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeRestoreFileMetadataError, lib.ErrMessageRestoreFileMetadataError, "", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package zip
+
+import "io/fs"
+
+// fileOwner - ownership is not recorded on this platform.
+func fileOwner(fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// hardLinkKey - hard links are packed as separate files on this platform.
+func hardLinkKey(fs.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
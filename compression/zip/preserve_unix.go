@@ -0,0 +1,28 @@
+//go:build unix
+
+package zip
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwner - the user and group owning the file of fi.
+func fileOwner(fi fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(st.Uid), int(st.Gid), true
+}
+
+// hardLinkKey - the key of a regular file of fi with more than one name.
+func hardLinkKey(fi fs.FileInfo) (fileKey, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || !fi.Mode().IsRegular() || st.Nlink < 2 {
+		return fileKey{}, false
+	}
+
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true // #nosec G115
+}
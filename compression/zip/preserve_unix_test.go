@@ -0,0 +1,188 @@
+//go:build unix
+
+package zip
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestZipPreserveRoundTrip(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "empty"), 0o705); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, "docs"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("alpha"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(srcDir, "a.txt"), filepath.Join(srcDir, "docs", "b.txt")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(srcDir, "pipe"), 0o600); err != nil {
+		t.Skipf("FIFOs not supported: %v", err)
+	}
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 123456789, time.UTC)
+	for _, name := range []string{"a.txt", "empty", "docs"} {
+		if err := os.Chtimes(filepath.Join(srcDir, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "empty"), 0o705); err != nil {
+		t.Fatal(err)
+	}
+
+	walker := &Walker{Preserve: true}
+	entries, size, count, _, _, err := walker.WalkFolder(srcDir, nil)
+	if err != nil {
+		t.Fatalf("WalkFolder failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.RelPath)
+	}
+	if want := []string{"a.txt", "docs", "docs/b.txt", "empty"}; !slices.Equal(got, want) {
+		t.Errorf("Walked entries = %q, want %q", got, want)
+	}
+	// The hard link is a file of its own, but its content is packed once.
+	if count != 2 || size != int64(len("alpha")) {
+		t.Errorf("Expected 2 files of 5 bytes, got count=%d size=%d", count, size)
+	}
+	if want := []string{filepath.Join(srcDir, "pipe")}; !slices.Equal(walker.Skipped, want) {
+		t.Errorf("Skipped = %q, want %q", walker.Skipped, want)
+	}
+
+	var packed bytes.Buffer
+	if err = New().(*zip).PackEntriesTo(context.Background(), entries, &packed); err != nil {
+		t.Fatalf("PackEntriesTo failed: %v", err)
+	}
+
+	targetDir := t.TempDir()
+	if err = New().UnpackFrom(context.Background(), bytes.NewReader(packed.Bytes()), int64(packed.Len()), targetDir); err != nil {
+		t.Fatalf("UnpackFrom failed: %v", err)
+	}
+
+	for name, mode := range map[string]os.FileMode{"a.txt": 0o640, "empty": os.ModeDir | 0o705, "docs": os.ModeDir | 0o750} {
+		st, err := os.Stat(filepath.Join(targetDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s to be extracted: %v", name, err)
+		}
+		if st.Mode() != mode || !st.ModTime().Equal(mtime) {
+			t.Errorf("%s: mode %v, mtime %v, want %v, %v", name, st.Mode(), st.ModTime(), mode, mtime)
+		}
+	}
+
+	a, errA := os.Stat(filepath.Join(targetDir, "a.txt"))
+	b, errB := os.Stat(filepath.Join(targetDir, "docs", "b.txt"))
+	if errA != nil || errB != nil || !os.SameFile(a, b) {
+		t.Errorf("Expected docs/b.txt to be a hard link to a.txt: %v, %v", errA, errB)
+	}
+}
+
+func TestZipWithoutPreserveSkipsDirectories(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "empty"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("alpha"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(srcDir, "pipe"), 0o600); err != nil {
+		t.Skipf("FIFOs not supported: %v", err)
+	}
+
+	walker := &Walker{}
+	entries, _, _, _, _, err := walker.WalkFolder(srcDir, nil)
+	if err != nil {
+		t.Fatalf("WalkFolder failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].RelPath != "a.txt" || entries[0].meta != nil {
+		t.Errorf("Expected a.txt alone without metadata, got %+v", entries)
+	}
+	if len(walker.Skipped) != 1 {
+		t.Errorf("Expected the FIFO to be skipped, got %q", walker.Skipped)
+	}
+}
+
+func TestZipUnpackPreserveOwnershipAndSpecialBits(t *testing.T) {
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "shared"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "tool"), []byte("#!/bin/sh\n"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ownership is only restored as root. A chown clears the setuid bit, so
+	// the modes are set after it.
+	root := os.Geteuid() == 0
+	const uid, gid = 4321, 8765
+	if root {
+		if err := os.Chown(filepath.Join(srcDir, "tool"), uid, gid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "shared"), 0o750|os.ModeSetgid|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(srcDir, "tool"), 0o750|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+
+	walker := &Walker{Preserve: true}
+	entries, _, _, _, _, err := walker.WalkFolder(srcDir, nil)
+	if err != nil {
+		t.Fatalf("WalkFolder failed: %v", err)
+	}
+
+	var packed bytes.Buffer
+	if err = New().(*zip).PackEntriesTo(context.Background(), entries, &packed); err != nil {
+		t.Fatalf("PackEntriesTo failed: %v", err)
+	}
+
+	for _, preserve := range []bool{false, true} {
+		z := New().(*zip)
+		z.SetPreserve(preserve)
+
+		targetDir := t.TempDir()
+		if err = z.UnpackFrom(context.Background(), bytes.NewReader(packed.Bytes()), int64(packed.Len()), targetDir); err != nil {
+			t.Fatalf("UnpackFrom(preserve=%v) failed: %v", preserve, err)
+		}
+
+		want := map[string]os.FileMode{"tool": 0o750, "shared": os.ModeDir | 0o750}
+		if preserve {
+			want = map[string]os.FileMode{"tool": 0o750 | os.ModeSetuid, "shared": os.ModeDir | 0o750 | os.ModeSetgid | os.ModeSticky}
+		}
+		for name, mode := range want {
+			st, err := os.Stat(filepath.Join(targetDir, name))
+			if err != nil {
+				t.Fatalf("Expected %s to be extracted: %v", name, err)
+			}
+			if st.Mode() != mode {
+				t.Errorf("preserve=%v: %s mode %v, want %v", preserve, name, st.Mode(), mode)
+			}
+		}
+
+		if !root {
+			continue
+		}
+		st, err := os.Stat(filepath.Join(targetDir, "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		owner := st.Sys().(*syscall.Stat_t)
+		if restored := owner.Uid == uid && owner.Gid == gid; restored != preserve {
+			t.Errorf("preserve=%v: tool owned by %d:%d, restored = %v", preserve, owner.Uid, owner.Gid, restored)
+		}
+	}
+}
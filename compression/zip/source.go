@@ -0,0 +1,146 @@
+package zip
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// Source - a file or folder to pack under Prefix, a slash-separated archive
+// path. A folder's content is packed under Prefix, or at the archive root when
+// it is empty; a file or symlink is packed as Prefix/<name>, or as <name>.
+type Source struct {
+	Path   string
+	Prefix string
+}
+
+// ParseSource - parses a "<path>[:<prefix>]" source. The prefix follows the
+// last colon, so a path holding a colon is given with a trailing one
+// ("/data/a:b:"). A prefix must be relative and must not hold "." or ".."
+// segments; surrounding slashes are dropped.
+func ParseSource(spec string) (Source, error) {
+	src := Source{Path: spec}
+	if i := strings.LastIndexByte(spec, ':'); i >= len(filepath.VolumeName(spec)) {
+		src.Path, src.Prefix = spec[:i], spec[i+1:]
+	}
+
+	prefix := strings.TrimSuffix(filepath.ToSlash(src.Prefix), "/")
+	if src.Path == "" || (prefix != "" && !fs.ValidPath(prefix)) || prefix == "." {
+		return Source{}, lib.ErrContainerSourceInvalid
+	}
+	src.Prefix = prefix
+
+	return src, nil
+}
+
+// WalkSources - walks every source like WalkFolderWithRules, with the rules of
+// the folder's own IgnoreFile followed by ignore, and merges them into one
+// entry list under their prefixes. A file or symlink source is always packed.
+// Two entries with the same archive path, or a file whose archive path is a
+// directory of another entry, fail the walk with lib.ErrSourceCollision.
+func WalkSources(sources []Source, ignore []string) (
+	entries []Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNames []string,
+	err error,
+) {
+	return new(Walker).WalkSources(sources, ignore)
+}
+
+// WalkSources - WalkSources with the options of the walker.
+func (w *Walker) WalkSources(sources []Source, ignore []string) (
+	entries []Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNames []string,
+	err error,
+) {
+	for _, src := range sources {
+		fi, err := os.Lstat(src.Path)
+		if err != nil {
+			return nil, 0, 0, 0, nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeWalkDirError, lib.ErrMessageWalkDirError, "", err)
+		}
+
+		if !fi.IsDir() {
+			entry, ok, err := w.entry(src.Path, path.Join(src.Prefix, fi.Name()), fi)
+			if err != nil {
+				return nil, 0, 0, 0, nil, err
+			}
+			if ok {
+				entries = append(entries, entry)
+				uncompressedSize += entry.size()
+				fileCount++
+				fileNames = append(fileNames, fi.Name())
+			}
+			continue
+		}
+
+		rules, err := LoadRules(src.Path, ignore)
+		if err != nil {
+			return nil, 0, 0, 0, nil, err
+		}
+
+		walked, size, count, excluded, names, err := w.walk(src.Path, src.Prefix, rules)
+		if err != nil {
+			return nil, 0, 0, 0, nil, err
+		}
+
+		entries = append(entries, walked...)
+		uncompressedSize += size
+		fileCount += count
+		excludedCount += excluded
+		fileNames = append(fileNames, names...)
+	}
+
+	if err = checkCollisions(entries); err != nil {
+		return nil, 0, 0, 0, nil, err
+	}
+
+	return entries, uncompressedSize, fileCount, excludedCount, fileNames, nil
+}
+
+// checkCollisions - rejects entries that would extract to the same path,
+// including a file in the place of another entry's directory. Two sources may
+// both hold a directory.
+func checkCollisions(entries []Entry) error {
+	var (
+		files = make(map[string]bool, len(entries))
+		dirs  = make(map[string]bool)
+	)
+
+	for _, e := range entries {
+		isDir := e.Info.IsDir()
+		if files[e.RelPath] || (dirs[e.RelPath] && !isDir) {
+			return sourceCollisionErr(e.RelPath)
+		}
+		if isDir {
+			dirs[e.RelPath] = true
+		} else {
+			files[e.RelPath] = true
+		}
+
+		for dir := path.Dir(e.RelPath); dir != "."; dir = path.Dir(dir) {
+			if files[dir] {
+				return sourceCollisionErr(dir)
+			}
+			dirs[dir] = true
+		}
+	}
+
+	return nil
+}
+
+func sourceCollisionErr(archivePath string) error {
+	return lib.NewError(
+		lib.ErrorTypeValidation,
+		lib.CategoryCompression,
+		lib.ErrCodeSourceCollision,
+		lib.ErrMessageSourceCollision,
+		"archive path "+archivePath,
+		lib.SuggestionSourceCollision,
+		lib.ErrSourceCollision,
+	)
+}
@@ -0,0 +1,124 @@
+package zip
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Source
+		err  bool
+	}{
+		{spec: "/etc/app", want: Source{Path: "/etc/app"}},
+		{spec: "/etc/app:config", want: Source{Path: "/etc/app", Prefix: "config"}},
+		{spec: "/etc/app:config/app/", want: Source{Path: "/etc/app", Prefix: "config/app"}},
+		{spec: "/data/a:b:", want: Source{Path: "/data/a:b"}},
+		{spec: ":config", err: true},
+		{spec: "/etc/app:/config", err: true},
+		{spec: "/etc/app:../config", err: true},
+		{spec: "/etc/app:a/./b", err: true},
+		{spec: "/etc/app:.", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSource(tt.spec)
+			if tt.err {
+				if !errors.Is(err, lib.ErrContainerSourceInvalid) {
+					t.Errorf("ParseSource(%q) error = %v, want %v", tt.spec, err, lib.ErrContainerSourceInvalid)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseSource(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkSources(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"app/app.yaml":      "app",
+		"app/conf.d/db.ini": "db",
+		"app/debug.log":     "noise",
+		"certs/ca.pem":      "ca",
+		"hosts":             "127.0.0.1",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, size, count, excluded, names, err := WalkSources([]Source{
+		{Path: filepath.Join(tempDir, "app"), Prefix: "config"},
+		{Path: filepath.Join(tempDir, "certs")},
+		{Path: filepath.Join(tempDir, "hosts"), Prefix: "etc"},
+	}, []string{"*.log"})
+	if err != nil {
+		t.Fatalf("WalkSources failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.RelPath)
+	}
+	want := []string{"config/app.yaml", "config/conf.d/db.ini", "ca.pem", "etc/hosts"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Packed entries = %q, want %q", got, want)
+	}
+	if count != 4 || len(names) != 4 || excluded != 1 {
+		t.Errorf("Expected 4 files and 1 excluded, got count=%d names=%d excluded=%d", count, len(names), excluded)
+	}
+	if size != int64(len("app")+len("db")+len("ca")+len("127.0.0.1")) {
+		t.Errorf("Unexpected uncompressed size: %d", size)
+	}
+}
+
+func TestWalkSourcesRejectsCollisions(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a/config", "b/config/x", "c/config"} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sources []Source
+	}{
+		{"same file", []Source{{Path: filepath.Join(tempDir, "a")}, {Path: filepath.Join(tempDir, "c")}}},
+		{"file over directory", []Source{{Path: filepath.Join(tempDir, "a")}, {Path: filepath.Join(tempDir, "b")}}},
+		{"directory over file", []Source{{Path: filepath.Join(tempDir, "b")}, {Path: filepath.Join(tempDir, "a", "config")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, _, _, err := WalkSources(tt.sources, nil); !errors.Is(err, lib.ErrSourceCollision) {
+				t.Errorf("WalkSources() error = %v, want %v", err, lib.ErrSourceCollision)
+			}
+		})
+	}
+
+	if _, _, _, _, _, err := WalkSources([]Source{
+		{Path: filepath.Join(tempDir, "a"), Prefix: "one"},
+		{Path: filepath.Join(tempDir, "c"), Prefix: "two"},
+	}, nil); err != nil {
+		t.Errorf("WalkSources() with distinct prefixes error = %v", err)
+	}
+}
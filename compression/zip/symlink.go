@@ -0,0 +1,266 @@
+package zip
+
+import (
+	archiveZip "archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// maxSymlinkTarget - the longest symlink target read for lib.SymlinksContain,
+// PATH_MAX on Linux; a longer one is refused.
+const maxSymlinkTarget = 4096
+
+// SetSymlinkPolicy registers what UnpackFrom does with symlink entries:
+// lib.SymlinksReject, lib.SymlinksSkip, lib.SymlinksContain or
+// lib.SymlinksAllow (the default). Like SetProgress it is reached via a type
+// assertion.
+func (z *zip) SetSymlinkPolicy(policy string) {
+	z.symlinks = policy
+}
+
+// confined - reports whether the symlink policy keeps writes from following
+// symlinks, which all but lib.SymlinksAllow do.
+func (z *zip) confined() bool {
+	return z.symlinks != "" && z.symlinks != lib.SymlinksAllow
+}
+
+// checkSymlinks - applies the symlink policy to jobs before anything is
+// written, returning the jobs to extract: lib.SymlinksReject refuses every
+// symlink, lib.SymlinksSkip drops them, listing them in the report, and
+// lib.SymlinksContain refuses those whose target could leave the folder. It
+// then refuses any entry written through a symlink, one the archive creates
+// or one already in targetDir. Every refusal is listed before an error is
+// returned.
+func (z *zip) checkSymlinks(targetDir string, jobs, links, dirs []unpackJob) ([]unpackJob, error) {
+	if !z.confined() {
+		return jobs, nil
+	}
+
+	var (
+		kept     = make([]unpackJob, 0, len(jobs))
+		archived = make(map[string]bool)
+		refused  []string
+	)
+	for _, j := range jobs {
+		if j.file.Mode()&os.ModeSymlink == 0 {
+			kept = append(kept, j)
+			continue
+		}
+
+		switch z.symlinks {
+		case lib.SymlinksReject:
+			refused = append(refused, j.file.Name)
+		case lib.SymlinksSkip:
+			z.report.skippedSymlink(j.file.Name)
+		default:
+			target, err := readSymlinkTarget(j.file)
+			if err != nil {
+				return nil, err
+			}
+			if !containedTarget(j.file.Name, target) {
+				refused = append(refused, j.file.Name+" -> "+target)
+				continue
+			}
+
+			archived[path.Clean(j.file.Name)] = true
+			kept = append(kept, j)
+		}
+	}
+
+	var (
+		checked = make(map[string]bool)
+		through = func(name string) (string, error) {
+			dirs := strings.Split(path.Clean(name), "/")
+			for i := 1; i < len(dirs); i++ {
+				dir := strings.Join(dirs[:i], "/")
+				if archived[dir] {
+					return dir, nil
+				}
+				if checked[dir] {
+					continue
+				}
+
+				fi, err := os.Lstat(filepath.Join(targetDir, filepath.FromSlash(dir)))
+				if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+					return dir, nil
+				}
+				if err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return "", lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+				}
+				checked[dir] = true
+			}
+
+			return "", nil
+		}
+	)
+	for _, group := range [][]unpackJob{kept, links, dirs} {
+		for _, j := range group {
+			names := []string{j.file.Name}
+			if j.meta != nil && j.meta.Link != "" {
+				names = append(names, j.meta.Link)
+			}
+
+			for _, name := range names {
+				link, err := through(name)
+				if err != nil {
+					return nil, err
+				}
+				if link != "" {
+					refused = append(refused, name+" through "+link)
+				}
+			}
+		}
+	}
+
+	if len(refused) > 0 {
+		return nil, lib.NewError(
+			lib.ErrorTypeValidation,
+			lib.CategoryCompression,
+			lib.ErrCodeSymlinkRejected,
+			lib.ErrSymlinkRejected.Error(),
+			strings.Join(refused, ", "),
+			lib.SuggestionSymlinkRejected,
+			lib.ErrSymlinkRejected,
+		)
+	}
+
+	return kept, nil
+}
+
+// containedTarget - reports whether target, that of the symlink entry name,
+// stays in the folder: it is relative, and its ".." elements all lead it
+// without climbing above the folder. A ".." after another element is refused,
+// since that element could be a symlink whose parent is elsewhere.
+func containedTarget(name, target string) bool {
+	if target == "" || path.IsAbs(target) || filepath.IsAbs(target) {
+		return false
+	}
+
+	depth := strings.Count(path.Clean(name), "/")
+	leading := true
+	for _, elem := range strings.Split(target, "/") {
+		switch elem {
+		case "", ".":
+		case "..":
+			if !leading || depth == 0 {
+				return false
+			}
+			depth--
+		default:
+			leading = false
+		}
+	}
+
+	return true
+}
+
+// readSymlinkTarget - the target a symlink entry holds as its content.
+func readSymlinkTarget(f *archiveZip.File) (string, error) {
+	if f.UncompressedSize64 > maxSymlinkTarget {
+		return "", nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return "", lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+	}
+
+	b, errRead := io.ReadAll(io.LimitReader(rc, maxSymlinkTarget))
+	if errClose := rc.Close(); errClose != nil {
+		return "", lib.IOErr(lib.CategoryCompression, lib.ErrCodeReaderCloserError, lib.ErrMessageReaderCloserError, "", errClose)
+	}
+	if errRead != nil {
+		return "", lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", errRead)
+	}
+
+	return string(b), nil
+}
+
+// openEntryFile - opens dst to write an entry to, through root when it is set.
+func openEntryFile(root *os.Root, dst string, mode fs.FileMode) (*os.File, error) {
+	const flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	var (
+		out *os.File
+		err error
+	)
+	if root == nil {
+		out, err = os.OpenFile(filepath.Clean(dst), flag, mode)
+	} else {
+		var rel string
+		if rel, err = rootPath(root, dst); err != nil {
+			return nil, err
+		}
+		out, err = root.OpenFile(rel, flag, mode)
+	}
+	if err != nil {
+		return nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+	}
+
+	return out, nil
+}
+
+// symlinkEntry - creates dst as a symlink to target, through root when it is
+// set.
+func symlinkEntry(root *os.Root, target, dst string) error {
+	var err error
+	if root == nil {
+		err = os.Symlink(target, dst)
+	} else {
+		var rel string
+		if rel, err = rootPath(root, dst); err != nil {
+			return err
+		}
+		err = root.Symlink(target, rel)
+	}
+	if err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+	}
+
+	return nil
+}
+
+// hardLink - creates dst as a hard link to src, through root when it is set.
+func hardLink(root *os.Root, src, dst string) error {
+	var err error
+	if root == nil {
+		err = os.Link(src, dst)
+	} else {
+		var relSrc, relDst string
+		if relSrc, err = rootPath(root, src); err != nil {
+			return err
+		}
+		if relDst, err = rootPath(root, dst); err != nil {
+			return err
+		}
+		err = root.Link(relSrc, relDst)
+	}
+	if err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+	}
+
+	return nil
+}
+
+// rootPath - dst relative to root, for the methods of os.Root.
+func rootPath(root *os.Root, dst string) (string, error) {
+	rel, err := filepath.Rel(root.Name(), dst)
+	if err != nil {
+		return "", lib.InternalErr(lib.CategoryCompression, lib.ErrCodeGetFilePathRelative, lib.ErrMessageGetFilePathRelative, "", err)
+	}
+
+	return rel, nil
+}
+
+func (r *ExtractReport) skippedSymlink(name string) {
+	if r != nil {
+		r.SkippedSymlinks = append(r.SkippedSymlinks, name)
+	}
+}
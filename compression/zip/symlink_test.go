@@ -0,0 +1,195 @@
+package zip
+
+import (
+	archiveZip "archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// symlinkArchive - a zip of the regular files and symlinks given by name, a
+// symlink as "->target".
+func symlinkArchive(t *testing.T, entries [][2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := archiveZip.NewWriter(&buf)
+	for _, e := range entries {
+		h := &archiveZip.FileHeader{Name: e[0], Method: archiveZip.Store}
+		content := e[1]
+		if len(content) > 2 && content[:2] == "->" {
+			h.SetMode(os.ModeSymlink | 0o777)
+			content = content[2:]
+		} else {
+			h.SetMode(0o644)
+		}
+
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatalf("CreateHeader failed: %v", err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func unpackWithSymlinks(t *testing.T, archive []byte, targetDir, policy string, report *ExtractReport) error {
+	t.Helper()
+
+	z := New().(*zip)
+	z.SetSymlinkPolicy(policy)
+	z.SetExtractReport(report)
+
+	return z.UnpackFrom(context.Background(), bytes.NewReader(archive), int64(len(archive)), targetDir)
+}
+
+func TestZipUnpackFromSymlinkPolicy(t *testing.T) {
+	archive := symlinkArchive(t, [][2]string{
+		{"sub/f.txt", "file"},
+		{"in", "->sub/f.txt"},
+		{"sub/up", "->.."},
+		{"abs", "->/etc/passwd"},
+	})
+
+	tests := []struct {
+		policy      string
+		wantErr     bool
+		wantLinks   []string
+		wantSkipped []string
+	}{
+		{policy: lib.SymlinksAllow, wantLinks: []string{"abs", "in", "sub/up"}},
+		{policy: lib.SymlinksReject, wantErr: true},
+		{policy: lib.SymlinksSkip, wantSkipped: []string{"in", "sub/up", "abs"}},
+		{policy: lib.SymlinksContain, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			targetDir := t.TempDir()
+
+			var report ExtractReport
+			err := unpackWithSymlinks(t, archive, targetDir, tt.policy, &report)
+			if tt.wantErr {
+				if !errors.Is(err, lib.ErrSymlinkRejected) {
+					t.Fatalf("Expected ErrSymlinkRejected, got %v", err)
+				}
+				if entries, _ := os.ReadDir(targetDir); len(entries) != 0 {
+					t.Errorf("Expected nothing extracted, got %d entries", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnpackFrom failed: %v", err)
+			}
+
+			var links []string
+			for _, name := range []string{"abs", "in", "sub/up"} {
+				if fi, errStat := os.Lstat(filepath.Join(targetDir, name)); errStat == nil && fi.Mode()&os.ModeSymlink != 0 {
+					links = append(links, name)
+				}
+			}
+			if !reflect.DeepEqual(links, tt.wantLinks) {
+				t.Errorf("Expected links %v, got %v", tt.wantLinks, links)
+			}
+			if !reflect.DeepEqual(report.SkippedSymlinks, tt.wantSkipped) {
+				t.Errorf("Expected skipped symlinks %v, got %v", tt.wantSkipped, report.SkippedSymlinks)
+			}
+			if got, _ := os.ReadFile(filepath.Join(targetDir, "sub", "f.txt")); string(got) != "file" {
+				t.Errorf("Expected sub/f.txt to be extracted, got %q", got)
+			}
+		})
+	}
+}
+
+func TestZipUnpackFromContainWritesNoEntryThroughSymlink(t *testing.T) {
+	outside := t.TempDir()
+
+	tests := []struct {
+		name    string
+		entries [][2]string
+		prepare func(t *testing.T, targetDir string)
+		wantErr bool
+	}{
+		{
+			name:    "contained links",
+			entries: [][2]string{{"sub/f.txt", "file"}, {"in", "->sub/f.txt"}, {"sub/up", "->.."}},
+		},
+		{
+			name:    "link in the archive",
+			entries: [][2]string{{"sub/f.txt", "file"}, {"link", "->sub"}, {"link/g.txt", "escape"}},
+			wantErr: true,
+		},
+		{
+			name:    "link in the folder",
+			entries: [][2]string{{"pre/g.txt", "escape"}},
+			prepare: func(t *testing.T, targetDir string) {
+				if err := os.Symlink(outside, filepath.Join(targetDir, "pre")); err != nil {
+					t.Skipf("symlinks not supported: %v", err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			if tt.prepare != nil {
+				tt.prepare(t, targetDir)
+			}
+
+			err := unpackWithSymlinks(t, symlinkArchive(t, tt.entries), targetDir, lib.SymlinksContain, nil)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("UnpackFrom failed: %v", err)
+				}
+				if got, _ := os.ReadFile(filepath.Join(targetDir, "in")); string(got) != "file" {
+					t.Errorf("Expected in to lead to sub/f.txt, got %q", got)
+				}
+				return
+			}
+
+			if !errors.Is(err, lib.ErrSymlinkRejected) {
+				t.Fatalf("Expected ErrSymlinkRejected, got %v", err)
+			}
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Errorf("Expected nothing written outside the folder, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestContainedTarget(t *testing.T) {
+	tests := []struct {
+		name, target string
+		want         bool
+	}{
+		{"link", "file", true},
+		{"link", "sub/./file", true},
+		{"a/b/link", "../../file", true},
+		{"a/link", "..", true},
+		{"link", "..", false},
+		{"a/link", "../../file", false},
+		{"a/link", "s/../file", false},
+		{"link", "/etc/passwd", false},
+		{"link", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := containedTarget(tt.name, tt.target); got != tt.want {
+			t.Errorf("containedTarget(%q, %q) = %v, want %v", tt.name, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package zip
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// readXattrs - the extended attributes of the file at path, or nil when it
+// has none or its filesystem does not support them.
+func readXattrs(path string) (map[string][]byte, error) {
+	names, err := xattrCall(func(buf []byte) (int, error) { return syscall.Listxattr(path, buf) })
+	if err != nil || len(names) == 0 {
+		return nil, ignoreUnsupported(err)
+	}
+
+	xattrs := make(map[string][]byte)
+	for _, name := range strings.Split(strings.TrimSuffix(string(names), "\x00"), "\x00") {
+		value, err := xattrCall(func(buf []byte) (int, error) { return syscall.Getxattr(path, name, buf) })
+		if errors.Is(err, syscall.ENODATA) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = value
+	}
+
+	return xattrs, nil
+}
+
+// writeXattrs - sets xattrs on the file at path. Without root only the user
+// namespace is written, as the others need privileges; a filesystem without
+// extended attributes is left as it is.
+func writeXattrs(path string, xattrs map[string][]byte) error {
+	root := os.Geteuid() == 0
+	for name, value := range xattrs {
+		if !root && !strings.HasPrefix(name, "user.") {
+			continue
+		}
+		if err := syscall.Setxattr(path, name, value, 0); err != nil {
+			return ignoreUnsupported(err)
+		}
+	}
+
+	return nil
+}
+
+// xattrCall - calls fn with a buffer large enough for its result, growing it
+// while the attribute changes between the size query and the read.
+func xattrCall(fn func(buf []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := fn(nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		n, err := fn(buf)
+		if errors.Is(err, syscall.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	}
+}
+
+func ignoreUnsupported(err error) error {
+	if errors.Is(err, syscall.ENOTSUP) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,41 @@
+package zip
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestZipPreserveXattrs(t *testing.T) {
+	srcDir := t.TempDir()
+	path := filepath.Join(srcDir, "a.txt")
+	if err := os.WriteFile(path, []byte("alpha"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Setxattr(path, "user.tvault", []byte("blue"), 0); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+
+	entries, _, _, _, _, err := (&Walker{Preserve: true}).WalkFolder(srcDir, nil)
+	if err != nil {
+		t.Fatalf("WalkFolder failed: %v", err)
+	}
+
+	var packed bytes.Buffer
+	if err = NewStore().(*zip).PackEntriesTo(context.Background(), entries, &packed); err != nil {
+		t.Fatalf("PackEntriesTo failed: %v", err)
+	}
+
+	targetDir := t.TempDir()
+	if err = NewStore().UnpackFrom(context.Background(), bytes.NewReader(packed.Bytes()), int64(packed.Len()), targetDir); err != nil {
+		t.Fatalf("UnpackFrom failed: %v", err)
+	}
+
+	xattrs, err := readXattrs(filepath.Join(targetDir, "a.txt"))
+	if err != nil || string(xattrs["user.tvault"]) != "blue" {
+		t.Errorf("Expected user.tvault=blue, got %q, err %v", xattrs, err)
+	}
+}
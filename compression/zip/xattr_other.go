@@ -0,0 +1,13 @@
+//go:build !linux
+
+package zip
+
+// readXattrs - extended attributes are not recorded on this platform.
+func readXattrs(string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// writeXattrs - extended attributes are not restored on this platform.
+func writeXattrs(string, map[string][]byte) error {
+	return nil
+}
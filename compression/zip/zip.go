@@ -4,6 +4,9 @@ import (
 	archiveZip "archive/zip"
 	"bytes"
 	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -11,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,6 +36,24 @@ type zip struct {
 	// lets a caller drive a byte-level progress bar without the packer needing to
 	// know how progress is displayed. Set via SetProgress.
 	progress func(n int64)
+	// extractLog, when set, records each extracted entry and lets UnpackFrom
+	// skip entries an interrupted extraction already wrote. Set via
+	// SetExtractLog.
+	extractLog ExtractLog
+	// onConflict is what UnpackFrom does with an entry whose path exists, empty
+	// meaning lib.ConflictOverwrite; with dryRun it writes nothing and only
+	// fills report. Set via SetConflictPolicy and SetExtractReport.
+	onConflict string
+	dryRun     bool
+	report     *ExtractReport
+	// limits bound the archive UnpackFrom accepts. Set via SetLimits.
+	limits Limits
+	// symlinks is the policy for symlink entries, empty meaning
+	// lib.SymlinksAllow. Set via SetSymlinkPolicy.
+	symlinks string
+	// preserve restores the ownership and the setuid, setgid and sticky bits
+	// recorded in the archive. Set via SetPreserve.
+	preserve bool
 }
 
 // SetProgress registers a callback invoked with uncompressed byte counts as
@@ -42,6 +64,42 @@ func (z *zip) SetProgress(fn func(n int64)) {
 	z.progress = fn
 }
 
+// ExtractLog records the entries an extraction has written with the SHA-256 of
+// their content, so an interrupted extraction can be resumed. Implementations
+// must be safe for concurrent use: entries are extracted in parallel.
+type ExtractLog interface {
+	// Done returns the hash recorded for the entry name, if it was extracted.
+	Done(name string) (sum []byte, ok bool)
+	// Extracted records that the entry name was written with content hash sum.
+	Extracted(name string, sum []byte) error
+}
+
+// SetExtractLog registers the log UnpackFrom records extracted entries in. An
+// entry the log already holds is skipped when the file on disk still has the
+// recorded hash, and extracted again otherwise. Like SetProgress it is reached
+// via a type assertion.
+func (z *zip) SetExtractLog(log ExtractLog) {
+	z.extractLog = log
+}
+
+// SetPreserve makes UnpackFrom restore the ownership (when running as root)
+// and the setuid, setgid and sticky bits an archive records, which the sender
+// chooses. Without it they are dropped. Like SetProgress it is reached via a
+// type assertion.
+func (z *zip) SetPreserve(preserve bool) {
+	z.preserve = preserve
+}
+
+// entryMode - the mode an entry is created with: that of its header, without
+// the setuid, setgid and sticky bits unless SetPreserve asked for them.
+func (z *zip) entryMode(f *archiveZip.File) fs.FileMode {
+	if z.preserve {
+		return f.Mode()
+	}
+
+	return f.Mode() &^ (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+}
+
 // progressCountWriter forwards writes to w and reports the byte count to fn.
 type progressCountWriter struct {
 	w  io.Writer
@@ -65,6 +123,24 @@ type Entry struct {
 	Info       fs.FileInfo
 	LinkTarget string // non-empty only for symlinks
 	IsSymlink  bool
+	// LinkOf - with Walker.Preserve, the archive path of an earlier entry
+	// this file is a hard link to; its content is not packed again.
+	LinkOf string
+
+	meta *entryMeta // recorded in the extra field; set with Walker.Preserve
+}
+
+// Walker walks folders and sources into the entries to pack. With Preserve
+// set it also returns directories, empty ones included, marks a file walked
+// before under another name as a hard link (LinkOf), and reads the metadata
+// recorded for lib.PreserveAll. Special files — FIFOs, sockets and devices —
+// are never packed; Skipped lists their paths. The zero Walker is the walk of
+// WalkFolderWithRules and WalkSources.
+type Walker struct {
+	Preserve bool
+	Skipped  []string
+
+	links map[fileKey]string // archive path of the first walked name of a file
 }
 
 var copyBufPool = sync.Pool{
@@ -90,7 +166,7 @@ func NewStore() compression.Compression {
 // Pack - packs to []byte
 func (z *zip) Pack(folder string) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := z.PackTo(folder, buf); err != nil {
+	if err := z.PackTo(context.Background(), folder, buf); err != nil {
 		return nil, err
 	}
 	z.compressedSize = int64(buf.Len())
@@ -105,17 +181,43 @@ func (z *zip) Pack(folder string) ([]byte, error) {
 // filesystem tree is traversed only once instead of once for stats and again for
 // packing.
 func WalkFolder(folder string) (entries []Entry, uncompressedSize, fileCount int64, fileNames []string, err error) {
-	walkErr := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
-		switch {
-		case err != nil:
-			return err
-		case d.IsDir():
-			return nil
-		}
+	entries, uncompressedSize, fileCount, _, fileNames, err = WalkFolderWithRules(folder, nil)
+	return entries, uncompressedSize, fileCount, fileNames, err
+}
 
-		fi, err := d.Info()
+// WalkFolderWithRules is WalkFolder leaving out the paths rules exclude.
+// excludedCount counts the excluded files and directories; the content of an
+// excluded directory is not walked, so it is not counted on its own.
+func WalkFolderWithRules(folder string, rules *Rules) (
+	entries []Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNames []string,
+	err error,
+) {
+	return new(Walker).WalkFolder(folder, rules)
+}
+
+// WalkFolder - WalkFolderWithRules with the options of the walker.
+func (w *Walker) WalkFolder(folder string, rules *Rules) (
+	entries []Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNames []string,
+	err error,
+) {
+	return w.walk(folder, "", rules)
+}
+
+// walk - walks folder into entries under the archive path prefix; rules match
+// the paths relative to folder.
+func (w *Walker) walk(folder, prefix string, rules *Rules) (
+	entries []Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNames []string,
+	err error,
+) {
+	walkErr := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return lib.InternalErr(lib.CategoryCompression, 0, "", "", err)
+			return err
 		}
 
 		relPath, err := filepath.Rel(folder, path)
@@ -130,43 +232,136 @@ func WalkFolder(folder string) (entries []Entry, uncompressedSize, fileCount int
 		}
 		relPath = filepath.ToSlash(relPath)
 
-		mode := fi.Mode()
-
 		switch {
-		case mode&os.ModeSymlink != 0:
-			target, err := os.Readlink(path)
-			if err != nil {
-				return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+		case d.IsDir() && relPath == ".":
+			return nil
+		case rules.Excluded(relPath, d.IsDir()):
+			excludedCount++
+			if d.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
+		case d.IsDir() && !w.Preserve:
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return lib.InternalErr(lib.CategoryCompression, 0, "", "", err)
+		}
 
-			entries = append(entries, Entry{AbsPath: path, RelPath: relPath, Info: fi, LinkTarget: target, IsSymlink: true})
-			uncompressedSize += int64(len(target))
-			fileCount++
-			fileNames = append(fileNames, fi.Name())
-		case mode.IsRegular():
-			entries = append(entries, Entry{AbsPath: path, RelPath: relPath, Info: fi})
-			uncompressedSize += fi.Size()
-			fileCount++
-			fileNames = append(fileNames, fi.Name())
+		archivePath := relPath
+		if prefix != "" {
+			archivePath = prefix + "/" + relPath
+		}
+
+		entry, ok, err := w.entry(path, archivePath, fi)
+		if err != nil || !ok {
+			return err
 		}
 
+		entries = append(entries, entry)
+		if fi.IsDir() {
+			return nil
+		}
+		uncompressedSize += entry.size()
+		fileCount++
+		fileNames = append(fileNames, fi.Name())
+
 		return nil
 	})
 	if walkErr != nil {
-		return nil, 0, 0, nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeWalkDirError, lib.ErrMessageWalkDirError, "", walkErr)
+		return nil, 0, 0, 0, nil, lib.IOErr(lib.CategoryCompression, lib.ErrCodeWalkDirError, lib.ErrMessageWalkDirError, "", walkErr)
+	}
+
+	return entries, uncompressedSize, fileCount, excludedCount, fileNames, nil
+}
+
+// newEntry - returns the entry of the regular file or symlink at path, and
+// false for anything else, which is not packed.
+func newEntry(path, relPath string, fi fs.FileInfo) (Entry, bool, error) {
+	mode := fi.Mode()
+
+	switch {
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return Entry{}, false, lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+		}
+
+		return Entry{AbsPath: path, RelPath: relPath, Info: fi, LinkTarget: target, IsSymlink: true}, true, nil
+	case mode.IsRegular():
+		return Entry{AbsPath: path, RelPath: relPath, Info: fi}, true, nil
 	}
 
-	return entries, uncompressedSize, fileCount, fileNames, nil
+	return Entry{}, false, nil
+}
+
+// entry - newEntry for the walker: a directory is an entry with Preserve set,
+// and a special file is added to Skipped. With Preserve set the entry carries
+// its metadata, and a file already walked under another name is a hard link.
+func (w *Walker) entry(path, archivePath string, fi fs.FileInfo) (Entry, bool, error) {
+	var (
+		entry Entry
+		ok    bool
+		err   error
+	)
+	if fi.IsDir() {
+		entry, ok = Entry{AbsPath: path, RelPath: archivePath, Info: fi}, w.Preserve
+	} else if entry, ok, err = newEntry(path, archivePath, fi); err != nil {
+		return Entry{}, false, err
+	} else if !ok {
+		w.Skipped = append(w.Skipped, path)
+	}
+	if !ok || !w.Preserve {
+		return entry, ok, nil
+	}
+
+	if key, linked := hardLinkKey(fi); linked {
+		if first, seen := w.links[key]; seen {
+			entry.LinkOf = first
+		} else {
+			if w.links == nil {
+				w.links = make(map[fileKey]string)
+			}
+			w.links[key] = archivePath
+		}
+	}
+
+	if entry.meta, err = readEntryMeta(path, fi); err != nil {
+		return Entry{}, false, err
+	}
+
+	return entry, true, nil
+}
+
+// size - the uncompressed bytes of the entry: the content of a file, or the
+// target of a symlink. A directory and a hard link have none.
+func (e Entry) size() int64 {
+	switch {
+	case e.IsSymlink:
+		return int64(len(e.LinkTarget))
+	case e.Info.IsDir(), e.LinkOf != "":
+		return 0
+	}
+
+	return e.Info.Size()
+}
+
+// stored - reports whether the entry has no file body to deflate: a symlink,
+// a directory or a hard link.
+func (e Entry) stored() bool {
+	return e.IsSymlink || e.Info.IsDir() || e.LinkOf != ""
 }
 
 // PackTo - streaming zip to writer.
-func (z *zip) PackTo(folder string, out io.Writer) error {
+func (z *zip) PackTo(ctx context.Context, folder string, out io.Writer) error {
 	entries, _, _, _, err := WalkFolder(folder)
 	if err != nil {
 		return err
 	}
 
-	return z.PackEntriesTo(entries, out)
+	return z.PackEntriesTo(ctx, entries, out)
 }
 
 // PackEntriesTo writes pre-walked entries into a zip stream. The tree is not
@@ -179,9 +374,11 @@ func (z *zip) PackTo(folder string, out io.Writer) error {
 // into buffers and the finished buffers are written into the single zip stream
 // in the original order via CreateRaw. The Store method has no CPU cost worth
 // parallelizing, so it stays on the simple sequential path.
-func (z *zip) PackEntriesTo(entries []Entry, out io.Writer) error {
+//
+// Cancelling ctx stops packing at the next entry or read and returns ctx.Err().
+func (z *zip) PackEntriesTo(ctx context.Context, entries []Entry, out io.Writer) error {
 	if z.method == archiveZip.Deflate && len(entries) > 1 {
-		return z.packEntriesParallel(entries, out)
+		return z.packEntriesParallel(ctx, entries, out)
 	}
 
 	zw := archiveZip.NewWriter(out)
@@ -193,7 +390,11 @@ func (z *zip) PackEntriesTo(entries []Entry, out io.Writer) error {
 	}
 
 	for i := range entries {
-		if err := z.packEntry(zw, &entries[i]); err != nil {
+		if err := ctx.Err(); err != nil {
+			_ = zw.Close()
+			return err
+		}
+		if err := z.packEntry(ctx, zw, &entries[i]); err != nil {
 			_ = zw.Close()
 			return err
 		}
@@ -226,12 +427,13 @@ var flateWriterPool = sync.Pool{
 }
 
 // packResult is the compressed form of one entry, handed from a worker to the
-// ordered writer. For symlinks comp is nil and the writer stores the target.
+// ordered writer. For stored entries comp is nil and the writer stores them.
 type packResult struct {
 	entry *Entry
-	comp  []byte // raw deflate bytes (nil for symlinks)
+	comp  []byte // raw deflate bytes (nil for stored entries)
 	crc   uint32
 	usize int64
+	held  int64 // budget bytes acquired for this entry, released by the writer
 	err   error
 }
 
@@ -239,7 +441,7 @@ type packResult struct {
 // stream in their original order. Ordering is preserved by handing the writer a
 // channel of per-entry result channels: the writer consumes them in dispatch
 // order, so entry N is always written before N+1 even if it finished later.
-func (z *zip) packEntriesParallel(entries []Entry, out io.Writer) error {
+func (z *zip) packEntriesParallel(ctx context.Context, entries []Entry, out io.Writer) error {
 	zw := archiveZip.NewWriter(out)
 
 	workers := runtime.GOMAXPROCS(0)
@@ -249,7 +451,7 @@ func (z *zip) packEntriesParallel(entries []Entry, out io.Writer) error {
 
 	var (
 		slots   = make(chan struct{}, workers)        // caps live goroutines
-		budget  = newByteSem(packBudgetBytes)         // caps bytes in flight
+		budget  = lib.NewByteSem(packBudgetBytes)     // caps bytes in flight
 		results = make(chan chan packResult, workers) // ordered result handoff
 	)
 
@@ -261,20 +463,28 @@ func (z *zip) packEntriesParallel(entries []Entry, out io.Writer) error {
 			ch := make(chan packResult, 1)
 			results <- ch
 
-			if e.IsSymlink {
-				// Symlinks carry no file body to deflate; let the writer store them.
+			// Once cancelled, stop dispatching: the writer reports ctx.Err()
+			// at this entry and drains those already in flight.
+			if err := ctx.Err(); err != nil {
+				ch <- packResult{entry: e, err: err}
+				return
+			}
+
+			if e.stored() {
+				// Symlinks, directories and hard links carry no file body to
+				// deflate; let the writer store them.
 				ch <- packResult{entry: e}
 				continue
 			}
 
-			budget.acquire(e.Info.Size())
+			budget.Acquire(e.Info.Size())
 			slots <- struct{}{}
 
 			go func(e *Entry, ch chan packResult) {
 				defer func() { <-slots }()
 
-				comp, crc, usize, err := z.deflateEntry(e)
-				ch <- packResult{entry: e, comp: comp, crc: crc, usize: usize, err: err}
+				comp, crc, usize, err := z.deflateEntry(ctx, e)
+				ch <- packResult{entry: e, comp: comp, crc: crc, usize: usize, held: e.Info.Size(), err: err}
 			}(e, ch)
 		}
 	}()
@@ -284,22 +494,17 @@ func (z *zip) packEntriesParallel(entries []Entry, out io.Writer) error {
 	var writeErr error
 	for ch := range results {
 		res := <-ch
-		if writeErr != nil {
-			if res.comp != nil {
-				budget.release(res.entry.Info.Size())
+		if writeErr == nil {
+			if res.err != nil {
+				writeErr = res.err
+			} else if err := z.writePackResult(zw, &res); err != nil {
+				writeErr = err
 			}
-			continue
-		}
-
-		if res.err != nil {
-			writeErr = res.err
-		} else if err := z.writePackResult(zw, &res); err != nil {
-			writeErr = err
 		}
 
-		if res.comp != nil {
-			budget.release(res.entry.Info.Size())
-		}
+		// Release even for failed entries: a cancelled deflate still holds its
+		// budget, and the producer may be blocked waiting for it.
+		budget.Release(res.held)
 	}
 
 	if writeErr != nil {
@@ -317,7 +522,7 @@ func (z *zip) packEntriesParallel(entries []Entry, out io.Writer) error {
 // deflateEntry reads and raw-deflates a single regular file into a buffer,
 // returning the compressed bytes, CRC-32 (IEEE) and uncompressed size that
 // CreateRaw needs to emit the entry without re-compressing.
-func (z *zip) deflateEntry(e *Entry) ([]byte, uint32, int64, error) {
+func (z *zip) deflateEntry(ctx context.Context, e *Entry) ([]byte, uint32, int64, error) {
 	// Symlinks are handled by the writer and never followed here; only regular
 	// files from the user's own trusted folder are opened (no TOCTOU boundary).
 	f, err := os.Open(filepath.Clean(e.AbsPath)) // #nosec G304 G122
@@ -325,6 +530,7 @@ func (z *zip) deflateEntry(e *Entry) ([]byte, uint32, int64, error) {
 		return nil, 0, 0, lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
 	}
 	defer func() { _ = f.Close() }()
+	src := lib.ContextReader(ctx, f)
 
 	buf := bytes.NewBuffer(make([]byte, 0, e.Info.Size()/2+64))
 
@@ -337,7 +543,7 @@ func (z *zip) deflateEntry(e *Entry) ([]byte, uint32, int64, error) {
 		bufPtr = copyBufPool.Get().(*[]byte)
 	)
 	for {
-		n, readErr := f.Read(*bufPtr)
+		n, readErr := src.Read(*bufPtr)
 		if n > 0 {
 			chunk := (*bufPtr)[:n]
 			crc = crc32.Update(crc, crc32.IEEETable, chunk)
@@ -373,32 +579,14 @@ func (z *zip) deflateEntry(e *Entry) ([]byte, uint32, int64, error) {
 	return buf.Bytes(), crc, usize, nil
 }
 
-// writePackResult writes one already-compressed entry (or a symlink) into the
-// zip stream. Runs only in the single writer goroutine, so the packer's stat
-// fields are updated without locking.
+// writePackResult writes one already-compressed entry (or a stored one) into
+// the zip stream. Runs only in the single writer goroutine, so the packer's
+// stat fields are updated without locking.
 func (z *zip) writePackResult(zw *archiveZip.Writer, res *packResult) error {
 	e := res.entry
 
-	if e.IsSymlink {
-		h := &archiveZip.FileHeader{Name: e.RelPath, Method: archiveZip.Store}
-		h.SetMode(os.ModeSymlink | 0o777)
-
-		w, err := zw.CreateHeader(h)
-		if err != nil {
-			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateZipError, lib.ErrMessageCreateZipError, "", err)
-		}
-		if _, err = w.Write([]byte(e.LinkTarget)); err != nil {
-			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", err)
-		}
-
-		if z.progress != nil {
-			z.progress(int64(len(e.LinkTarget)))
-		}
-
-		z.uncompressedSize += int64(len(e.LinkTarget))
-		z.fileCount++
-		z.fileNameList = append(z.fileNameList, e.Info.Name())
-		return nil
+	if e.stored() {
+		return z.packStored(zw, e)
 	}
 
 	// Preserve mode and modtime from the walked FileInfo, then supply the
@@ -413,6 +601,9 @@ func (z *zip) writePackResult(zw *archiveZip.Writer, res *packResult) error {
 	h.CRC32 = res.crc
 	h.CompressedSize64 = uint64(len(res.comp)) // #nosec G115
 	h.UncompressedSize64 = uint64(res.usize)   // #nosec G115
+	if err = setEntryMeta(h, e); err != nil {
+		return err
+	}
 
 	w, err := zw.CreateRaw(h)
 	if err != nil {
@@ -429,71 +620,58 @@ func (z *zip) writePackResult(zw *archiveZip.Writer, res *packResult) error {
 	return nil
 }
 
-// byteSem is a weighted counting semaphore bounding total bytes in flight. A
-// request larger than the maximum is clamped so it can still proceed once the
-// pool is otherwise empty, rather than deadlocking.
-type byteSem struct {
-	mu   sync.Mutex
-	cond *sync.Cond
-	cur  int64
-	max  int64
-}
-
-func newByteSem(max int64) *byteSem {
-	s := &byteSem{max: max}
-	s.cond = sync.NewCond(&s.mu)
-	return s
-}
-
-func (s *byteSem) acquire(n int64) {
-	if n > s.max {
-		n = s.max
+// packStored - writes an entry without a file body to deflate: a symlink,
+// stored with its target as content, or a directory or hard link, which have
+// none. A directory is not counted as a file.
+func (z *zip) packStored(zw *archiveZip.Writer, e *Entry) error {
+	var (
+		h    = &archiveZip.FileHeader{Name: e.RelPath, Method: archiveZip.Store}
+		body []byte
+	)
+	switch {
+	case e.IsSymlink:
+		h.SetMode(os.ModeSymlink | 0o777)
+		body = []byte(e.LinkTarget)
+	case e.Info.IsDir():
+		h.Name += "/"
+		h.SetMode(e.Info.Mode())
+	default:
+		h.SetMode(e.Info.Mode())
+	}
+	if e.meta != nil {
+		h.Modified = e.Info.ModTime()
+	}
+	if err := setEntryMeta(h, e); err != nil {
+		return err
 	}
 
-	s.mu.Lock()
-	for s.cur > 0 && s.cur+n > s.max {
-		s.cond.Wait()
+	w, err := zw.CreateHeader(h)
+	if err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateZipError, lib.ErrMessageCreateZipError, "", err)
+	}
+	if _, err = w.Write(body); err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", err)
+	}
+	if e.Info.IsDir() {
+		return nil
 	}
-	s.cur += n
-	s.mu.Unlock()
-}
 
-func (s *byteSem) release(n int64) {
-	if n > s.max {
-		n = s.max
+	if z.progress != nil {
+		z.progress(int64(len(body)))
 	}
 
-	s.mu.Lock()
-	s.cur -= n
-	s.cond.Broadcast()
-	s.mu.Unlock()
+	z.uncompressedSize += int64(len(body))
+	z.fileCount++
+	z.fileNameList = append(z.fileNameList, e.Info.Name())
+	return nil
 }
 
-func (z *zip) packEntry(zw *archiveZip.Writer, e *Entry) error {
-	if e.IsSymlink {
-		h := &archiveZip.FileHeader{Name: e.RelPath, Method: archiveZip.Store}
-		h.SetMode(os.ModeSymlink | 0o777)
-
-		w, err := zw.CreateHeader(h)
-		if err != nil {
-			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateZipError, lib.ErrMessageCreateZipError, "", err)
-		}
-
-		if _, err := w.Write([]byte(e.LinkTarget)); err != nil {
-			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", err)
-		}
-
-		if z.progress != nil {
-			z.progress(int64(len(e.LinkTarget)))
-		}
-
-		z.uncompressedSize += int64(len(e.LinkTarget))
-		z.fileCount++
-		z.fileNameList = append(z.fileNameList, e.Info.Name())
-		return nil
+func (z *zip) packEntry(ctx context.Context, zw *archiveZip.Writer, e *Entry) error {
+	if e.stored() {
+		return z.packStored(zw, e)
 	}
 
-	// Symlinks are handled above and never followed here; only regular files
+	// Stored entries are handled above and never followed here; only regular files
 	// from the user's own trusted folder are opened, so there is no TOCTOU boundary.
 	f, err := os.Open(filepath.Clean(e.AbsPath)) // #nosec G304 G122
 	if err != nil {
@@ -508,6 +686,10 @@ func (z *zip) packEntry(zw *archiveZip.Writer, e *Entry) error {
 	h.Name = e.RelPath
 	h.Method = z.method
 	h.SetMode(e.Info.Mode())
+	if err = setEntryMeta(h, e); err != nil {
+		_ = f.Close()
+		return err
+	}
 
 	w, err := zw.CreateHeader(h)
 	if err != nil {
@@ -521,7 +703,7 @@ func (z *zip) packEntry(zw *archiveZip.Writer, e *Entry) error {
 	}
 
 	bufPtr := copyBufPool.Get().(*[]byte)
-	_, copyErr := io.CopyBuffer(dst, f, *bufPtr)
+	_, copyErr := io.CopyBuffer(dst, lib.ContextReader(ctx, f), *bufPtr)
 	copyBufPool.Put(bufPtr)
 
 	if errClose := f.Close(); errClose != nil {
@@ -539,25 +721,50 @@ func (z *zip) packEntry(zw *archiveZip.Writer, e *Entry) error {
 }
 
 func (z *zip) Unpack(data []byte, targetDir string) error {
-	return z.UnpackFrom(bytes.NewReader(data), int64(len(data)), targetDir)
+	return z.UnpackFrom(context.Background(), bytes.NewReader(data), int64(len(data)), targetDir)
 }
 
 // unpackJob is a regular file or symlink entry queued for parallel extraction,
-// with its already-validated destination path.
+// with its already-validated destination path and recorded metadata, if any.
+// For a directory or hard link it is applied once every file is extracted.
+// replace is set when the conflict policy overwrites what is at dst, and
+// linkTo is the path a hard link's file was extracted to.
 type unpackJob struct {
-	file *archiveZip.File
-	dst  string
+	file    *archiveZip.File
+	dst     string
+	meta    *entryMeta
+	replace bool
+	linkTo  string
 }
 
 // UnpackFrom - unzip from file-like ReaderAt (e.g., *os.File).
 //
 // Extraction runs in two passes: a sequential pass validates every entry path
-// (fail-fast on traversal) and creates directories, then regular files and
-// symlinks — which write to distinct paths and can be inflated independently —
-// are extracted across a worker pool. archive/zip's per-file Open reads through
+// (fail-fast on traversal) and, once conflicts are resolved, creates
+// directories, then regular files and symlinks — which write to distinct
+// paths and can be inflated independently — are extracted across a worker pool. archive/zip's per-file Open reads through
 // the shared ReaderAt (an *os.File or bytes.Reader), whose ReadAt is safe for
 // concurrent use, so the workers do not contend on a single stream position.
-func (z *zip) UnpackFrom(r io.ReaderAt, size int64, targetDir string) error {
+//
+// Entries packed with lib.PreserveAll carry their metadata in an extra field:
+// it is restored on each file as it is extracted, hard links are created once
+// the files they point at are in place, and directories get theirs last, so
+// extracting into them does not change their modification times. Ownership is
+// restored only when running as root.
+//
+// An archive beyond the limits set with SetLimits is rejected before anything
+// is written. So are the entries whose path already exists: they are resolved
+// by the conflict policy (see SetConflictPolicy) and recorded in the extract
+// report, so lib.ConflictFail and a dry run leave the target as it was.
+// The symlink policy (see SetSymlinkPolicy) is applied first; under all but
+// lib.SymlinksAllow no entry is written through a symlink, and files and
+// links are created through an os.Root of targetDir, which cannot be left.
+//
+// Cancelling ctx stops extraction at the next entry or read, removes the files
+// and directories this extraction created, and returns ctx.Err(). Files that
+// already existed are left in place, and so are the files an ExtractLog
+// recorded, for a resumed extraction to skip.
+func (z *zip) UnpackFrom(ctx context.Context, r io.ReaderAt, size int64, targetDir string) (err error) {
 	zr, err := archiveZip.NewReader(r, size)
 	if err != nil {
 		return lib.IOErr(
@@ -569,33 +776,118 @@ func (z *zip) UnpackFrom(r io.ReaderAt, size int64, targetDir string) error {
 		)
 	}
 
+	if err = z.limits.check(zr.File); err != nil {
+		return err
+	}
+
 	base := filepath.Clean(targetDir) + string(os.PathSeparator)
 
-	jobs := make([]unpackJob, 0, len(zr.File))
+	created := &unpackLog{}
+	defer func() {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			created.rollback()
+			err = ctxErr
+		}
+	}()
+
+	var jobs, links, dirs []unpackJob
 	for _, f := range zr.File {
-		rel := filepath.FromSlash(f.Name)
-		dst := filepath.Clean(filepath.Join(targetDir, rel)) // #nosec G305
+		if ctx.Err() != nil {
+			return nil
+		}
 
-		if !strings.HasPrefix(dst+string(os.PathSeparator), base) && dst != filepath.Clean(targetDir) {
-			return lib.IOErr(
-				lib.CategoryCompression,
-				lib.ErrCodeCreateDirectoryError,
-				lib.ErrMessageCreateDirectoryError,
-				"zip path traversal detected",
-				fmt.Errorf("invalid zip entry path: %q", f.Name),
-			)
+		dst, err := entryPath(targetDir, base, f.Name)
+		if err != nil {
+			return err
+		}
+
+		meta, err := readFileMeta(f)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case f.FileInfo().IsDir():
+			dirs = append(dirs, unpackJob{file: f, dst: dst, meta: meta})
+		case meta != nil && meta.Link != "":
+			links = append(links, unpackJob{file: f, dst: dst, meta: meta})
+		default:
+			jobs = append(jobs, unpackJob{file: f, dst: dst, meta: meta})
 		}
+	}
 
-		if f.FileInfo().IsDir() {
-			if err = os.MkdirAll(dst, 0o750); err != nil {
-				return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateDirectoryError, lib.ErrMessageCreateDirectoryError, "", err)
+	if jobs, err = z.checkSymlinks(targetDir, jobs, links, dirs); err != nil {
+		return err
+	}
+	if jobs, links, err = z.planEntries(targetDir, jobs, links); err != nil || z.dryRun {
+		return err
+	}
+
+	// Files, symlinks and hard links are created through root when the
+	// symlink policy confines writes, so none can leave targetDir.
+	var root *os.Root
+	if z.confined() {
+		if err = created.mkdirAll(targetDir); err != nil {
+			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateDirectoryError, lib.ErrMessageCreateDirectoryError, "", err)
+		}
+		if root, err = os.OpenRoot(targetDir); err != nil {
+			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOpenFileError, lib.ErrMessageOpenFileError, "", err)
+		}
+		defer func() { _ = root.Close() }()
+	}
+
+	for _, j := range dirs {
+		if err = created.mkdirAll(j.dst); err != nil {
+			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateDirectoryError, lib.ErrMessageCreateDirectoryError, "", err)
+		}
+	}
+
+	if err = z.extractEntries(ctx, created, root, jobs); err != nil || ctx.Err() != nil {
+		return err
+	}
+
+	for _, j := range links {
+		src := j.linkTo
+		if src == "" {
+			if src, err = entryPath(targetDir, base, j.meta.Link); err != nil {
+				return err
 			}
-			continue
 		}
+		if err = linkEntry(created, root, src, j.dst); err != nil {
+			return err
+		}
+	}
+
+	for _, j := range dirs {
+		if err = restoreEntryMeta(j.dst, z.entryMode(j.file), j.meta, z.preserve); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryPath - the path under targetDir the entry name extracts to, which must
+// not leave it; base is targetDir with a trailing separator.
+func entryPath(targetDir, base, name string) (string, error) {
+	dst := filepath.Clean(filepath.Join(targetDir, filepath.FromSlash(name))) // #nosec G305
 
-		jobs = append(jobs, unpackJob{file: f, dst: dst})
+	if !strings.HasPrefix(dst+string(os.PathSeparator), base) && dst != filepath.Clean(targetDir) {
+		return "", lib.IOErr(
+			lib.CategoryCompression,
+			lib.ErrCodeCreateDirectoryError,
+			lib.ErrMessageCreateDirectoryError,
+			"zip path traversal detected",
+			fmt.Errorf("invalid zip entry path: %q", name),
+		)
 	}
 
+	return dst, nil
+}
+
+// extractEntries - extracts the regular files and symlinks of jobs across a
+// worker pool, returning the first error.
+func (z *zip) extractEntries(ctx context.Context, created *unpackLog, root *os.Root, jobs []unpackJob) error {
 	if len(jobs) == 0 {
 		return nil
 	}
@@ -619,7 +911,7 @@ func (z *zip) UnpackFrom(r io.ReaderAt, size int64, targetDir string) error {
 		stop     atomic.Bool
 	)
 	for i := range jobs {
-		if stop.Load() {
+		if stop.Load() || ctx.Err() != nil {
 			break
 		}
 
@@ -634,7 +926,7 @@ func (z *zip) UnpackFrom(r io.ReaderAt, size int64, targetDir string) error {
 			if stop.Load() {
 				return
 			}
-			if e := z.extractEntry(j.file, j.dst); e != nil {
+			if e := z.extractEntry(ctx, created, root, j); e != nil {
 				errOnce.Do(func() {
 					firstErr = e
 					stop.Store(true)
@@ -647,10 +939,14 @@ func (z *zip) UnpackFrom(r io.ReaderAt, size int64, targetDir string) error {
 	return firstErr
 }
 
-// extractEntry writes one regular file or symlink to dst, creating its parent
-// directory. Safe to call concurrently for distinct dst paths.
-func (z *zip) extractEntry(f *archiveZip.File, dst string) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+// extractEntry writes one regular file or symlink to dst, through root when
+// it is set, creating its parent directory, restores its recorded metadata,
+// and records what it creates in created. Safe to call concurrently for
+// distinct dst paths.
+func (z *zip) extractEntry(ctx context.Context, created *unpackLog, root *os.Root, j unpackJob) error {
+	f, dst := j.file, j.dst
+
+	if err := created.mkdirAll(filepath.Dir(dst)); err != nil {
 		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateDirectoryError, lib.ErrMessageCreateDirectoryError, "", err)
 	}
 
@@ -668,20 +964,59 @@ func (z *zip) extractEntry(f *archiveZip.File, dst string) error {
 			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", errRead)
 		}
 
-		if err := os.Symlink(string(b), dst); err != nil {
-			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
-		}
-
 		if z.progress != nil {
 			z.progress(int64(len(b)))
 		}
 
-		return nil
+		// A link's target is its whole content, so a resumed extraction checks
+		// an existing link directly instead of against the log.
+		sum := sha256.Sum256(b)
+		if z.extractLog != nil && symlinkHasTarget(dst, string(b)) {
+			return z.logExtracted(created, f.Name, dst, sum[:])
+		}
+
+		if j.replace {
+			if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+			}
+		}
+		if err := symlinkEntry(root, string(b), dst); err != nil {
+			return err
+		}
+		if !j.replace {
+			created.file(dst)
+		}
+
+		if err := restoreEntryMeta(dst, z.entryMode(f), j.meta, z.preserve); err != nil {
+			return err
+		}
+
+		return z.logExtracted(created, f.Name, dst, sum[:])
 	}
 
-	out, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if z.extractLog != nil {
+		if done, ok := z.extractLog.Done(f.Name); ok && fileHasSum(dst, done) {
+			if z.progress != nil {
+				z.progress(int64(f.UncompressedSize64)) // #nosec G115
+			}
+
+			return nil
+		}
+	}
+
+	existing, statErr := os.Lstat(dst)
+	if statErr == nil && j.replace && !existing.Mode().IsRegular() {
+		// Truncating would follow a symlink out of the target.
+		if err := os.Remove(dst); err != nil {
+			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+		}
+	}
+	out, err := openEntryFile(root, dst, z.entryMode(f))
 	if err != nil {
-		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+		return err
+	}
+	if errors.Is(statErr, fs.ErrNotExist) {
+		created.file(dst)
 	}
 
 	rc, err := f.Open()
@@ -695,8 +1030,15 @@ func (z *zip) extractEntry(f *archiveZip.File, dst string) error {
 		unpackDst = &progressCountWriter{w: out, fn: z.progress}
 	}
 
+	hash := sha256.New()
+	if z.extractLog != nil {
+		unpackDst = io.MultiWriter(unpackDst, hash)
+	}
+
+	// archive/zip fails a read past the entry's recorded size, the one the
+	// limits were checked against, so the copy is bounded.
 	bufPtr := copyBufPool.Get().(*[]byte)
-	_, copyErr := io.CopyBuffer(unpackDst, rc, *bufPtr) // #nosec G110
+	_, copyErr := io.CopyBuffer(unpackDst, lib.ContextReader(ctx, rc), *bufPtr) // #nosec G110
 	copyBufPool.Put(bufPtr)
 
 	if errClose := out.Close(); errClose != nil {
@@ -709,9 +1051,152 @@ func (z *zip) extractEntry(f *archiveZip.File, dst string) error {
 		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", copyErr)
 	}
 
+	if err = restoreEntryMeta(dst, z.entryMode(f), j.meta, z.preserve); err != nil {
+		return err
+	}
+
+	return z.logExtracted(created, f.Name, dst, hash.Sum(nil))
+}
+
+// linkEntry - creates dst as a hard link to the extracted file src, through
+// root when it is set, replacing what is at dst unless it already is that
+// file.
+func linkEntry(created *unpackLog, root *os.Root, src, dst string) error {
+	if err := created.mkdirAll(filepath.Dir(dst)); err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeCreateDirectoryError, lib.ErrMessageCreateDirectoryError, "", err)
+	}
+
+	existing, statErr := os.Lstat(dst)
+	if statErr == nil {
+		if target, err := os.Lstat(src); err == nil && os.SameFile(existing, target) {
+			return nil
+		}
+		if err := os.Remove(dst); err != nil {
+			return lib.IOErr(lib.CategoryCompression, lib.ErrCodeOSOpenFileError, lib.ErrMessageOSOpenFileError, "", err)
+		}
+	}
+
+	if err := hardLink(root, src, dst); err != nil {
+		return err
+	}
+	if errors.Is(statErr, fs.ErrNotExist) {
+		created.file(dst)
+	}
+
 	return nil
 }
 
+// logExtracted - records the entry name, extracted to dst, in the extract log
+// if one is set, and keeps dst out of a rollback.
+func (z *zip) logExtracted(created *unpackLog, name, dst string, sum []byte) error {
+	if z.extractLog == nil {
+		return nil
+	}
+
+	if err := z.extractLog.Extracted(name, sum); err != nil {
+		return lib.IOErr(lib.CategoryCompression, lib.ErrCodeIOCopyError, lib.ErrMessageIOCopyError, "", err)
+	}
+	created.keep(dst)
+
+	return nil
+}
+
+// fileHasSum - reports whether the regular file at path hashes to sum.
+func fileHasSum(path string, sum []byte) bool {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	if st, errStat := f.Stat(); errStat != nil || !st.Mode().IsRegular() {
+		return false
+	}
+
+	hash := sha256.New()
+	bufPtr := copyBufPool.Get().(*[]byte)
+	_, err = io.CopyBuffer(hash, f, *bufPtr)
+	copyBufPool.Put(bufPtr)
+
+	return err == nil && bytes.Equal(hash.Sum(nil), sum)
+}
+
+// symlinkHasTarget - reports whether path is a symlink pointing at target.
+func symlinkHasTarget(path, target string) bool {
+	got, err := os.Readlink(path)
+	return err == nil && got == target
+}
+
+// unpackLog records the files and directories an extraction creates, so a
+// cancelled extraction can remove them again. Files an ExtractLog recorded are
+// kept for the resumed extraction. Safe for concurrent use.
+type unpackLog struct {
+	mu    sync.Mutex
+	files []string
+	dirs  []string
+	kept  map[string]bool
+}
+
+// mkdirAll - os.MkdirAll, recording every directory it creates.
+func (l *unpackLog) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		missing = append(missing, d)
+
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.dirs = append(l.dirs, missing...)
+	l.mu.Unlock()
+
+	return nil
+}
+
+func (l *unpackLog) file(path string) {
+	l.mu.Lock()
+	l.files = append(l.files, path)
+	l.mu.Unlock()
+}
+
+// keep - leaves the complete file at path in place on rollback.
+func (l *unpackLog) keep(path string) {
+	l.mu.Lock()
+	if l.kept == nil {
+		l.kept = make(map[string]bool)
+	}
+	l.kept[path] = true
+	l.mu.Unlock()
+}
+
+// rollback - removes the recorded files that are not kept, then the recorded
+// directories deepest first, so each is empty by the time it is removed; a
+// directory still holding a kept file stays.
+func (l *unpackLog) rollback() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, path := range l.files {
+		if !l.kept[path] {
+			_ = os.Remove(path)
+		}
+	}
+
+	sort.Slice(l.dirs, func(i, j int) bool { return len(l.dirs[i]) > len(l.dirs[j]) })
+	for _, dir := range l.dirs {
+		_ = os.Remove(dir)
+	}
+}
+
 func (z *zip) GetUncompressedSize() int64 {
 	return z.uncompressedSize
 }
@@ -3,9 +3,13 @@ package zip
 import (
 	archiveZip "archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/namelesscorp/tvault-core/compression"
 )
@@ -171,10 +175,10 @@ func TestWalkFolderPackEntriesMatchesPackTo(t *testing.T) {
 	// PackEntriesTo (single-walk path) and PackTo (walks internally) must produce
 	// archives with the same set of entries.
 	var viaEntries, viaPackTo bytes.Buffer
-	if err = New().(*zip).PackEntriesTo(entries, &viaEntries); err != nil {
+	if err = New().(*zip).PackEntriesTo(context.Background(), entries, &viaEntries); err != nil {
 		t.Fatalf("PackEntriesTo failed: %v", err)
 	}
-	if err = New().PackTo(tempDir, &viaPackTo); err != nil {
+	if err = New().PackTo(context.Background(), tempDir, &viaPackTo); err != nil {
 		t.Fatalf("PackTo failed: %v", err)
 	}
 
@@ -213,3 +217,195 @@ func TestZipPackError(t *testing.T) {
 		t.Error("Expected error when packing non-existent directory, got nil")
 	}
 }
+
+func TestZipUnpackFromCancelRemovesExtracted(t *testing.T) {
+	srcDir := t.TempDir()
+	for _, name := range []string{"a.bin", "b.bin", filepath.Join("sub", "c.bin"), filepath.Join("sub", "deep", "d.bin")} {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, bytes.Repeat([]byte(name), 64*1024), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	packed, err := New().Pack(srcDir)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	// A file already in the target must survive the rollback.
+	targetDir := filepath.Join(t.TempDir(), "out")
+	if err = os.MkdirAll(targetDir, 0750); err != nil {
+		t.Fatalf("Failed to create target: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(targetDir, "keep.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel as soon as the first bytes reach the disk.
+	z := New().(*zip)
+	z.SetProgress(func(int64) { cancel() })
+
+	if err = z.UnpackFrom(ctx, bytes.NewReader(packed), int64(len(packed)), targetDir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		t.Fatalf("Failed to read target: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "keep.txt" {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("Expected only keep.txt after cancel, got %v", names)
+	}
+}
+
+func TestZipPackEntriesToCancel(t *testing.T) {
+	srcDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+
+	entries, _, _, _, err := WalkFolder(srcDir)
+	if err != nil {
+		t.Fatalf("WalkFolder failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, z := range []compression.Compression{New(), NewStore()} {
+		var out bytes.Buffer
+		if err = z.(*zip).PackEntriesTo(ctx, entries, &out); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled for type %d, got %v", z.ID(), err)
+		}
+	}
+}
+
+// bigInfo reports a large size for a file that does not exist, so a worker
+// reserves most of the pack budget and then fails to open it.
+type bigInfo struct{ os.FileInfo }
+
+func (bigInfo) Size() int64 { return packBudgetBytes - 1 }
+
+func TestZipPackEntriesFailedEntryReleasesBudget(t *testing.T) {
+	srcDir := t.TempDir()
+	path := filepath.Join(srcDir, "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	entries := make([]Entry, 3)
+	for i := range entries {
+		entries[i] = Entry{
+			AbsPath: filepath.Join(srcDir, "missing.txt"),
+			RelPath: "missing.txt",
+			Info:    bigInfo{info},
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- New().(*zip).PackEntriesTo(context.Background(), entries, &bytes.Buffer{}) }()
+
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("Expected error packing missing files, got nil")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("PackEntriesTo hung: a failed entry did not release its budget")
+	}
+}
+
+// memExtractLog is an in-memory ExtractLog.
+type memExtractLog struct {
+	mu   sync.Mutex
+	sums map[string][]byte
+}
+
+func (l *memExtractLog) Done(name string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sum, ok := l.sums[name]
+	return sum, ok
+}
+
+func (l *memExtractLog) Extracted(name string, sum []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sums[name] = sum
+	return nil
+}
+
+func TestZipUnpackFromExtractLogSkipsVerifiedEntries(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, data := range map[string]string{"a.txt": "alpha", "b.txt": "bravo"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(srcDir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	packed, err := New().Pack(srcDir)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	targetDir := t.TempDir()
+	log := &memExtractLog{sums: make(map[string][]byte)}
+	unpack := func() {
+		t.Helper()
+
+		z := New().(*zip)
+		z.SetExtractLog(log)
+		if err := z.UnpackFrom(context.Background(), bytes.NewReader(packed), int64(len(packed)), targetDir); err != nil {
+			t.Fatalf("UnpackFrom failed: %v", err)
+		}
+	}
+
+	unpack()
+	if len(log.sums) != 3 {
+		t.Fatalf("Expected 3 logged entries, got %d", len(log.sums))
+	}
+
+	// An entry whose file still matches is skipped, one that changed since is
+	// extracted again, and the existing link does not fail the second run.
+	old := time.Unix(1_000_000_000, 0)
+	aPath := filepath.Join(targetDir, "a.txt")
+	if err = os.Chtimes(aPath, old, old); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(targetDir, "b.txt"), []byte("tampered"), 0644); err != nil {
+		t.Fatalf("Failed to change file: %v", err)
+	}
+
+	unpack()
+
+	if st, errStat := os.Stat(aPath); errStat != nil || !st.ModTime().Equal(old) {
+		t.Errorf("Expected a.txt to be skipped, stat %v, err %v", st, errStat)
+	}
+	if got, _ := os.ReadFile(filepath.Join(targetDir, "b.txt")); string(got) != "bravo" {
+		t.Errorf("Expected b.txt to be extracted again, got %q", got)
+	}
+	if target, _ := os.Readlink(filepath.Join(targetDir, "link")); target != "a.txt" {
+		t.Errorf("Expected link to a.txt, got %q", target)
+	}
+}
@@ -0,0 +1,153 @@
+package container
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// chunkBudgetBytes bounds the total chunk buffers held in flight across the
+// read, seal/open and write stages, so a many-core machine cannot hold dozens
+// of 16 MiB chunks at once. With the default ChunkSize this still keeps 15
+// chunks in flight, enough to saturate AES-GCM on most hosts.
+const chunkBudgetBytes = 256 * 1024 * 1024
+
+// chunk is one payload chunk moving through the pipeline. data holds the
+// plaintext before sealing and the ciphertext after it (and the reverse when
+// opening); both GCM directions work in place, so one buffer serves a chunk.
+type chunk struct {
+	counter  uint64
+	plainLen int
+	data     []byte
+	buf      *[]byte
+	cost     int64
+	err      error
+	// ref is the plaintext a chunk already on disk must decrypt to; set only
+	// while ResumeEncrypted verifies a payload.
+	ref []byte
+}
+
+// chunkPool hands out chunk buffers within the byte budget and recycles them
+// once the writer is done with a chunk.
+type chunkPool struct {
+	budget *lib.ByteSem
+	bufs   sync.Pool
+}
+
+func newChunkPool() *chunkPool {
+	return &chunkPool{budget: lib.NewByteSem(chunkBudgetBytes)}
+}
+
+// get - reserves n bytes of budget and returns a chunk whose data has length n.
+func (p *chunkPool) get(n int) *chunk {
+	p.budget.Acquire(int64(n))
+
+	buf, _ := p.bufs.Get().(*[]byte)
+	if buf == nil || cap(*buf) < n {
+		b := make([]byte, n)
+		buf = &b
+	}
+
+	return &chunk{data: (*buf)[:n], buf: buf, cost: int64(n)}
+}
+
+// put - releases a chunk's budget and buffer. Chunks without a buffer (a
+// pipeline error) are ignored.
+func (p *chunkPool) put(c *chunk) {
+	if c.buf == nil {
+		return
+	}
+
+	p.budget.Release(c.cost)
+	p.bufs.Put(c.buf)
+	c.buf, c.data = nil, nil
+}
+
+// runChunks reads chunks with next, transforms them with work on up to
+// GOMAXPROCS goroutines and hands them to emit in the order they were read.
+// Ordering follows the zip packer: the reader queues one result channel per
+// chunk and the writer drains them in that order, so chunk N is emitted before
+// N+1 even if it finished later.
+//
+// next returns (nil, nil) at the end of the stream; an error from next, work
+// or emit, or ctx being cancelled, stops reading and is returned once the
+// chunks in flight are drained.
+func runChunks(
+	ctx context.Context,
+	pool *chunkPool,
+	next func() (*chunk, error),
+	work func(*chunk) error,
+	emit func(*chunk) error,
+) error {
+	workers := runtime.GOMAXPROCS(0)
+
+	var (
+		slots   = make(chan struct{}, workers)    // caps live goroutines
+		results = make(chan chan *chunk, workers) // ordered result handoff
+		stop    = make(chan struct{})             // closed by the writer on error
+	)
+
+	go func() {
+		defer close(results)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			c, err := next()
+			if c == nil && err == nil {
+				return
+			}
+
+			ch := make(chan *chunk, 1)
+			results <- ch
+
+			if err != nil {
+				ch <- &chunk{err: err}
+				return
+			}
+
+			slots <- struct{}{}
+			go func(c *chunk, ch chan *chunk) {
+				defer func() { <-slots }()
+
+				c.err = work(c)
+				ch <- c
+			}(c, ch)
+		}
+	}()
+
+	// Writer: emit results in order. After the first error keep draining so
+	// every buffer returns to the budget and no goroutine is left blocked.
+	var firstErr error
+	for ch := range results {
+		c := <-ch
+		if firstErr == nil {
+			if c.err != nil {
+				firstErr = c.err
+			} else if err := ctx.Err(); err != nil {
+				firstErr = err
+			} else if err := emit(c); err != nil {
+				firstErr = err
+			}
+			if firstErr != nil {
+				close(stop)
+			}
+		}
+		pool.put(c)
+	}
+
+	// The reader stops without an error when ctx is cancelled between chunks.
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	return firstErr
+}
@@ -1,27 +1,32 @@
 package container
 
-// Container implementation for Trust Vault Core (format v1).
+// Container implementation for Trust Vault Core (format v2).
 // --------------------------------------------------------------
 //
-// +--------+-------+-------------------------------------------+
-// | Offset | Size  | Field									    |
-// +--------+-------+-------------------------------------------+
-// | 0x00   | 4	  | "TVLT" signature						    |
-// | 0x04   | 1	  | version								        |
-// | 0x05   | 1	  | flags (reserved)						    |
-// | 0x06   | 16  | salt (PBKDF2)						        |
-// | 0x16   | 4	  | iterations (PBKDF2)					        |
-// | 0x1A   | 1	  | compression type					     	|
-// | 0x1B   | 1	  | integrity provider type				     	|
-// | 0x1C   | 1	  | token type							        |
-// | 0x1D   | 12  | nonce (AES-GCM)						        |
-// | 0x29   | 4	  | metadata length						        |
-// | 0x2D   | 1	  | shares								        |
-// | 0x2E   | 1	  | threshold							        |
-// | 0x2F   | 4	  | chunk size (plaintext bytes)			        |
-// | 0x33   | N	  | metadata JSON (plaintext)			        |
-// | 0x33+N | ... | length-prefixed AES-GCM chunks		        |
-// +--------+-------+-------------------------------------------+
+// +--------+------+------------------------------------------+
+// | Offset | Size | Field                                    |
+// +--------+------+------------------------------------------+
+// | 0x00   | 4    | "TVLT" signature                         |
+// | 0x04   | 1    | version (1 or 2)                         |
+// | 0x05   | 1    | flags (reserved)                         |
+// | 0x06   | 16   | salt (PBKDF2)                            |
+// | 0x16   | 4    | iterations (PBKDF2)                      |
+// | 0x1A   | 1    | compression type                         |
+// | 0x1B   | 1    | integrity provider type                  |
+// | 0x1C   | 1    | token type                               |
+// | 0x1D   | 12   | nonce (AES-GCM)                          |
+// | 0x29   | 4    | metadata length                          |
+// | 0x2D   | 1    | shares                                   |
+// | 0x2E   | 1    | threshold                                |
+// | 0x2F   | 4    | chunk size (plaintext bytes)             |
+// | 0x33   | 4    | key epoch (version 2 only)               |
+// | H      | N    | metadata JSON (plaintext)                |
+// | H+N    | ...  | length-prefixed AES-GCM chunks           |
+// +--------+------+------------------------------------------+
+//
+// The header size H depends on the version: 0x37 for version 2, and 0x33 for
+// version 1, which has no key epoch field. Version 1 containers are read with
+// key epoch 0 and written back as version 2.
 //
 // The payload is a sequence of chunks, each a little-endian uint32 plaintext
 // length followed by that chunk's ciphertext + 16-byte GCM tag, terminated by
@@ -30,6 +35,7 @@ package container
 //
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -40,16 +46,22 @@ import (
 	"math"
 	"os"
 
+	"github.com/namelesscorp/tvault-core/compression"
 	"github.com/namelesscorp/tvault-core/lib"
 )
 
 type (
 	// Container - defines an interface for creating, opening, decrypting, and retrieving data from a container.
 	Container interface {
-		WriteEncrypted(r io.Reader, key []byte) error
+		WriteEncrypted(ctx context.Context, r io.Reader, key []byte) error
+		WriteEncryptedTo(ctx context.Context, r io.Reader, w io.Writer, key []byte) error
+		ResumeEncrypted(ctx context.Context, r io.Reader, from Checkpoint) error
+		SetCheckpoint(every int64, fn CheckpointFunc)
 
 		Read() error
-		DecryptTo(w io.Writer, masterKey []byte) error
+		DecryptTo(ctx context.Context, w io.Writer, masterKey []byte) error
+		CheckKey(masterKey []byte) error
+		Rewrite(path string) error
 
 		GetHeader() Header
 		GetMetadata() Metadata
@@ -58,6 +70,7 @@ type (
 		SetPath(path string)
 		SetMasterKey(key []byte)
 		SetMetadata(metadata Metadata)
+		SetHeader(header Header)
 	}
 
 	container struct {
@@ -65,6 +78,9 @@ type (
 		header    Header
 		metadata  Metadata
 		masterKey []byte
+
+		checkpoint      CheckpointFunc
+		checkpointEvery int64
 	}
 )
 
@@ -86,30 +102,25 @@ func NewContainer(
 	}
 }
 
-// WriteEncrypted - writes encrypted data to the container
-func (c *container) WriteEncrypted(r io.Reader, key []byte) error {
-	if len(c.masterKey) == 0 || c.masterKey == nil {
-		c.masterKey = lib.PBKDF2Key(key, c.header.Salt[:], c.header.Iterations, lib.KeyLen)
-	}
-
-	block, err := aes.NewCipher(c.masterKey)
+// WriteEncrypted - writes encrypted data to the container. A container left
+// incomplete by an error or by ctx being cancelled is removed, unless a
+// checkpoint function is set: the caller then owns the file, which
+// ResumeEncrypted can complete.
+func (c *container) WriteEncrypted(ctx context.Context, r io.Reader, key []byte) (err error) {
+	aesGcm, err := c.newSealCipher(key)
 	if err != nil {
-		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewCipherError, lib.ErrMessageCreateNewCipherError, "", err)
-	}
-	aesGcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewGCMError, lib.ErrMessageCreateNewGCMError, "", err)
-	}
-
-	if _, err = io.ReadFull(rand.Reader, c.header.Nonce[:]); err != nil {
-		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeGenerateNonceError, lib.ErrMessageGenerateNonceError, "", err)
+		return err
 	}
 
 	// The compressed size is only known once the whole stream has been consumed,
 	// but the metadata is written before the payload. Marshal it with the widest
 	// possible CompressedSize so the field can be patched in place afterwards
-	// without changing the metadata length (see patch below the streaming loop).
+	// without changing the metadata length (see writePayload). A raw payload is
+	// stored as is, so its UncompressedSize is patched the same way.
 	c.metadata.CompressedSize = math.MaxInt64
+	if c.header.CompressionType == compression.TypeRaw {
+		c.metadata.UncompressedSize = math.MaxInt64
+	}
 	metaBytes, err := json.Marshal(c.metadata)
 	if err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeJSONMarshalMetadataError, lib.ErrMessageJSONMarshalMetadataError, "", err)
@@ -125,8 +136,13 @@ func (c *container) WriteEncrypted(r io.Reader, key []byte) error {
 		if errClose := f.Close(); errClose != nil {
 			fmt.Printf("error closing file; %v", errClose)
 		}
+		if err != nil && c.checkpoint == nil {
+			_ = os.Remove(c.path)
+		}
 	}()
 
+	// A version 1 header read from disk is written back in the current layout.
+	c.header.Version = Version
 	if err = binary.Write(f, binary.LittleEndian, &c.header); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteHeaderBinaryError, lib.ErrMessageWriteHeaderBinaryError, "", err)
 	}
@@ -134,69 +150,107 @@ func (c *container) WriteEncrypted(r io.Reader, key []byte) error {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteMetadataError, lib.ErrMessageWriteMetadataError, "", err)
 	}
 
-	var chunkSize = int(c.header.ChunkSize)
-	if chunkSize <= 0 {
-		chunkSize = 4 * 1024 * 1024
+	return c.writePayload(ctx, f, f, aesGcm, r, Checkpoint{Offset: c.header.Size() + int64(metadataSize)})
+}
+
+// WriteEncryptedTo - writes the container to w, a stream such as stdout that
+// cannot seek. The metadata precedes the payload and cannot be patched once
+// it is known, so CompressedSize, and UncompressedSize for a raw payload, are
+// recorded as 0. w is neither synced nor removed on failure, and no
+// checkpoint is taken.
+func (c *container) WriteEncryptedTo(ctx context.Context, r io.Reader, w io.Writer, key []byte) error {
+	aesGcm, err := c.newSealCipher(key)
+	if err != nil {
+		return err
 	}
 
-	var (
-		plainBuf = make([]byte, chunkSize)
-		// Reused across chunks so Seal does not allocate a fresh ciphertext
-		// slice every iteration. Capacity holds a full chunk plus the GCM tag.
-		cipherBuf        = make([]byte, 0, chunkSize+aesGcm.Overhead())
-		lenBuf           = make([]byte, 4)
-		counter   uint64 = 0
-		// Total plaintext (i.e. compressed archive) bytes consumed from the
-		// stream; patched into the metadata's CompressedSize once known.
-		compressedSize int64 = 0
-	)
-	for {
-		// io.ReadFull coalesces the many small reads returned by the unbuffered
-		// source pipe (archive/zip+flate flushes in small blocks) into a full
-		// chunkSize block. Without it, each tiny read became its own AES-GCM
-		// chunk with a 16-byte tag + 4-byte length, producing hundreds of
-		// thousands of chunks and allocations for large inputs.
-		n, readErr := io.ReadFull(r, plainBuf)
-		if n > 0 {
-			nonce := c.header.Nonce
-			binary.LittleEndian.PutUint64(nonce[4:], counter)
-
-			// nonce is a random prefix (crypto/rand, see header.Nonce) combined
-			// with a per-chunk counter, so it is unique per chunk and not hardcoded.
-			cipherChunk := aesGcm.Seal(cipherBuf[:0], nonce[:], plainBuf[:n], nil) // #nosec G407
-
-			// n is the number of bytes read into plainBuf, so 0 <= n <= chunkSize <= math.MaxUint32.
-			binary.LittleEndian.PutUint32(lenBuf, uint32(n)) // #nosec G115
-			if _, err := f.Write(lenBuf); err != nil {
-				return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
-			}
-			if _, err := f.Write(cipherChunk); err != nil {
-				return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
-			}
+	c.metadata.CompressedSize = 0
+	if c.header.CompressionType == compression.TypeRaw {
+		c.metadata.UncompressedSize = 0
+	}
+	metaBytes, err := json.Marshal(c.metadata)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeJSONMarshalMetadataError, lib.ErrMessageJSONMarshalMetadataError, "", err)
+	}
+	c.header.MetadataSize = uint32(len(metaBytes)) // #nosec G115
 
-			compressedSize += int64(n)
-			counter++
-		}
+	c.header.Version = Version
+	if err = binary.Write(w, binary.LittleEndian, &c.header); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteHeaderBinaryError, lib.ErrMessageWriteHeaderBinaryError, "", err)
+	}
+	if _, err = w.Write(metaBytes); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteMetadataError, lib.ErrMessageWriteMetadataError, "", err)
+	}
 
-		if readErr != nil {
-			// EOF (clean end) and ErrUnexpectedEOF (final short chunk) both mean
-			// the stream is fully consumed; anything else is a real read error.
-			if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
-				break
-			}
-			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", readErr)
+	_, err = c.writeChunks(ctx, w, aesGcm, r, Checkpoint{Offset: c.header.Size() + int64(len(metaBytes))}, nil)
+	return err
+}
+
+// newSealCipher - derives the master key from key unless one is set, and
+// returns its AES-GCM cipher after drawing a fresh base nonce.
+func (c *container) newSealCipher(key []byte) (cipher.AEAD, error) {
+	if len(c.masterKey) == 0 || c.masterKey == nil {
+		c.masterKey = lib.PBKDF2Key(key, c.header.Salt[:], c.header.Iterations, lib.KeyLen)
+	}
+
+	block, err := aes.NewCipher(c.masterKey)
+	if err != nil {
+		return nil, lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewCipherError, lib.ErrMessageCreateNewCipherError, "", err)
+	}
+	aesGcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewGCMError, lib.ErrMessageCreateNewGCMError, "", err)
+	}
+
+	if _, err = io.ReadFull(rand.Reader, c.header.Nonce[:]); err != nil {
+		return nil, lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeGenerateNonceError, lib.ErrMessageGenerateNonceError, "", err)
+	}
+
+	return aesGcm, nil
+}
+
+// writePayload - encrypts r into chunks written through w at the current
+// position of f, which is start.Offset, numbering them from start.Chunks, then
+// writes the terminator, drops whatever f held past it, patches
+// CompressedSize into the metadata and syncs f. w is f, or a writer to f that
+// checks what it overwrites. With a checkpoint function set, f is synced and
+// the function called at start and after every checkpointEvery plaintext
+// bytes.
+func (c *container) writePayload(ctx context.Context, f *os.File, w io.Writer, aesGcm cipher.AEAD, r io.Reader, start Checkpoint) error {
+	if err := c.saveCheckpoint(f, start); err != nil {
+		return err
+	}
+
+	lastCheckpoint := start.PlainSize
+	compressedSize, err := c.writeChunks(ctx, w, aesGcm, r, start, func(cp Checkpoint) error {
+		if c.checkpoint == nil || cp.PlainSize-lastCheckpoint < c.checkpointEvery {
+			return nil
 		}
+		lastCheckpoint = cp.PlainSize
+
+		return c.saveCheckpoint(f, cp)
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := binary.Write(f, binary.LittleEndian, uint32(0)); err != nil {
+	end, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
+	}
+	if err = f.Truncate(end); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
 	}
 
-	// Patch the now-known CompressedSize back into the metadata. The reserved
-	// marshal above used math.MaxInt64, the widest decimal value, so the real
+	// Patch the now-known CompressedSize back into the metadata. The metadata
+	// was marshalled with math.MaxInt64, the widest decimal value, so the real
 	// value is never longer; pad the remainder with spaces (which json.Unmarshal
 	// ignores) to keep MetadataSize and the payload offset unchanged.
+	metadataSize := int(c.header.MetadataSize)
 	c.metadata.CompressedSize = compressedSize
+	if c.header.CompressionType == compression.TypeRaw {
+		c.metadata.UncompressedSize = compressedSize
+	}
 	patched, err := json.Marshal(c.metadata)
 	if err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeJSONMarshalMetadataError, lib.ErrMessageJSONMarshalMetadataError, "", err)
@@ -207,7 +261,7 @@ func (c *container) WriteEncrypted(r io.Reader, key []byte) error {
 	for len(patched) < metadataSize {
 		patched = append(patched, ' ')
 	}
-	if _, err = f.WriteAt(patched, int64(binary.Size(c.header))); err != nil {
+	if _, err = f.WriteAt(patched, c.header.Size()); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteMetadataError, lib.ErrMessageWriteMetadataError, "", err)
 	}
 
@@ -221,6 +275,109 @@ func (c *container) WriteEncrypted(r io.Reader, key []byte) error {
 	return nil
 }
 
+// writeChunks - encrypts r into chunks written to w, which is at start.Offset,
+// numbering them from start.Chunks, and writes the terminator. written, when
+// set, is called after every chunk with the checkpoint that follows it. It
+// returns the plaintext bytes of the whole payload, start.PlainSize included.
+func (c *container) writeChunks(
+	ctx context.Context,
+	w io.Writer,
+	aesGcm cipher.AEAD,
+	r io.Reader,
+	start Checkpoint,
+	written func(Checkpoint) error,
+) (int64, error) {
+	var chunkSize = int(c.header.ChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = 4 * 1024 * 1024
+	}
+
+	var (
+		pool    = newChunkPool()
+		lenBuf  = make([]byte, 4)
+		counter = start.Chunks
+		eof     = false
+		// Total plaintext (i.e. compressed archive) bytes consumed from the
+		// stream; patched into the metadata's CompressedSize once known.
+		compressedSize = start.PlainSize
+		offset         = start.Offset
+	)
+	next := func() (*chunk, error) {
+		if eof {
+			return nil, nil
+		}
+
+		// The buffer holds a full chunk plus the GCM tag so Seal can encrypt it
+		// in place without reallocating.
+		ch := pool.get(chunkSize + aesGcm.Overhead())
+
+		// io.ReadFull coalesces the many small reads returned by the unbuffered
+		// source pipe (archive/zip+flate flushes in small blocks) into a full
+		// chunkSize block. Without it, each tiny read became its own AES-GCM
+		// chunk with a 16-byte tag + 4-byte length, producing hundreds of
+		// thousands of chunks and allocations for large inputs.
+		n, readErr := io.ReadFull(r, ch.data[:chunkSize])
+		if readErr != nil {
+			// EOF (clean end) and ErrUnexpectedEOF (final short chunk) both mean
+			// the stream is fully consumed; anything else is a real read error.
+			if readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+				pool.put(ch)
+				return nil, lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", readErr)
+			}
+			eof = true
+		}
+		if n == 0 {
+			pool.put(ch)
+			return nil, nil
+		}
+
+		ch.data, ch.plainLen, ch.counter = ch.data[:n], n, counter
+		counter++
+
+		return ch, nil
+	}
+	seal := func(ch *chunk) error {
+		nonce := c.header.Nonce
+		binary.LittleEndian.PutUint64(nonce[4:], ch.counter)
+
+		// nonce is a random prefix (crypto/rand, see header.Nonce) combined
+		// with a per-chunk counter, so it is unique per chunk and not hardcoded.
+		ch.data = aesGcm.Seal(ch.data[:0], nonce[:], ch.data, nil) // #nosec G407
+		return nil
+	}
+	write := func(ch *chunk) error {
+		// plainLen is the number of bytes read into the chunk, so 0 <= plainLen <= chunkSize <= math.MaxUint32.
+		binary.LittleEndian.PutUint32(lenBuf, uint32(ch.plainLen)) // #nosec G115
+		if _, err := w.Write(lenBuf); err != nil {
+			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
+		}
+		if _, err := w.Write(ch.data); err != nil {
+			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
+		}
+
+		compressedSize += int64(ch.plainLen)
+		offset += int64(len(lenBuf) + len(ch.data))
+
+		if written == nil {
+			return nil
+		}
+
+		return written(Checkpoint{Chunks: ch.counter + 1, PlainSize: compressedSize, Offset: offset})
+	}
+
+	// Chunks are sealed in parallel but written in counter order, so the
+	// payload is byte-for-byte what a sequential writer would produce.
+	if err := runChunks(ctx, pool, next, seal, write); err != nil {
+		return 0, err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(0)); err != nil {
+		return 0, lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
+	}
+
+	return compressedSize, nil
+}
+
 // Read - reads encrypted data from the container
 func (c *container) Read() error {
 	f, err := os.Open(c.path)
@@ -236,14 +393,14 @@ func (c *container) Read() error {
 	if c.header, err = NewHeader(0, 0, 0, 0, 0); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeInitHeaderError, lib.ErrMessageInitHeaderError, "", err)
 	}
-	if err = binary.Read(f, binary.LittleEndian, &c.header); err != nil {
+	if c.header, err = readHeader(f); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadBinaryError, lib.ErrMessageReadBinaryError, "", err)
 	}
 
 	if string(c.header.Signature[:]) != signature {
 		return lib.ErrInvalidContainerSignature
 	}
-	if c.header.Version != Version {
+	if c.header.Version != Version && c.header.Version != VersionNoEpoch {
 		return lib.ErrInvalidContainerVersion
 	}
 
@@ -264,7 +421,7 @@ func (c *container) Read() error {
 }
 
 // DecryptTo - decrypts the container data and writes it to the provided writer
-func (c *container) DecryptTo(w io.Writer, masterKey []byte) error {
+func (c *container) DecryptTo(ctx context.Context, w io.Writer, masterKey []byte) error {
 	if len(c.masterKey) == 0 || c.masterKey == nil {
 		c.masterKey = masterKey
 	}
@@ -284,65 +441,186 @@ func (c *container) DecryptTo(w io.Writer, masterKey []byte) error {
 	}
 	defer func() { _ = f.Close() }()
 
-	headerSize := int64(binary.Size(Header{}))
-	payloadOffset := headerSize + int64(c.header.MetadataSize)
+	payloadOffset := c.header.Size() + int64(c.header.MetadataSize)
 	if _, err := f.Seek(payloadOffset, io.SeekStart); err != nil {
 		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
 	}
 
 	var (
-		// Buffers reused across chunks so each iteration does not allocate a
-		// fresh ciphertext/plaintext slice; they grow on demand and are then
-		// retained for subsequent same-size chunks (the common case).
-		lenBuf    = make([]byte, 4)
-		cipherBuf []byte
-		plainBuf  []byte
-		counter   uint64 = 0
+		pool           = newChunkPool()
+		lenBuf         = make([]byte, 4)
+		counter uint64 = 0
 	)
-	for {
+	next := func() (*chunk, error) {
 		if _, err := io.ReadFull(f, lenBuf); err != nil {
-			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+			return nil, lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
 		}
 		plainLen := binary.LittleEndian.Uint32(lenBuf)
 		if plainLen == 0 {
-			break
+			return nil, nil
 		}
 		// plainLen is read from an untrusted file; reject an over-large chunk
 		// before allocating so a hostile header cannot force a huge allocation.
 		if plainLen > MaxChunkSize {
-			return lib.FormatErr(lib.CategoryContainer, lib.ErrCodeChunkSizeExceedsError, lib.ErrMessageChunkSizeExceedsError, "", nil)
+			return nil, lib.FormatErr(lib.CategoryContainer, lib.ErrCodeChunkSizeExceedsError, lib.ErrMessageChunkSizeExceedsError, "", nil)
 		}
 
-		cipherLen := int(plainLen) + aesGcm.Overhead()
-		if cap(cipherBuf) < cipherLen {
-			cipherBuf = make([]byte, cipherLen)
-		}
-		cipherBuf = cipherBuf[:cipherLen]
-		if _, err := io.ReadFull(f, cipherBuf); err != nil {
-			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+		ch := pool.get(int(plainLen) + aesGcm.Overhead())
+		if _, err := io.ReadFull(f, ch.data); err != nil {
+			pool.put(ch)
+			return nil, lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
 		}
 
+		ch.plainLen, ch.counter = int(plainLen), counter
+		counter++
+
+		return ch, nil
+	}
+	open := func(ch *chunk) error {
 		nonce := c.header.Nonce
-		binary.LittleEndian.PutUint64(nonce[4:], counter)
+		binary.LittleEndian.PutUint64(nonce[4:], ch.counter)
 
-		// Decrypt into plainBuf (distinct from cipherBuf, so no overlap); retain
-		// the possibly-grown backing array for the next chunk.
-		plain, err := aesGcm.Open(plainBuf[:0], nonce[:], cipherBuf, nil)
+		// Decrypt in place; the plaintext is the ciphertext minus the tag.
+		plain, err := aesGcm.Open(ch.data[:0], nonce[:], ch.data, nil)
 		if err != nil {
 			return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeOpenCipherTextError, lib.ErrMessageOpenCipherTextError, "", err)
 		}
-		plainBuf = plain
+		ch.data = plain
 
-		if _, err := w.Write(plain); err != nil {
+		return nil
+	}
+	write := func(ch *chunk) error {
+		if _, err := w.Write(ch.data); err != nil {
 			return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
 		}
+		return nil
+	}
 
-		counter++
+	// Chunks are opened in parallel but written in counter order; a chunk that
+	// fails authentication stops the stream after every chunk before it.
+	if err := runChunks(ctx, pool, next, open, write); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CheckKey - reports whether masterKey is the container's key: against the key
+// check value when the key block records one, otherwise by authenticating the
+// first payload chunk with it. A container with neither, an empty payload
+// sealed without tokens or by an older version, fails with
+// lib.ErrContainerKeyUnverifiable rather than accepting any key.
+func (c *container) CheckKey(masterKey []byte) error {
+	if keyBlock := c.metadata.Keys; keyBlock.HasKeyCheck() {
+		if !keyBlock.MatchesKey(masterKey) {
+			return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeOpenCipherTextError, lib.ErrMessageOpenCipherTextError, "", lib.ErrContainerKeyMismatch)
+		}
+
+		return nil
+	}
+
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewCipherError, lib.ErrMessageCreateNewCipherError, "", err)
+	}
+	aesGcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewGCMError, lib.ErrMessageCreateNewGCMError, "", err)
+	}
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerOpenFileError, lib.ErrMessageContainerOpenFileError, "", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	payloadOffset := c.header.Size() + int64(c.header.MetadataSize)
+	if _, err = f.Seek(payloadOffset, io.SeekStart); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+
+	lenBuf := make([]byte, 4)
+	if _, err = io.ReadFull(f, lenBuf); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+	plainLen := binary.LittleEndian.Uint32(lenBuf)
+	if plainLen == 0 {
+		return lib.ValidationErr(lib.CategoryContainer, lib.ErrContainerKeyUnverifiable)
+	}
+	if plainLen > MaxChunkSize {
+		return lib.FormatErr(lib.CategoryContainer, lib.ErrCodeChunkSizeExceedsError, lib.ErrMessageChunkSizeExceedsError, "", nil)
+	}
+
+	cipherBuf := make([]byte, int(plainLen)+aesGcm.Overhead())
+	if _, err = io.ReadFull(f, cipherBuf); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+
+	nonce := c.header.Nonce
+	binary.LittleEndian.PutUint64(nonce[4:], 0)
+	if _, err = aesGcm.Open(cipherBuf[:0], nonce[:], cipherBuf, nil); err != nil {
+		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeOpenCipherTextError, lib.ErrMessageOpenCipherTextError, "", err)
 	}
 
 	return nil
 }
 
+// Rewrite - writes the current header and metadata to path followed by the
+// encrypted payload copied byte for byte from the container file, so key
+// material can change without decrypting or re-encrypting the payload.
+func (c *container) Rewrite(path string) error {
+	metaBytes, err := json.Marshal(c.metadata)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeJSONMarshalMetadataError, lib.ErrMessageJSONMarshalMetadataError, "", err)
+	}
+	if len(metaBytes) > MaxMetadataSize {
+		return lib.FormatErr(lib.CategoryContainer, lib.ErrCodeMetadataSizeExceedsError, lib.ErrMessageMetadataSizeExceedsError, "", nil)
+	}
+
+	src, err := os.Open(c.path)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerOpenFileError, lib.ErrMessageContainerOpenFileError, "", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	// The payload starts after the metadata as it is on disk, which may differ
+	// in length from the metadata about to be written.
+	onDisk, err := readHeader(src)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadBinaryError, lib.ErrMessageReadBinaryError, "", err)
+	}
+	if _, err = src.Seek(int64(onDisk.MetadataSize), io.SeekCurrent); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerOpenFileError, lib.ErrMessageContainerOpenFileError, "", err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	header := c.header
+	header.Version = Version
+	header.MetadataSize = uint32(len(metaBytes)) // #nosec G115 -- bounded by MaxMetadataSize above
+	if err = binary.Write(dst, binary.LittleEndian, &header); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteHeaderBinaryError, lib.ErrMessageWriteHeaderBinaryError, "", err)
+	}
+	if _, err = dst.Write(metaBytes); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteMetadataError, lib.ErrMessageWriteMetadataError, "", err)
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeWriteCipherTextError, lib.ErrMessageWriteCipherTextError, "", err)
+	}
+
+	if err = dst.Sync(); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerSyncFileError, lib.ErrMessageContainerSyncFileError, "", err)
+	}
+
+	c.header = header
+
+	return nil
+}
+
 // GetHeader - returns the Header associated with the container.
 func (c *container) GetHeader() Header {
 	return c.header
@@ -373,3 +651,8 @@ func (c *container) SetMasterKey(key []byte) {
 func (c *container) SetMetadata(metadata Metadata) {
 	c.metadata = metadata
 }
+
+// SetHeader - sets the Header associated with the container.
+func (c *container) SetHeader(header Header) {
+	c.header = header
+}
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"context"
 	"crypto/rand"
 	"io"
 	"os"
@@ -52,7 +53,7 @@ func benchWriteEncryptedPiped(b *testing.B, totalBytes int, producerWrite int) {
 			_ = pw.Close()
 		}()
 
-		if err := cont.WriteEncrypted(pr, passphrase); err != nil {
+		if err := cont.WriteEncrypted(context.Background(), pr, passphrase); err != nil {
 			b.Fatal(err)
 		}
 
@@ -89,7 +90,7 @@ func BenchmarkDecryptTo(b *testing.B) {
 	if _, err := rand.Read(src); err != nil {
 		b.Fatal(err)
 	}
-	if err := cont.WriteEncrypted(bytesReader(src), passphrase); err != nil {
+	if err := cont.WriteEncrypted(context.Background(), bytesReader(src), passphrase); err != nil {
 		b.Fatal(err)
 	}
 	masterKey := cont.GetMasterKey()
@@ -103,7 +104,7 @@ func BenchmarkDecryptTo(b *testing.B) {
 		if err := rc.Read(); err != nil {
 			b.Fatal(err)
 		}
-		if err := rc.DecryptTo(io.Discard, masterKey); err != nil {
+		if err := rc.DecryptTo(context.Background(), io.Discard, masterKey); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -2,11 +2,18 @@ package container
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
+
+	"github.com/namelesscorp/tvault-core/compression"
+	"github.com/namelesscorp/tvault-core/lib"
 )
 
 func TestContainerCreate(t *testing.T) {
@@ -49,7 +56,7 @@ func TestContainerCreate(t *testing.T) {
 		t.Errorf("Expected Comment to be %s, got %s", "Test comment", cont.GetMetadata().Comment)
 	}
 
-	err = cont.WriteEncrypted(bytes.NewReader(testData), passphrase)
+	err = cont.WriteEncrypted(context.Background(), bytes.NewReader(testData), passphrase)
 	if err != nil {
 		t.Fatalf("Failed to write encrypted data: %v", err)
 	}
@@ -78,7 +85,7 @@ func TestContainerCreate(t *testing.T) {
 	}
 
 	var decrypted bytes.Buffer
-	err = readContainer.DecryptTo(&decrypted, cont.GetMasterKey())
+	err = readContainer.DecryptTo(context.Background(), &decrypted, cont.GetMasterKey())
 	if err != nil {
 		t.Fatalf("Failed to decrypt data: %v", err)
 	}
@@ -134,7 +141,7 @@ func TestContainerCompressedSizeIsRecorded(t *testing.T) {
 
 	payload := bytes.Repeat([]byte("x"), 40000)
 	cont := NewContainer(tempFile.Name(), nil, Metadata{Comment: "size"}, header)
-	if err = cont.WriteEncrypted(bytes.NewReader(payload), []byte("pw")); err != nil {
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(payload), []byte("pw")); err != nil {
 		t.Fatalf("Failed to write container: %v", err)
 	}
 
@@ -157,6 +164,80 @@ func TestContainerCompressedSizeIsRecorded(t *testing.T) {
 	}
 }
 
+func TestContainerRawSizesAreRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.tvlt")
+
+	header, err := NewHeader(compression.TypeRaw, 0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("r"), 40000)
+	cont := NewContainer(path, nil, Metadata{FileCount: 1}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(payload), []byte("pw")); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	// A raw payload is stored as is, so both sizes are the stream length.
+	rc := NewContainer(path, nil, Metadata{}, Header{})
+	if err = rc.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+	if got := rc.GetMetadata().UncompressedSize; got != int64(len(payload)) {
+		t.Errorf("Expected persisted UncompressedSize %d, got %d", len(payload), got)
+	}
+	if got := rc.GetMetadata().CompressedSize; got != int64(len(payload)) {
+		t.Errorf("Expected persisted CompressedSize %d, got %d", len(payload), got)
+	}
+}
+
+// onlyWriter hides every method of the writer but Write, so a container
+// written to it cannot seek back to patch the metadata.
+type onlyWriter struct{ w io.Writer }
+
+func (o onlyWriter) Write(p []byte) (int, error) { return o.w.Write(p) }
+
+func TestContainerWriteEncryptedToStream(t *testing.T) {
+	header, err := NewHeader(compression.TypeRaw, 0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("stream"), 20000)
+	cont := NewContainer("", nil, Metadata{Comment: "stream"}, header)
+
+	var stream bytes.Buffer
+	if err = cont.WriteEncryptedTo(context.Background(), bytes.NewReader(payload), onlyWriter{&stream}, []byte("pw")); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "stream.tvlt")
+	if err = os.WriteFile(path, stream.Bytes(), 0o600); err != nil {
+		t.Fatalf("Failed to save stream: %v", err)
+	}
+
+	rc := NewContainer(path, nil, Metadata{}, Header{})
+	if err = rc.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+	// The sizes follow the payload, so a streamed container records them as 0.
+	if rc.GetMetadata().CompressedSize != 0 || rc.GetMetadata().UncompressedSize != 0 {
+		t.Errorf("Expected unknown sizes of 0, got %d and %d",
+			rc.GetMetadata().CompressedSize, rc.GetMetadata().UncompressedSize)
+	}
+	if rc.GetMetadata().Comment != "stream" {
+		t.Errorf("Metadata did not round-trip: comment = %q", rc.GetMetadata().Comment)
+	}
+
+	var out bytes.Buffer
+	if err = rc.DecryptTo(context.Background(), &out, cont.GetMasterKey()); err != nil {
+		t.Fatalf("Failed to decrypt container: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Error("Decrypted payload does not match the stream")
+	}
+}
+
 func TestContainerDecryptRejectsOversizedChunkSize(t *testing.T) {
 	tempFile, err := os.CreateTemp("", "container_chunk_*.tvlt")
 	if err != nil {
@@ -171,7 +252,7 @@ func TestContainerDecryptRejectsOversizedChunkSize(t *testing.T) {
 	}
 
 	cont := NewContainer(tempFile.Name(), nil, Metadata{Comment: "chunk"}, header)
-	if err = cont.WriteEncrypted(bytes.NewReader([]byte("some payload data")), []byte("pass")); err != nil {
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("some payload data")), []byte("pass")); err != nil {
 		t.Fatalf("Failed to write container: %v", err)
 	}
 	masterKey := cont.GetMasterKey()
@@ -196,11 +277,160 @@ func TestContainerDecryptRejectsOversizedChunkSize(t *testing.T) {
 	}
 	_ = f.Close()
 
-	if err = rc.DecryptTo(io.Discard, masterKey); err == nil {
+	if err = rc.DecryptTo(context.Background(), io.Discard, masterKey); err == nil {
 		t.Fatal("Expected DecryptTo to reject oversized chunk size, got nil error")
 	}
 }
 
+// writeChunkedContainer seals payload with a small chunk size so it spans
+// many chunks, exercising the parallel pipeline on a small input.
+func writeChunkedContainer(t *testing.T, payload []byte, chunkSize uint32) (string, []byte) {
+	t.Helper()
+
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	header.ChunkSize = chunkSize
+
+	path := filepath.Join(t.TempDir(), "chunks.tvlt")
+	cont := NewContainer(path, nil, Metadata{Comment: "chunks"}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(payload), []byte("pass")); err != nil {
+		t.Fatalf("Failed to write container: %v", err)
+	}
+
+	return path, cont.GetMasterKey()
+}
+
+func TestContainerParallelChunksRoundTrip(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	payload := make([]byte, 100*1000+500)
+	for i := range payload {
+		payload[i] = byte(i * 7 / 1000)
+	}
+
+	path, masterKey := writeChunkedContainer(t, payload, 1000)
+
+	rc := NewContainer(path, nil, Metadata{}, Header{})
+	if err := rc.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	// 101 chunks, each framed by a length prefix and a GCM tag, plus the terminator.
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat container: %v", err)
+	}
+	want := rc.GetHeader().Size() + int64(rc.GetHeader().MetadataSize) + int64(len(payload)) + 101*(4+16) + 4
+	if st.Size() != want {
+		t.Errorf("Expected container size %d, got %d", want, st.Size())
+	}
+
+	var out bytes.Buffer
+	if err = rc.DecryptTo(context.Background(), &out, masterKey); err != nil {
+		t.Fatalf("Failed to decrypt container: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Error("Decrypted payload does not match, chunks out of order or corrupted")
+	}
+}
+
+func TestContainerDecryptStopsAtTamperedChunk(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	const chunkSize = 1000
+	payload := bytes.Repeat([]byte("0123456789"), 10*chunkSize)
+	path, masterKey := writeChunkedContainer(t, payload, chunkSize)
+
+	rc := NewContainer(path, nil, Metadata{}, Header{})
+	if err := rc.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	// Flip a ciphertext byte in chunk 50.
+	f, err := os.OpenFile(path, os.O_RDWR, 0o600)
+	if err != nil {
+		t.Fatalf("Failed to open for corruption: %v", err)
+	}
+	offset := rc.GetHeader().Size() + int64(rc.GetHeader().MetadataSize) + 50*(4+chunkSize+16) + 4
+	var b [1]byte
+	if _, err = f.ReadAt(b[:], offset); err != nil {
+		t.Fatalf("Failed to read chunk byte: %v", err)
+	}
+	b[0] ^= 0xFF
+	if _, err = f.WriteAt(b[:], offset); err != nil {
+		t.Fatalf("Failed to corrupt chunk: %v", err)
+	}
+	_ = f.Close()
+
+	var out bytes.Buffer
+	if err = rc.DecryptTo(context.Background(), &out, masterKey); err == nil {
+		t.Fatal("Expected DecryptTo to fail on a tampered chunk, got nil error")
+	}
+	// Every chunk before the tampered one is written, and nothing after it.
+	if !bytes.Equal(out.Bytes(), payload[:50*chunkSize]) {
+		t.Errorf("Expected %d bytes before the tampered chunk, got %d", 50*chunkSize, out.Len())
+	}
+}
+
+// cancelReader cancels its context once n reads have been served.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	if c.n--; c.n == 0 {
+		c.cancel()
+	}
+
+	return c.r.Read(p)
+}
+
+func TestContainerWriteEncryptedCancelRemovesFile(t *testing.T) {
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	header.ChunkSize = 1000
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "cancel.tvlt")
+	src := &cancelReader{r: bytes.NewReader(make([]byte, 100*1000)), n: 10, cancel: cancel}
+
+	cont := NewContainer(path, nil, Metadata{Comment: "cancel"}, header)
+	if err = cont.WriteEncrypted(ctx, src, []byte("pass")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial container to be removed, stat error = %v", err)
+	}
+}
+
+func TestContainerDecryptToCancel(t *testing.T) {
+	path, masterKey := writeChunkedContainer(t, make([]byte, 100*1000), 1000)
+
+	rc := NewContainer(path, nil, Metadata{}, Header{})
+	if err := rc.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	if err := rc.DecryptTo(ctx, &out, masterKey); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written after cancel, got %d bytes", out.Len())
+	}
+}
+
 func TestContainerSetterMethods(t *testing.T) {
 	cont := NewContainer("", nil, Metadata{}, Header{})
 
@@ -229,3 +459,209 @@ func TestContainerSetterMethods(t *testing.T) {
 		t.Errorf("Expected Comment to be %s, got %s", "New comment", cont.GetMetadata().Comment)
 	}
 }
+
+func TestContainerCheckKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check_key.tvlt")
+
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	cont := NewContainer(path, nil, Metadata{}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("test data")), []byte("test passphrase")); err != nil {
+		t.Fatalf("Failed to write encrypted data: %v", err)
+	}
+
+	readContainer := NewContainer(path, nil, Metadata{}, Header{})
+	if err = readContainer.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	if err = readContainer.CheckKey(cont.GetMasterKey()); err != nil {
+		t.Errorf("Expected the master key to pass, got %v", err)
+	}
+
+	wrongKey := bytes.Repeat([]byte{0x42}, len(cont.GetMasterKey()))
+	if err = readContainer.CheckKey(wrongKey); err == nil {
+		t.Errorf("Expected a wrong key to be rejected")
+	}
+}
+
+func TestContainerCheckKeyEmptyPayload(t *testing.T) {
+	var (
+		masterKey = bytes.Repeat([]byte{0x42}, lib.KeyLen)
+		wrongKey  = bytes.Repeat([]byte{0x07}, lib.KeyLen)
+	)
+
+	write := func(t *testing.T, keys *KeyBlock) Container {
+		t.Helper()
+
+		header, err := NewHeader(1, 1, 1, 3, 2)
+		if err != nil {
+			t.Fatalf("Failed to create header: %v", err)
+		}
+
+		path := filepath.Join(t.TempDir(), "empty.tvlt")
+		cont := NewContainer(path, nil, Metadata{Keys: keys}, header)
+		cont.SetMasterKey(masterKey)
+		if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(nil), nil); err != nil {
+			t.Fatalf("Failed to write encrypted data: %v", err)
+		}
+
+		readContainer := NewContainer(path, nil, Metadata{}, Header{})
+		if err = readContainer.Read(); err != nil {
+			t.Fatalf("Failed to read container: %v", err)
+		}
+
+		return readContainer
+	}
+
+	t.Run("no_key_check", func(t *testing.T) {
+		cont := write(t, nil)
+		for _, key := range [][]byte{masterKey, wrongKey} {
+			if err := cont.CheckKey(key); !errors.Is(err, lib.ErrContainerKeyUnverifiable) {
+				t.Errorf("CheckKey() error = %v, want %v", err, lib.ErrContainerKeyUnverifiable)
+			}
+		}
+	})
+
+	t.Run("key_check", func(t *testing.T) {
+		cont := write(t, &KeyBlock{KeyCheck: NewKeyCheck(masterKey)})
+		if err := cont.CheckKey(masterKey); err != nil {
+			t.Errorf("CheckKey() of the master key error = %v", err)
+		}
+		if err := cont.CheckKey(wrongKey); !errors.Is(err, lib.ErrContainerKeyMismatch) {
+			t.Errorf("CheckKey() of a wrong key error = %v, want %v", err, lib.ErrContainerKeyMismatch)
+		}
+	})
+}
+
+func TestContainerRewrite(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		path      = filepath.Join(dir, "original.tvlt")
+		rewritten = filepath.Join(dir, "rewritten.tvlt")
+		data      = []byte("payload that must survive a rewrite")
+	)
+
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	cont := NewContainer(path, nil, Metadata{Name: "short"}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(data), []byte("test passphrase")); err != nil {
+		t.Fatalf("Failed to write encrypted data: %v", err)
+	}
+
+	readContainer := NewContainer(path, nil, Metadata{}, Header{})
+	if err = readContainer.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	newHeader := readContainer.GetHeader()
+	newHeader.Shares, newHeader.Threshold = 7, 4
+	readContainer.SetHeader(newHeader)
+
+	metadata := readContainer.GetMetadata()
+	metadata.Name = "a considerably longer name that grows the metadata"
+	readContainer.SetMetadata(metadata)
+
+	if err = readContainer.Rewrite(rewritten); err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+
+	result := NewContainer(rewritten, nil, Metadata{}, Header{})
+	if err = result.Read(); err != nil {
+		t.Fatalf("Failed to read rewritten container: %v", err)
+	}
+
+	if result.GetHeader().Shares != 7 || result.GetHeader().Threshold != 4 {
+		t.Errorf("Expected 4 of 7, got %d of %d", result.GetHeader().Threshold, result.GetHeader().Shares)
+	}
+	if result.GetMetadata().Name != metadata.Name {
+		t.Errorf("Expected name %q, got %q", metadata.Name, result.GetMetadata().Name)
+	}
+
+	var decrypted bytes.Buffer
+	if err = result.DecryptTo(context.Background(), &decrypted, cont.GetMasterKey()); err != nil {
+		t.Fatalf("Failed to decrypt rewritten container: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), data) {
+		t.Errorf("Expected payload %q, got %q", data, decrypted.Bytes())
+	}
+}
+
+func TestContainerReadsVersionNoEpoch(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		path      = filepath.Join(dir, "current.tvlt")
+		legacy    = filepath.Join(dir, "legacy.tvlt")
+		rewritten = filepath.Join(dir, "rewritten.tvlt")
+		data      = []byte("payload of a version 1 container")
+	)
+
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	cont := NewContainer(path, nil, Metadata{Name: "legacy"}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(data), []byte("test passphrase")); err != nil {
+		t.Fatalf("Failed to write encrypted data: %v", err)
+	}
+
+	// A version 1 file is the same container without the trailing KeyEpoch
+	// field of the header.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read container file: %v", err)
+	}
+	v1 := append([]byte{}, content[:headerSizeNoEpoch]...)
+	v1[4] = VersionNoEpoch
+	v1 = append(v1, content[binary.Size(Header{}):]...)
+	if err = os.WriteFile(legacy, v1, 0o600); err != nil {
+		t.Fatalf("Failed to write legacy container: %v", err)
+	}
+
+	readContainer := NewContainer(legacy, nil, Metadata{}, Header{})
+	if err = readContainer.Read(); err != nil {
+		t.Fatalf("Failed to read version 1 container: %v", err)
+	}
+	if readContainer.GetHeader().Version != VersionNoEpoch || readContainer.GetHeader().KeyEpoch != 0 {
+		t.Errorf("Expected version %d at key epoch 0, got version %d at key epoch %d",
+			VersionNoEpoch, readContainer.GetHeader().Version, readContainer.GetHeader().KeyEpoch)
+	}
+	if readContainer.GetMetadata().Name != "legacy" {
+		t.Errorf("Expected name %q, got %q", "legacy", readContainer.GetMetadata().Name)
+	}
+
+	var decrypted bytes.Buffer
+	if err = readContainer.DecryptTo(context.Background(), &decrypted, cont.GetMasterKey()); err != nil {
+		t.Fatalf("Failed to decrypt version 1 container: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), data) {
+		t.Errorf("Expected payload %q, got %q", data, decrypted.Bytes())
+	}
+
+	if err = readContainer.Rewrite(rewritten); err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+
+	result := NewContainer(rewritten, nil, Metadata{}, Header{})
+	if err = result.Read(); err != nil {
+		t.Fatalf("Failed to read rewritten container: %v", err)
+	}
+	if result.GetHeader().Version != Version {
+		t.Errorf("Expected rewritten version %d, got %d", Version, result.GetHeader().Version)
+	}
+
+	decrypted.Reset()
+	if err = result.DecryptTo(context.Background(), &decrypted, cont.GetMasterKey()); err != nil {
+		t.Fatalf("Failed to decrypt rewritten container: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), data) {
+		t.Errorf("Expected payload %q, got %q", data, decrypted.Bytes())
+	}
+}
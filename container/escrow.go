@@ -0,0 +1,64 @@
+package container
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// escrowInfo - HKDF info binding the X25519 wrap to the escrow key.
+const escrowInfo = "tvault-core escrow v1"
+
+// Escrow - the master key wrapped to an organisation's escrow X25519 key.
+// PublicKey (hex) is kept so reseal can wrap a rotated key again and rewrap can
+// tell which escrow key a container belongs to.
+type Escrow struct {
+	PublicKey string `json:"public_key"`
+	// Key - hex lib.X25519Seal of the master key, bound to the container salt.
+	Key string `json:"key"`
+}
+
+// NewEscrow - wraps masterKey to the escrow public key.
+func NewEscrow(masterKey, publicKey []byte, salt [16]byte) (*Escrow, error) {
+	sealed, err := lib.X25519Seal(publicKey, masterKey, salt[:], escrowInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Escrow{PublicKey: hex.EncodeToString(publicKey), Key: hex.EncodeToString(sealed)}, nil
+}
+
+// GetEscrow - returns the escrow wrap, or nil when the container has none.
+func (k *KeyBlock) GetEscrow() *Escrow {
+	if k == nil {
+		return nil
+	}
+
+	return k.Escrow
+}
+
+// OpenEscrow - unwraps the master key with the escrow private key.
+func (k *KeyBlock) OpenEscrow(privateKey []byte, salt [16]byte) ([]byte, error) {
+	escrow := k.GetEscrow()
+	if escrow == nil {
+		return nil, lib.ErrEscrowNotAvailable
+	}
+
+	publicKey, err := lib.X25519PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := hex.DecodeString(escrow.PublicKey)
+	if err != nil || !bytes.Equal(publicKey, recorded) {
+		return nil, lib.ErrEscrowKeyMismatch
+	}
+
+	sealed, err := hex.DecodeString(escrow.Key)
+	if err != nil {
+		return nil, lib.ErrX25519OpenFailed
+	}
+
+	return lib.X25519Open(privateKey, sealed, salt[:], escrowInfo)
+}
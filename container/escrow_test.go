@@ -0,0 +1,58 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestKeyBlockOpenEscrow(t *testing.T) {
+	var (
+		masterKey = bytes.Repeat([]byte{0x42}, lib.KeyLen)
+		salt      = [16]byte{1, 2, 3}
+	)
+
+	privateKey, publicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+
+	otherPrivateKey, _, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+
+	escrow, err := NewEscrow(masterKey, publicKey, salt)
+	if err != nil {
+		t.Fatalf("NewEscrow() error = %v", err)
+	}
+	keys := &KeyBlock{Escrow: escrow}
+
+	tests := []struct {
+		name       string
+		keys       *KeyBlock
+		privateKey []byte
+		salt       [16]byte
+		wantErr    error
+	}{
+		{name: "opened", keys: keys, privateKey: privateKey, salt: salt},
+		{name: "other_key", keys: keys, privateKey: otherPrivateKey, salt: salt, wantErr: lib.ErrEscrowKeyMismatch},
+		{name: "other_container", keys: keys, privateKey: privateKey, salt: [16]byte{9}, wantErr: lib.ErrX25519OpenFailed},
+		{name: "no_escrow", keys: &KeyBlock{}, privateKey: privateKey, salt: salt, wantErr: lib.ErrEscrowNotAvailable},
+		{name: "no_key_block", privateKey: privateKey, salt: salt, wantErr: lib.ErrEscrowNotAvailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keys.OpenEscrow(tt.privateKey, tt.salt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenEscrow() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, masterKey) {
+				t.Errorf("OpenEscrow() = %x, want %x", got, masterKey)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package container
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
 
 	"github.com/namelesscorp/tvault-core/lib"
 )
@@ -11,7 +16,11 @@ const (
 	signature = "TVLT"
 
 	// Version container version for backward compatibility
-	Version = 1
+	Version = 2
+
+	// VersionNoEpoch - version 1 containers, whose header ends before KeyEpoch.
+	// They are still read, with key epoch 0, and written back as Version.
+	VersionNoEpoch = 1
 
 	ChunkSize = 16 * 1024 * 1024 // 16 MiB
 
@@ -33,7 +42,7 @@ const (
 
 type Header struct {
 	Signature             [4]byte  // signature for validate container - "TVLT"
-	Version               uint8    // container version - "0x01"
+	Version               uint8    // container version - "0x02", or "0x01" for VersionNoEpoch
 	Flags                 uint8    // binary flags - "0x01". NOT SUPPORTED
 	Salt                  [16]byte // salt for passphrase
 	Iterations            uint32   // PBKDF2 rounds
@@ -45,8 +54,12 @@ type Header struct {
 	Shares                uint8    // shamir number of shares
 	Threshold             uint8    // shamir threshold count
 	ChunkSize             uint32   // plaintext chunk size (bytes)
+	KeyEpoch              uint32   // master key epoch, incremented by reseal -rotate-key
 }
 
+// headerSizeNoEpoch - size of a version 1 header.
+var headerSizeNoEpoch = binary.Size(Header{}) - 4
+
 func NewHeader(
 	compressionType byte,
 	integrityProviderType byte,
@@ -88,3 +101,45 @@ func NewHeader(
 
 	return h, nil
 }
+
+// Size - size of the header on disk, which depends on its version.
+func (h Header) Size() int64 {
+	if h.Version == VersionNoEpoch {
+		return int64(headerSizeNoEpoch)
+	}
+
+	return int64(binary.Size(h))
+}
+
+// ID - identifies the container and its current master key: the salt and the
+// key epoch hashed, so the ID changes when reseal -rotate-key replaces the key.
+func (h Header) ID() string {
+	var epoch [4]byte
+	binary.LittleEndian.PutUint32(epoch[:], h.KeyEpoch)
+	sum := sha256.Sum256(append(h.Salt[:], epoch[:]...))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// readHeader - reads a header of any supported version from r. A version 1
+// header is shorter; its missing KeyEpoch is left 0.
+func readHeader(r io.Reader) (Header, error) {
+	var (
+		h   Header
+		buf = make([]byte, binary.Size(h))
+	)
+	if _, err := io.ReadFull(r, buf[:headerSizeNoEpoch]); err != nil {
+		return h, err
+	}
+
+	// The version byte follows the 4-byte signature.
+	if buf[4] != VersionNoEpoch {
+		if _, err := io.ReadFull(r, buf[headerSizeNoEpoch:]); err != nil {
+			return h, err
+		}
+	}
+
+	err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &h)
+
+	return h, err
+}
@@ -81,4 +81,25 @@ func TestHeader(t *testing.T) {
 			t.Errorf("Expected Nonce to be non-zero, got all zeros")
 		}
 	})
+
+	t.Run("id", func(t *testing.T) {
+		header, err := NewHeader(0, 0, 0, 0, 0)
+		if err != nil {
+			t.Fatalf("Failed to create header: %v", err)
+		}
+
+		id := header.ID()
+		if len(id) != 16 {
+			t.Errorf("Expected ID of 16 characters, got %q", id)
+		}
+
+		if header.ID() != id {
+			t.Errorf("Expected ID to be stable, got %q and %q", id, header.ID())
+		}
+
+		header.KeyEpoch++
+		if header.ID() == id {
+			t.Errorf("Expected ID to change with the key epoch")
+		}
+	})
 }
@@ -1,6 +1,7 @@
 package container
 
 import (
+	"cmp"
 	"fmt"
 	"io"
 	"strings"
@@ -12,11 +13,13 @@ import (
 	"github.com/namelesscorp/tvault-core/token"
 )
 
-const containerInformationMessage = "[container information]\nName: %s\nVersion: %d\nCreated at: %s\nUpdated at: %s\n" +
-	"Comment: %s\nTags: %s\nToken type: %s\nProvider type: %s\nCompression type: %s\nShares: %d\nThreshold: %d\n" +
-	"Compression Size: %d\nUncompressed Size: %d\nSecurity Score: %.2f\nFile Count: %d\n"
+const containerInformationMessage = "[container information]\nID: %s\nName: %s\nVersion: %d\nCreated at: %s\nUpdated at: %s\n" +
+	"Comment: %s\nTags: %s\nToken type: %s\nProvider type: %s\nCompression type: %s\nShares: %d\nThreshold: %d\nKey epoch: %d\n" +
+	"Recovery code: %s\nEscrow key: %s\nKey provider: %s\nKMS key: %s\n" +
+	"Compression Size: %d\nUncompressed Size: %d\nSecurity Score: %.2f\nFile Count: %d\nExcluded Count: %d\nSkipped Count: %d\nPreserve: %s\nHolders: %s\n"
 
 type Information struct {
+	ID                    string   `json:"id"`
 	Name                  string   `json:"name"`
 	Version               uint8    `json:"version"`
 	CreatedAt             string   `json:"created_at"`
@@ -28,10 +31,21 @@ type Information struct {
 	CompressionType       string   `json:"compression_type"`
 	Shares                uint8    `json:"shares"`
 	Threshold             uint8    `json:"threshold"`
+	KeyEpoch              uint32   `json:"key_epoch"`
+	Recovery              bool     `json:"recovery"`
+	Escrow                bool     `json:"escrow"`
+	EscrowPublicKey       string   `json:"escrow_public_key,omitempty"`
+	KeyProvider           string   `json:"key_provider,omitempty"`
+	KMSKey                string   `json:"kms_key,omitempty"`
+	KMSKeyVersion         int      `json:"kms_key_version,omitempty"`
 	FileCount             int64    `json:"file_count"`
+	ExcludedCount         int64    `json:"excluded_count"`
+	SkippedCount          int64    `json:"skipped_count"`
+	Preserve              string   `json:"preserve"`
 	CompressedSize        int64    `json:"compressed_size"`
 	UncompressedSize      int64    `json:"uncompressed_size"`
 	SecurityScore         float64  `json:"security_score"`
+	Holders               []string `json:"holders,omitempty"`
 }
 
 func Info(opts Options) error {
@@ -67,6 +81,7 @@ func Info(opts Options) error {
 	case lib.WriterFormatPlaintext:
 		msg = fmt.Sprintf(
 			containerInformationMessage,
+			cont.GetHeader().ID(),
 			cont.GetMetadata().Name,
 			cont.GetHeader().Version,
 			cont.GetMetadata().CreatedAt.Format(time.DateTime),
@@ -78,13 +93,23 @@ func Info(opts Options) error {
 			compression.ConvertIDToName(cont.GetHeader().CompressionType),
 			cont.GetHeader().Shares,
 			cont.GetHeader().Threshold,
+			cont.GetHeader().KeyEpoch,
+			formatYesNo(cont.GetMetadata().Keys.HasRecovery()),
+			cmp.Or(escrowPublicKey(cont.GetMetadata().Keys.GetEscrow()), "none"),
+			cmp.Or(keyProvider(cont.GetMetadata().Keys.GetPlugin()), "none"),
+			cmp.Or(kmsKey(cont.GetMetadata().Keys.GetKMS()), "none"),
 			cont.GetMetadata().CompressedSize,
 			cont.GetMetadata().UncompressedSize,
 			cont.GetMetadata().SecurityScore,
 			cont.GetMetadata().FileCount,
+			cont.GetMetadata().ExcludedCount,
+			cont.GetMetadata().SkippedCount,
+			cmp.Or(cont.GetMetadata().Preserve, lib.PreserveNone),
+			strings.Join(cont.GetMetadata().Keys.HolderNames(), ","),
 		)
 	case lib.WriterFormatJSON:
 		msg = Information{
+			ID:                    cont.GetHeader().ID(),
 			Name:                  cont.GetMetadata().Name,
 			Version:               cont.GetHeader().Version,
 			CreatedAt:             cont.GetMetadata().CreatedAt.Format(time.DateTime),
@@ -96,10 +121,21 @@ func Info(opts Options) error {
 			CompressionType:       compression.ConvertIDToName(cont.GetHeader().CompressionType),
 			Shares:                cont.GetHeader().Shares,
 			Threshold:             cont.GetHeader().Threshold,
+			KeyEpoch:              cont.GetHeader().KeyEpoch,
+			Recovery:              cont.GetMetadata().Keys.HasRecovery(),
+			Escrow:                cont.GetMetadata().Keys.GetEscrow() != nil,
+			EscrowPublicKey:       escrowPublicKey(cont.GetMetadata().Keys.GetEscrow()),
+			KeyProvider:           keyProvider(cont.GetMetadata().Keys.GetPlugin()),
+			KMSKey:                cont.GetMetadata().Keys.GetKMS().KeyName(),
+			KMSKeyVersion:         kmsKeyVersion(cont.GetMetadata().Keys.GetKMS()),
 			CompressedSize:        cont.GetMetadata().CompressedSize,
 			UncompressedSize:      cont.GetMetadata().UncompressedSize,
 			SecurityScore:         cont.GetMetadata().SecurityScore,
 			FileCount:             cont.GetMetadata().FileCount,
+			ExcludedCount:         cont.GetMetadata().ExcludedCount,
+			SkippedCount:          cont.GetMetadata().SkippedCount,
+			Preserve:              cmp.Or(cont.GetMetadata().Preserve, lib.PreserveNone),
+			Holders:               cont.GetMetadata().Keys.HolderNames(),
 		}
 	}
 
@@ -115,3 +151,43 @@ func Info(opts Options) error {
 
 	return nil
 }
+
+func formatYesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func escrowPublicKey(escrow *Escrow) string {
+	if escrow == nil {
+		return ""
+	}
+
+	return escrow.PublicKey
+}
+
+func keyProvider(plugin *PluginWrap) string {
+	if plugin == nil {
+		return ""
+	}
+
+	return plugin.Name
+}
+
+func kmsKey(kms *KMSWrap) string {
+	if kms == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s v%d", kms.KeyName(), kms.Version)
+}
+
+func kmsKeyVersion(kms *KMSWrap) int {
+	if kms == nil {
+		return 0
+	}
+
+	return kms.Version
+}
@@ -0,0 +1,127 @@
+package container
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/namelesscorp/tvault-core/shamir"
+)
+
+// keyCheckLabel - message authenticated by the master key to produce KeyCheck.
+const keyCheckLabel = "tvault-core key check v1"
+
+// KeyBlock - key-management records stored with the plaintext metadata. It
+// holds nothing usable without the shares, the recovery code, the escrow
+// private key, the key provider or the KMS: token routing, the sharing policy,
+// the public Feldman commitments, a key check value and the master key wrapped
+// to the recovery code, the escrow key, a key provider plugin and a KMS key.
+type KeyBlock struct {
+	Holders []HolderRecord  `json:"holders,omitempty"`
+	Policy  *shamir.Policy  `json:"policy,omitempty"`
+	Feldman *shamir.Feldman `json:"feldman,omitempty"`
+	// KeyCheck - hex HMAC-SHA256 of keyCheckLabel under the master key, so a
+	// recovered key can be checked without decrypting the payload.
+	KeyCheck string `json:"key_check,omitempty"`
+	// Recovery - hex nonce || AES-GCM of the master key under a key derived
+	// from the recovery code (see NewRecoveryWrap); empty without one.
+	Recovery string `json:"recovery,omitempty"`
+	// Escrow - the master key wrapped to the escrow public key; nil without one.
+	Escrow *Escrow `json:"escrow,omitempty"`
+	// Plugin - the master key wrapped by the key provider of token -type=plugin;
+	// nil for other token types.
+	Plugin *PluginWrap `json:"plugin,omitempty"`
+	// KMS - the master key encrypted by the KMS of token -type=kms; nil for
+	// other token types.
+	KMS *KMSWrap `json:"kms,omitempty"`
+}
+
+// HolderRecord - maps a share id to the holder it was encrypted for. PublicKey
+// (hex) is kept for X25519 holders so reseal can re-issue their shares without
+// the holders file.
+type HolderRecord struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// Holder - returns the holder record for share id.
+func (k *KeyBlock) Holder(id int) (HolderRecord, bool) {
+	if k == nil {
+		return HolderRecord{}, false
+	}
+
+	for _, record := range k.Holders {
+		if record.ID == id {
+			return record, true
+		}
+	}
+
+	return HolderRecord{}, false
+}
+
+// GetPolicy - returns the sharing policy, or nil for a flat t-of-n split.
+func (k *KeyBlock) GetPolicy() *shamir.Policy {
+	if k == nil {
+		return nil
+	}
+
+	return k.Policy
+}
+
+// GetFeldman - returns the Feldman commitments, or nil when shares are not
+// verifiable.
+func (k *KeyBlock) GetFeldman() *shamir.Feldman {
+	if k == nil {
+		return nil
+	}
+
+	return k.Feldman
+}
+
+// HolderNames - returns the holder names in share order.
+func (k *KeyBlock) HolderNames() []string {
+	if k == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(k.Holders))
+	for _, record := range k.Holders {
+		names = append(names, record.Name)
+	}
+
+	return names
+}
+
+// NewKeyCheck - returns the key check value of masterKey.
+func NewKeyCheck(masterKey []byte) string {
+	return hex.EncodeToString(keyCheckMAC(masterKey))
+}
+
+// HasKeyCheck - reports whether the key block records a key check value.
+func (k *KeyBlock) HasKeyCheck() bool {
+	return k != nil && k.KeyCheck != ""
+}
+
+// MatchesKey - reports whether masterKey matches the recorded key check value.
+// It is false when no value is recorded.
+func (k *KeyBlock) MatchesKey(masterKey []byte) bool {
+	if !k.HasKeyCheck() {
+		return false
+	}
+
+	expected, err := hex.DecodeString(k.KeyCheck)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(keyCheckMAC(masterKey), expected)
+}
+
+func keyCheckMAC(masterKey []byte) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(keyCheckLabel))
+
+	return mac.Sum(nil)
+}
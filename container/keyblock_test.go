@@ -0,0 +1,83 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKeyBlockRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.tvlt")
+
+	header, err := NewHeader(1, 1, 1, 2, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	keys := &KeyBlock{Holders: []HolderRecord{
+		{ID: 1, Name: "alice", Kind: "passphrase"},
+		{ID: 2, Name: "bob", Kind: "x25519", PublicKey: "00ff"},
+	}}
+
+	cont := NewContainer(path, nil, Metadata{Keys: keys}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("payload")), []byte("passphrase")); err != nil {
+		t.Fatalf("Failed to write encrypted data: %v", err)
+	}
+
+	readContainer := NewContainer(path, nil, Metadata{}, Header{})
+	if err = readContainer.Read(); err != nil {
+		t.Fatalf("Failed to read container: %v", err)
+	}
+
+	if !reflect.DeepEqual(readContainer.GetMetadata().Keys, keys) {
+		t.Errorf("Keys = %+v, want %+v", readContainer.GetMetadata().Keys, keys)
+	}
+
+	record, ok := readContainer.GetMetadata().Keys.Holder(2)
+	if !ok || record.Name != "bob" {
+		t.Errorf("Holder(2) = %+v, %v; want bob", record, ok)
+	}
+
+	if got := readContainer.GetMetadata().Keys.HolderNames(); !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("HolderNames() = %v", got)
+	}
+
+	var decrypted bytes.Buffer
+	if err = readContainer.DecryptTo(context.Background(), &decrypted, cont.GetMasterKey()); err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if decrypted.String() != "payload" {
+		t.Errorf("decrypted = %q", decrypted.String())
+	}
+}
+
+func TestKeyBlockNil(t *testing.T) {
+	var keys *KeyBlock
+
+	if _, ok := keys.Holder(1); ok {
+		t.Error("Holder() on nil key block reported a record")
+	}
+	if names := keys.HolderNames(); names != nil {
+		t.Errorf("HolderNames() = %v, want nil", names)
+	}
+	if keys.MatchesKey([]byte("key")) {
+		t.Error("MatchesKey() on nil key block reported a match")
+	}
+}
+
+func TestKeyBlockMatchesKey(t *testing.T) {
+	masterKey := bytes.Repeat([]byte{0x42}, 32)
+	keys := &KeyBlock{KeyCheck: NewKeyCheck(masterKey)}
+
+	if !keys.HasKeyCheck() {
+		t.Fatal("HasKeyCheck() = false")
+	}
+	if !keys.MatchesKey(masterKey) {
+		t.Error("MatchesKey() rejected the master key")
+	}
+	if keys.MatchesKey(bytes.Repeat([]byte{0x43}, 32)) {
+		t.Error("MatchesKey() accepted another key")
+	}
+}
@@ -0,0 +1,32 @@
+package container
+
+// KMSTypeVaultTransit - KMSWrap type of a Vault Transit secrets engine.
+const KMSTypeVaultTransit = "vault-transit"
+
+// KMSWrap - the master key encrypted by a KMS for token -type=kms. Ciphertext
+// is the transit vault:v<Version>:... string; only Key under Mount decrypts it.
+type KMSWrap struct {
+	Type       string `json:"type"`
+	Mount      string `json:"mount"`
+	Key        string `json:"key"`
+	Version    int    `json:"version"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// GetKMS - returns the KMS wrap, or nil when the container has none.
+func (k *KeyBlock) GetKMS() *KMSWrap {
+	if k == nil {
+		return nil
+	}
+
+	return k.KMS
+}
+
+// KeyName - mount/key of the transit key, for display.
+func (w *KMSWrap) KeyName() string {
+	if w == nil {
+		return ""
+	}
+
+	return w.Mount + "/" + w.Key
+}
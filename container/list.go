@@ -0,0 +1,126 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/agent"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+// Ext - file extension of containers, which directory commands look for.
+const Ext = ".tvlt"
+
+type (
+	// Listing - containers found by container ls; paths are relative to the
+	// directory walked.
+	Listing struct {
+		Containers []ListEntry `json:"containers"`
+	}
+
+	// ListEntry - one container of a listing. Unlocked reports whether the
+	// agent holds its key; Error is set when the container could not be read.
+	ListEntry struct {
+		Path      string `json:"path"`
+		ID        string `json:"id,omitempty"`
+		Name      string `json:"name,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Unlocked  bool   `json:"unlocked"`
+		Error     string `json:"error,omitempty"`
+	}
+)
+
+// List - walks a directory for containers and reports each one with whether
+// the agent holds its key. The walk stops once ctx is cancelled.
+func List(ctx context.Context, opts Options) error {
+	// Without a running agent every container is locked.
+	ids, _ := agent.NewClient(*opts.Agent.Socket).List()
+
+	var (
+		dir     = *opts.Dir
+		listing = Listing{Containers: make([]ListEntry, 0)}
+	)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || filepath.Ext(path) != Ext {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+
+		listing.Containers = append(listing.Containers, listEntry(path, name, ids))
+
+		return nil
+	})
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerListError, lib.ErrMessageContainerListError, "", err)
+	}
+
+	return writeListing(opts.InfoWriter, listing)
+}
+
+func listEntry(path, name string, ids []string) ListEntry {
+	cont := NewContainer(path, nil, Metadata{Tags: make([]string, 0)}, Header{})
+	if err := cont.Read(); err != nil {
+		return ListEntry{Path: name, Error: err.Error()}
+	}
+
+	id := cont.GetHeader().ID()
+
+	return ListEntry{
+		Path:      name,
+		ID:        id,
+		Name:      cont.GetMetadata().Name,
+		TokenType: token.ConvertIDToName(cont.GetHeader().TokenType),
+		Unlocked:  slices.Contains(ids, id),
+	}
+}
+
+func writeListing(opts *lib.Writer, listing Listing) error {
+	writer, closer, err := lib.NewWriter(opts)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any = listing
+	if *opts.Format == lib.WriterFormatPlaintext {
+		var b strings.Builder
+		b.WriteString("[containers]\n")
+		for _, entry := range listing.Containers {
+			switch {
+			case entry.Error != "":
+				fmt.Fprintf(&b, "%s: %s\n", entry.Path, entry.Error)
+			case entry.Unlocked:
+				fmt.Fprintf(&b, "%s: %s %s (%s) unlocked\n", entry.Path, entry.ID, entry.Name, entry.TokenType)
+			default:
+				fmt.Fprintf(&b, "%s: %s %s (%s) locked\n", entry.Path, entry.ID, entry.Name, entry.TokenType)
+			}
+		}
+		msg = b.String()
+	}
+
+	if _, err = lib.WriteFormatted(writer, *opts.Format, msg); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerListError, lib.ErrMessageContainerListError, "", err)
+	}
+
+	return nil
+}
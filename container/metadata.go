@@ -15,4 +15,8 @@ type Metadata struct {
 	UncompressedSize int64     `json:"uncompressed_size"`
 	SecurityScore    float64   `json:"security_score"`
 	FileCount        int64     `json:"file_count"`
+	ExcludedCount    int64     `json:"excluded_count,omitempty"`
+	SkippedCount     int64     `json:"skipped_count,omitempty"`
+	Preserve         string    `json:"preserve,omitempty"`
+	Keys             *KeyBlock `json:"keys,omitempty"`
 }
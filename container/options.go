@@ -6,6 +6,8 @@ import (
 
 type Options struct {
 	Path       *string
+	Dir        *string
+	Agent      *lib.Agent
 	InfoWriter *lib.Writer
 	LogWriter  *lib.Writer
 }
@@ -26,6 +28,19 @@ func (o *Options) Validate() error {
 	return nil
 }
 
+// ValidateList - validates the options of container ls.
+func (o *Options) ValidateList() error {
+	if *o.Dir == "" {
+		return lib.ValidationErr(lib.CategoryContainer, lib.ErrContainerDirRequired)
+	}
+
+	if err := o.validateInfoWriter(); err != nil {
+		return err
+	}
+
+	return o.validateLogWriter()
+}
+
 func (o *Options) validateInfoWriter() error {
 	if _, ok := lib.WriterTypes[*o.InfoWriter.Type]; !ok {
 		return lib.ValidationErr(lib.CategoryContainer, lib.ErrInfoWriterTypeInvalid)
@@ -0,0 +1,17 @@
+package container
+
+// PluginWrap - the master key wrapped by a key provider plugin. Key is opaque:
+// only the provider Name, run as tvault-keyprovider-<Name>, can unwrap it.
+type PluginWrap struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// GetPlugin - returns the key provider wrap, or nil when the container has none.
+func (k *KeyBlock) GetPlugin() *PluginWrap {
+	if k == nil {
+		return nil
+	}
+
+	return k.Plugin
+}
@@ -0,0 +1,89 @@
+package container
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// recoveryInfo - HKDF info binding the wrapping key to its purpose.
+const recoveryInfo = "tvault-core recovery v1"
+
+// NewRecoveryWrap - wraps masterKey under a key derived from the recovery
+// secret and the container salt, for KeyBlock.Recovery. The secret is a
+// random 128-bit value, so HKDF rather than PBKDF2 is enough to derive from it.
+func NewRecoveryWrap(masterKey, secret []byte, salt [16]byte) (string, error) {
+	aesGCM, err := recoveryAEAD(secret, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize(), aesGCM.NonceSize()+len(masterKey)+aesGCM.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(aesGCM.Seal(nonce, nonce, masterKey, nil)), nil
+}
+
+// HasRecovery - reports whether the master key is wrapped to a recovery code.
+func (k *KeyBlock) HasRecovery() bool {
+	return k != nil && k.Recovery != ""
+}
+
+// RecoveryWrap - returns the recovery wrap, empty when there is none.
+func (k *KeyBlock) RecoveryWrap() string {
+	if k == nil {
+		return ""
+	}
+
+	return k.Recovery
+}
+
+// OpenRecovery - unwraps the master key with the recovery secret.
+func (k *KeyBlock) OpenRecovery(secret []byte, salt [16]byte) ([]byte, error) {
+	if !k.HasRecovery() {
+		return nil, lib.ErrRecoveryNotAvailable
+	}
+
+	wrapped, err := hex.DecodeString(k.Recovery)
+	if err != nil {
+		return nil, errors.Join(lib.ErrRecoveryCodeMismatch, err)
+	}
+
+	aesGCM, err := recoveryAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrapped) < aesGCM.NonceSize()+aesGCM.Overhead() {
+		return nil, lib.ErrRecoveryCodeMismatch
+	}
+
+	masterKey, err := aesGCM.Open(nil, wrapped[:aesGCM.NonceSize()], wrapped[aesGCM.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Join(lib.ErrRecoveryCodeMismatch, err)
+	}
+
+	return masterKey, nil
+}
+
+func recoveryAEAD(secret []byte, salt [16]byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, salt[:], recoveryInfo, lib.KeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,49 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestKeyBlockOpenRecovery(t *testing.T) {
+	var (
+		masterKey = bytes.Repeat([]byte{0x42}, lib.KeyLen)
+		secret    = bytes.Repeat([]byte{0x07}, lib.RecoverySecretLen)
+		salt      = [16]byte{1, 2, 3}
+	)
+
+	wrapped, err := NewRecoveryWrap(masterKey, secret, salt)
+	if err != nil {
+		t.Fatalf("NewRecoveryWrap() error = %v", err)
+	}
+	keys := &KeyBlock{Recovery: wrapped}
+
+	tests := []struct {
+		name    string
+		keys    *KeyBlock
+		secret  []byte
+		salt    [16]byte
+		wantErr error
+	}{
+		{name: "recovered", keys: keys, secret: secret, salt: salt},
+		{name: "other_secret", keys: keys, secret: bytes.Repeat([]byte{0x08}, lib.RecoverySecretLen), salt: salt, wantErr: lib.ErrRecoveryCodeMismatch},
+		{name: "other_container", keys: keys, secret: secret, salt: [16]byte{9}, wantErr: lib.ErrRecoveryCodeMismatch},
+		{name: "no_recovery", keys: &KeyBlock{}, secret: secret, salt: salt, wantErr: lib.ErrRecoveryNotAvailable},
+		{name: "no_key_block", secret: secret, salt: salt, wantErr: lib.ErrRecoveryNotAvailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keys.OpenRecovery(tt.secret, tt.salt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenRecovery() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, masterKey) {
+				t.Errorf("OpenRecovery() = %x, want %x", got, masterKey)
+			}
+		})
+	}
+}
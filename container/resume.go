@@ -0,0 +1,239 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// Checkpoint - a consistent point of a payload being written: the number of
+// chunks on disk, the plaintext bytes they hold and the file offset after them.
+type Checkpoint struct {
+	Chunks    uint64 `json:"chunks"`
+	PlainSize int64  `json:"plain_size"`
+	Offset    int64  `json:"offset"`
+}
+
+// CheckpointFunc - records a checkpoint. The payload up to it is on stable
+// storage when it is called; an error stops the write.
+type CheckpointFunc func(Checkpoint) error
+
+// SetCheckpoint - has WriteEncrypted and ResumeEncrypted sync the file and call
+// fn when the payload starts and after every `every` plaintext bytes, so an
+// interrupted write can be resumed from the last checkpoint.
+func (c *container) SetCheckpoint(every int64, fn CheckpointFunc) {
+	c.checkpoint, c.checkpointEvery = fn, every
+}
+
+func (c *container) saveCheckpoint(f *os.File, cp Checkpoint) error {
+	if c.checkpoint == nil {
+		return nil
+	}
+
+	if err := f.Sync(); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerSyncFileError, lib.ErrMessageContainerSyncFileError, "", err)
+	}
+
+	return c.checkpoint(cp)
+}
+
+// ResumeEncrypted - completes a container WriteEncrypted was writing when it
+// was interrupted after checkpoint from. The container must have been read
+// with Read and its master key set. r is the plaintext stream WriteEncrypted
+// was given: its first from.PlainSize bytes are compared with the chunks
+// already in the file, which authenticates them too, and the rest of r is
+// encrypted after them. A stream that differs fails with lib.ErrResumeMismatch
+// before anything is written.
+//
+// The chunks after from keep their nonces, and those the interrupted write
+// got into the file after the checkpoint may already be on disk. They are
+// overwritten through an overwriteGuard, so a chunk is only encrypted again
+// under its nonce with the same plaintext; a stream that changed after the
+// checkpoint fails with lib.ErrResumeMismatch and leaves the file as it was.
+func (c *container) ResumeEncrypted(ctx context.Context, r io.Reader, from Checkpoint) error {
+	block, err := aes.NewCipher(c.masterKey)
+	if err != nil {
+		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewCipherError, lib.ErrMessageCreateNewCipherError, "", err)
+	}
+	aesGcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeCreateNewGCMError, lib.ErrMessageCreateNewGCMError, "", err)
+	}
+
+	payloadOffset := c.header.Size() + int64(c.header.MetadataSize)
+	if from.Offset < payloadOffset {
+		return lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeJournalInvalid)
+	}
+
+	f, err := os.OpenFile(c.path, os.O_RDWR, 0)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerOpenFileError, lib.ErrMessageContainerOpenFileError, "", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err = f.Seek(payloadOffset, io.SeekStart); err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+	if err = verifyPayload(ctx, f, aesGcm, c.header.Nonce, r, from); err != nil {
+		return err
+	}
+
+	// Whatever follows the checkpoint was written after the last sync and may
+	// be torn, so it is written again, over the same bytes.
+	fi, err := f.Stat()
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeContainerOpenFileError, lib.ErrMessageContainerOpenFileError, "", err)
+	}
+	guard := &overwriteGuard{f: f, offset: from.Offset, size: fi.Size()}
+
+	err = c.writePayload(ctx, f, guard, aesGcm, r, from)
+	if errors.Is(err, lib.ErrResumeMismatch) {
+		return lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeMismatch)
+	}
+
+	return err
+}
+
+// guardBlock - the unit in which overwriteGuard tells data the interrupted
+// write left from blocks that never reached the disk and read as zeros.
+const guardBlock = 4096
+
+// overwriteGuard - writes to f at its position, offset, after checking that
+// every block of the first size bytes it overwrites is all zero or holds the
+// bytes written. Otherwise the write fails with lib.ErrResumeMismatch.
+type overwriteGuard struct {
+	f      *os.File
+	offset int64
+	size   int64
+	block  []byte
+}
+
+func (g *overwriteGuard) Write(p []byte) (int, error) {
+	if err := g.check(p); err != nil {
+		return 0, err
+	}
+
+	n, err := g.f.Write(p)
+	g.offset += int64(n)
+
+	return n, err
+}
+
+func (g *overwriteGuard) check(p []byte) error {
+	end := g.offset + int64(len(p))
+	if g.offset >= g.size {
+		return nil
+	}
+	if g.block == nil {
+		g.block = make([]byte, guardBlock)
+	}
+
+	for start := g.offset / guardBlock * guardBlock; start < end && start < g.size; start += guardBlock {
+		n, err := g.f.ReadAt(g.block, start)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		existing := g.block[:n]
+		if !slices.ContainsFunc(existing, func(b byte) bool { return b != 0 }) {
+			continue
+		}
+
+		lo, hi := max(start, g.offset), min(start+int64(n), end)
+		if lo < hi && !bytes.Equal(existing[lo-start:hi-start], p[lo-g.offset:hi-g.offset]) {
+			return lib.ErrResumeMismatch
+		}
+	}
+
+	return nil
+}
+
+// verifyPayload - opens the from.Chunks chunks at the position of f in
+// parallel and compares each with the next bytes of r, leaving f at from.Offset.
+func verifyPayload(
+	ctx context.Context,
+	f *os.File,
+	aesGcm cipher.AEAD,
+	baseNonce [12]byte,
+	r io.Reader,
+	from Checkpoint,
+) error {
+	var (
+		pool             = newChunkPool()
+		lenBuf           = make([]byte, 4)
+		counter   uint64 = 0
+		plainSize int64  = 0
+		overhead         = aesGcm.Overhead()
+	)
+	next := func() (*chunk, error) {
+		if counter == from.Chunks {
+			return nil, nil
+		}
+
+		if _, err := io.ReadFull(f, lenBuf); err != nil {
+			return nil, lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeJournalInvalid)
+		}
+		plainLen := int(binary.LittleEndian.Uint32(lenBuf))
+		if plainLen == 0 || plainLen > MaxChunkSize {
+			return nil, lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeJournalInvalid)
+		}
+
+		// One buffer holds the stored ciphertext followed by the plaintext
+		// the stream gives for the same chunk.
+		ch := pool.get(2*plainLen + overhead)
+		ch.data, ch.ref = ch.data[:plainLen+overhead], ch.data[plainLen+overhead:]
+		if _, err := io.ReadFull(f, ch.data); err != nil {
+			pool.put(ch)
+			return nil, lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeJournalInvalid)
+		}
+		if _, err := io.ReadFull(r, ch.ref); err != nil {
+			pool.put(ch)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeMismatch)
+			}
+			return nil, lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+		}
+
+		ch.plainLen, ch.counter = plainLen, counter
+		counter++
+		plainSize += int64(plainLen)
+
+		return ch, nil
+	}
+	open := func(ch *chunk) error {
+		nonce := baseNonce
+		binary.LittleEndian.PutUint64(nonce[4:], ch.counter)
+
+		plain, err := aesGcm.Open(ch.data[:0], nonce[:], ch.data, nil)
+		if err != nil {
+			return lib.CryptoErr(lib.CategoryContainer, lib.ErrCodeOpenCipherTextError, lib.ErrMessageOpenCipherTextError, "", err)
+		}
+		if !bytes.Equal(plain, ch.ref) {
+			return lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeMismatch)
+		}
+
+		return nil
+	}
+
+	if err := runChunks(ctx, pool, next, open, func(*chunk) error { return nil }); err != nil {
+		return err
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return lib.IOErr(lib.CategoryContainer, lib.ErrCodeReadCipherTextError, lib.ErrMessageReadCipherTextError, "", err)
+	}
+	if offset != from.Offset || plainSize != from.PlainSize {
+		return lib.ValidationErr(lib.CategoryContainer, lib.ErrResumeJournalInvalid)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// interruptedContainer writes payload with a checkpoint after every chunk and
+// fails the stream after cut bytes, returning the path, master key and the
+// last checkpoint recorded.
+func interruptedContainer(t *testing.T, payload []byte, cut int) (string, []byte, Checkpoint) {
+	t.Helper()
+
+	header, err := NewHeader(1, 1, 1, 3, 2)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	header.ChunkSize = 1000
+
+	var checkpoints []Checkpoint
+	path := filepath.Join(t.TempDir(), "resume.tvlt")
+	cont := NewContainer(path, nil, Metadata{Comment: "resume"}, header)
+	cont.SetCheckpoint(1, func(cp Checkpoint) error {
+		checkpoints = append(checkpoints, cp)
+		return nil
+	})
+
+	failAt := io.MultiReader(bytes.NewReader(payload[:cut]), failingReader{})
+	if err = cont.WriteEncrypted(context.Background(), failAt, []byte("pass")); err == nil {
+		t.Fatal("Expected WriteEncrypted to fail on the interrupted stream")
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Fatalf("Partial container was removed despite the checkpoint function: %v", err)
+	}
+
+	written := cont.GetHeader()
+	if first := checkpoints[0]; first.Chunks != 0 || first.Offset != written.Size()+int64(written.MetadataSize) {
+		t.Errorf("First checkpoint = %+v, want the start of the payload", first)
+	}
+
+	return path, cont.GetMasterKey(), checkpoints[len(checkpoints)-1]
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("simulated interruption") }
+
+func TestContainerResumeEncrypted(t *testing.T) {
+	payload := make([]byte, 10_500)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("Failed to generate payload: %v", err)
+	}
+
+	path, key, last := interruptedContainer(t, payload, 4_500)
+	if last.Chunks != 4 || last.PlainSize != 4_000 {
+		t.Fatalf("Last checkpoint = %+v, want 4 chunks of 1000 bytes", last)
+	}
+
+	cont := NewContainer(path, key, Metadata{}, Header{})
+	if err := cont.Read(); err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+	if err := cont.ResumeEncrypted(context.Background(), bytes.NewReader(payload), last); err != nil {
+		t.Fatalf("ResumeEncrypted() error = %v", err)
+	}
+
+	opened := NewContainer(path, nil, Metadata{}, Header{})
+	if err := opened.Read(); err != nil {
+		t.Fatalf("Failed to read resumed container: %v", err)
+	}
+	if got := opened.GetMetadata().CompressedSize; got != int64(len(payload)) {
+		t.Errorf("CompressedSize = %d, want %d", got, len(payload))
+	}
+
+	var out bytes.Buffer
+	if err := opened.DecryptTo(context.Background(), &out, key); err != nil {
+		t.Fatalf("DecryptTo() error = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Error("Resumed container does not decrypt to the payload")
+	}
+}
+
+func TestContainerResumeEncryptedRejectsChangedStream(t *testing.T) {
+	payload := make([]byte, 6_000)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("Failed to generate payload: %v", err)
+	}
+
+	path, key, last := interruptedContainer(t, payload, 3_200)
+	before, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+
+	changed := bytes.Clone(payload)
+	changed[1_500] ^= 0xFF
+
+	cont := NewContainer(path, key, Metadata{}, Header{})
+	if err = cont.Read(); err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+	if err = cont.ResumeEncrypted(context.Background(), bytes.NewReader(changed), last); !errors.Is(err, lib.ErrResumeMismatch) {
+		t.Fatalf("ResumeEncrypted() error = %v, want %v", err, lib.ErrResumeMismatch)
+	}
+
+	after, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("A rejected resume modified the partial container")
+	}
+}
+
+func TestContainerResumeEncryptedKeepsChunksPastCheckpoint(t *testing.T) {
+	payload := make([]byte, 6_000)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatalf("Failed to generate payload: %v", err)
+	}
+
+	// The file holds 4 chunks, but the last checkpoint that reached the
+	// journal is after the second: chunks 2 and 3 are on disk under their
+	// nonces and must not be encrypted again with other plaintext.
+	path, key, last := interruptedContainer(t, payload, 4_500)
+	chunkLen := int64(4 + 1000 + 16)
+	earlier := Checkpoint{Chunks: 2, PlainSize: 2_000, Offset: last.Offset - 2*chunkLen}
+
+	before, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+
+	changed := bytes.Clone(payload)
+	changed[2_500] ^= 0xFF
+
+	cont := NewContainer(path, key, Metadata{}, Header{})
+	if err = cont.Read(); err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+	if err = cont.ResumeEncrypted(context.Background(), bytes.NewReader(changed), earlier); !errors.Is(err, lib.ErrResumeMismatch) {
+		t.Fatalf("ResumeEncrypted() of a stream changed past the checkpoint error = %v, want %v", err, lib.ErrResumeMismatch)
+	}
+
+	after, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read partial container: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("A rejected resume modified the partial container")
+	}
+
+	if err = cont.ResumeEncrypted(context.Background(), bytes.NewReader(payload), earlier); err != nil {
+		t.Fatalf("ResumeEncrypted() of the same stream error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err = cont.DecryptTo(context.Background(), &out, key); err != nil {
+		t.Fatalf("DecryptTo() error = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Error("Resumed container does not decrypt to the payload")
+	}
+}
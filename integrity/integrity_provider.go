@@ -4,15 +4,18 @@ const (
 	TypeNone    byte = 0x00
 	TypeHMAC    byte = 0x01
 	TypeEd25519 byte = 0x02
+	TypeFeldman byte = 0x03
 
 	TypeNameNone    string = "none"
 	TypeNameHMAC    string = "hmac"
 	TypeNameEd25519 string = "ed25519"
+	TypeNameFeldman string = "feldman"
 )
 
 var Types = map[string]struct{}{
-	TypeNameNone: {},
-	TypeNameHMAC: {},
+	TypeNameNone:    {},
+	TypeNameHMAC:    {},
+	TypeNameFeldman: {},
 }
 
 type (
@@ -23,6 +26,10 @@ type (
 	}
 
 	noneProvider struct{}
+
+	// feldmanProvider - shares are not signed; each one is checked against the
+	// container's public commitments by shamir.VerifyFeldman instead.
+	feldmanProvider struct{}
 )
 
 func NewNoneProvider() Provider {
@@ -41,6 +48,22 @@ func (n *noneProvider) ID() byte {
 	return TypeNone
 }
 
+func NewFeldmanProvider() Provider {
+	return &feldmanProvider{}
+}
+
+func (f *feldmanProvider) Sign(_ byte, _ []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *feldmanProvider) IsVerify(_ byte, _, _ []byte) (bool, error) {
+	return true, nil
+}
+
+func (f *feldmanProvider) ID() byte {
+	return TypeFeldman
+}
+
 func ConvertIDToName(id byte) string {
 	switch id {
 	case TypeNone:
@@ -49,6 +72,8 @@ func ConvertIDToName(id byte) string {
 		return TypeNameHMAC
 	case TypeEd25519:
 		return TypeNameEd25519
+	case TypeFeldman:
+		return TypeNameFeldman
 	default:
 		return ""
 	}
@@ -62,6 +87,8 @@ func ConvertNameToID(name string) byte {
 		return TypeHMAC
 	case TypeNameEd25519:
 		return TypeEd25519
+	case TypeNameFeldman:
+		return TypeFeldman
 	default:
 		return TypeNone
 	}
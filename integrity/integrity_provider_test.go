@@ -63,6 +63,11 @@ func TestConvertIDToName(t *testing.T) {
 			id:       TypeEd25519,
 			expected: TypeNameEd25519,
 		},
+		{
+			name:     "TypeFeldman",
+			id:       TypeFeldman,
+			expected: TypeNameFeldman,
+		},
 		{
 			name:     "Unknown type",
 			id:       0xFF,
@@ -101,6 +106,11 @@ func TestConvertNameToID(t *testing.T) {
 			typeName: TypeNameEd25519,
 			expected: TypeEd25519,
 		},
+		{
+			name:     "TypeNameFeldman",
+			typeName: TypeNameFeldman,
+			expected: TypeFeldman,
+		},
 		{
 			name:     "Unknown type",
 			typeName: "unknown",
@@ -127,6 +137,10 @@ func TestTypes(t *testing.T) {
 		t.Errorf("Expected %q to be in Types map", TypeNameHMAC)
 	}
 
+	if _, ok := Types[TypeNameFeldman]; !ok {
+		t.Errorf("Expected %q to be in Types map", TypeNameFeldman)
+	}
+
 	if _, ok := Types[TypeNameEd25519]; ok {
 		t.Errorf("Expected %q not to be in Types map", TypeNameEd25519)
 	}
@@ -0,0 +1,194 @@
+package keyprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ProgramPrefix - a key provider named n is the program
+	// tvault-keyprovider-n, looked up on PATH.
+	ProgramPrefix = "tvault-keyprovider-"
+
+	// Version - protocol version sent with every request.
+	Version = 1
+
+	OpWrap   = "wrap"
+	OpUnwrap = "unwrap"
+
+	// DefaultTimeout - how long a provider may run before it is killed, when
+	// the caller's context has no earlier deadline.
+	DefaultTimeout = 30 * time.Second
+
+	// maxStdout - the largest response read from a provider.
+	maxStdout = 64 << 10
+
+	// maxStderr - how much of a failing provider's stderr is kept for the error.
+	maxStderr = 4 << 10
+
+	// waitDelay - how long the provider's pipes may stay open once it is
+	// killed, e.g. by a child it left running.
+	waitDelay = time.Second
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type (
+	// Request - written as JSON to the provider's stdin. Key is the base64
+	// container key of a wrap; Wrapped is the blob of an unwrap, as the
+	// provider returned it. ContainerID lets the provider bind the wrap to the
+	// container, e.g. as a KMS encryption context.
+	Request struct {
+		Version     int    `json:"version"`
+		Op          string `json:"op"`
+		ContainerID string `json:"container_id"`
+		Key         string `json:"key,omitempty"`
+		Wrapped     string `json:"wrapped,omitempty"`
+	}
+
+	// Response - read as JSON from the provider's stdout. A provider reports a
+	// failure with Error, or with a non-zero exit status.
+	Response struct {
+		Key     string `json:"key,omitempty"`
+		Wrapped string `json:"wrapped,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+)
+
+// ValidName - reports whether name can name a key provider: letters, digits,
+// '-' and '_', so it never reaches outside PATH.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Wrap - asks the provider name to wrap key for the container containerID and
+// returns the opaque wrapped blob.
+func Wrap(ctx context.Context, name, containerID string, key []byte) (string, error) {
+	resp, err := call(ctx, name, Request{
+		Version:     Version,
+		Op:          OpWrap,
+		ContainerID: containerID,
+		Key:         base64.StdEncoding.EncodeToString(key),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Wrapped == "" {
+		return "", fmt.Errorf("%s%s: empty wrapped key", ProgramPrefix, name)
+	}
+
+	return resp.Wrapped, nil
+}
+
+// Unwrap - asks the provider name to unwrap the blob wrapped for the container
+// containerID and returns the key.
+func Unwrap(ctx context.Context, name, containerID, wrapped string) ([]byte, error) {
+	resp, err := call(ctx, name, Request{
+		Version:     Version,
+		Op:          OpUnwrap,
+		ContainerID: containerID,
+		Wrapped:     wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(resp.Key)
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("%s%s: invalid key in response", ProgramPrefix, name)
+	}
+
+	return key, nil
+}
+
+// call - runs the provider with req on stdin and decodes its response. The
+// provider is killed when ctx is done or after DefaultTimeout, and a response
+// over maxStdout bytes fails the call.
+func call(ctx context.Context, name string, req Request) (Response, error) {
+	if !ValidName(name) {
+		return Response{}, errors.New("invalid key provider name")
+	}
+
+	program, err := exec.LookPath(ProgramPrefix + name)
+	if err != nil {
+		return Response{}, err
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, program) // #nosec G204 -- the name is validated and resolved on PATH
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &limitedBuffer{max: maxStderr, buf: &stderr}
+	cmd.WaitDelay = waitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return Response{}, err
+	}
+	if err = cmd.Start(); err != nil {
+		return Response{}, fmt.Errorf("%s%s: %w", ProgramPrefix, name, err)
+	}
+
+	output, err := io.ReadAll(io.LimitReader(stdout, maxStdout+1))
+	if err == nil && len(output) > maxStdout {
+		err = fmt.Errorf("response exceeds %d bytes", maxStdout)
+	}
+	if err != nil {
+		cancel()
+		_ = cmd.Wait()
+
+		return Response{}, fmt.Errorf("%s%s: %w", ProgramPrefix, name, err)
+	}
+
+	if err = cmd.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return Response{}, fmt.Errorf("%s%s: %w: %s", ProgramPrefix, name, err, msg)
+		}
+
+		return Response{}, fmt.Errorf("%s%s: %w", ProgramPrefix, name, err)
+	}
+
+	var resp Response
+	if err = json.Unmarshal(output, &resp); err != nil {
+		return Response{}, fmt.Errorf("%s%s: invalid response: %w", ProgramPrefix, name, err)
+	}
+	if resp.Error != "" {
+		return Response{}, fmt.Errorf("%s%s: %s", ProgramPrefix, name, resp.Error)
+	}
+
+	return resp, nil
+}
+
+// limitedBuffer - keeps the first max bytes written and discards the rest, so
+// a chatty provider cannot grow memory without bound.
+type limitedBuffer struct {
+	max int
+	buf *bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,174 @@
+package keyprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envStub - set when the test binary runs as the stub key provider.
+const envStub = "TVAULT_KEYPROVIDER_STUB"
+
+// TestMain - lets the test binary act as the stub provider tvault-keyprovider-stub:
+// it "wraps" a key by prefixing the container ID to its reversed bytes, and
+// refuses to unwrap a blob of another container. The container IDs "hang" and
+// "flood" make it never answer or answer with more than maxStdout bytes.
+func TestMain(m *testing.M) {
+	if os.Getenv(envStub) != "" {
+		os.Exit(runStub())
+	}
+
+	os.Exit(m.Run())
+}
+
+func runStub() int {
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		_, _ = os.Stderr.WriteString("bad request")
+		return 2
+	}
+
+	switch req.ContainerID {
+	case "hang":
+		time.Sleep(time.Minute)
+	case "flood":
+		_, _ = os.Stdout.WriteString(strings.Repeat(" ", 2*maxStdout))
+	}
+
+	var resp Response
+	switch req.Op {
+	case OpWrap:
+		key, _ := base64.StdEncoding.DecodeString(req.Key)
+		resp.Wrapped = req.ContainerID + ":" + base64.StdEncoding.EncodeToString(reverse(key))
+	case OpUnwrap:
+		id, blob, _ := strings.Cut(req.Wrapped, ":")
+		if id != req.ContainerID {
+			resp.Error = "wrapped for another container"
+			break
+		}
+		key, _ := base64.StdEncoding.DecodeString(blob)
+		resp.Key = base64.StdEncoding.EncodeToString(reverse(key))
+	default:
+		resp.Error = "unknown op"
+	}
+
+	_ = json.NewEncoder(os.Stdout).Encode(resp)
+
+	return 0
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+
+	return out
+}
+
+// installStub - puts a copy of the test binary on PATH as tvault-keyprovider-stub.
+func installStub(t *testing.T) {
+	t.Helper()
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Executable() error = %v", err)
+	}
+	content, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	name := ProgramPrefix + "stub"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, name), content, 0o700); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv("PATH", dir)
+	t.Setenv(envStub, "1")
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	installStub(t)
+
+	key := bytes.Repeat([]byte{1, 2, 3, 4}, 8)
+	wrapped, err := Wrap(context.Background(), "stub", "c1", key)
+	if err != nil {
+		t.Fatalf("Wrap() error = %v", err)
+	}
+
+	got, err := Unwrap(context.Background(), "stub", "c1", wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("Unwrap() = %x, want %x", got, key)
+	}
+
+	if _, err = Unwrap(context.Background(), "stub", "c2", wrapped); err == nil || !strings.Contains(err.Error(), "another container") {
+		t.Errorf("Unwrap() for another container error = %v", err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	installStub(t)
+
+	tests := []struct {
+		name     string
+		provider string
+	}{
+		{name: "missing_provider", provider: "missing"},
+		{name: "path_in_name", provider: "../stub"},
+		{name: "empty_name", provider: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Wrap(context.Background(), tt.provider, "c1", []byte{1}); err == nil {
+				t.Errorf("Wrap() with provider %q succeeded", tt.provider)
+			}
+		})
+	}
+}
+
+func TestCallLimits(t *testing.T) {
+	installStub(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := Wrap(ctx, "stub", "hang", []byte{1}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wrap() of a hanging provider error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err := Wrap(context.Background(), "stub", "flood", []byte{1}); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Wrap() of an oversized response error = %v", err)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"vault-transit": true,
+		"kms_1":         true,
+		"":              false,
+		"a/b":           false,
+		"..":            false,
+		"a b":           false,
+	} {
+		if got := ValidName(name); got != want {
+			t.Errorf("ValidName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
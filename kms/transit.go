@@ -0,0 +1,287 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvAddress, EnvToken, EnvCACert, EnvClientCert and EnvClientKey - the
+	// environment variables the Vault CLI reads, used as defaults.
+	EnvAddress    = "VAULT_ADDR"
+	EnvToken      = "VAULT_TOKEN"
+	EnvCACert     = "VAULT_CACERT"
+	EnvClientCert = "VAULT_CLIENT_CERT"
+	EnvClientKey  = "VAULT_CLIENT_KEY"
+
+	// DefaultMount - path the transit secrets engine is mounted at.
+	DefaultMount = "transit"
+
+	// DefaultTimeout - limit of one request, connection included.
+	DefaultTimeout = 10 * time.Second
+
+	// maxResponse - largest response body read from Vault.
+	maxResponse = 1 << 20
+
+	ciphertextPrefix = "vault:v"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("transit ciphertext must look like vault:v<version>:<data>")
+	ErrInvalidMount      = errors.New("transit mount must be path segments of letters, digits, '-', '_' and '.'")
+	ErrInvalidKeyName    = errors.New("transit key name must be letters, digits, '-', '_' and '.'")
+	ErrInvalidResponse   = errors.New("unexpected response from transit")
+	ErrTokenRequired     = errors.New("no Vault token: the token file and VAULT_TOKEN are empty")
+)
+
+type (
+	// Config - how to reach the transit engine. CACert, ClientCert and
+	// ClientKey are PEM files; the client certificate is only sent when both
+	// are set.
+	Config struct {
+		Address    string
+		Mount      string
+		Token      string
+		CACert     string
+		ClientCert string
+		ClientKey  string
+		Timeout    time.Duration
+	}
+
+	// Transit - client of the Vault Transit API, authenticated with a token.
+	Transit struct {
+		address string
+		mount   string
+		token   string
+		client  *http.Client
+	}
+
+	transitRequest struct {
+		Plaintext  string `json:"plaintext,omitempty"`
+		Ciphertext string `json:"ciphertext,omitempty"`
+	}
+
+	transitResponse struct {
+		Data struct {
+			Plaintext  string `json:"plaintext"`
+			Ciphertext string `json:"ciphertext"`
+			KeyVersion int    `json:"key_version"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+)
+
+// NewTransit - returns a transit client for cfg, with its TLS settings loaded.
+func NewTransit(cfg Config) (*Transit, error) {
+	address, err := url.Parse(cfg.Address)
+	if err != nil || address.Host == "" || (address.Scheme != "http" && address.Scheme != "https") {
+		return nil, fmt.Errorf("invalid transit address %q", cfg.Address)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", cfg.CACert)
+		}
+	}
+	if cfg.ClientCert != "" && cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	mount := strings.Trim(cfg.Mount, "/")
+	if mount == "" {
+		mount = DefaultMount
+	}
+	for _, segment := range strings.Split(mount, "/") {
+		if !validSegment(segment) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidMount, cfg.Mount)
+		}
+	}
+
+	return &Transit{
+		address: strings.TrimRight(cfg.Address, "/"),
+		mount:   mount,
+		token:   cfg.Token,
+		client:  &http.Client{Transport: transport, Timeout: timeout},
+	}, nil
+}
+
+// Mount - path of the transit engine the client talks to.
+func (t *Transit) Mount() string {
+	return t.mount
+}
+
+// Encrypt - encrypts plaintext with the transit key and returns the
+// ciphertext with the key version that encrypted it.
+func (t *Transit) Encrypt(ctx context.Context, key string, plaintext []byte) (string, int, error) {
+	resp, err := t.do(ctx, "encrypt", key, transitRequest{Plaintext: base64.StdEncoding.EncodeToString(plaintext)})
+	if err != nil {
+		return "", 0, err
+	}
+
+	version, err := CiphertextVersion(resp.Data.Ciphertext)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return resp.Data.Ciphertext, version, nil
+}
+
+// Decrypt - decrypts a ciphertext of the transit key.
+func (t *Transit) Decrypt(ctx context.Context, key, ciphertext string) ([]byte, error) {
+	resp, err := t.do(ctx, "decrypt", key, transitRequest{Ciphertext: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil || len(plaintext) == 0 {
+		return nil, ErrInvalidResponse
+	}
+
+	return plaintext, nil
+}
+
+// validSegment - reports whether s is one path segment of letters, digits,
+// '-', '_' and '.', other than "." and "..".
+func validSegment(s string) bool {
+	if s == "" || s == "." || s == ".." {
+		return false
+	}
+
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReadToken - returns the Vault token held in the file at path, or the value
+// of VAULT_TOKEN when path is empty.
+func ReadToken(path string) (string, error) {
+	token := os.Getenv(EnvToken)
+	if path != "" {
+		raw, err := os.ReadFile(path) // #nosec G304
+		if err != nil {
+			return "", err
+		}
+		token = string(raw)
+	}
+
+	if token = strings.TrimSpace(token); token == "" {
+		return "", ErrTokenRequired
+	}
+
+	return token, nil
+}
+
+// CiphertextVersion - returns the key version of a transit ciphertext,
+// vault:v<version>:<data>.
+func CiphertextVersion(ciphertext string) (int, error) {
+	rest, ok := strings.CutPrefix(ciphertext, ciphertextPrefix)
+	if !ok {
+		return 0, ErrInvalidCiphertext
+	}
+
+	version, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, ErrInvalidCiphertext
+	}
+
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 1 {
+		return 0, ErrInvalidCiphertext
+	}
+
+	return n, nil
+}
+
+func (t *Transit) do(ctx context.Context, op, key string, body transitRequest) (transitResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return transitResponse{}, err
+	}
+
+	// The mount and key may come from container metadata, so neither may
+	// reach another path of the server.
+	if !validSegment(key) {
+		return transitResponse{}, fmt.Errorf("%w: %q", ErrInvalidKeyName, key)
+	}
+
+	segments := strings.Split(t.mount, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", t.address, strings.Join(segments, "/"), op, url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return transitResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", t.token)
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return transitResponse{}, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	raw, err := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+	if err != nil {
+		return transitResponse{}, err
+	}
+
+	var resp transitResponse
+	if err = json.Unmarshal(raw, &resp); err != nil && res.StatusCode == http.StatusOK {
+		return transitResponse{}, ErrInvalidResponse
+	}
+
+	if res.StatusCode != http.StatusOK {
+		if len(resp.Errors) > 0 {
+			return transitResponse{}, fmt.Errorf("transit %s: %s: %s", op, res.Status, strings.Join(resp.Errors, "; "))
+		}
+
+		return transitResponse{}, fmt.Errorf("transit %s: %s", op, res.Status)
+	}
+
+	return resp, nil
+}
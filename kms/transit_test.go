@@ -0,0 +1,251 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "s.test-token"
+
+// newTransitServer - stands in for the transit engine at mount "transit" with
+// one key, "tvault", at version 3. Ciphertexts are the base64 plaintext,
+// reversed, so decrypt can check it was given one of its own.
+func newTransitServer(t *testing.T, tlsServer bool) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/transit/{op}/{key}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.PathValue("key") != "tvault" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["encryption key not found"]}`))
+			return
+		}
+
+		var req transitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var resp transitResponse
+		switch r.PathValue("op") {
+		case "encrypt":
+			resp.Data.Ciphertext = "vault:v3:" + reverse(req.Plaintext)
+			resp.Data.KeyVersion = 3
+		case "decrypt":
+			data, ok := strings.CutPrefix(req.Ciphertext, "vault:v3:")
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid ciphertext"]}`))
+				return
+			}
+			resp.Data.Plaintext = reverse(data)
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("POST /v1/slow/{op}/{key}", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+
+	var server *httptest.Server
+	if tlsServer {
+		server = httptest.NewTLSServer(mux)
+	} else {
+		server = httptest.NewServer(mux)
+	}
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+func TestTransitEncryptDecrypt(t *testing.T) {
+	server := newTransitServer(t, false)
+
+	transit, err := NewTransit(Config{Address: server.URL, Token: testToken})
+	if err != nil {
+		t.Fatalf("NewTransit() error = %v", err)
+	}
+
+	key := bytes.Repeat([]byte{7, 8, 9}, 11)
+	ciphertext, version, err := transit.Encrypt(context.Background(), "tvault", key)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if version != 3 || !strings.HasPrefix(ciphertext, "vault:v3:") {
+		t.Errorf("Encrypt() = %q, %d, want a vault:v3 ciphertext", ciphertext, version)
+	}
+	if strings.Contains(ciphertext, base64.StdEncoding.EncodeToString(key)) {
+		t.Errorf("Encrypt() ciphertext holds the plaintext")
+	}
+
+	got, err := transit.Decrypt(context.Background(), "tvault", ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("Decrypt() = %x, want %x", got, key)
+	}
+}
+
+func TestTransitErrors(t *testing.T) {
+	server := newTransitServer(t, false)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		key     string
+		wantErr string
+	}{
+		{name: "bad_token", cfg: Config{Address: server.URL, Token: "wrong"}, key: "tvault", wantErr: "permission denied"},
+		{name: "unknown_key", cfg: Config{Address: server.URL, Token: testToken}, key: "other", wantErr: "encryption key not found"},
+		{name: "timeout", cfg: Config{Address: server.URL, Mount: "slow", Token: testToken, Timeout: 50 * time.Millisecond}, key: "tvault", wantErr: "Timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transit, err := NewTransit(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTransit() error = %v", err)
+			}
+
+			_, _, err = transit.Encrypt(context.Background(), tt.key, []byte{1})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Encrypt() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransitInvalidKeyName(t *testing.T) {
+	server := newTransitServer(t, false)
+
+	transit, err := NewTransit(Config{Address: server.URL, Token: testToken})
+	if err != nil {
+		t.Fatalf("NewTransit() error = %v", err)
+	}
+
+	for _, key := range []string{"", "..", "tvault/../x", "tvault?x=1"} {
+		if _, err = transit.Decrypt(context.Background(), key, "vault:v1:x"); !errors.Is(err, ErrInvalidKeyName) {
+			t.Errorf("Decrypt(%q) error = %v, want %v", key, err, ErrInvalidKeyName)
+		}
+	}
+}
+
+func TestTransitTLS(t *testing.T) {
+	server := newTransitServer(t, true)
+
+	// Without the server's CA the certificate is not trusted.
+	transit, err := NewTransit(Config{Address: server.URL, Token: testToken})
+	if err != nil {
+		t.Fatalf("NewTransit() error = %v", err)
+	}
+	if _, _, err = transit.Encrypt(context.Background(), "tvault", []byte{1}); err == nil {
+		t.Fatalf("Encrypt() trusted an unknown certificate")
+	}
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err = os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if transit, err = NewTransit(Config{Address: server.URL, Token: testToken, CACert: caPath}); err != nil {
+		t.Fatalf("NewTransit() error = %v", err)
+	}
+	if _, _, err = transit.Encrypt(context.Background(), "tvault", []byte{1}); err != nil {
+		t.Errorf("Encrypt() with the CA error = %v", err)
+	}
+}
+
+func TestNewTransitInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no_address":   {},
+		"bad_scheme":   {Address: "ftp://vault:8200"},
+		"missing_ca":   {Address: "https://vault:8200", CACert: filepath.Join(t.TempDir(), "none.pem")},
+		"ca_not_pem":   {Address: "https://vault:8200", CACert: writeFile(t, "not a certificate")},
+		"bad_keypair":  {Address: "https://vault:8200", ClientCert: writeFile(t, "x"), ClientKey: writeFile(t, "y")},
+		"mount_dotdot": {Address: "https://vault:8200", Mount: "transit/../sys/policies"},
+		"mount_query":  {Address: "https://vault:8200", Mount: "sys/policies/acl/x#"},
+		"mount_empty":  {Address: "https://vault:8200", Mount: "a//b"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewTransit(cfg); err == nil {
+				t.Errorf("NewTransit() accepted %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestCiphertextVersion(t *testing.T) {
+	for ciphertext, want := range map[string]int{
+		"vault:v1:abc":  1,
+		"vault:v12:abc": 12,
+		"vault:v0:abc":  0,
+		"vault:vx:abc":  0,
+		"vault:v3":      0,
+		"other:v1:abc":  0,
+	} {
+		got, err := CiphertextVersion(ciphertext)
+		if got != want || (want == 0) != (err != nil) {
+			t.Errorf("CiphertextVersion(%q) = %d, %v, want %d", ciphertext, got, err, want)
+		}
+	}
+}
+
+func TestReadToken(t *testing.T) {
+	t.Setenv(EnvToken, "s.from-env")
+
+	if got, err := ReadToken(""); got != "s.from-env" || err != nil {
+		t.Errorf("ReadToken(\"\") = %q, %v, want the VAULT_TOKEN value", got, err)
+	}
+	if got, err := ReadToken(writeFile(t, "s.from-file\n")); got != "s.from-file" || err != nil {
+		t.Errorf("ReadToken(file) = %q, %v, want the trimmed file content", got, err)
+	}
+
+	t.Setenv(EnvToken, "")
+	if _, err := ReadToken(""); err != ErrTokenRequired {
+		t.Errorf("ReadToken(\"\") error = %v, want ErrTokenRequired", err)
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatalf("CreateTemp() error = %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+
+	return f.Name()
+}
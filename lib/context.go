@@ -0,0 +1,44 @@
+package lib
+
+import (
+	"context"
+	"io"
+)
+
+// Canceled - reports a failure of an operation whose ctx is done as the
+// cancellation itself, instead of the I/O or crypto error it surfaced through.
+// err is returned unchanged when it is nil or ctx is still live.
+func Canceled(ctx context.Context, category ErrorCategory, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	return NewError(
+		ErrorTypeInternal,
+		category,
+		ErrCodeCanceled,
+		ErrMessageCanceled,
+		"",
+		SuggestionCanceled,
+		ctx.Err(),
+	)
+}
+
+// ContextReader - returns a reader that fails with ctx.Err() once ctx is done,
+// so a long copy stops at the next read.
+func ContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCanceled(t *testing.T) {
+	cause := errors.New("io copy error")
+
+	if err := Canceled(context.Background(), CategorySeal, cause); err != cause {
+		t.Errorf("Canceled() with a live context = %v, want the error unchanged", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Canceled(ctx, CategorySeal, nil); err != nil {
+		t.Errorf("Canceled() without an error = %v, want nil", err)
+	}
+
+	err := Canceled(ctx, CategorySeal, cause)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Canceled() = %v, want it to wrap context.Canceled", err)
+	}
+	if e, ok := AsError(err); !ok || e.Code != ErrCodeCanceled || e.Category != CategorySeal {
+		t.Errorf("Canceled() = %#v, want code %d in category %d", err, ErrCodeCanceled, CategorySeal)
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, ContextReader(ctx, bytes.NewReader([]byte("data")))); err != nil {
+		t.Fatalf("copy with a live context: %v", err)
+	}
+	if out.String() != "data" {
+		t.Errorf("copied %q, want %q", out.String(), "data")
+	}
+
+	cancel()
+
+	if _, err := ContextReader(ctx, bytes.NewReader([]byte("data"))).Read(make([]byte, 4)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() after cancel = %v, want context.Canceled", err)
+	}
+}
@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/namelesscorp/tvault-core/debug"
 )
 
-var unwrappedErrorList = make(map[error]struct{})
+var (
+	// unwrappedErrorList is shared by every NewError call, including those made
+	// from packer and chunk workers, so it is guarded by unwrappedErrorMu.
+	unwrappedErrorList = make(map[error]struct{})
+	unwrappedErrorMu   sync.Mutex
+)
 
 type ErrorType byte
 
@@ -32,6 +38,7 @@ const (
 	CategoryToken       ErrorCategory = 0x500
 	CategoryShamir      ErrorCategory = 0x600
 	CategoryContainer   ErrorCategory = 0x700
+	CategoryAgent       ErrorCategory = 0x800
 )
 
 type ErrorCode uint16
@@ -167,6 +174,134 @@ const (
 
 	ErrCodeTokenGCMSealError ErrorCode = 0x0010C
 	ErrCodeTokenGCMOpenError ErrorCode = 0x0010D
+
+	ErrCodeHoldersRequireShareToken ErrorCode = 0x0010E
+	ErrCodeHoldersCountMismatch     ErrorCode = 0x0010F
+
+	ErrCodeTokenHolderSealError        ErrorCode = 0x00110
+	ErrCodeTokenHolderOpenError        ErrorCode = 0x00111
+	ErrCodeSealLoadHoldersError        ErrorCode = 0x00112
+	ErrCodeUnsealResolveHolderKeyError ErrorCode = 0x00113
+	ErrCodeResealLoadHoldersError      ErrorCode = 0x00114
+
+	ErrCodeShamirPolicyRequiresShareToken ErrorCode = 0x00115
+	ErrCodeShamirPolicyUnsatisfied        ErrorCode = 0x00116
+	ErrCodeSealLoadPolicyError            ErrorCode = 0x00117
+
+	ErrCodeShamirNoConsistentSubset ErrorCode = 0x00118
+
+	ErrCodeShamirFeldmanSealError          ErrorCode = 0x00119
+	ErrCodeShamirFeldmanInsufficientShares ErrorCode = 0x0011A
+	ErrCodeShamirFeldmanOpenError          ErrorCode = 0x0011B
+	ErrCodeFeldmanRequiresShareToken       ErrorCode = 0x0011C
+	ErrCodeFeldmanPolicyUnsupported        ErrorCode = 0x0011D
+	ErrCodeVerifyContainerPathRequired     ErrorCode = 0x0011E
+	ErrCodeVerifyOpenContainerError        ErrorCode = 0x0011F
+	ErrCodeVerifyGetTokenStringError       ErrorCode = 0x00120
+	ErrCodeVerifyParseTokensError          ErrorCode = 0x00121
+	ErrCodeVerifyUnsupportedContainer      ErrorCode = 0x00122
+	ErrCodeVerifyWriteResultError          ErrorCode = 0x00123
+
+	ErrCodeReshareContainerPathRequired ErrorCode = 0x00124
+	ErrCodeReshareShareTokenRequired    ErrorCode = 0x00125
+	ErrCodeResharePolicyUnsupported     ErrorCode = 0x00126
+	ErrCodeReshareNewHoldersRequired    ErrorCode = 0x00127
+	ErrCodeReshareCheckKeyError         ErrorCode = 0x00128
+
+	ErrCodeInspectContainerPathRequired ErrorCode = 0x00129
+
+	ErrCodeTokenNotBeforeInvalid         ErrorCode = 0x0012A
+	ErrCodeTokenExpiresAtInvalid         ErrorCode = 0x0012B
+	ErrCodeTokenWindowEmpty              ErrorCode = 0x0012C
+	ErrCodeTokenWindowRequiresEncryption ErrorCode = 0x0012D
+	ErrCodeTokenNotYetValid              ErrorCode = 0x0012E
+	ErrCodeTokenExpired                  ErrorCode = 0x0012F
+
+	ErrCodeTokenRevoked         ErrorCode = 0x00130
+	ErrCodeResealRotateKeyError ErrorCode = 0x00131
+
+	ErrCodeRecoveryCodeInvalid         ErrorCode = 0x00132
+	ErrCodeRecoveryCodeChecksum        ErrorCode = 0x00133
+	ErrCodeRecoveryNotAvailable        ErrorCode = 0x00134
+	ErrCodeRecoveryCodeMismatch        ErrorCode = 0x00135
+	ErrCodeSealRecoveryCodeError       ErrorCode = 0x00136
+	ErrCodeSealWriteRecoveryCodeError  ErrorCode = 0x00137
+	ErrCodeRecoveryWriterTypeInvalid   ErrorCode = 0x00138
+	ErrCodeRecoveryWriterFormatInvalid ErrorCode = 0x00139
+	ErrCodeRecoveryWriterPathRequired  ErrorCode = 0x0013A
+
+	ErrCodeEscrowPublicKeyInvalid       ErrorCode = 0x0013B
+	ErrCodeEscrowNotAvailable           ErrorCode = 0x0013C
+	ErrCodeEscrowKeyMismatch            ErrorCode = 0x0013D
+	ErrCodeEscrowPrivateKeyPathRequired ErrorCode = 0x0013E
+	ErrCodeEscrowDirRequired            ErrorCode = 0x0013F
+	ErrCodeEscrowNewPublicKeyRequired   ErrorCode = 0x00140
+	ErrCodeEscrowReadKeyError           ErrorCode = 0x00141
+	ErrCodeEscrowWrapError              ErrorCode = 0x00142
+	ErrCodeEscrowOpenError              ErrorCode = 0x00143
+	ErrCodeEscrowRewrapError            ErrorCode = 0x00144
+
+	ErrCodeAgentTTLInvalid      ErrorCode = 0x00145
+	ErrCodeAgentListenError     ErrorCode = 0x00146
+	ErrCodeAgentRequestError    ErrorCode = 0x00147
+	ErrCodeContainerDirRequired ErrorCode = 0x00148
+	ErrCodeContainerListError   ErrorCode = 0x00149
+
+	ErrCodeKeyProviderNameInvalid  ErrorCode = 0x0014A
+	ErrCodeKeyProviderWrapError    ErrorCode = 0x0014B
+	ErrCodeKeyProviderUnwrapError  ErrorCode = 0x0014C
+	ErrCodeKeyProviderNotAvailable ErrorCode = 0x0014D
+
+	ErrCodeKMSAddressRequired ErrorCode = 0x0014E
+	ErrCodeKMSKeyRequired     ErrorCode = 0x0014F
+	ErrCodeKMSTokenRequired   ErrorCode = 0x00150
+	ErrCodeKMSConfigError     ErrorCode = 0x00151
+	ErrCodeKMSWrapError       ErrorCode = 0x00152
+	ErrCodeKMSUnwrapError     ErrorCode = 0x00153
+	ErrCodeKMSNotAvailable    ErrorCode = 0x00154
+
+	ErrCodeCanceled ErrorCode = 0x00155
+
+	ErrCodeContainerOverwriteInvalid ErrorCode = 0x00156
+	ErrCodeContainerExists           ErrorCode = 0x00157
+	ErrCodeSealWriteContainerError   ErrorCode = 0x00158
+	ErrCodeSealSyncDirError          ErrorCode = 0x00159
+	ErrCodeSealBackupContainerError  ErrorCode = 0x0015A
+
+	ErrCodeResumeJournalNotFound ErrorCode = 0x0015B
+	ErrCodeResumeJournalInvalid  ErrorCode = 0x0015C
+	ErrCodeResumeMismatch        ErrorCode = 0x0015D
+	ErrCodeJournalWriteError     ErrorCode = 0x0015E
+	ErrCodeJournalOpenError      ErrorCode = 0x0015F
+
+	ErrCodeIgnoreRulesError ErrorCode = 0x00160
+
+	ErrCodeContainerSourceRequired ErrorCode = 0x00161
+	ErrCodeContainerSourceInvalid  ErrorCode = 0x00162
+	ErrCodeSourceCollision         ErrorCode = 0x00163
+
+	ErrCodeStdinRequiresRaw    ErrorCode = 0x00164
+	ErrCodeStdinConflict       ErrorCode = 0x00165
+	ErrCodeStdioResume         ErrorCode = 0x00166
+	ErrCodeStdoutInUse         ErrorCode = 0x00167
+	ErrCodeRawPayloadRequired  ErrorCode = 0x00168
+	ErrCodeRawPayloadNotStdout ErrorCode = 0x00169
+
+	ErrCodeContainerPreserveInvalid ErrorCode = 0x0016A
+	ErrCodeReadFileMetadataError    ErrorCode = 0x0016B
+	ErrCodeRestoreFileMetadataError ErrorCode = 0x0016C
+
+	ErrCodeExtractConflict            ErrorCode = 0x0016D
+	ErrCodeContainerOnConflictInvalid ErrorCode = 0x0016E
+	ErrCodeDryRunConflict             ErrorCode = 0x0016F
+
+	ErrCodeExtractLimitExceeded ErrorCode = 0x00170
+	ErrCodeLimitsInvalid        ErrorCode = 0x00171
+
+	ErrCodeSymlinkRejected          ErrorCode = 0x00172
+	ErrCodeContainerSymlinksInvalid ErrorCode = 0x00173
+	ErrCodeResealHoldersAbsent      ErrorCode = 0x00174
+	ErrCodeContainerKeyUnverifiable ErrorCode = 0x00175
 )
 
 const (
@@ -215,6 +350,8 @@ const (
 	ErrMessageTokenDecodeBase64Error  = "failed to decode Base64 token"
 	ErrMessageTokenGCMSealError       = "failed to seal token (AES-GCM)"
 	ErrMessageTokenGCMOpenError       = "token authentication failed (AES-GCM)"
+	ErrMessageTokenHolderSealError    = "failed to seal token for holder"
+	ErrMessageTokenHolderOpenError    = "failed to open holder token"
 
 	ErrMessageShamirInvalidThresholdOrShares = "invalid threshold or number of shares"
 	ErrMessageShamirIOReadFullError          = "io read full error"
@@ -223,6 +360,60 @@ const (
 	ErrMessageShamirVerifySignatureFailed    = "verify share signature failed"
 	ErrMessageShamirDuplicateShareID         = "duplicate or invalid share id"
 	ErrMessageShamirShareLengthMismatch      = "share value lengths do not match"
+	ErrMessageShamirPolicyUnsatisfied        = "shares do not satisfy the sharing policy"
+	ErrMessageShamirNoConsistentSubset       = "no consistent subset of shares recovers the key"
+	ErrMessageShamirFeldmanSealError         = "seal secret under feldman scalar error"
+	ErrMessageShamirFeldmanInsufficient      = "not enough valid feldman shares"
+	ErrMessageShamirFeldmanOpenError         = "open secret sealed under feldman scalar error"
+
+	ErrMessageVerifyOpenContainerError   = "open container error"
+	ErrMessageVerifyGetTokenStringError  = "get token string error" // #nosec G101
+	ErrMessageVerifyParseTokensError     = "parse tokens error"     // #nosec G101
+	ErrMessageVerifyUnsupportedContainer = "container has no data to verify shares against"
+	ErrMessageVerifyWriteResultError     = "write token result error"
+
+	ErrMessageReshareCheckKeyError = "recovered key does not open the container"
+
+	ErrMessageTokenNotYetValid = "token is not valid yet"
+	ErrMessageTokenExpired     = "token has expired"
+
+	ErrMessageTokenRevoked         = "revoked token (epoch %d)"
+	ErrMessageResealRotateKeyError = "rotate master key error"
+
+	ErrMessageRecoveryCodeMismatch       = "recovery code does not open the container"
+	ErrMessageSealRecoveryCodeError      = "create recovery code error"
+	ErrMessageSealWriteRecoveryCodeError = "write recovery code error"
+
+	ErrMessageEscrowReadKeyError = "read escrow private key error"
+	ErrMessageEscrowWrapError    = "wrap master key to escrow key error"
+	ErrMessageEscrowOpenError    = "escrow key does not open the container"
+	ErrMessageEscrowRewrapError  = "rewrap escrow error"
+
+	ErrMessageAgentListenError   = "start agent error"
+	ErrMessageAgentRequestError  = "agent request error"
+	ErrMessageContainerListError = "list containers error"
+
+	ErrMessageKeyProviderWrapError   = "key provider wrap error"
+	ErrMessageKeyProviderUnwrapError = "key provider unwrap error"
+
+	ErrMessageKMSConfigError = "kms client error"
+	ErrMessageKMSWrapError   = "kms wrap error"
+	ErrMessageKMSUnwrapError = "kms unwrap error"
+
+	ErrMessageCanceled = "operation canceled"
+
+	ErrMessageSealWriteContainerError  = "write container error"
+	ErrMessageSealSyncDirError         = "sync directory error"
+	ErrMessageSealBackupContainerError = "back up existing container error"
+
+	ErrMessageJournalWriteError = "write journal error"
+	ErrMessageJournalOpenError  = "journal does not open with the container key"
+
+	ErrMessageIgnoreRulesError = "read ignore rules error"
+	ErrMessageSourceCollision  = "two sources map to the same archive path"
+
+	ErrMessageReadFileMetadataError    = "read file metadata error"
+	ErrMessageRestoreFileMetadataError = "restore file metadata error"
 
 	ErrMessageUnsealOpenContainerError        = "open container error"
 	ErrMessageUnsealGetTokenStringError       = "get token string error"
@@ -239,6 +430,7 @@ const (
 	ErrMessageUnsealDecodeShareValueError     = "decode share value error"
 	ErrMessageUnsealDecodeShareSignatureError = "decode share signature error"
 	ErrMessageUnsealCompressionUnpackError    = "compression unpack error"
+	ErrMessageUnsealResolveHolderKeyError     = "resolve holder key error"
 
 	ErrMessageSealCompressFolderError                    = "compress folder error"
 	ErrMessageSealCreateContainerError                   = "create container error"
@@ -253,6 +445,8 @@ const (
 	ErrMessageSealBuildShareTokenError                   = "build token (share) error"  // #nosec G101
 	ErrMessageSealWriteTokenMasterError                  = "write token (master) error" // #nosec G101
 	ErrMessageSealBuildMasterTokenError                  = "build token (master) error" // #nosec G101
+	ErrMessageSealLoadHoldersError                       = "load holders error"
+	ErrMessageSealLoadPolicyError                        = "load shamir policy error"
 
 	ErrMessageResealOpenContainerError            = "open container error"
 	ErrMessageResealGetTokenStringError           = "get token string error"
@@ -265,6 +459,7 @@ const (
 	ErrMessageResealDeriveAdditionalPasswordError = "derive additional password error"
 	ErrMessageResealWriteTokensError              = "write tokens error" // #nosec G101
 	ErrMessageResealSyncDirError                  = "sync directory error"
+	ErrMessageResealLoadHoldersError              = "load holders error"
 
 	ErrMessageContainerSyncFileError = "sync container file error"
 )
@@ -275,19 +470,19 @@ const (
 	SuggestionContainerFolderPath  = "specify the container folder path using the -folder-path flag"
 	SuggestionContainerPassphrase  = "specify the container passphrase using the -passphrase flag"
 
-	SuggestionTokenType = "specify a valid token type, available options: [none | share | master]"
+	SuggestionTokenType = "specify a valid token type, available options: [none | share | master | plugin | kms]"
 
-	SuggestionIntegrityProviderNotNone       = "for token type none, you must not specify an integrity provider"
+	SuggestionIntegrityProviderNotNone       = "for token type none, plugin or kms, you must not specify an integrity provider"
 	SuggestionIntegrityProviderType          = "specify a valid integrity provider type, available options: [none | hmac]"
 	SuggestionIntegrityProviderNewPassphrase = "for integrity provider type hmac, you must specify a new passphrase using the -new-passphrase flag"
 
 	SuggestionCompressionType = "specify a valid compression type, the only available option is: [zip]"
 
-	SuggestionTokenWriterType   = "specify a valid token writer type, available options: [file | stdout]"
+	SuggestionTokenWriterType   = "specify a valid token writer type, available options: [file | stdout | stderr]"
 	SuggestionTokenWriterFormat = "specify a valid token writer format, available options: [plaintext | json]"
 	SuggestionTokenWriterPath   = "for token writer type file, you must specify a path using the -path flag"
 
-	SuggestionLogWriterType   = "specify a valid log writer type, available options: [file | stdout]"
+	SuggestionLogWriterType   = "specify a valid log writer type, available options: [file | stdout | stderr]"
 	SuggestionLogWriterFormat = "specify a valid log writer format, available options: [plaintext | json]"
 	SuggestionLogWriterPath   = "for log writer type file, you must specify a path using the -path flag"
 
@@ -305,11 +500,101 @@ const (
 	SuggestionShamirSharesGreaterThan255    = "number of shares must not exceed 255, specify a value <= 255"
 	SuggestionShamirThresholdGreaterThan255 = "threshold must not exceed 255, specify a value <= 255"
 
-	SuggestionInfoWriterType   = "specify a valid info writer type, available options: [file | stdout]"
+	SuggestionInfoWriterType   = "specify a valid info writer type, available options: [file | stdout | stderr]"
 	SuggestionInfoWriterFormat = "specify a valid info writer format, available options: [plaintext | json]"
 	SuggestionInfoWriterPath   = "for info writer type file, you must specify a path using the -path flag"
 
 	SuggestionInfoPathRequired = "for container info, you must specify a path using the -path flag"
+
+	SuggestionHoldersRequireShareToken = "holders encrypt individual shares, use token -type=[share] with shamir -is-enabled=true"
+	SuggestionHoldersCountMismatch     = "list exactly one holder per share in the holders file, or change shamir -shares"
+
+	SuggestionShamirPolicyRequiresShareToken = "a sharing policy splits the key into shares, use token -type=[share] with shamir -is-enabled=true"
+
+	SuggestionFeldmanRequiresShareToken   = "feldman verifies shamir shares, use token -type=[share] with shamir -is-enabled=true"
+	SuggestionFeldmanPolicyUnsupported    = "use integrity-provider -type=[hmac] with a sharing policy, or drop shamir -policy-path"
+	SuggestionVerifyContainerPathRequired = "for token verify, you must specify the container using the -container flag"
+
+	SuggestionReshareContainerPathRequired = "for token reshare, you must specify the container using the -container flag"
+	SuggestionReshareShareTokenRequired    = "only containers sealed with token -type=[share] have shares to reshare; use reseal otherwise"
+	SuggestionResharePolicyUnsupported     = "the sharing policy defines the quorum; use reseal to change it"
+	SuggestionReshareNewHoldersRequired    = "shares of this container are sealed per holder; list one holder per new share in the file given with holders -new-path"
+
+	SuggestionInspectContainerPathRequired = "encrypted tokens are opened with keys derived from the container salt; specify the container using inspect -container"
+
+	SuggestionTokenNotBeforeInvalid         = "give token -token-not-before as an RFC 3339 time, e.g. 2026-01-31T09:00:00Z"
+	SuggestionTokenExpiresAtInvalid         = "give token -token-expires-at as an RFC 3339 time, e.g. 2026-12-31T18:00:00Z"
+	SuggestionTokenWindowEmpty              = "token -token-not-before must be earlier than token -token-expires-at"
+	SuggestionTokenWindowRequiresEncryption = "the validity window is only authenticated inside an encrypted token; use integrity-provider -type=[hmac] with a passphrase or holders -path"
+	SuggestionTokenNotYetValid              = "wait until the token's validity window opens, or ask the container owner to reseal with a new window"
+	SuggestionTokenExpired                  = "the token's validity window has ended; ask the container owner for a newly issued token"
+	SuggestionTokenRevoked                  = "the container's master key was rotated after this token was issued; use the tokens written by the latest reseal -rotate-key"
+
+	SuggestionRecoveryCodeInvalid  = "enter the recovery code shown by seal, e.g. ABCD-EFGH-IJKL-MNOP-QRST-UVWX-YZ23-4567; dashes and case are ignored"
+	SuggestionRecoveryCodeChecksum = "the recovery code has a typo; check each group against the code shown by seal"
+	SuggestionRecoveryNotAvailable = "the container was sealed without container -recovery-code; open it with its tokens or passphrase"
+	SuggestionRecoveryCodeMismatch = "the recovery code belongs to another container, or the container key was rotated after it was issued"
+	SuggestionRecoveryWriterType   = "specify a valid recovery writer type, available options: [file | stdout | stderr]"
+	SuggestionRecoveryWriterFormat = "specify a valid recovery writer format, available options: [plaintext | json]"
+	SuggestionRecoveryWriterPath   = "for recovery writer type file, you must specify a path using the -path flag"
+
+	SuggestionEscrowPublicKeyInvalid       = "give the escrow X25519 public key as 64 hex characters, with container -escrow-public-key or " + EnvEscrowPublicKey
+	SuggestionEscrowNotAvailable           = "the container was sealed without an escrow key; open it with its tokens or passphrase"
+	SuggestionEscrowKeyMismatch            = "the private key does not belong to the escrow public key recorded in the container; check container info"
+	SuggestionEscrowPrivateKeyPathRequired = "specify the file holding the hex escrow private key using the -private-key-path flag"
+	SuggestionEscrowDirRequired            = "specify the directory of containers to rewrap using the -dir flag"
+	SuggestionEscrowNewPublicKeyRequired   = "specify the new escrow X25519 public key using the -new-public-key flag"
+	SuggestionEscrowOpenError              = "the escrow wrap of the container is damaged; open it with its tokens or passphrase"
+
+	SuggestionAgentTTLInvalid      = "give agent start -ttl as a positive duration, e.g. 30m or 8h"
+	SuggestionAgentListen          = "stop the agent already listening on the socket, or give another path with -socket"
+	SuggestionAgentRequest         = "start the agent with tvault-core agent start, and check TVAULT_AGENT_SOCK"
+	SuggestionContainerDirRequired = "specify the directory of containers using the -dir flag"
+
+	SuggestionKeyProviderNameInvalid  = "for token -type=plugin, name the key provider with token -plugin, using letters, digits, '-' and '_'"
+	SuggestionKeyProvider             = "check that tvault-keyprovider-<name> is on PATH and can reach its key service"
+	SuggestionKeyProviderNotAvailable = "the container has no key provider wrap; open it with its recovery code or escrow key"
+
+	SuggestionKMSAddressRequired = "specify the transit server address using kms -address, or set VAULT_ADDR"
+	SuggestionKMSKeyRequired     = "for token -type=kms, name the transit key using the kms -key flag"
+	SuggestionKMSTokenRequired   = "set VAULT_TOKEN, or give a file holding the token using kms -token-path"
+	SuggestionKMSConfig          = "check the kms -address, -ca-cert, -client-cert and -client-key values"
+	SuggestionKMS                = "check that the transit server is reachable and the token may use the key"
+	SuggestionKMSNotAvailable    = "the container has no kms wrap; open it with its recovery code or escrow key"
+
+	SuggestionCanceled = "the operation was interrupted; continue a seal or unseal with container -resume, or run the command again"
+
+	SuggestionContainerOverwriteInvalid = "specify a valid overwrite policy, available options: [never | always | backup]"
+	SuggestionContainerExists           = "choose another container -new-path, or use container -overwrite=[always] to replace the container or -overwrite=[backup] to keep it as <path>.bak"
+
+	SuggestionResumeJournalNotFound = "nothing was interrupted at this path; run the command without container -resume"
+	SuggestionResumeStartOver       = "run the command without container -resume to start over"
+	SuggestionJournalOpen           = "give the container -passphrase of the interrupted seal, or run it without container -resume to start over"
+
+	SuggestionContainerSourceRequired = "specify the folder to seal using the -folder-path flag, or files and folders using repeated -source flags"
+	SuggestionContainerSourceInvalid  = "give each -source as <path> or <path>:<prefix>, where the prefix is a relative archive path without '.' or '..' segments"
+	SuggestionSourceCollision         = "map the sources to distinct archive paths with -source <path>:<prefix>"
+
+	SuggestionStdinRequiresRaw    = "seal stdin with compression -type=raw, and files or folders with -type=[zip | none]"
+	SuggestionStdinConflict       = "give either container -stdin or the -folder-path and -source flags"
+	SuggestionStdioResume         = "a stream cannot be resumed; write the container or payload to a file to use container -resume"
+	SuggestionStdoutInUse         = "stdout carries the stream; send this output to -type=stderr or -type=file"
+	SuggestionRawPayloadRequired  = "this container holds an archive; extract it with container -folder-path"
+	SuggestionRawPayloadNotStdout = "this container holds a raw stream; write it to stdout with container -stdout"
+
+	SuggestionContainerPreserve = "specify a valid preserve mode, available options: [none | all]"
+
+	SuggestionExtractConflict            = "choose container -on-conflict=[skip | overwrite | rename | newer], or unseal to an empty folder; -dry-run lists the conflicts"
+	SuggestionContainerOnConflictInvalid = "specify a valid conflict policy, available options: [fail | skip | overwrite | rename | newer]"
+	SuggestionDryRunConflict             = "run container -dry-run on its own, then unseal with -resume or -stdout"
+
+	SuggestionExtractLimitExceeded = "raise the limit named in the details with limits, or set it to 0, only if the container comes from a trusted sender"
+	SuggestionLimitsInvalid        = "give a positive limit, or 0 to disable it"
+
+	SuggestionSymlinkRejected          = "check the links named in the details; container -symlinks=skip leaves them out, and -symlinks=allow extracts them as they are, only for a trusted container"
+	SuggestionContainerSymlinksInvalid = "specify a valid symlink policy, available options: [reject | skip | contain | allow]"
+	SuggestionContainerKeyUnverifiable = "the container holds no data and was sealed without a key check value; seal its content again to get one"
+	SuggestionResealHoldersAbsent      = "give the tokens of the holders named in the details, or name them in holders -path; a new validity window alone keeps the split and needs only the shares given"
 )
 
 // Validation errors
@@ -319,23 +604,23 @@ var (
 	ErrContainerFolderPathRequired  = errors.New("container -folder-path is required")
 	ErrContainerPassphraseRequired  = errors.New("container -passphrase is required")
 
-	ErrTokenTypeInvalid = errors.New("token -type must be [none | share | master]")
+	ErrTokenTypeInvalid = errors.New("token -type must be [none | share | master | plugin | kms]")
 
 	ErrTokenCiphertextTooShort  = errors.New("token ciphertext is shorter than the envelope header")
 	ErrTokenIDOutOfRange        = errors.New("token id is out of byte range [0, 255]")
 	ErrTokenUnsupportedEncoding = errors.New("unsupported token encryption format")
 
-	ErrIntegrityProviderTypeNotNone           = errors.New("integrity-provider -type must be [none] for token -type=[none]")
+	ErrIntegrityProviderTypeNotNone           = errors.New("integrity-provider -type must be [none] for token -type=[none | plugin | kms]")
 	ErrIntegrityProviderTypeInvalid           = errors.New("integrity-provider -type must be [none | hmac ]")
 	ErrIntegrityProviderNewPassphraseRequired = errors.New("integrity-provider -new-passphrase is required for integrity-provider -type=[hmac]")
 
 	ErrCompressionTypeInvalid = errors.New("compression -type must be [zip]")
 
-	ErrTokenWriterTypeInvalid   = errors.New("token-writer -type must be [file | stdout]")
+	ErrTokenWriterTypeInvalid   = errors.New("token-writer -type must be [file | stdout | stderr]")
 	ErrTokenWriterFormatInvalid = errors.New("token-writer -format must be [plaintext | json]")
 	ErrTokenWriterPathRequired  = errors.New("token-writer -path is required for token-writer -type=[file]")
 
-	ErrLogWriterTypeInvalid   = errors.New("log-writer -type must be [file | stdout]")
+	ErrLogWriterTypeInvalid   = errors.New("log-writer -type must be [file | stdout | stderr]")
 	ErrLogWriterFormatInvalid = errors.New("log-writer -format must be [plaintext | json]")
 	ErrLogWriterPathRequired  = errors.New("log-writer -path is required for log-writer -type=[file]")
 
@@ -353,11 +638,89 @@ var (
 	ErrShamirSharesGreaterThan255    = errors.New("shamir -shares must be less than 255")
 	ErrShamirThresholdGreaterThan255 = errors.New("shamir -threshold must be less than 255")
 
-	ErrInfoWriterTypeInvalid   = errors.New("info-writer -type must be [file | stdout]")
+	ErrInfoWriterTypeInvalid   = errors.New("info-writer -type must be [file | stdout | stderr]")
 	ErrInfoWriterFormatInvalid = errors.New("info-writer -format must be [plaintext | json]")
 	ErrInfoWriterPathRequired  = errors.New("info-writer -path is required for info-writer -type=[file]")
 
 	ErrInfoPathRequired = errors.New("info -path is required for command container")
+
+	ErrHoldersRequireShareToken = errors.New("holders -path requires token -type=[share]")
+	ErrHoldersCountMismatch     = errors.New("holders file must list exactly one holder per share")
+
+	ErrShamirPolicyRequiresShareToken = errors.New("shamir -policy-path requires token -type=[share]")
+
+	ErrFeldmanRequiresShareToken   = errors.New("integrity-provider -type=[feldman] requires token -type=[share]")
+	ErrFeldmanPolicyUnsupported    = errors.New("integrity-provider -type=[feldman] does not support shamir -policy-path")
+	ErrVerifyContainerPathRequired = errors.New("verify -container is required for command token")
+
+	ErrReshareContainerPathRequired = errors.New("reshare -container is required for command token")
+	ErrReshareShareTokenRequired    = errors.New("token reshare requires a container sealed with token -type=[share]")
+	ErrResharePolicyUnsupported     = errors.New("token reshare does not support containers sealed with a sharing policy")
+	ErrReshareNewHoldersRequired    = errors.New("holders -new-path is required for containers with per-holder shares")
+
+	ErrInspectContainerPathRequired = errors.New("inspect -container is required to open tokens with a passphrase or holders file")
+
+	ErrTokenNotBeforeInvalid         = errors.New("token -token-not-before must be an RFC 3339 time")
+	ErrTokenExpiresAtInvalid         = errors.New("token -token-expires-at must be an RFC 3339 time")
+	ErrTokenWindowEmpty              = errors.New("token -token-not-before must be before -token-expires-at")
+	ErrTokenWindowRequiresEncryption = errors.New("a token validity window requires encrypted tokens")
+
+	ErrRecoveryCodeInvalid         = errors.New("recovery code is not valid")
+	ErrRecoveryCodeChecksum        = errors.New("recovery code checksum does not match")
+	ErrRecoveryNotAvailable        = errors.New("container has no recovery code")
+	ErrRecoveryWriterTypeInvalid   = errors.New("recovery-writer -type must be [file | stdout | stderr]")
+	ErrRecoveryWriterFormatInvalid = errors.New("recovery-writer -format must be [plaintext | json]")
+	ErrRecoveryWriterPathRequired  = errors.New("recovery-writer -path is required for recovery-writer -type=[file]")
+
+	ErrEscrowPublicKeyInvalid       = errors.New("escrow public key must be a hex X25519 public key")
+	ErrEscrowNotAvailable           = errors.New("container has no escrow key")
+	ErrEscrowKeyMismatch            = errors.New("escrow private key does not match the container's escrow public key")
+	ErrEscrowPrivateKeyPathRequired = errors.New("escrow -private-key-path is required")
+	ErrEscrowDirRequired            = errors.New("rewrap -dir is required")
+	ErrEscrowNewPublicKeyRequired   = errors.New("rewrap -new-public-key is required")
+
+	ErrAgentTTLInvalid      = errors.New("agent -ttl must be greater than 0")
+	ErrContainerDirRequired = errors.New("ls -dir is required")
+
+	ErrKeyProviderNameInvalid  = errors.New("token -plugin must name a key provider of letters, digits, '-' and '_'")
+	ErrKeyProviderNotAvailable = errors.New("container has no key provider wrap")
+
+	ErrKMSAddressRequired = errors.New("kms -address is required")
+	ErrKMSKeyRequired     = errors.New("kms -key is required")
+	ErrKMSTokenRequired   = errors.New("kms token is required")
+	ErrKMSNotAvailable    = errors.New("container has no kms wrap")
+
+	ErrContainerOverwriteInvalid = errors.New("container -overwrite must be [never | always | backup]")
+	ErrContainerExists           = errors.New("container -new-path already exists")
+
+	ErrResumeJournalNotFound = errors.New("container -resume found no journal of an interrupted run")
+	ErrResumeJournalInvalid  = errors.New("resume journal does not match the partial output")
+	ErrResumeMismatch        = errors.New("source or options changed since the interrupted run")
+
+	ErrContainerSourceRequired = errors.New("container -folder-path or -source is required")
+	ErrContainerSourceInvalid  = errors.New("container -source must be <path>[:<prefix>] with a relative prefix")
+	ErrSourceCollision         = errors.New("two sources map to the same archive path")
+
+	ErrStdinRequiresRaw    = errors.New("container -stdin and compression -type=raw must be given together")
+	ErrStdinConflict       = errors.New("container -stdin cannot be combined with -folder-path or -source")
+	ErrStdioResume         = errors.New("container -resume cannot be combined with stdin or stdout")
+	ErrStdoutInUse         = errors.New("writer -type=stdout cannot be used while stdout carries the stream")
+	ErrRawPayloadRequired  = errors.New("container -stdout needs a container sealed from stdin")
+	ErrRawPayloadNotStdout = errors.New("container sealed from stdin needs container -stdout")
+
+	ErrContainerPreserveInvalid = errors.New("container -preserve must be [none | all]")
+
+	ErrExtractConflict            = errors.New("files in the folder would be overwritten")
+	ErrContainerOnConflictInvalid = errors.New("container -on-conflict must be [fail | skip | overwrite | rename | newer]")
+	ErrDryRunConflict             = errors.New("container -dry-run cannot be combined with -resume or -stdout")
+
+	ErrExtractLimitExceeded = errors.New("the container exceeds an extraction limit")
+	ErrLimitsInvalid        = errors.New("limits -max-files, -max-bytes, -max-ratio and -max-depth must not be negative")
+
+	ErrSymlinkRejected          = errors.New("the container holds symlinks refused by container -symlinks")
+	ErrContainerSymlinksInvalid = errors.New("container -symlinks must be [reject | skip | contain | allow]")
+	ErrContainerKeyUnverifiable = errors.New("the key cannot be verified: the container has an empty payload and no key check value")
+	ErrResealHoldersAbsent      = errors.New("new shares need every passphrase holder, by their token or in holders -path")
 )
 
 var errorToSuggestion = map[error]string{
@@ -401,6 +764,84 @@ var errorToSuggestion = map[error]string{
 	ErrInfoWriterPathRequired:  SuggestionInfoWriterPath,
 
 	ErrInfoPathRequired: SuggestionInfoPathRequired,
+
+	ErrHoldersRequireShareToken: SuggestionHoldersRequireShareToken,
+	ErrHoldersCountMismatch:     SuggestionHoldersCountMismatch,
+
+	ErrShamirPolicyRequiresShareToken: SuggestionShamirPolicyRequiresShareToken,
+
+	ErrFeldmanRequiresShareToken:   SuggestionFeldmanRequiresShareToken,
+	ErrFeldmanPolicyUnsupported:    SuggestionFeldmanPolicyUnsupported,
+	ErrVerifyContainerPathRequired: SuggestionVerifyContainerPathRequired,
+
+	ErrReshareContainerPathRequired: SuggestionReshareContainerPathRequired,
+	ErrReshareShareTokenRequired:    SuggestionReshareShareTokenRequired,
+	ErrResharePolicyUnsupported:     SuggestionResharePolicyUnsupported,
+	ErrReshareNewHoldersRequired:    SuggestionReshareNewHoldersRequired,
+
+	ErrInspectContainerPathRequired: SuggestionInspectContainerPathRequired,
+
+	ErrTokenNotBeforeInvalid:         SuggestionTokenNotBeforeInvalid,
+	ErrTokenExpiresAtInvalid:         SuggestionTokenExpiresAtInvalid,
+	ErrTokenWindowEmpty:              SuggestionTokenWindowEmpty,
+	ErrTokenWindowRequiresEncryption: SuggestionTokenWindowRequiresEncryption,
+
+	ErrRecoveryCodeInvalid:         SuggestionRecoveryCodeInvalid,
+	ErrRecoveryCodeChecksum:        SuggestionRecoveryCodeChecksum,
+	ErrRecoveryNotAvailable:        SuggestionRecoveryNotAvailable,
+	ErrRecoveryWriterTypeInvalid:   SuggestionRecoveryWriterType,
+	ErrRecoveryWriterFormatInvalid: SuggestionRecoveryWriterFormat,
+	ErrRecoveryWriterPathRequired:  SuggestionRecoveryWriterPath,
+
+	ErrEscrowPublicKeyInvalid:       SuggestionEscrowPublicKeyInvalid,
+	ErrEscrowNotAvailable:           SuggestionEscrowNotAvailable,
+	ErrEscrowKeyMismatch:            SuggestionEscrowKeyMismatch,
+	ErrEscrowPrivateKeyPathRequired: SuggestionEscrowPrivateKeyPathRequired,
+	ErrEscrowDirRequired:            SuggestionEscrowDirRequired,
+	ErrEscrowNewPublicKeyRequired:   SuggestionEscrowNewPublicKeyRequired,
+
+	ErrAgentTTLInvalid:      SuggestionAgentTTLInvalid,
+	ErrContainerDirRequired: SuggestionContainerDirRequired,
+
+	ErrKeyProviderNameInvalid:  SuggestionKeyProviderNameInvalid,
+	ErrKeyProviderNotAvailable: SuggestionKeyProviderNotAvailable,
+
+	ErrKMSAddressRequired: SuggestionKMSAddressRequired,
+	ErrKMSKeyRequired:     SuggestionKMSKeyRequired,
+	ErrKMSTokenRequired:   SuggestionKMSTokenRequired,
+	ErrKMSNotAvailable:    SuggestionKMSNotAvailable,
+
+	ErrContainerOverwriteInvalid: SuggestionContainerOverwriteInvalid,
+	ErrContainerExists:           SuggestionContainerExists,
+
+	ErrResumeJournalNotFound: SuggestionResumeJournalNotFound,
+	ErrResumeJournalInvalid:  SuggestionResumeStartOver,
+	ErrResumeMismatch:        SuggestionResumeStartOver,
+
+	ErrContainerSourceRequired: SuggestionContainerSourceRequired,
+	ErrContainerSourceInvalid:  SuggestionContainerSourceInvalid,
+	ErrSourceCollision:         SuggestionSourceCollision,
+
+	ErrStdinRequiresRaw:    SuggestionStdinRequiresRaw,
+	ErrStdinConflict:       SuggestionStdinConflict,
+	ErrStdioResume:         SuggestionStdioResume,
+	ErrStdoutInUse:         SuggestionStdoutInUse,
+	ErrRawPayloadRequired:  SuggestionRawPayloadRequired,
+	ErrRawPayloadNotStdout: SuggestionRawPayloadNotStdout,
+
+	ErrContainerPreserveInvalid: SuggestionContainerPreserve,
+
+	ErrExtractConflict:            SuggestionExtractConflict,
+	ErrContainerOnConflictInvalid: SuggestionContainerOnConflictInvalid,
+	ErrDryRunConflict:             SuggestionDryRunConflict,
+
+	ErrExtractLimitExceeded: SuggestionExtractLimitExceeded,
+	ErrLimitsInvalid:        SuggestionLimitsInvalid,
+
+	ErrSymlinkRejected:          SuggestionSymlinkRejected,
+	ErrContainerSymlinksInvalid: SuggestionContainerSymlinksInvalid,
+	ErrResealHoldersAbsent:      SuggestionResealHoldersAbsent,
+	ErrContainerKeyUnverifiable: SuggestionContainerKeyUnverifiable,
 }
 
 var errorToCode = map[error]ErrorCode{
@@ -444,6 +885,84 @@ var errorToCode = map[error]ErrorCode{
 	ErrInfoWriterPathRequired:  ErrCodeInfoWriterPathRequired,
 
 	ErrInfoPathRequired: ErrCodeInfoPathRequired,
+
+	ErrHoldersRequireShareToken: ErrCodeHoldersRequireShareToken,
+	ErrHoldersCountMismatch:     ErrCodeHoldersCountMismatch,
+
+	ErrShamirPolicyRequiresShareToken: ErrCodeShamirPolicyRequiresShareToken,
+
+	ErrFeldmanRequiresShareToken:   ErrCodeFeldmanRequiresShareToken,
+	ErrFeldmanPolicyUnsupported:    ErrCodeFeldmanPolicyUnsupported,
+	ErrVerifyContainerPathRequired: ErrCodeVerifyContainerPathRequired,
+
+	ErrReshareContainerPathRequired: ErrCodeReshareContainerPathRequired,
+	ErrReshareShareTokenRequired:    ErrCodeReshareShareTokenRequired,
+	ErrResharePolicyUnsupported:     ErrCodeResharePolicyUnsupported,
+	ErrReshareNewHoldersRequired:    ErrCodeReshareNewHoldersRequired,
+
+	ErrInspectContainerPathRequired: ErrCodeInspectContainerPathRequired,
+
+	ErrTokenNotBeforeInvalid:         ErrCodeTokenNotBeforeInvalid,
+	ErrTokenExpiresAtInvalid:         ErrCodeTokenExpiresAtInvalid,
+	ErrTokenWindowEmpty:              ErrCodeTokenWindowEmpty,
+	ErrTokenWindowRequiresEncryption: ErrCodeTokenWindowRequiresEncryption,
+
+	ErrRecoveryCodeInvalid:         ErrCodeRecoveryCodeInvalid,
+	ErrRecoveryCodeChecksum:        ErrCodeRecoveryCodeChecksum,
+	ErrRecoveryNotAvailable:        ErrCodeRecoveryNotAvailable,
+	ErrRecoveryWriterTypeInvalid:   ErrCodeRecoveryWriterTypeInvalid,
+	ErrRecoveryWriterFormatInvalid: ErrCodeRecoveryWriterFormatInvalid,
+	ErrRecoveryWriterPathRequired:  ErrCodeRecoveryWriterPathRequired,
+
+	ErrEscrowPublicKeyInvalid:       ErrCodeEscrowPublicKeyInvalid,
+	ErrEscrowNotAvailable:           ErrCodeEscrowNotAvailable,
+	ErrEscrowKeyMismatch:            ErrCodeEscrowKeyMismatch,
+	ErrEscrowPrivateKeyPathRequired: ErrCodeEscrowPrivateKeyPathRequired,
+	ErrEscrowDirRequired:            ErrCodeEscrowDirRequired,
+	ErrEscrowNewPublicKeyRequired:   ErrCodeEscrowNewPublicKeyRequired,
+
+	ErrAgentTTLInvalid:      ErrCodeAgentTTLInvalid,
+	ErrContainerDirRequired: ErrCodeContainerDirRequired,
+
+	ErrKeyProviderNameInvalid:  ErrCodeKeyProviderNameInvalid,
+	ErrKeyProviderNotAvailable: ErrCodeKeyProviderNotAvailable,
+
+	ErrKMSAddressRequired: ErrCodeKMSAddressRequired,
+	ErrKMSKeyRequired:     ErrCodeKMSKeyRequired,
+	ErrKMSTokenRequired:   ErrCodeKMSTokenRequired,
+	ErrKMSNotAvailable:    ErrCodeKMSNotAvailable,
+
+	ErrContainerOverwriteInvalid: ErrCodeContainerOverwriteInvalid,
+	ErrContainerExists:           ErrCodeContainerExists,
+
+	ErrResumeJournalNotFound: ErrCodeResumeJournalNotFound,
+	ErrResumeJournalInvalid:  ErrCodeResumeJournalInvalid,
+	ErrResumeMismatch:        ErrCodeResumeMismatch,
+
+	ErrContainerSourceRequired: ErrCodeContainerSourceRequired,
+	ErrContainerSourceInvalid:  ErrCodeContainerSourceInvalid,
+	ErrSourceCollision:         ErrCodeSourceCollision,
+
+	ErrStdinRequiresRaw:    ErrCodeStdinRequiresRaw,
+	ErrStdinConflict:       ErrCodeStdinConflict,
+	ErrStdioResume:         ErrCodeStdioResume,
+	ErrStdoutInUse:         ErrCodeStdoutInUse,
+	ErrRawPayloadRequired:  ErrCodeRawPayloadRequired,
+	ErrRawPayloadNotStdout: ErrCodeRawPayloadNotStdout,
+
+	ErrContainerPreserveInvalid: ErrCodeContainerPreserveInvalid,
+
+	ErrExtractConflict:            ErrCodeExtractConflict,
+	ErrContainerOnConflictInvalid: ErrCodeContainerOnConflictInvalid,
+	ErrDryRunConflict:             ErrCodeDryRunConflict,
+
+	ErrExtractLimitExceeded: ErrCodeExtractLimitExceeded,
+	ErrLimitsInvalid:        ErrCodeLimitsInvalid,
+
+	ErrSymlinkRejected:          ErrCodeSymlinkRejected,
+	ErrContainerSymlinksInvalid: ErrCodeContainerSymlinksInvalid,
+	ErrResealHoldersAbsent:      ErrCodeResealHoldersAbsent,
+	ErrContainerKeyUnverifiable: ErrCodeContainerKeyUnverifiable,
 }
 
 // Internal errors
@@ -463,6 +982,51 @@ var (
 	ErrInvalidTokenVersion       = errors.New("invalid token version")
 	ErrInvalidContainerVersion   = errors.New("invalid container version")
 	ErrInvalidContainerSignature = errors.New("invalid container signature")
+
+	ErrX25519InvalidKey      = errors.New("invalid X25519 key")
+	ErrX25519SealedTooShort  = errors.New("X25519 sealed data is shorter than the envelope header")
+	ErrX25519OpenFailed      = errors.New("X25519 sealed data authentication failed")
+	ErrX25519GenerateFailure = errors.New("failed to generate X25519 key")
+
+	ErrHolderNotFound         = errors.New("no holder is recorded for share")
+	ErrHolderSecretMissing    = errors.New("holder secret is missing")
+	ErrHolderIDMismatch       = errors.New("holder envelope id does not match token id")
+	ErrUnknownHolderKind      = errors.New("unknown holder kind")
+	ErrHolderNameRequired     = errors.New("holder name is required")
+	ErrHolderNameDuplicated   = errors.New("holder name is duplicated")
+	ErrHolderEnvelopeRequired = errors.New("token is not a holder envelope")
+
+	ErrShamirPolicyNoGroups         = errors.New("shamir policy has no groups")
+	ErrShamirPolicyThresholdInvalid = errors.New("shamir policy threshold must be between 1 and the number of shares below it")
+	ErrShamirPolicyNameRequired     = errors.New("shamir policy group and member names are required")
+	ErrShamirPolicyNameDuplicated   = errors.New("shamir policy group or member name is duplicated")
+	ErrShamirPolicyWeightInvalid    = errors.New("shamir policy weights must be positive and groups must have members")
+	ErrShamirPolicyUnsatisfied      = errors.New("shares do not satisfy the sharing policy")
+	ErrShamirNoConsistentSubset     = errors.New("no consistent subset of shares recovers the key")
+
+	ErrShamirFeldmanInsufficientShares = errors.New("not enough valid feldman shares")
+	ErrShamirFeldmanSealedTooShort     = errors.New("feldman sealed secret is too short")
+	ErrVerifyUnsupportedContainer      = errors.New("container has no feldman commitments, hmac signatures or key check value")
+
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenRevoked     = errors.New("revoked token")
+
+	ErrRecoveryCodeMismatch = errors.New("recovery code does not open the container")
+
+	ErrAgentRunning    = errors.New("an agent is already listening on the socket")
+	ErrAgentUnknownOp  = errors.New("unknown agent request")
+	ErrAgentInvalidKey = errors.New("agent request needs a container id and a hex master key")
+	ErrAgentSocket     = errors.New("agent socket is not owned by the current user or is open to others")
+	ErrAgentSocketDir  = errors.New("agent socket directory is not private to the current user")
+	ErrAgentPeer       = errors.New("agent runs as another user")
+
+	ErrKeyProviderKeyMismatch = errors.New("key provider returned a key that does not open the container")
+	ErrKMSKeyMismatch         = errors.New("kms returned a key that does not open the container")
+	ErrEscrowWrapMismatch     = errors.New("escrow holds a key that does not open the container")
+	ErrContainerKeyMismatch   = errors.New("key does not match the container's key check value")
+
+	ErrPromptStdinUsed = errors.New("cannot prompt for a secret: stdin was read for the tokens; list the secret in holders -path or read the tokens with token-reader -type=file or -type=flag")
 )
 
 type (
@@ -615,6 +1179,9 @@ func unwrap(err error) []string {
 		return []string{}
 	}
 
+	unwrappedErrorMu.Lock()
+	defer unwrappedErrorMu.Unlock()
+
 	unwrappedErrorList[err] = struct{}{}
 
 	var (
@@ -653,6 +1220,18 @@ func AsError(err error) (*Error, bool) {
 	return nil, false
 }
 
+// ErrDetails - returns the first non-empty Details found along err's chain, so
+// a wrapping error can surface the explanation given by the error it wraps.
+func ErrDetails(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(*Error); ok && e.Details != "" {
+			return e.Details
+		}
+	}
+
+	return ""
+}
+
 func IsValidationError(err error) bool {
 	e, ok := AsError(err)
 	return ok && e.IsType(ErrorTypeValidation)
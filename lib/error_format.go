@@ -6,6 +6,37 @@ import (
 	"io"
 )
 
+// Warning - a non-fatal problem reported on the log writer of a successful operation.
+type Warning struct {
+	Operation string `json:"operation"`
+	Message   string `json:"message"`
+	Details   string `json:"details"`
+}
+
+// WarningFormatted - writes a Warning to the log writer in its format.
+func WarningFormatted(logWriter *Writer, warning Warning) {
+	writer, closer, _ := NewWriter(logWriter)
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var message any = warning
+	if *logWriter.Format == WriterFormatPlaintext {
+		message = fmt.Sprintf(
+			"[warning]\noperation: %s;\nmessage: %s;\ndetails: %s;",
+			warning.Operation,
+			warning.Message,
+			warning.Details,
+		)
+	}
+
+	if _, err := WriteFormatted(writer, *logWriter.Format, message); err != nil {
+		fmt.Printf("failed to write warning message; %v", err)
+	}
+}
+
 func ErrorFormatted(logWriter *Writer, operation string, err error) {
 	writer, closer, _ := NewWriter(logWriter)
 	if closer != nil {
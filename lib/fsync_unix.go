@@ -0,0 +1,17 @@
+//go:build !windows
+
+package lib
+
+import "os"
+
+// SyncDir - flushes a directory's metadata to stable storage so that a rename
+// into that directory becomes durable across a power failure.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+
+	return d.Sync()
+}
@@ -1,10 +1,10 @@
 //go:build windows
 
-package reseal
+package lib
 
-// fsyncDir - on Windows there is no supported way to flush a directory handle
+// SyncDir - on Windows there is no supported way to flush a directory handle
 // (FlushFileBuffers fails on directories), so this is a no-op. Durability of the
 // rename relies on the NTFS metadata journal instead.
-func fsyncDir(_ string) error {
+func SyncDir(_ string) error {
 	return nil
 }
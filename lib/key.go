@@ -4,6 +4,8 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/binary"
+
+	"github.com/namelesscorp/tvault-core/lib/secret"
 )
 
 const (
@@ -20,6 +22,7 @@ const (
 // salt.        - 16‑byte random value stored in header
 // iterations   - cost factor (>= 100k recommended)
 // keyLen       - desired output length in bytes
+// Intermediate blocks are wiped; the caller owns, and wipes, the key.
 func PBKDF2Key(data, salt []byte, iterations, keyLen uint32) []byte {
 	blocks := (keyLen + hLen - 1) / hLen // ceil(keyLen / hLen)
 	derived := make([]byte, 0, blocks*hLen)
@@ -38,7 +41,7 @@ func PBKDF2Key(data, salt []byte, iterations, keyLen uint32) []byte {
 		for i := uint32(1); i < iterations; i++ {
 			mac.Reset()
 			mac.Write(ui)
-			ui = mac.Sum(nil)
+			ui = mac.Sum(ui[:0])
 
 			for j := 0; j < hLen; j++ {
 				ti[j] ^= ui[j]
@@ -46,7 +49,10 @@ func PBKDF2Key(data, salt []byte, iterations, keyLen uint32) []byte {
 		}
 
 		derived = append(derived, ti...)
+		secret.Wipe(ui)
+		secret.Wipe(ti)
 	}
+	secret.Wipe(derived[keyLen:])
 
 	return derived[:keyLen]
 }
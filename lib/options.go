@@ -1,5 +1,67 @@
 package lib
 
+import "time"
+
+// EnvEscrowPublicKey - environment variable holding the organisation's escrow
+// X25519 public key (hex), so every seal and reseal wraps to it unasked.
+const EnvEscrowPublicKey = "TVAULT_ESCROW_PUBLIC_KEY"
+
+// Overwrite policies of seal container -overwrite, applied when -new-path
+// already exists.
+const (
+	OverwriteNever  = "never"
+	OverwriteAlways = "always"
+	OverwriteBackup = "backup"
+)
+
+// Preserve modes of seal and reseal container -preserve. PreserveNone packs
+// file content, symlinks and modes; PreserveAll also records directories,
+// modification times, ownership, extended attributes and hard links.
+const (
+	PreserveNone = "none"
+	PreserveAll  = "all"
+)
+
+// Conflict policies of unseal container -on-conflict, applied to an entry
+// whose path already exists in the folder.
+const (
+	ConflictFail      = "fail"
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+	ConflictNewer     = "newer"
+)
+
+// Symlink policies of unseal container -symlinks. SymlinksReject fails on any
+// symlink, SymlinksSkip leaves them out, SymlinksContain extracts those whose
+// target stays in the folder, and SymlinksAllow extracts them as they are.
+// Under all but SymlinksAllow no entry is written through a symlink.
+const (
+	SymlinksReject  = "reject"
+	SymlinksSkip    = "skip"
+	SymlinksContain = "contain"
+	SymlinksAllow   = "allow"
+)
+
+// Default limits of unseal limits: an archive with more entries or deeper
+// paths is not extracted. Size and ratio are unlimited unless given.
+const (
+	DefaultMaxFiles = 1_000_000
+	DefaultMaxDepth = 256
+)
+
+// BackupSuffix - appended to a container path to name the copy kept by
+// OverwriteBackup.
+const BackupSuffix = ".bak"
+
+// JournalSuffix - appended to the container path of a seal, or the folder path
+// of an unseal, to name the checkpoint journal that container -resume reads.
+const JournalSuffix = ".journal"
+
+// StdioPath - given as seal container -new-path, writes the container to
+// stdout instead of a file.
+const StdioPath = "-"
+
 type (
 	Writer struct {
 		Type   *string
@@ -15,9 +77,10 @@ type (
 	}
 
 	Shamir struct {
-		Shares    *int
-		Threshold *int
-		IsEnabled *bool
+		Shares     *int
+		Threshold  *int
+		IsEnabled  *bool
+		PolicyPath *string
 	}
 
 	IntegrityProvider struct {
@@ -38,9 +101,108 @@ type (
 		Passphrase  *string
 		Comment     *string
 		Tags        *string
+		// RotateKey - reseal only: replace the master key and revoke all tokens.
+		RotateKey *bool
+		// Recovery - seal only: wrap the master key to a new recovery code.
+		Recovery *bool
+		// RecoveryCode - unseal only: open the container with this code.
+		RecoveryCode *string
+		// EscrowPublicKey - seal and reseal: hex X25519 public key the master
+		// key is also wrapped to; defaults to EnvEscrowPublicKey.
+		EscrowPublicKey *string
+		// EscrowKeyPath - escrow unseal only: file with the hex escrow private key.
+		EscrowKeyPath *string
+		// Overwrite - seal only: OverwriteNever, OverwriteAlways or
+		// OverwriteBackup, for when NewPath already exists.
+		Overwrite *string
+		// Resume - seal and unseal: continue an interrupted run from its
+		// checkpoint journal.
+		Resume *bool
+		// Ignore - seal and reseal: gitignore-syntax rules from -exclude, and
+		// -include prefixed with "!", in command-line order. They follow the
+		// rules of the folder's .tvaultignore.
+		Ignore *[]string
+		// Sources - seal only: files and folders packed with FolderPath, each
+		// given as <path>[:<prefix>] with the archive path it is packed under.
+		Sources *[]string
+		// Stdin - seal only: seal stdin as a raw payload instead of files.
+		Stdin *bool
+		// Preserve - seal, reseal and unseal: PreserveNone or PreserveAll; an
+		// empty reseal mode keeps the one the container was sealed with. On
+		// unseal PreserveAll also restores ownership and the setuid, setgid
+		// and sticky bits.
+		Preserve *string
+		// Stdout - unseal only: write the raw payload to stdout instead of
+		// extracting to FolderPath.
+		Stdout *bool
+		// OnConflict - unseal only: ConflictFail, ConflictSkip,
+		// ConflictOverwrite, ConflictRename or ConflictNewer, for entries
+		// whose path already exists in FolderPath.
+		OnConflict *string
+		// DryRun - unseal only: report what would be extracted without
+		// writing to FolderPath.
+		DryRun *bool
+		// Symlinks - unseal only: SymlinksReject, SymlinksSkip,
+		// SymlinksContain or SymlinksAllow.
+		Symlinks *string
+	}
+
+	// Limits - unseal only: bounds an archive must stay within to be
+	// extracted; 0 disables a limit.
+	Limits struct {
+		// MaxFiles - entries, directories and links included.
+		MaxFiles *int64
+		// MaxBytes - total uncompressed size.
+		MaxBytes *int64
+		// MaxRatio - uncompressed bytes per compressed byte of one entry.
+		MaxRatio *int64
+		// MaxDepth - path segments of an entry name.
+		MaxDepth *int
+	}
+
+	Agent struct {
+		// Socket - Unix socket of the key agent; empty disables the agent.
+		Socket *string
+		// TTL - agent start only: how long a key is cached.
+		TTL *time.Duration
+	}
+
+	KMS struct {
+		// Address - base URL of the Vault Transit server; defaults to VAULT_ADDR.
+		Address *string
+		// Mount - path the transit engine is mounted at; unseal and reseal
+		// use the mount recorded in the container when it is empty.
+		Mount *string
+		// Key - seal only: transit key the master key is wrapped with. unseal
+		// and reseal use the mount and key recorded in the container.
+		Key *string
+		// TokenPath - file holding the Vault token; empty reads VAULT_TOKEN.
+		TokenPath  *string
+		CACert     *string
+		ClientCert *string
+		ClientKey  *string
+		Timeout    *time.Duration
+	}
+
+	Escrow struct {
+		Dir            *string
+		PrivateKeyPath *string
+		NewPublicKey   *string
 	}
 
 	Token struct {
 		Type *string
+		// Plugin - key provider of token -type=plugin: the program
+		// tvault-keyprovider-<Plugin> on PATH.
+		Plugin *string
+		// NotBefore and ExpiresAt - RFC 3339 validity window of issued tokens;
+		// empty leaves that side open.
+		NotBefore *string
+		ExpiresAt *string
+	}
+
+	Holders struct {
+		Path    *string
+		NewPath *string
 	}
 )
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
 const (
@@ -23,6 +24,17 @@ var ReaderTypes = map[string]struct{}{
 	ReaderTypeStdin: {},
 }
 
+var (
+	// stdin - the one buffered reader of standard input. A reader per call
+	// would keep what it buffered past its line, losing the next secret of
+	// piped input.
+	stdin = bufio.NewReader(os.Stdin)
+
+	// stdinTokens - set once the stdin token reader has read standard input,
+	// after which Prompt refuses to read secrets from it.
+	stdinTokens bool
+)
+
 type (
 	byteSliceReader struct {
 		data   []byte
@@ -77,7 +89,8 @@ func newStdinReader(format string) (io.Reader, io.ReadCloser, error) {
 
 	fmt.Println(prompt)
 
-	line, readErr := bufio.NewReader(os.Stdin).ReadBytes(delim)
+	stdinTokens = true
+	line, readErr := stdin.ReadBytes(delim)
 	if readErr != nil && !errors.Is(readErr, io.EOF) {
 		return nil, nil, fmt.Errorf("read stdin; %w", readErr)
 	}
@@ -122,3 +135,30 @@ func (c CloserWrapper) Close() error {
 
 	return nil
 }
+
+// Prompt - prints label to stderr and reads a single line from stdin, without
+// the trailing newline. It is used for interactive secrets such as per-holder
+// share passphrases: a terminal does not echo them, and piped input gives one
+// per line. Once the tokens were read from stdin it fails with
+// ErrPromptStdinUsed. The label stays off stdout, which may carry an unsealed
+// payload.
+func Prompt(label string) (string, error) {
+	if stdinTokens {
+		return "", ErrPromptStdinUsed
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "%s: ", label)
+	if restore, ok := echoOff(os.Stdin.Fd()); ok {
+		defer func() {
+			restore()
+			_, _ = fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read stdin; %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,50 @@
+package lib
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withStdin - has the stdin readers read input until the test ends.
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	saved, savedTokens := stdin, stdinTokens
+	stdin, stdinTokens = bufio.NewReader(strings.NewReader(input)), false
+	t.Cleanup(func() {
+		stdin, stdinTokens = saved, savedTokens
+	})
+}
+
+func TestPromptReadsOneLineEach(t *testing.T) {
+	withStdin(t, "alice-secret\nbob-secret\r\ncarol-secret")
+
+	for _, want := range []string{"alice-secret", "bob-secret", "carol-secret"} {
+		got, err := Prompt("secret")
+		if err != nil {
+			t.Fatalf("Prompt() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Prompt() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestPromptRefusesAfterStdinTokens(t *testing.T) {
+	withStdin(t, "token_1|token_2\nsecret\n")
+
+	reader, _, err := NewReader(&Reader{Type: StringPtr(ReaderTypeStdin), Format: StringPtr(ReaderFormatPlaintext)})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	line := make([]byte, 64)
+	if n, _ := reader.Read(line); string(line[:n]) != "token_1|token_2\n" {
+		t.Fatalf("token reader read %q", line[:n])
+	}
+
+	if _, err = Prompt("secret"); !errors.Is(err, ErrPromptStdinUsed) {
+		t.Fatalf("Prompt() error = %v, want %v", err, ErrPromptStdinUsed)
+	}
+}
@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"strings"
+)
+
+const (
+	// RecoverySecretLen - entropy of a recovery code in bytes.
+	RecoverySecretLen = 16
+
+	// recoveryChecksumLen - bytes of SHA-256(secret) appended to the secret,
+	// so a mistyped code is reported before any decryption is attempted.
+	recoveryChecksumLen = 4
+
+	// recoveryGroupLen - characters per dash-separated group of a code.
+	recoveryGroupLen = 4
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCode - generates a random recovery secret and returns it
+// with its human-readable code.
+func GenerateRecoveryCode() (code string, secret []byte, err error) {
+	secret = make([]byte, RecoverySecretLen)
+	if _, err = rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	return FormatRecoveryCode(secret), secret, nil
+}
+
+// FormatRecoveryCode - encodes secret || checksum in base32 and splits it into
+// groups of four, e.g. ABCD-EFGH-IJKL-MNOP-QRST-UVWX-YZ23-4567.
+func FormatRecoveryCode(secret []byte) string {
+	encoded := recoveryEncoding.EncodeToString(append(append([]byte{}, secret...), recoveryChecksum(secret)...))
+
+	groups := make([]string, 0, len(encoded)/recoveryGroupLen+1)
+	for len(encoded) > recoveryGroupLen {
+		groups = append(groups, encoded[:recoveryGroupLen])
+		encoded = encoded[recoveryGroupLen:]
+	}
+
+	return strings.Join(append(groups, encoded), "-")
+}
+
+// ParseRecoveryCode - returns the secret of a recovery code. Case, dashes and
+// spaces are ignored, and 0, 1 and 8, which base32 does not use, are read as
+// the letters O, I and B they are mistaken for.
+func ParseRecoveryCode(code string) ([]byte, error) {
+	normalized := strings.NewReplacer("-", "", " ", "", "0", "O", "1", "I", "8", "B").
+		Replace(strings.ToUpper(strings.TrimSpace(code)))
+
+	decoded, err := recoveryEncoding.DecodeString(normalized)
+	if err != nil || len(decoded) != RecoverySecretLen+recoveryChecksumLen {
+		return nil, ErrRecoveryCodeInvalid
+	}
+
+	secret, checksum := decoded[:RecoverySecretLen], decoded[RecoverySecretLen:]
+	if subtle.ConstantTimeCompare(checksum, recoveryChecksum(secret)) != 1 {
+		return nil, ErrRecoveryCodeChecksum
+	}
+
+	return secret, nil
+}
+
+func recoveryChecksum(secret []byte) []byte {
+	sum := sha256.Sum256(secret)
+
+	return sum[:recoveryChecksumLen]
+}
@@ -0,0 +1,51 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryCode(t *testing.T) {
+	code, secret, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCode() error = %v", err)
+	}
+	if groups := strings.Split(code, "-"); len(groups) != 8 || len(groups[0]) != recoveryGroupLen {
+		t.Fatalf("code %q is not 8 groups of %d", code, recoveryGroupLen)
+	}
+
+	// Replace one character of the code with another base32 letter.
+	typo := []byte(code)
+	if typo[0] == 'A' {
+		typo[0] = 'B'
+	} else {
+		typo[0] = 'A'
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "as_shown", code: code},
+		{name: "lower_case_without_dashes", code: strings.ToLower(strings.ReplaceAll(code, "-", ""))},
+		{name: "spaces", code: strings.ReplaceAll(code, "-", " ")},
+		{name: "typo", code: string(typo), wantErr: ErrRecoveryCodeChecksum},
+		{name: "truncated", code: code[:len(code)-5], wantErr: ErrRecoveryCodeInvalid},
+		{name: "not_base32", code: "not a recovery code!", wantErr: ErrRecoveryCodeInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecoveryCode(tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseRecoveryCode() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, secret) {
+				t.Errorf("ParseRecoveryCode() = %x, want %x", got, secret)
+			}
+		})
+	}
+}
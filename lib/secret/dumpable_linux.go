@@ -0,0 +1,23 @@
+package secret
+
+import "syscall"
+
+// prSetDumpable and prGetDumpable - prctl options, see prctl(2).
+const (
+	prGetDumpable = 3
+	prSetDumpable = 4
+)
+
+// setNotDumpable - clears the dumpable flag, which also stops core dumps
+// piped to a handler such as systemd-coredump, where RLIMIT_CORE is ignored.
+func setNotDumpable() func() {
+	dumpable, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prGetDumpable, 0, 0)
+	if errno != 0 {
+		return func() {}
+	}
+	_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetDumpable, 0, 0)
+
+	return func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetDumpable, dumpable, 0)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build darwin
+
+package secret
+
+// setNotDumpable - there is no dumpable flag here; RLIMIT_CORE suffices.
+func setNotDumpable() func() {
+	return func() {}
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin)
+
+package secret
+
+// alloc - memory locking is not available here; secrets may be swapped out.
+func alloc(n int) []byte {
+	return make([]byte, n)
+}
+
+func free(_ []byte) {}
+
+func disableCoreDumps() func() {
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin
+
+package secret
+
+import (
+	"os"
+	"syscall"
+)
+
+// alloc - maps whole pages for n bytes and locks them, so no other data
+// shares them and they are never swapped out. Locking is best effort: with a
+// low RLIMIT_MEMLOCK the pages are still private, only swappable.
+func alloc(n int) []byte {
+	size := (n + os.Getpagesize()) &^ (os.Getpagesize() - 1)
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, n)
+	}
+	_ = syscall.Mlock(mem)
+
+	return mem
+}
+
+// free - unlocks and unmaps mem, which must come from alloc and be wiped.
+func free(mem []byte) {
+	_ = syscall.Munlock(mem)
+	_ = syscall.Munmap(mem)
+}
+
+// disableCoreDumps - sets RLIMIT_CORE to 0, and marks the process as not
+// dumpable where that exists, and returns how to restore both.
+func disableCoreDumps() func() {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &limit); err != nil {
+		return nil
+	}
+	_ = syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{Cur: 0, Max: limit.Max})
+	restoreDumpable := setNotDumpable()
+
+	return func() {
+		_ = syscall.Setrlimit(syscall.RLIMIT_CORE, &limit)
+		restoreDumpable()
+	}
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package secret
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestCoreDumpsDisabledWhileLive(t *testing.T) {
+	if Live() != 0 {
+		t.Fatalf("Live() = %d, want 0 before the test", Live())
+	}
+
+	var before syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &before); err != nil {
+		t.Skipf("Getrlimit() error = %v", err)
+	}
+
+	first, second := New(32), New(32)
+	var during syscall.Rlimit
+	_ = syscall.Getrlimit(syscall.RLIMIT_CORE, &during)
+	if during.Cur != 0 {
+		t.Errorf("RLIMIT_CORE while secrets are live = %d, want 0", during.Cur)
+	}
+
+	first.Wipe()
+	if Live() != 1 {
+		t.Errorf("Live() = %d, want 1", Live())
+	}
+
+	second.Wipe()
+	var after syscall.Rlimit
+	_ = syscall.Getrlimit(syscall.RLIMIT_CORE, &after)
+	if after != before {
+		t.Errorf("RLIMIT_CORE after the last wipe = %+v, want %+v", after, before)
+	}
+}
+
+func TestNewOwnPages(t *testing.T) {
+	s := New(32)
+	defer s.Wipe()
+
+	// The mapping is page aligned, so no other data shares its pages.
+	if len(s.mem)%syscall.Getpagesize() != 0 {
+		t.Errorf("New() mapped %d bytes, want whole pages", len(s.mem))
+	}
+	if cap(s.Bytes()) != 32 {
+		t.Errorf("cap(Bytes()) = %d, want 32 so appends do not write into the mapping", cap(s.Bytes()))
+	}
+}
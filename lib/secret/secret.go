@@ -0,0 +1,131 @@
+// Package secret holds key material outside the reach of swap, core dumps and
+// logs: a Secret is kept in its own locked pages where the platform allows it,
+// is zeroed by Wipe, and prints as a placeholder with fmt and encoding/json.
+package secret
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Redacted - what a Secret prints as.
+const Redacted = "[redacted]"
+
+// Secret - key material in memory of its own. It is not safe for concurrent
+// use; its owner wipes it once done, usually with defer.
+type Secret struct {
+	// mem - the allocation holding data, released by Wipe.
+	mem  []byte
+	data []byte
+}
+
+var (
+	// live - number of secrets not yet wiped; core dumps are disabled while
+	// it is not zero.
+	live   int
+	liveMu sync.Mutex
+	// restoreCore - undoes disableCoreDumps once the last secret is wiped.
+	restoreCore func()
+)
+
+// New - returns a zeroed secret of n bytes.
+func New(n int) *Secret {
+	acquire()
+
+	mem := alloc(n)
+	return &Secret{mem: mem, data: mem[:n:n]}
+}
+
+// From - moves b into a new secret: b is copied, then wiped.
+func From(b []byte) *Secret {
+	s := New(len(b))
+	copy(s.data, b)
+	Wipe(b)
+
+	return s
+}
+
+// Bytes - returns the secret's bytes, valid until Wipe; nil afterwards.
+func (s *Secret) Bytes() []byte {
+	if s == nil {
+		return nil
+	}
+
+	return s.data
+}
+
+// Len - returns the length of the secret; 0 once wiped.
+func (s *Secret) Len() int {
+	return len(s.Bytes())
+}
+
+// Wipe - zeroes the secret and releases its memory. It is safe to call more
+// than once, and on nil.
+func (s *Secret) Wipe() {
+	if s == nil || s.mem == nil {
+		return
+	}
+
+	Wipe(s.mem)
+	free(s.mem)
+	s.mem, s.data = nil, nil
+
+	release()
+}
+
+// String, GoString and Format - keep the secret out of fmt output, whatever
+// the verb.
+func (s *Secret) String() string {
+	return Redacted
+}
+
+func (s *Secret) GoString() string {
+	return Redacted
+}
+
+func (s *Secret) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(Redacted))
+}
+
+// MarshalJSON and MarshalText - keep the secret out of encoded output.
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+func (s *Secret) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// Wipe - zeroes b, e.g. a key derived into an ordinary slice once it has been
+// moved into a Secret or is no longer needed.
+func Wipe(b []byte) {
+	clear(b)
+}
+
+// Live - returns the number of secrets not yet wiped.
+func Live() int {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+
+	return live
+}
+
+func acquire() {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+
+	if live == 0 {
+		restoreCore = disableCoreDumps()
+	}
+	live++
+}
+
+func release() {
+	liveMu.Lock()
+	defer liveMu.Unlock()
+
+	if live--; live == 0 && restoreCore != nil {
+		restoreCore()
+		restoreCore = nil
+	}
+}
@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFromAndWipe(t *testing.T) {
+	src := []byte("0123456789abcdef0123456789abcdef")
+	want := bytes.Clone(src)
+
+	s := From(src)
+	if !bytes.Equal(s.Bytes(), want) || s.Len() != len(want) {
+		t.Fatalf("Bytes() = %q, want %q", s.Bytes(), want)
+	}
+	if !bytes.Equal(src, make([]byte, len(src))) {
+		t.Errorf("From() left the source as %q, want it zeroed", src)
+	}
+
+	s.Wipe()
+	if s.Bytes() != nil || s.Len() != 0 {
+		t.Errorf("Bytes() after Wipe() = %q, want nil", s.Bytes())
+	}
+
+	// Wipe is idempotent, and nil-safe.
+	s.Wipe()
+	(*Secret)(nil).Wipe()
+}
+
+func TestRedaction(t *testing.T) {
+	s := From([]byte("hunter2-hunter2"))
+	defer s.Wipe()
+
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		if got := fmt.Sprintf(verb, s); got != Redacted {
+			t.Errorf("Sprintf(%q) = %q, want %q", verb, got, Redacted)
+		}
+	}
+
+	got, err := json.Marshal(struct {
+		Key *Secret `json:"key"`
+	}{Key: s})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(got), "hunter2") || !strings.Contains(string(got), Redacted) {
+		t.Errorf("json.Marshal() = %s, want the key redacted", got)
+	}
+}
@@ -0,0 +1,45 @@
+package lib
+
+import "sync"
+
+// ByteSem is a weighted counting semaphore bounding total bytes in flight. A
+// request larger than the maximum is clamped so it can still proceed once the
+// pool is otherwise empty, rather than deadlocking.
+type ByteSem struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	cur  int64
+	max  int64
+}
+
+func NewByteSem(max int64) *ByteSem {
+	s := &ByteSem{max: max}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire - blocks until n bytes fit in the budget, then reserves them.
+func (s *ByteSem) Acquire(n int64) {
+	if n > s.max {
+		n = s.max
+	}
+
+	s.mu.Lock()
+	for s.cur > 0 && s.cur+n > s.max {
+		s.cond.Wait()
+	}
+	s.cur += n
+	s.mu.Unlock()
+}
+
+// Release - returns n bytes previously reserved with Acquire.
+func (s *ByteSem) Release(n int64) {
+	if n > s.max {
+		n = s.max
+	}
+
+	s.mu.Lock()
+	s.cur -= n
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
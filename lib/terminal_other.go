@@ -0,0 +1,9 @@
+//go:build !(linux || darwin)
+
+package lib
+
+// echoOff - echo cannot be turned off on this platform; secrets are read as
+// typed.
+func echoOff(uintptr) (restore func(), ok bool) {
+	return nil, false
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin
+
+package lib
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// echoOff - turns off echo on the terminal fd and returns the function that
+// turns it back on. ok is false when fd is not a terminal.
+func echoOff(fd uintptr) (restore func(), ok bool) {
+	var state syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&state))); errno != 0 {
+		return nil, false
+	}
+
+	quiet := state
+	quiet.Lflag &^= syscall.ECHO
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&quiet))); errno != 0 {
+		return nil, false
+	}
+
+	return func() {
+		_, _, _ = syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&state)))
+	}, true
+}
@@ -9,12 +9,14 @@ import (
 const (
 	WriterTypeFile   = "file"
 	WriterTypeStdout = "stdout"
+	WriterTypeStderr = "stderr"
 )
 
 var (
 	WriterTypes = map[string]struct{}{
 		WriterTypeFile:   {},
 		WriterTypeStdout: {},
+		WriterTypeStderr: {},
 	}
 )
 
@@ -23,6 +25,10 @@ type (
 		format string
 	}
 
+	StderrWriter struct {
+		format string
+	}
+
 	FileWriter struct {
 		format string
 		file   *os.File
@@ -30,13 +36,17 @@ type (
 )
 
 // NewWriter - creates an io.Writer and optionally an io.Closer based on the provided Writer configuration.
-// Supported types: "file", "stdout".
+// Supported types: "file", "stdout", "stderr".
 // Supported formats: "plaintext", "json".
 //
 // Stdout writer:
 // - writes a formatted message to stdout based on the specified format.
 // - supports two formats: "plaintext" and "json".
 //
+// Stderr writer:
+// - writes like the stdout writer, to stderr; used when stdout carries a
+// container or payload stream.
+//
 // File writer:
 // - writes a formatted message to the provided file path based on the specified format.
 func NewWriter(opts *Writer) (io.Writer, io.Closer, error) {
@@ -50,6 +60,8 @@ func NewWriter(opts *Writer) (io.Writer, io.Closer, error) {
 		return w, w, nil
 	case WriterTypeStdout:
 		return newStdoutWriter(*opts.Format), nil, nil
+	case WriterTypeStderr:
+		return newStderrWriter(*opts.Format), nil, nil
 	default:
 		return nil, nil, ErrUnknownWriterType
 	}
@@ -63,6 +75,14 @@ func (s StdoutWriter) Write(p []byte) (int, error) {
 	return fmt.Println(string(p))
 }
 
+func newStderrWriter(format string) StderrWriter {
+	return StderrWriter{format: format}
+}
+
+func (s StderrWriter) Write(p []byte) (int, error) {
+	return fmt.Fprintln(os.Stderr, string(p))
+}
+
 func newFileWriter(path, format string) (*FileWriter, error) {
 	f, err := os.Create(path) // #nosec G304
 	if err != nil {
@@ -0,0 +1,157 @@
+package lib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+const (
+	// X25519KeyLen - length of an X25519 public or private key in bytes.
+	X25519KeyLen = 32
+
+	// x25519NonceLen - AES-GCM nonce length used by X25519Seal.
+	x25519NonceLen = 12
+)
+
+// GenerateX25519Key - generates a new X25519 key pair and returns the raw
+// private and public keys.
+func GenerateX25519Key() (privateKey, publicKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Join(ErrX25519GenerateFailure, err)
+	}
+
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// X25519PublicKey - returns the public key for a raw X25519 private key.
+func X25519PublicKey(privateKey []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	return key.PublicKey().Bytes(), nil
+}
+
+// CheckX25519PublicKey - parses a raw X25519 public key and rejects the
+// low-order points, for which every shared secret is zero and X25519Seal
+// would fail.
+func CheckX25519PublicKey(publicKey []byte) error {
+	key, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	probe, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.Join(ErrX25519GenerateFailure, err)
+	}
+	if _, err = probe.ECDH(key); err != nil {
+		return errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	return nil
+}
+
+// X25519Seal - encrypts plaintext to the holder of publicKey. A fresh ephemeral
+// key pair is generated per call; the shared secret is expanded with
+// HKDF-SHA256 (salt = ephemeral || recipient public key, info = info) into an
+// AES-256-GCM key. The result is ephemeral public key || nonce || ciphertext+tag,
+// with the ephemeral public key and aad bound as additional authenticated data.
+func X25519Seal(publicKey, plaintext, aad []byte, info string) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Join(ErrX25519GenerateFailure, err)
+	}
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	ephemeralPublic := ephemeral.PublicKey().Bytes()
+	aesGCM, err := x25519AEAD(shared, ephemeralPublic, publicKey, info)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, X25519KeyLen+x25519NonceLen, X25519KeyLen+x25519NonceLen+len(plaintext)+aesGCM.Overhead())
+	copy(sealed, ephemeralPublic)
+	if _, err = io.ReadFull(rand.Reader, sealed[X25519KeyLen:]); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(sealed, sealed[X25519KeyLen:], plaintext, x25519AAD(ephemeralPublic, aad)), nil
+}
+
+// X25519Open - reverses X25519Seal with the recipient's raw private key.
+func X25519Open(privateKey, sealed, aad []byte, info string) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	if len(sealed) < X25519KeyLen+x25519NonceLen+16 {
+		return nil, ErrX25519SealedTooShort
+	}
+
+	ephemeralPublic := sealed[:X25519KeyLen]
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralPublic)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, errors.Join(ErrX25519InvalidKey, err)
+	}
+
+	aesGCM, err := x25519AEAD(shared, ephemeralPublic, key.PublicKey().Bytes(), info)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := sealed[X25519KeyLen : X25519KeyLen+x25519NonceLen]
+	plaintext, err := aesGCM.Open(nil, nonce, sealed[X25519KeyLen+x25519NonceLen:], x25519AAD(ephemeralPublic, aad))
+	if err != nil {
+		return nil, errors.Join(ErrX25519OpenFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+func x25519AEAD(shared, ephemeralPublic, recipientPublic []byte, info string) (cipher.AEAD, error) {
+	salt := make([]byte, 0, len(ephemeralPublic)+len(recipientPublic))
+	salt = append(salt, ephemeralPublic...)
+	salt = append(salt, recipientPublic...)
+
+	key, err := hkdf.Key(sha256.New, shared, salt, info, KeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func x25519AAD(ephemeralPublic, aad []byte) []byte {
+	out := make([]byte, 0, len(ephemeralPublic)+len(aad))
+	out = append(out, ephemeralPublic...)
+	return append(out, aad...)
+}
@@ -0,0 +1,124 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestX25519SealOpen(t *testing.T) {
+	privateKey, publicKey, err := GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+
+	derived, err := X25519PublicKey(privateKey)
+	if err != nil {
+		t.Fatalf("X25519PublicKey() error = %v", err)
+	}
+	if !bytes.Equal(derived, publicKey) {
+		t.Fatalf("X25519PublicKey() = %x, want %x", derived, publicKey)
+	}
+
+	var (
+		plaintext = []byte("share token")
+		aad       = []byte{0x02, 0x01, 0x02}
+		info      = "test"
+	)
+
+	sealed, err := X25519Seal(publicKey, plaintext, aad, info)
+	if err != nil {
+		t.Fatalf("X25519Seal() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     func() []byte
+		sealed  func() []byte
+		aad     []byte
+		info    string
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			key:    func() []byte { return privateKey },
+			sealed: func() []byte { return sealed },
+			aad:    aad,
+			info:   info,
+		},
+		{
+			name: "wrong_private_key",
+			key: func() []byte {
+				other, _, _ := GenerateX25519Key()
+				return other
+			},
+			sealed:  func() []byte { return sealed },
+			aad:     aad,
+			info:    info,
+			wantErr: ErrX25519OpenFailed,
+		},
+		{
+			name:    "wrong_aad",
+			key:     func() []byte { return privateKey },
+			sealed:  func() []byte { return sealed },
+			aad:     []byte{0x02, 0x02, 0x02},
+			info:    info,
+			wantErr: ErrX25519OpenFailed,
+		},
+		{
+			name:    "wrong_info",
+			key:     func() []byte { return privateKey },
+			sealed:  func() []byte { return sealed },
+			aad:     aad,
+			info:    "other",
+			wantErr: ErrX25519OpenFailed,
+		},
+		{
+			name: "tampered_ciphertext",
+			key:  func() []byte { return privateKey },
+			sealed: func() []byte {
+				tampered := bytes.Clone(sealed)
+				tampered[len(tampered)-1] ^= 0xFF
+				return tampered
+			},
+			aad:     aad,
+			info:    info,
+			wantErr: ErrX25519OpenFailed,
+		},
+		{
+			name:    "too_short",
+			key:     func() []byte { return privateKey },
+			sealed:  func() []byte { return sealed[:X25519KeyLen] },
+			aad:     aad,
+			info:    info,
+			wantErr: ErrX25519SealedTooShort,
+		},
+		{
+			name:    "invalid_private_key",
+			key:     func() []byte { return []byte("short") },
+			sealed:  func() []byte { return sealed },
+			aad:     aad,
+			info:    info,
+			wantErr: ErrX25519InvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := X25519Open(tt.key(), tt.sealed(), tt.aad, tt.info)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("X25519Open() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("X25519Open() error = %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("X25519Open() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
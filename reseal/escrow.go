@@ -0,0 +1,166 @@
+package reseal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+	"github.com/namelesscorp/tvault-core/seal"
+	"github.com/namelesscorp/tvault-core/unseal"
+)
+
+type (
+	// RewrapResult - outcome of escrow rewrap; paths are relative to the
+	// directory walked.
+	RewrapResult struct {
+		Rewrapped []string        `json:"rewrapped"`
+		Skipped   []string        `json:"skipped"`
+		Failed    []RewrapFailure `json:"failed"`
+	}
+
+	// RewrapFailure - a container escrow rewrap could not rewrap.
+	RewrapFailure struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}
+)
+
+// RewrapEscrow - walks a directory for containers and wraps the master key of
+// each one escrowed to the current key to the new escrow public key. Only the
+// metadata is rewritten; the payload is copied unchanged. Containers without
+// an escrow, or already wrapped to the new key, are skipped. A failing
+// container does not stop the walk; failures are reported and fail the run.
+func RewrapEscrow(opts RewrapOptions) error {
+	privateKey, err := unseal.ReadEscrowPrivateKey(lib.CategoryReseal, *opts.Escrow.PrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	newPublicKey, err := seal.DecodeEscrowPublicKey(*opts.Escrow.NewPublicKey)
+	if err != nil {
+		return lib.ValidationErr(lib.CategoryReseal, err)
+	}
+
+	var (
+		dir    = *opts.Escrow.Dir
+		result = RewrapResult{Rewrapped: []string{}, Skipped: []string{}, Failed: []RewrapFailure{}}
+	)
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || filepath.Ext(path) != container.Ext {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+
+		rewrapped, err := rewrapContainer(path, privateKey, newPublicKey)
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, RewrapFailure{Path: name, Error: err.Error()})
+		case rewrapped:
+			result.Rewrapped = append(result.Rewrapped, name)
+		default:
+			result.Skipped = append(result.Skipped, name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeEscrowRewrapError, lib.ErrMessageEscrowRewrapError, "", err)
+	}
+
+	if err = writeRewrapResult(opts.InfoWriter, result); err != nil {
+		return err
+	}
+
+	if len(result.Failed) > 0 {
+		return lib.NewError(
+			lib.ErrorTypeInternal,
+			lib.CategoryReseal,
+			lib.ErrCodeEscrowRewrapError,
+			lib.ErrMessageEscrowRewrapError,
+			fmt.Sprintf("%d container(s) failed", len(result.Failed)),
+			"see the failed list of the rewrap result; rewrapped containers need no second run",
+			nil,
+		)
+	}
+
+	return nil
+}
+
+// rewrapContainer - rewraps the escrow of the container at path, reporting
+// whether it was rewritten. newPublicKey has been checked by
+// seal.DecodeEscrowPublicKey before any container is opened, and the key the
+// current escrow holds must open the container before it is wrapped again.
+func rewrapContainer(path string, privateKey, newPublicKey []byte) (bool, error) {
+	cont := container.NewContainer(path, nil, container.Metadata{Tags: make([]string, 0)}, container.Header{})
+	if err := cont.Read(); err != nil {
+		return false, err
+	}
+
+	escrow := cont.GetMetadata().Keys.GetEscrow()
+	if escrow == nil || strings.EqualFold(escrow.PublicKey, hex.EncodeToString(newPublicKey)) {
+		return false, nil
+	}
+
+	masterKey, err := unseal.OpenEscrow(lib.CategoryReseal, cont, privateKey)
+	if err != nil {
+		return false, err
+	}
+	defer secret.Wipe(masterKey)
+
+	if !unseal.CheckMasterKey(cont, masterKey) {
+		return false, lib.CryptoErr(lib.CategoryReseal, lib.ErrCodeEscrowOpenError, lib.ErrMessageEscrowOpenError, "", lib.ErrEscrowWrapMismatch)
+	}
+
+	if escrow, err = container.NewEscrow(masterKey, newPublicKey, cont.GetHeader().Salt); err != nil {
+		return false, err
+	}
+
+	updateKeyBlock(cont, func(keyBlock *container.KeyBlock) {
+		keyBlock.Escrow = escrow
+	})
+
+	return true, rewriteContainerAtomic(cont, path)
+}
+
+func writeRewrapResult(opts *lib.Writer, result RewrapResult) error {
+	writer, closer, err := lib.NewWriter(opts)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any = result
+	if *opts.Format == lib.WriterFormatPlaintext {
+		var b strings.Builder
+		b.WriteString("[escrow rewrap]\n")
+		_, _ = fmt.Fprintf(&b, "Rewrapped: %s\n", strings.Join(result.Rewrapped, ","))
+		_, _ = fmt.Fprintf(&b, "Skipped: %s\n", strings.Join(result.Skipped, ","))
+		for _, failure := range result.Failed {
+			_, _ = fmt.Fprintf(&b, "Failed: %s: %s\n", failure.Path, failure.Error)
+		}
+		msg = b.String()
+	}
+
+	if _, err = lib.WriteFormatted(writer, *opts.Format, msg); err != nil {
+		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeEscrowRewrapError, lib.ErrMessageEscrowRewrapError, "", err)
+	}
+
+	return nil
+}
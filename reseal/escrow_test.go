@@ -0,0 +1,206 @@
+package reseal
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+func TestRewrapEscrow(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		keyPath    = filepath.Join(dir, "escrow.key")
+		resultPath = filepath.Join(t.TempDir(), "result.json")
+		masterKey  = bytes.Repeat([]byte{0x42}, lib.KeyLen)
+	)
+
+	oldPrivateKey, oldPublicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+	newPrivateKey, newPublicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+	if err = os.WriteFile(keyPath, []byte(hex.EncodeToString(oldPrivateKey)+"\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	writeContainer := func(name string, escrowPublicKey []byte) string {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+
+		header, err := container.NewHeader(0, integrity.TypeNone, token.TypeMaster, 0, 0)
+		if err != nil {
+			t.Fatalf("NewHeader() error = %v", err)
+		}
+
+		metadata := container.Metadata{Tags: []string{}}
+		if escrowPublicKey != nil {
+			escrow, err := container.NewEscrow(masterKey, escrowPublicKey, header.Salt)
+			if err != nil {
+				t.Fatalf("NewEscrow() error = %v", err)
+			}
+			metadata.Keys = &container.KeyBlock{Escrow: escrow}
+		}
+
+		cont := container.NewContainer(path, nil, metadata, header)
+		cont.SetMasterKey(masterKey)
+		if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("payload")), nil); err != nil {
+			t.Fatalf("WriteEncrypted() error = %v", err)
+		}
+
+		return path
+	}
+
+	escrowed := writeContainer(filepath.Join("team", "a.tvlt"), oldPublicKey)
+	writeContainer("b.tvlt", nil)
+	writeContainer("c.tvlt", newPublicKey)
+
+	opts := RewrapOptions{
+		Escrow: &lib.Escrow{
+			Dir:            lib.StringPtr(dir),
+			PrivateKeyPath: lib.StringPtr(keyPath),
+			NewPublicKey:   lib.StringPtr(hex.EncodeToString(newPublicKey)),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(resultPath),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+	if err = opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err = RewrapEscrow(opts); err != nil {
+		t.Fatalf("RewrapEscrow() error = %v", err)
+	}
+
+	content, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	var result RewrapResult
+	if err = json.Unmarshal(content, &result); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	want := RewrapResult{
+		Rewrapped: []string{filepath.Join("team", "a.tvlt")},
+		Skipped:   []string{"b.tvlt", "c.tvlt"},
+		Failed:    []RewrapFailure{},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("RewrapEscrow() result = %+v, want %+v", result, want)
+	}
+
+	cont := container.NewContainer(escrowed, nil, container.Metadata{Tags: []string{}}, container.Header{})
+	if err = cont.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if _, err = cont.GetMetadata().Keys.OpenEscrow(oldPrivateKey, cont.GetHeader().Salt); err == nil {
+		t.Error("old escrow key still opens the rewrapped container")
+	}
+
+	got, err := cont.GetMetadata().Keys.OpenEscrow(newPrivateKey, cont.GetHeader().Salt)
+	if err != nil {
+		t.Fatalf("OpenEscrow() error = %v", err)
+	}
+	if !bytes.Equal(got, masterKey) {
+		t.Errorf("OpenEscrow() = %x, want %x", got, masterKey)
+	}
+
+	var payload bytes.Buffer
+	if err = cont.DecryptTo(context.Background(), &payload, got); err != nil || payload.String() != "payload" {
+		t.Errorf("DecryptTo() = %q, %v; want the unchanged payload", payload.String(), err)
+	}
+}
+
+func TestRewrapContainerChecksEscrowedKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.tvlt")
+
+	privateKey, publicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+	_, newPublicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+
+	header, err := container.NewHeader(0, integrity.TypeNone, token.TypeMaster, 0, 0)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+
+	// The escrow wraps a key other than the one the payload is sealed with.
+	escrow, err := container.NewEscrow(bytes.Repeat([]byte{0x07}, lib.KeyLen), publicKey, header.Salt)
+	if err != nil {
+		t.Fatalf("NewEscrow() error = %v", err)
+	}
+
+	cont := container.NewContainer(path, nil, container.Metadata{Tags: []string{}, Keys: &container.KeyBlock{Escrow: escrow}}, header)
+	cont.SetMasterKey(bytes.Repeat([]byte{0x42}, lib.KeyLen))
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("payload")), nil); err != nil {
+		t.Fatalf("WriteEncrypted() error = %v", err)
+	}
+
+	before, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("read container: %v", err)
+	}
+
+	if _, err = rewrapContainer(path, privateKey, newPublicKey); !errors.Is(err, lib.ErrEscrowWrapMismatch) {
+		t.Fatalf("rewrapContainer() error = %v, want %v", err, lib.ErrEscrowWrapMismatch)
+	}
+
+	after, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("read container: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("a failed rewrap modified the container")
+	}
+}
+
+func TestRewrapOptionsRejectLowOrderKey(t *testing.T) {
+	opts := RewrapOptions{
+		Escrow: &lib.Escrow{
+			Dir:            lib.StringPtr(t.TempDir()),
+			PrivateKeyPath: lib.StringPtr(filepath.Join(t.TempDir(), "escrow.key")),
+			NewPublicKey:   lib.StringPtr(hex.EncodeToString(make([]byte, lib.X25519KeyLen))),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+	}
+	if err := opts.Validate(); !errors.Is(err, lib.ErrEscrowPublicKeyInvalid) {
+		t.Fatalf("Validate() error = %v, want %v", err, lib.ErrEscrowPublicKeyInvalid)
+	}
+}
@@ -0,0 +1,12 @@
+package reseal
+
+import "github.com/namelesscorp/tvault-core/lib"
+
+// fsyncDir - flushes the directory a container or token file was renamed into.
+func fsyncDir(dir string) error {
+	if err := lib.SyncDir(dir); err != nil {
+		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealSyncDirError, lib.ErrMessageResealSyncDirError, "", err)
+	}
+
+	return nil
+}
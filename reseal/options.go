@@ -1,13 +1,21 @@
 package reseal
 
-import "github.com/namelesscorp/tvault-core/lib"
+import (
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/seal"
+	"github.com/namelesscorp/tvault-core/token"
+)
 
 type Options struct {
 	Container         *lib.Container
+	Token             *lib.Token
 	IntegrityProvider *lib.IntegrityProvider
 	TokenReader       *lib.Reader
 	TokenWriter       *lib.Writer
 	LogWriter         *lib.Writer
+	Holders           *lib.Holders
+	Agent             *lib.Agent
+	KMS               *lib.KMS
 }
 
 func (o *Options) Validate() error {
@@ -15,10 +23,12 @@ func (o *Options) Validate() error {
 		return err
 	}
 
-	if err := o.validateTokenReader(); err != nil {
+	if err := o.validateTokenWindow(); err != nil {
 		return err
 	}
 
+	// The token reader is checked by Reseal, once it is known that the agent
+	// does not hold the key.
 	if err := o.validateTokenWriter(); err != nil {
 		return err
 	}
@@ -30,15 +40,36 @@ func (o *Options) Validate() error {
 	return nil
 }
 
+// validateTokenWindow - checks the window format only; whether the tokens are
+// encrypted depends on the container and is checked by Reseal.
+func (o *Options) validateTokenWindow() error {
+	if _, err := token.ParseWindow(*o.Token.NotBefore, *o.Token.ExpiresAt); err != nil {
+		return lib.ValidationErr(lib.CategoryReseal, err)
+	}
+
+	return nil
+}
+
 func (o *Options) validateContainer() error {
 	switch {
 	case *o.Container.CurrentPath == "":
 		return lib.ValidationErr(lib.CategoryReseal, lib.ErrContainerCurrentPathRequired)
 	case *o.Container.FolderPath == "":
 		return lib.ValidationErr(lib.CategoryReseal, lib.ErrContainerFolderPathRequired)
+	}
+
+	if _, err := seal.DecodeEscrowPublicKey(*o.Container.EscrowPublicKey); err != nil {
+		return lib.ValidationErr(lib.CategoryReseal, err)
+	}
+
+	// An empty mode keeps the one the container was sealed with.
+	switch *o.Container.Preserve {
+	case "", lib.PreserveNone, lib.PreserveAll:
 	default:
-		return nil
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrContainerPreserveInvalid)
 	}
+
+	return nil
 }
 
 func (o *Options) validateTokenReader() error {
@@ -95,3 +126,99 @@ func (o *Options) validateLogWriter() error {
 
 	return nil
 }
+
+// ReshareOptions - options of token reshare: the container, the tokens that
+// open it and the quorum to split its key under.
+type ReshareOptions struct {
+	Container         *lib.Container
+	IntegrityProvider *lib.IntegrityProvider
+	Shamir            *lib.Shamir
+	TokenReader       *lib.Reader
+	TokenWriter       *lib.Writer
+	LogWriter         *lib.Writer
+	Holders           *lib.Holders
+}
+
+func (o *ReshareOptions) Validate() error {
+	if *o.Container.CurrentPath == "" {
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrReshareContainerPathRequired)
+	}
+
+	if err := o.validateShamir(); err != nil {
+		return err
+	}
+
+	opts := Options{
+		TokenReader: o.TokenReader,
+		TokenWriter: o.TokenWriter,
+		LogWriter:   o.LogWriter,
+	}
+	if err := opts.validateTokenReader(); err != nil {
+		return err
+	}
+
+	if err := opts.validateTokenWriter(); err != nil {
+		return err
+	}
+
+	return opts.validateLogWriter()
+}
+
+func (o *ReshareOptions) validateShamir() error {
+	switch {
+	case *o.Shamir.Shares == 0:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirSharesEqual0)
+	case *o.Shamir.Threshold == 0:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirThresholdEqual0)
+	case *o.Shamir.Shares < *o.Shamir.Threshold:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirSharesLessThanThreshold)
+	case *o.Shamir.Shares < 2:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirSharesLessThan2)
+	case *o.Shamir.Threshold < 2:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirThresholdLessThan2)
+	case *o.Shamir.Shares > 255:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirSharesGreaterThan255)
+	case *o.Shamir.Threshold > 255:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrShamirThresholdGreaterThan255)
+	default:
+		return nil
+	}
+}
+
+// RewrapOptions - options of escrow rewrap: the directory of containers, the
+// current escrow private key and the new escrow public key.
+type RewrapOptions struct {
+	Escrow     *lib.Escrow
+	InfoWriter *lib.Writer
+	LogWriter  *lib.Writer
+}
+
+func (o *RewrapOptions) Validate() error {
+	switch {
+	case *o.Escrow.Dir == "":
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrEscrowDirRequired)
+	case *o.Escrow.PrivateKeyPath == "":
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrEscrowPrivateKeyPathRequired)
+	case *o.Escrow.NewPublicKey == "":
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrEscrowNewPublicKeyRequired)
+	}
+
+	if _, err := seal.DecodeEscrowPublicKey(*o.Escrow.NewPublicKey); err != nil {
+		return lib.ValidationErr(lib.CategoryReseal, err)
+	}
+
+	if _, ok := lib.WriterTypes[*o.InfoWriter.Type]; !ok {
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrInfoWriterTypeInvalid)
+	}
+
+	if *o.InfoWriter.Type == lib.WriterTypeFile && *o.InfoWriter.Path == "" {
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrInfoWriterPathRequired)
+	}
+
+	if _, ok := lib.WriterFormats[*o.InfoWriter.Format]; !ok {
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrInfoWriterFormatInvalid)
+	}
+
+	opts := Options{LogWriter: o.LogWriter}
+	return opts.validateLogWriter()
+}
@@ -2,6 +2,9 @@ package reseal
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +18,7 @@ import (
 	"github.com/namelesscorp/tvault-core/container"
 	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
 	"github.com/namelesscorp/tvault-core/seal"
 	"github.com/namelesscorp/tvault-core/security"
 	"github.com/namelesscorp/tvault-core/shamir"
@@ -23,7 +27,13 @@ import (
 )
 
 // Reseal - processes a sealed container by decrypting, modifying, and re-encrypting it with updated metadata and tokens.
-func Reseal(opts Options) error {
+// Cancelling ctx stops packing and encryption before the new container replaces
+// the current one, removes the temporary file and returns an ErrCodeCanceled error.
+func Reseal(ctx context.Context, opts Options) (err error) {
+	defer func() {
+		err = lib.Canceled(ctx, lib.CategoryReseal, err)
+	}()
+
 	currentContainer := container.NewContainer(
 		*opts.Container.CurrentPath,
 		nil,
@@ -40,6 +50,15 @@ func Reseal(opts Options) error {
 		)
 	}
 
+	window, err := token.ParseWindow(*opts.Token.NotBefore, *opts.Token.ExpiresAt)
+	if err != nil {
+		return lib.ValidationErr(lib.CategoryReseal, err)
+	}
+
+	if !window.IsZero() && !hasTokenEnvelope(currentContainer) {
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrTokenWindowRequiresEncryption)
+	}
+
 	var comment = currentContainer.GetMetadata().Comment
 	if *opts.Container.Comment != comment {
 		comment = *opts.Container.Comment
@@ -56,75 +75,125 @@ func Reseal(opts Options) error {
 	}
 
 	var (
-		masterKey         []byte
 		originalRawTokens []string
+		resolveHolderKey  token.HolderKeyFunc
 	)
-	switch currentContainer.GetHeader().TokenType {
-	case token.TypeMaster, token.TypeShare:
-		derivedPassphrase := unseal.DeriveIntegrityProviderPassphrase(
-			*opts.IntegrityProvider.CurrentPassphrase,
-			currentContainer.GetHeader().Salt,
-		)
-
-		var tokenString string
-		tokenString, err := unseal.GetTokenString(opts.TokenReader)
-		if err != nil {
-			return lib.InternalErr(
-				lib.CategoryReseal,
-				lib.ErrCodeResealGetTokenStringError,
-				lib.ErrMessageResealGetTokenStringError,
-				"",
-				err,
-			)
+	masterKey, cached := unseal.CachedMasterKey(opts.Agent, currentContainer)
+	switch tokenType := currentContainer.GetHeader().TokenType; {
+	case cached:
+		// Without tokens there are none to write back; passphrase holders of
+		// re-issued shares are resolved from the holders file or prompted for.
+		if tokenType == token.TypeShare {
+			if resolveHolderKey, err = unseal.NewHolderKeyResolver(currentContainer, opts.Holders); err != nil {
+				return err
+			}
 		}
-
-		if originalRawTokens, err = extractRawTokens(tokenString, *opts.TokenReader.Format); err != nil {
-			return lib.InternalErr(
-				lib.CategoryReseal,
-				lib.ErrCodeResealParseTokensError,
-				lib.ErrMessageResealParseTokensError,
-				"",
-				err,
-			)
+	case tokenType == token.TypePlugin:
+		if masterKey, err = unseal.OpenPlugin(ctx, lib.CategoryReseal, currentContainer); err != nil {
+			return err
+		}
+	case tokenType == token.TypeKMS:
+		if masterKey, err = unseal.OpenKMS(ctx, lib.CategoryReseal, opts.KMS, currentContainer); err != nil {
+			return err
+		}
+	case tokenType == token.TypeMaster || tokenType == token.TypeShare:
+		if err = opts.validateTokenReader(); err != nil {
+			return err
 		}
 
-		var shares []shamir.Share
-		masterKey, shares, err = unseal.ParseTokens(
-			currentContainer.GetHeader().TokenType,
-			tokenString,
-			*opts.TokenReader.Format,
-			derivedPassphrase,
+		masterKey, originalRawTokens, resolveHolderKey, err = openTokens(
+			currentContainer,
+			opts.IntegrityProvider,
+			opts.TokenReader,
+			opts.Holders,
+			opts.LogWriter,
+			"reseal",
 		)
 		if err != nil {
-			return lib.InternalErr(
-				lib.CategoryReseal,
-				lib.ErrCodeResealParseTokensError,
-				lib.ErrMessageResealParseTokensError,
-				"",
-				err,
-			)
-		}
-
-		if len(masterKey) == 0 {
-			masterKey, err = unseal.RestoreMasterKey(shares, derivedPassphrase)
-			if err != nil {
-				return lib.InternalErr(
-					lib.CategoryReseal,
-					lib.ErrCodeResealRestoreMasterKeyError,
-					lib.ErrMessageResealRestoreMasterKeyError,
-					"",
-					err,
-				)
-			}
+			return err
 		}
-	case token.TypeNone:
-		var salt = currentContainer.GetHeader().Salt
+	case tokenType == token.TypeNone:
+		var (
+			salt       = currentContainer.GetHeader().Salt
+			passphrase = []byte(*opts.Container.Passphrase)
+		)
 		masterKey = lib.PBKDF2Key(
-			[]byte(*opts.Container.Passphrase),
+			passphrase,
 			salt[:],
 			currentContainer.GetHeader().Iterations,
 			lib.KeyLen,
 		)
+		secret.Wipe(passphrase)
+	}
+
+	// The key is moved into locked memory, wiped when reseal returns.
+	key := secret.From(masterKey)
+	defer func() {
+		key.Wipe()
+	}()
+	masterKey = key.Bytes()
+
+	// The current key was recovered above, so only a caller able to open the
+	// container can rotate it.
+	if *opts.Container.RotateKey {
+		var rotated []byte
+		if rotated, err = rotateMasterKey(currentContainer, *opts.Container.Passphrase); err != nil {
+			return err
+		}
+		key.Wipe()
+		key = secret.From(rotated)
+		masterKey = key.Bytes()
+
+		// The key provider wraps the new key, for the new container ID.
+		if plugin := currentContainer.GetMetadata().Keys.GetPlugin(); plugin != nil {
+			if plugin, err = seal.WrapPlugin(ctx, lib.CategoryReseal, plugin.Name, currentContainer.GetHeader().ID(), masterKey); err != nil {
+				return err
+			}
+			updateKeyBlock(currentContainer, func(keyBlock *container.KeyBlock) {
+				keyBlock.Plugin = plugin
+			})
+		}
+
+		// So does the transit key, at its latest version.
+		if kmsWrap := currentContainer.GetMetadata().Keys.GetKMS(); kmsWrap != nil {
+			if kmsWrap, err = seal.WrapKMS(ctx, lib.CategoryReseal, opts.KMS, unseal.KMSMount(opts.KMS, kmsWrap), kmsWrap.Key, masterKey); err != nil {
+				return err
+			}
+			updateKeyBlock(currentContainer, func(keyBlock *container.KeyBlock) {
+				keyBlock.KMS = kmsWrap
+			})
+		}
+
+		// The recovery code is not stored, so the new key cannot be wrapped
+		// to it; the stale wrap is dropped rather than left to fail.
+		if currentContainer.GetMetadata().Keys.HasRecovery() {
+			updateKeyBlock(currentContainer, func(keyBlock *container.KeyBlock) {
+				keyBlock.Recovery = ""
+			})
+			lib.WarningFormatted(opts.LogWriter, lib.Warning{
+				Operation: "reseal",
+				Message:   "recovery code was removed",
+				Details:   "the rotated key is not wrapped to the old recovery code; seal a new container to get one",
+			})
+		}
+	}
+
+	// The key is wrapped to the configured escrow key, or again to the one
+	// already recorded, so neither a rotation nor a reseal without the
+	// setting drops the escrow.
+	escrowPublicKey := *opts.Container.EscrowPublicKey
+	if escrow := currentContainer.GetMetadata().Keys.GetEscrow(); escrowPublicKey == "" && escrow != nil {
+		escrowPublicKey = escrow.PublicKey
+	}
+
+	escrow, err := seal.WrapEscrow(lib.CategoryReseal, escrowPublicKey, masterKey, currentContainer.GetHeader().Salt)
+	if err != nil {
+		return err
+	}
+	if escrow != nil {
+		updateKeyBlock(currentContainer, func(keyBlock *container.KeyBlock) {
+			keyBlock.Escrow = escrow
+		})
 	}
 
 	// One monotonic "PROGRESS <pct>" bar across the reseal, driven off the
@@ -144,8 +213,22 @@ func Reseal(opts Options) error {
 
 	// Walk the folder once for the stats the metadata/security score need before
 	// the payload is written; the same entries are handed to the packer below so
-	// the tree is not walked again to compress it.
-	entries, uncompressedSize, fileCount, fileNameList, err := zip.WalkFolder(*opts.Container.FolderPath)
+	// the tree is not walked again to compress it. Excluded paths are left out
+	// of both, so the score sees included names only. Without container
+	// -preserve the container keeps the mode it was sealed with.
+	rules, err := zip.LoadRules(*opts.Container.FolderPath, *opts.Container.Ignore)
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealCompressFolderError,
+			lib.ErrMessageResealCompressFolderError,
+			"",
+			err,
+		)
+	}
+	preserve := cmp.Or(*opts.Container.Preserve, currentContainer.GetMetadata().Preserve)
+	walker := &zip.Walker{Preserve: preserve == lib.PreserveAll}
+	entries, uncompressedSize, fileCount, excludedCount, fileNameList, err := walker.WalkFolder(*opts.Container.FolderPath, rules)
 	if err != nil {
 		return lib.InternalErr(
 			lib.CategoryReseal,
@@ -155,6 +238,10 @@ func Reseal(opts Options) error {
 			err,
 		)
 	}
+	seal.WarnSkipped(opts.LogWriter, "reseal", walker.Skipped)
+	if !walker.Preserve {
+		preserve = ""
+	}
 
 	packPhase := progress.Phase(0, 100, uncompressedSize)
 	if p, ok := comp.(interface{ SetProgress(func(int64)) }); ok {
@@ -182,7 +269,11 @@ func Reseal(opts Options) error {
 		// has been streamed, so it need not be known here.
 		UncompressedSize: uncompressedSize,
 		FileCount:        fileCount,
+		ExcludedCount:    excludedCount,
+		SkippedCount:     int64(len(walker.Skipped)),
+		Preserve:         preserve,
 		SecurityScore:    secScore.Calculate(),
+		Keys:             currentContainer.GetMetadata().Keys,
 	})
 
 	currentContainer.SetMasterKey(masterKey)
@@ -194,9 +285,12 @@ func Reseal(opts Options) error {
 	// written. Any failure in token generation (integrity artifacts, Shamir split,
 	// encryption) then aborts the whole reseal without having touched the existing
 	// container or token files.
-	var tokenBuf bytes.Buffer
-	if tokenType != token.TypeNone {
-		if err = generateResealTokens(opts, currentContainer, masterKey, originalRawTokens, &tokenBuf); err != nil {
+	var (
+		tokenBuf    bytes.Buffer
+		writeTokens = hasTokens(tokenType) && (!cached || reissueTokens(opts, window))
+	)
+	if writeTokens {
+		if err = generateResealTokens(opts, currentContainer, masterKey, originalRawTokens, resolveHolderKey, window, &tokenBuf); err != nil {
 			return err
 		}
 	}
@@ -206,11 +300,15 @@ func Reseal(opts Options) error {
 	// staged on disk and compression overlaps with encryption. The new container
 	// is written to a temp file and atomically renamed, so the original is only
 	// destroyed once a complete new one is in place.
-	if err = compressEncryptAtomic(currentContainer, comp, entries, targetContainerPath); err != nil {
+	if err = compressEncryptAtomic(ctx, currentContainer, comp, entries, targetContainerPath); err != nil {
 		return err
 	}
 
-	if tokenType == token.TypeNone {
+	// The agent caches the key under the ID of the written container, which a
+	// rotation has changed.
+	unseal.CacheMasterKey(opts.Agent, currentContainer, masterKey)
+
+	if !writeTokens {
 		progress.Finish()
 		return nil
 	}
@@ -225,18 +323,115 @@ func Reseal(opts Options) error {
 	return nil
 }
 
+// openTokens - reads the tokens for cont and recovers its master key from a
+// master token or from shares. Returns the raw token strings, so they can be
+// written back unchanged, and the holder key resolver they were opened with.
+func openTokens(
+	cont container.Container,
+	integrityProviderOpts *lib.IntegrityProvider,
+	tokenReaderOpts *lib.Reader,
+	holdersOpts *lib.Holders,
+	logWriter *lib.Writer,
+	operation string,
+) ([]byte, []string, token.HolderKeyFunc, error) {
+	derivedPassphrase := unseal.DeriveIntegrityProviderPassphrase(
+		*integrityProviderOpts.CurrentPassphrase,
+		cont.GetHeader().Salt,
+	)
+	defer secret.Wipe(derivedPassphrase)
+
+	tokenString, err := unseal.GetTokenString(tokenReaderOpts)
+	if err != nil {
+		return nil, nil, nil, lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealGetTokenStringError,
+			lib.ErrMessageResealGetTokenStringError,
+			"",
+			err,
+		)
+	}
+
+	rawTokens, err := extractRawTokens(tokenString, *tokenReaderOpts.Format)
+	if err != nil {
+		return nil, nil, nil, lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealParseTokensError,
+			lib.ErrMessageResealParseTokensError,
+			"",
+			err,
+		)
+	}
+
+	resolveHolderKey, err := unseal.NewHolderKeyResolver(cont, holdersOpts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	masterKey, shares, err := unseal.ParseTokens(
+		cont.GetHeader().TokenType,
+		cont.GetHeader().KeyEpoch,
+		tokenString,
+		*tokenReaderOpts.Format,
+		derivedPassphrase,
+		resolveHolderKey,
+	)
+	if token.IsValidityError(err) {
+		return nil, nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, nil, lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealParseTokensError,
+			lib.ErrMessageResealParseTokensError,
+			"",
+			err,
+		)
+	}
+
+	if len(masterKey) == 0 {
+		// The shares only serve to recover the key; the share of a master
+		// token is the key itself, returned to the caller.
+		defer shamir.WipeShares(shares)
+
+		var corrupted []byte
+		masterKey, corrupted, err = unseal.RecoverMasterKey(cont, shares, derivedPassphrase)
+		if err != nil {
+			return nil, nil, nil, lib.InternalErr(
+				lib.CategoryReseal,
+				lib.ErrCodeResealRestoreMasterKeyError,
+				lib.ErrMessageResealRestoreMasterKeyError,
+				lib.ErrDetails(err),
+				err,
+			)
+		}
+
+		if len(corrupted) > 0 {
+			lib.WarningFormatted(logWriter, lib.Warning{
+				Operation: operation,
+				Message:   "corrupted shares were excluded",
+				Details:   "share ids inconsistent with the recovered key: " + shamir.FormatIDs(corrupted),
+			})
+		}
+	}
+
+	return masterKey, rawTokens, resolveHolderKey, nil
+}
+
 // generateResealTokens - produces the token output for reseal into w, without
 // touching any files. Tokens are only re-issued (fresh Shamir split + fresh
-// AES-CTR IV) when a new integrity-provider passphrase is set; otherwise the
-// original token strings are written back verbatim so they remain unchanged.
+// AES-CTR IV) when a new integrity-provider passphrase or a validity window is
+// set, or the master key was rotated; otherwise the original token strings are
+// written back verbatim so they remain unchanged.
 func generateResealTokens(
 	opts Options,
 	cont container.Container,
 	masterKey []byte,
 	originalRawTokens []string,
+	resolveHolderKey token.HolderKeyFunc,
+	window token.Window,
 	w io.Writer,
 ) error {
-	if !isIntegrityProviderPassphraseChanged(opts.IntegrityProvider) {
+	if !reissueTokens(opts, window) {
 		return writeRawTokens(
 			cont.GetHeader().TokenType,
 			originalRawTokens,
@@ -256,6 +451,7 @@ func generateResealTokens(
 	if err != nil {
 		return err
 	}
+	defer secret.Wipe(additionalPassword)
 
 	switch cont.GetHeader().TokenType {
 	case token.TypeShare:
@@ -263,14 +459,62 @@ func generateResealTokens(
 			numShares = int(cont.GetHeader().Shares)
 			threshold = int(cont.GetHeader().Threshold)
 		)
-		return seal.SaveShareTokens(
+
+		// A new split needs a new share for every holder. Without them all
+		// only a new window can be issued, on the current split.
+		absent, err := absentHolders(cont.GetMetadata().Keys, originalRawTokens, opts.Holders)
+		if err != nil {
+			return err
+		}
+		if len(absent) > 0 {
+			if *opts.Container.RotateKey || isIntegrityProviderPassphraseChanged(opts.IntegrityProvider) || len(originalRawTokens) == 0 {
+				return lib.NewError(
+					lib.ErrorTypeValidation,
+					lib.CategoryReseal,
+					lib.ErrCodeResealHoldersAbsent,
+					lib.ErrResealHoldersAbsent.Error(),
+					strings.Join(absent, ", "),
+					lib.SuggestionResealHoldersAbsent,
+					lib.ErrResealHoldersAbsent,
+				)
+			}
+
+			return reissueGivenShares(opts, cont, originalRawTokens, additionalPassword, resolveHolderKey, window, w)
+		}
+
+		holderKeys, err := resealHolderKeys(cont.GetMetadata().Keys, resolveHolderKey)
+		if err != nil {
+			return err
+		}
+
+		policy := cont.GetMetadata().Keys.GetPolicy()
+		shares, feldman, err := seal.SplitMasterKey(
+			masterKey,
 			&lib.Shamir{
 				Shares:    &numShares,
 				Threshold: &threshold,
 			},
-			additionalPassword,
-			masterKey,
+			policy,
 			integrityProvider,
+		)
+		if err != nil {
+			return err
+		}
+		defer shamir.WipeShares(shares)
+
+		// A fresh Feldman split comes with fresh commitments, which must replace
+		// the old ones in the metadata written with the new container.
+		if feldman != nil {
+			setFeldman(cont, feldman)
+		}
+
+		return seal.SaveShareTokens(
+			shares,
+			policy,
+			additionalPassword,
+			holderKeys,
+			window,
+			cont.GetHeader().KeyEpoch,
 			*opts.TokenWriter.Format,
 			w,
 		)
@@ -278,6 +522,8 @@ func generateResealTokens(
 		return seal.SaveMasterToken(
 			additionalPassword,
 			masterKey,
+			window,
+			cont.GetHeader().KeyEpoch,
 			*opts.TokenWriter.Format,
 			w,
 		)
@@ -286,6 +532,241 @@ func generateResealTokens(
 	return nil
 }
 
+// reissueTokens - reports whether reseal issues new tokens rather than writing
+// the original ones back. A key cached by the agent keeps the tokens unchanged
+// and valid, so they are then not written at all.
+func reissueTokens(opts Options, window token.Window) bool {
+	return isIntegrityProviderPassphraseChanged(opts.IntegrityProvider) || !window.IsZero() || *opts.Container.RotateKey
+}
+
+// hasTokens - reports whether containers of tokenType are opened with tokens.
+func hasTokens(tokenType byte) bool {
+	return tokenType == token.TypeMaster || tokenType == token.TypeShare
+}
+
+// hasTokenEnvelope - reports whether the tokens of cont are encrypted, so a
+// validity window inside them is authenticated: with the HMAC integrity
+// passphrase or to their holders.
+func hasTokenEnvelope(cont container.Container) bool {
+	if !hasTokens(cont.GetHeader().TokenType) {
+		return false
+	}
+
+	return cont.GetHeader().IntegrityProviderType == integrity.TypeHMAC ||
+		len(cont.GetMetadata().Keys.HolderNames()) > 0
+}
+
+// rotateMasterKey - replaces the master key of cont and increments its key
+// epoch, so tokens issued before are rejected as revoked. Token containers get
+// a random key and a key check value for it; a container without tokens
+// derives its key from the passphrase, so it gets a fresh salt instead.
+func rotateMasterKey(cont container.Container, passphrase string) ([]byte, error) {
+	var (
+		header    = cont.GetHeader()
+		masterKey = make([]byte, lib.KeyLen)
+	)
+	header.KeyEpoch++
+
+	if header.TokenType == token.TypeNone {
+		if _, err := rand.Read(header.Salt[:]); err != nil {
+			return nil, lib.CryptoErr(
+				lib.CategoryReseal,
+				lib.ErrCodeResealRotateKeyError,
+				lib.ErrMessageResealRotateKeyError,
+				"",
+				err,
+			)
+		}
+
+		masterKey = lib.PBKDF2Key([]byte(passphrase), header.Salt[:], header.Iterations, lib.KeyLen)
+	} else {
+		if _, err := rand.Read(masterKey); err != nil {
+			return nil, lib.CryptoErr(
+				lib.CategoryReseal,
+				lib.ErrCodeResealRotateKeyError,
+				lib.ErrMessageResealRotateKeyError,
+				"",
+				err,
+			)
+		}
+
+		updateKeyBlock(cont, func(keyBlock *container.KeyBlock) {
+			keyBlock.KeyCheck = container.NewKeyCheck(masterKey)
+		})
+	}
+
+	cont.SetHeader(header)
+
+	return masterKey, nil
+}
+
+// setFeldman - records feldman in the key block of the container metadata.
+func setFeldman(cont container.Container, feldman *shamir.Feldman) {
+	updateKeyBlock(cont, func(keyBlock *container.KeyBlock) {
+		keyBlock.Feldman = feldman
+	})
+}
+
+// updateKeyBlock - applies update to a copy of the key block of the container
+// metadata and stores it back.
+func updateKeyBlock(cont container.Container, update func(*container.KeyBlock)) {
+	var (
+		metadata = cont.GetMetadata()
+		keyBlock container.KeyBlock
+	)
+	if metadata.Keys != nil {
+		keyBlock = *metadata.Keys
+	}
+
+	update(&keyBlock)
+	metadata.Keys = &keyBlock
+	cont.SetMetadata(metadata)
+}
+
+// resealHolderKeys - returns the keys re-issued shares are sealed to, in share
+// order. X25519 holders are sealed to the public key recorded in the key block;
+// passphrase holders reuse the key their share was opened with, or the one of
+// the holders file, so every holder keeps their own secret across a reseal.
+// absentHolders has checked that every passphrase holder is one of these.
+func resealHolderKeys(keyBlock *container.KeyBlock, resolveHolderKey token.HolderKeyFunc) ([]token.HolderKey, error) {
+	if keyBlock == nil || len(keyBlock.Holders) == 0 {
+		return nil, nil
+	}
+
+	holderKeys := make([]token.HolderKey, 0, len(keyBlock.Holders))
+	for _, record := range keyBlock.Holders {
+		holderKey, err := resealHolderKey(record, resolveHolderKey)
+		if err != nil {
+			return nil, err
+		}
+
+		holderKeys = append(holderKeys, holderKey)
+	}
+
+	return holderKeys, nil
+}
+
+// resealHolderKey - returns the key a re-issued share of the holder of record
+// is sealed to.
+func resealHolderKey(record container.HolderRecord, resolveHolderKey token.HolderKeyFunc) (token.HolderKey, error) {
+	holderKey := token.HolderKey{
+		Name: record.Name,
+		Kind: token.ConvertHolderNameToKind(record.Kind),
+	}
+
+	var err error
+	switch holderKey.Kind {
+	case token.HolderKindX25519:
+		holderKey.Key, err = token.DecodeX25519Key(record.PublicKey)
+	default:
+		holderKey.Key, err = resolveHolderKey(record.ID, holderKey.Kind)
+	}
+	if err != nil {
+		return token.HolderKey{}, lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealLoadHoldersError,
+			lib.ErrMessageResealLoadHoldersError,
+			record.Name,
+			err,
+		)
+	}
+
+	return holderKey, nil
+}
+
+// absentHolders - returns the names of the passphrase holders whose share was
+// not among rawTokens and who are not named in the holders file. Their keys
+// are unknown, so no new share can be sealed to them.
+func absentHolders(keyBlock *container.KeyBlock, rawTokens []string, holdersOpts *lib.Holders) ([]string, error) {
+	if keyBlock == nil || len(keyBlock.Holders) == 0 {
+		return nil, nil
+	}
+
+	var named []token.Holder
+	if holdersOpts != nil && *holdersOpts.Path != "" {
+		var err error
+		if named, err = token.LoadHolders(*holdersOpts.Path); err != nil {
+			return nil, lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealLoadHoldersError, lib.ErrMessageResealLoadHoldersError, "", err)
+		}
+	}
+
+	given := make(map[int]bool, len(rawTokens))
+	for _, raw := range rawTokens {
+		if id, _, ok := token.PeekHolder([]byte(raw)); ok {
+			given[id] = true
+		}
+	}
+
+	var absent []string
+	for _, record := range keyBlock.Holders {
+		if token.ConvertHolderNameToKind(record.Kind) == token.HolderKindX25519 || given[record.ID] {
+			continue
+		}
+		if _, ok := token.FindHolder(named, record.Name); ok {
+			continue
+		}
+
+		absent = append(absent, record.Name)
+	}
+
+	return absent, nil
+}
+
+// reissueGivenShares - re-issues the tokens of the shares in rawTokens with
+// window, keeping the current split and its commitments. The holders who did
+// not take part keep their tokens, which stay valid.
+func reissueGivenShares(
+	opts Options,
+	cont container.Container,
+	rawTokens []string,
+	additionalPassword []byte,
+	resolveHolderKey token.HolderKeyFunc,
+	window token.Window,
+	w io.Writer,
+) error {
+	list, err := json.Marshal(token.List{TokenList: rawTokens})
+	if err != nil {
+		return lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealParseTokensError, lib.ErrMessageResealParseTokensError, "", err)
+	}
+
+	_, shares, err := unseal.ParseTokens(
+		token.TypeShare,
+		cont.GetHeader().KeyEpoch,
+		string(list),
+		lib.ReaderFormatJSON,
+		additionalPassword,
+		resolveHolderKey,
+	)
+	if err != nil {
+		return lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealParseTokensError, lib.ErrMessageResealParseTokensError, "", err)
+	}
+	defer shamir.WipeShares(shares)
+
+	keyBlock := cont.GetMetadata().Keys
+	holderKeys := make([]token.HolderKey, len(keyBlock.Holders))
+	for _, share := range shares {
+		record, ok := keyBlock.Holder(int(share.ID))
+		if !ok || record.ID < 1 || record.ID > len(holderKeys) {
+			return lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealLoadHoldersError, lib.ErrMessageResealLoadHoldersError, "", lib.ErrHolderNotFound)
+		}
+
+		if holderKeys[record.ID-1], err = resealHolderKey(record, resolveHolderKey); err != nil {
+			return err
+		}
+	}
+
+	return seal.SaveShareTokens(
+		shares,
+		keyBlock.GetPolicy(),
+		additionalPassword,
+		holderKeys,
+		window,
+		cont.GetHeader().KeyEpoch,
+		*opts.TokenWriter.Format,
+		w,
+	)
+}
+
 // newResealCompressor selects a compressor instance for the container's
 // compression type. Both "zip" (Deflate) and "none" (Store) are produced by the
 // zip package.
@@ -305,13 +786,14 @@ func newResealCompressor(compressionType string) (compression.Compression, error
 // compression overlaps with encryption. It delegates the durable temp-file +
 // rename write to writeContainerAtomic.
 func compressEncryptAtomic(
+	ctx context.Context,
 	cont container.Container,
 	comp compression.Compression,
 	entries []zip.Entry,
 	targetPath string,
 ) error {
 	packer, ok := comp.(interface {
-		PackEntriesTo([]zip.Entry, io.Writer) error
+		PackEntriesTo(context.Context, []zip.Entry, io.Writer) error
 	})
 	if !ok {
 		return lib.ErrUnknownCompressionType
@@ -323,7 +805,7 @@ func compressEncryptAtomic(
 	go func() {
 		defer func() { _ = pw.Close() }()
 
-		if packErr := packer.PackEntriesTo(entries, pw); packErr != nil {
+		if packErr := packer.PackEntriesTo(ctx, entries, pw); packErr != nil {
 			_ = pw.CloseWithError(packErr)
 			packErrCh <- packErr
 
@@ -333,7 +815,7 @@ func compressEncryptAtomic(
 		packErrCh <- nil
 	}()
 
-	if err := writeContainerAtomic(cont, pr, targetPath); err != nil {
+	if err := writeContainerAtomic(ctx, cont, pr, targetPath); err != nil {
 		// Unblock the packer if it is still mid-write, then reap it.
 		_ = pr.CloseWithError(err)
 		<-packErrCh
@@ -352,7 +834,7 @@ func compressEncryptAtomic(
 // directory and atomically renames it over targetPath. This guarantees the
 // previous container is never truncated in place: it is replaced only once a
 // complete, valid new container exists on disk.
-func writeContainerAtomic(cont container.Container, src io.Reader, targetPath string) error {
+func writeContainerAtomic(ctx context.Context, cont container.Container, src io.Reader, targetPath string) error {
 	tmp, err := os.CreateTemp(filepath.Dir(targetPath), ".tvault-container-*.tmp")
 	if err != nil {
 		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealWriteContainerError, lib.ErrMessageResealWriteContainerError, "", err)
@@ -370,7 +852,7 @@ func writeContainerAtomic(cont container.Container, src io.Reader, targetPath st
 	// WriteEncrypted fsyncs the temp file's contents before returning, so the
 	// data is durable before the rename below.
 	cont.SetPath(tmpPath)
-	if err = cont.WriteEncrypted(src, nil); err != nil {
+	if err = cont.WriteEncrypted(ctx, src, nil); err != nil {
 		return lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealEncryptContainerError, lib.ErrMessageResealEncryptContainerError, "", err)
 	}
 
@@ -439,6 +921,12 @@ func writeTokensAtomic(writerOpts *lib.Writer, data []byte) error {
 			return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealWriteTokensError, lib.ErrMessageResealWriteTokensError, "", err)
 		}
 
+		return nil
+	case lib.WriterTypeStderr:
+		if _, err := fmt.Fprintln(os.Stderr, string(data)); err != nil {
+			return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealWriteTokensError, lib.ErrMessageResealWriteTokensError, "", err)
+		}
+
 		return nil
 	default:
 		return lib.ErrUnknownWriterType
@@ -506,19 +994,7 @@ func isIntegrityProviderPassphraseChanged(integrityProviderOpts *lib.IntegrityPr
 // the individual (still-encrypted) token strings, without decrypting them, so
 // they can be written back verbatim when tokens are not being re-issued.
 func extractRawTokens(tokenString, readerFormat string) ([]string, error) {
-	switch readerFormat {
-	case lib.ReaderFormatPlaintext:
-		return strings.Split(tokenString, "|"), nil
-	case lib.ReaderFormatJSON:
-		var list token.List
-		if err := json.Unmarshal([]byte(tokenString), &list); err != nil {
-			return nil, err
-		}
-
-		return list.TokenList, nil
-	default:
-		return nil, lib.ErrUnknownReaderType
-	}
+	return unseal.SplitTokens(tokenString, readerFormat)
 }
 
 // writeRawTokens - writes the original token strings back to the token writer
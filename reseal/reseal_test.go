@@ -2,15 +2,20 @@ package reseal
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/namelesscorp/tvault-core/compression/zip"
 	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/seal"
 	"github.com/namelesscorp/tvault-core/token"
+	"github.com/namelesscorp/tvault-core/unseal"
 )
 
 // failingReader always fails on Read with a non-EOF error, so WriteEncrypted
@@ -172,7 +177,7 @@ func TestWriteContainerAtomicSuccess(t *testing.T) {
 	key := bytes.Repeat([]byte{0x01}, lib.KeyLen)
 	cont := container.NewContainer(targetPath, key, container.Metadata{Tags: []string{}}, container.Header{})
 
-	if err := writeContainerAtomic(cont, bytes.NewReader([]byte("plaintext-payload")), targetPath); err != nil {
+	if err := writeContainerAtomic(context.Background(), cont, bytes.NewReader([]byte("plaintext-payload")), targetPath); err != nil {
 		t.Fatalf("writeContainerAtomic() error: %v", err)
 	}
 
@@ -205,7 +210,7 @@ func TestWriteContainerAtomicFailurePreservesTarget(t *testing.T) {
 
 	// A source that errors mid-read makes the encrypt fail after the temp file is
 	// created but before the target would be replaced.
-	err := writeContainerAtomic(cont, &failingReader{}, targetPath)
+	err := writeContainerAtomic(context.Background(), cont, &failingReader{}, targetPath)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -222,6 +227,55 @@ func TestWriteContainerAtomicFailurePreservesTarget(t *testing.T) {
 	}
 }
 
+// TestCompressEncryptAtomicCancelPreservesTarget - a cancelled reseal leaves the
+// current container in place and removes its temporary file.
+func TestCompressEncryptAtomicCancelPreservesTarget(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		targetPath = filepath.Join(dir, "vault.tvlt")
+		folderPath = filepath.Join(dir, "folder")
+	)
+
+	const original = "ORIGINAL-CONTAINER-BYTES"
+	if err := os.WriteFile(targetPath, []byte(original), 0o600); err != nil {
+		t.Fatalf("seed target: %v", err)
+	}
+	if err := os.MkdirAll(folderPath, 0o700); err != nil {
+		t.Fatalf("create folder: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(folderPath, name), bytes.Repeat([]byte(name), 4096), 0o600); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	entries, _, _, _, err := zip.WalkFolder(folderPath)
+	if err != nil {
+		t.Fatalf("WalkFolder() error = %v", err)
+	}
+
+	key := bytes.Repeat([]byte{0x01}, lib.KeyLen)
+	cont := container.NewContainer(targetPath, key, container.Metadata{Tags: []string{}}, container.Header{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err = compressEncryptAtomic(ctx, cont, zip.New(), entries, targetPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("compressEncryptAtomic() error = %v, want context.Canceled", err)
+	}
+
+	got, readErr := os.ReadFile(targetPath)
+	if readErr != nil {
+		t.Fatalf("original container gone: %v", readErr)
+	}
+	if string(got) != original {
+		t.Fatalf("original container mutated: got %q, want %q", got, original)
+	}
+	if n := countTempFiles(t, dir); n != 0 {
+		t.Fatalf("leftover temp files after cancel: %d", n)
+	}
+}
+
 func TestWriteTokensAtomicFile(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "tokens.json")
@@ -292,3 +346,188 @@ func equalStrings(a, b []string) bool {
 	}
 	return true
 }
+
+func TestResealRotateKey(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		targetPath = filepath.Join(dir, "vault.tvlt")
+		folderPath = filepath.Join(dir, "folder")
+		oldTokens  = filepath.Join(dir, "old.json")
+		newTokens  = filepath.Join(dir, "new.json")
+	)
+
+	if err := os.MkdirAll(folderPath, 0o700); err != nil {
+		t.Fatalf("create folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(folderPath, "file.txt"), []byte("content"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	header, err := container.NewHeader(0, integrity.TypeNone, token.TypeMaster, 0, 0)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+
+	cont := container.NewContainer(targetPath, nil, container.Metadata{Tags: []string{}}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("payload")), []byte("passphrase")); err != nil {
+		t.Fatalf("WriteEncrypted() error = %v", err)
+	}
+	oldKey := cont.GetMasterKey()
+
+	// The container is escrowed; rotation must wrap the new key to the same
+	// escrow key without being given it again.
+	escrowPrivateKey, escrowPublicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+	escrow, err := container.NewEscrow(oldKey, escrowPublicKey, header.Salt)
+	if err != nil {
+		t.Fatalf("NewEscrow() error = %v", err)
+	}
+	cont.SetMetadata(container.Metadata{Tags: []string{}, Keys: &container.KeyBlock{Escrow: escrow}})
+	if err = cont.Rewrite(targetPath + ".escrowed"); err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if err = os.Rename(targetPath+".escrowed", targetPath); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	var tokenBuf bytes.Buffer
+	if err = seal.SaveMasterToken(nil, oldKey, token.Window{}, 0, lib.WriterFormatJSON, &tokenBuf); err != nil {
+		t.Fatalf("SaveMasterToken() error = %v", err)
+	}
+	if err = os.WriteFile(oldTokens, tokenBuf.Bytes(), 0o600); err != nil {
+		t.Fatalf("write tokens: %v", err)
+	}
+
+	opts := Options{
+		Container: &lib.Container{
+			Name:            lib.StringPtr(""),
+			NewPath:         lib.StringPtr(""),
+			CurrentPath:     lib.StringPtr(targetPath),
+			FolderPath:      lib.StringPtr(folderPath),
+			Passphrase:      lib.StringPtr(""),
+			Comment:         lib.StringPtr(""),
+			Tags:            lib.StringPtr(""),
+			RotateKey:       lib.BoolPtr(true),
+			EscrowPublicKey: lib.StringPtr(""),
+			Ignore:          &[]string{},
+			Preserve:        lib.StringPtr(""),
+		},
+		Token:             &lib.Token{NotBefore: lib.StringPtr(""), ExpiresAt: lib.StringPtr("")},
+		IntegrityProvider: &lib.IntegrityProvider{CurrentPassphrase: lib.StringPtr(""), NewPassphrase: lib.StringPtr("")},
+		TokenReader: &lib.Reader{
+			Type:   lib.StringPtr(lib.ReaderTypeFile),
+			Path:   lib.StringPtr(oldTokens),
+			Flag:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.ReaderFormatJSON),
+		},
+		TokenWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(newTokens),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeStdout),
+			Path:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{Path: lib.StringPtr("")},
+	}
+	if err = opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err = Reseal(context.Background(), opts); err != nil {
+		t.Fatalf("Reseal() error = %v", err)
+	}
+
+	rotated := container.NewContainer(targetPath, nil, container.Metadata{Tags: []string{}}, container.Header{})
+	if err = rotated.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if rotated.GetHeader().KeyEpoch != 1 {
+		t.Fatalf("KeyEpoch = %d, want 1", rotated.GetHeader().KeyEpoch)
+	}
+
+	oldTokenString, _ := os.ReadFile(oldTokens)
+	if _, _, err = unseal.ParseTokens(token.TypeMaster, 1, string(oldTokenString), lib.ReaderFormatJSON, nil, nil); !errors.Is(err, lib.ErrTokenRevoked) {
+		t.Fatalf("ParseTokens() of the old token error = %v, want %v", err, lib.ErrTokenRevoked)
+	}
+
+	newTokenString, _ := os.ReadFile(newTokens)
+	newKey, _, err := unseal.ParseTokens(token.TypeMaster, 1, string(newTokenString), lib.ReaderFormatJSON, nil, nil)
+	if err != nil {
+		t.Fatalf("ParseTokens() of the new token error = %v", err)
+	}
+	if bytes.Equal(newKey, oldKey) {
+		t.Fatal("master key was not rotated")
+	}
+	if !unseal.CheckMasterKey(rotated, newKey) || unseal.CheckMasterKey(rotated, oldKey) {
+		t.Error("key check does not match the rotated key")
+	}
+
+	escrowKey, err := rotated.GetMetadata().Keys.OpenEscrow(escrowPrivateKey, rotated.GetHeader().Salt)
+	if err != nil {
+		t.Fatalf("OpenEscrow() error = %v", err)
+	}
+	if !bytes.Equal(escrowKey, newKey) {
+		t.Error("escrow does not wrap the rotated key")
+	}
+}
+
+func TestAbsentHolders(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		salt  = []byte("0123456789abcdef")
+		names = []string{"alice", "bob", "carol"}
+	)
+
+	keyBlock := &container.KeyBlock{}
+	holderKeys := make([]token.HolderKey, 0, len(names))
+	for i, name := range names {
+		keyBlock.Holders = append(keyBlock.Holders, container.HolderRecord{ID: i + 1, Name: name, Kind: token.HolderKindNamePassphrase})
+		holderKeys = append(holderKeys, token.HolderKey{Name: name, Kind: token.HolderKindPassphrase, Key: token.DeriveHolderKey(name, salt, name)})
+	}
+
+	shares, _, err := seal.SplitMasterKey(bytes.Repeat([]byte{0x42}, 32), &lib.Shamir{Shares: lib.IntPtr(3), Threshold: lib.IntPtr(2)}, nil, integrity.NewNoneProvider())
+	if err != nil {
+		t.Fatalf("SplitMasterKey() error = %v", err)
+	}
+
+	var tokenBuf bytes.Buffer
+	if err = seal.SaveShareTokens(shares, nil, nil, holderKeys, token.Window{}, 0, lib.WriterFormatJSON, &tokenBuf); err != nil {
+		t.Fatalf("SaveShareTokens() error = %v", err)
+	}
+	rawTokens, err := extractRawTokens(tokenBuf.String(), lib.ReaderFormatJSON)
+	if err != nil {
+		t.Fatalf("extractRawTokens() error = %v", err)
+	}
+
+	holdersPath := filepath.Join(dir, "holders.json")
+	if err = os.WriteFile(holdersPath, []byte(`{"holders":[{"name":"carol","passphrase":"carol"}]}`), 0o600); err != nil {
+		t.Fatalf("write holders: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		tokens  []string
+		holders string
+		want    []string
+	}{
+		{name: "all tokens", tokens: rawTokens, want: nil},
+		{name: "two tokens", tokens: rawTokens[:2], want: []string{"carol"}},
+		{name: "two tokens and holders file", tokens: rawTokens[:2], holders: holdersPath, want: nil},
+		{name: "no tokens", tokens: nil, holders: holdersPath, want: []string{"alice", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := absentHolders(keyBlock, tt.tokens, &lib.Holders{Path: lib.StringPtr(tt.holders)})
+			if err != nil {
+				t.Fatalf("absentHolders() error = %v", err)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("absentHolders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
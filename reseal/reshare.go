@@ -0,0 +1,197 @@
+package reseal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+	"github.com/namelesscorp/tvault-core/seal"
+	"github.com/namelesscorp/tvault-core/shamir"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+// Reshare - recovers the master key of a share container from its tokens and
+// splits it under a new quorum. Only the header and metadata are rewritten:
+// the encrypted payload is copied unchanged, so no source folder is needed.
+// The master key itself is kept, so a threshold of old shares still recovers
+// it: a warning on the log writer says so. Once ctx is cancelled the container
+// is left as it was.
+func Reshare(ctx context.Context, opts ReshareOptions) error {
+	cont := container.NewContainer(
+		*opts.Container.CurrentPath,
+		nil,
+		container.Metadata{Tags: make([]string, 0)},
+		container.Header{},
+	)
+	if err := cont.Read(); err != nil {
+		return lib.IOErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealOpenContainerError,
+			lib.ErrMessageResealOpenContainerError,
+			"",
+			err,
+		)
+	}
+
+	keyBlock := cont.GetMetadata().Keys
+	switch {
+	case cont.GetHeader().TokenType != token.TypeShare:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrReshareShareTokenRequired)
+	case keyBlock.GetPolicy() != nil:
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrResharePolicyUnsupported)
+	case len(keyBlock.HolderNames()) > 0 && *opts.Holders.NewPath == "":
+		return lib.ValidationErr(lib.CategoryReseal, lib.ErrReshareNewHoldersRequired)
+	}
+
+	masterKey, _, _, err := openTokens(
+		cont,
+		opts.IntegrityProvider,
+		opts.TokenReader,
+		opts.Holders,
+		opts.LogWriter,
+		"reshare",
+	)
+	if err != nil {
+		return err
+	}
+	key := secret.From(masterKey)
+	defer key.Wipe()
+	masterKey = key.Bytes()
+
+	// A threshold of shares combines to some key whether or not they belong
+	// together; make sure it is this container's before issuing shares of it.
+	if err = cont.CheckKey(masterKey); err != nil {
+		return lib.CryptoErr(
+			lib.CategoryReseal,
+			lib.ErrCodeReshareCheckKeyError,
+			lib.ErrMessageReshareCheckKeyError,
+			"",
+			err,
+		)
+	}
+
+	salt := cont.GetHeader().Salt
+	integrityProvider, additionalPassword, err := newIntegrityArtifacts(
+		&lib.IntegrityProvider{
+			Type:          lib.StringPtr(integrity.ConvertIDToName(cont.GetHeader().IntegrityProviderType)),
+			NewPassphrase: getIntegrityProviderPassphrasePtr(opts.IntegrityProvider),
+		},
+		salt[:],
+	)
+	if err != nil {
+		return err
+	}
+	defer secret.Wipe(additionalPassword)
+
+	holders, holderRecords, err := seal.LoadHolders(&lib.Holders{Path: opts.Holders.NewPath}, opts.Shamir, nil)
+	if err != nil {
+		return err
+	}
+
+	holderKeys, err := seal.HolderSealKeys(holders, salt[:])
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategoryReseal,
+			lib.ErrCodeResealLoadHoldersError,
+			lib.ErrMessageResealLoadHoldersError,
+			"",
+			err,
+		)
+	}
+
+	shares, feldman, err := seal.SplitMasterKey(masterKey, opts.Shamir, nil, integrityProvider)
+	if err != nil {
+		return err
+	}
+	defer shamir.WipeShares(shares)
+
+	var tokenBuf bytes.Buffer
+	if err = seal.SaveShareTokens(
+		shares,
+		nil,
+		additionalPassword,
+		holderKeys,
+		token.Window{},
+		cont.GetHeader().KeyEpoch,
+		*opts.TokenWriter.Format,
+		&tokenBuf,
+	); err != nil {
+		return err
+	}
+
+	header := cont.GetHeader()
+	header.Shares = uint8(*opts.Shamir.Shares)       // #nosec G115 -- validated to be at most 255
+	header.Threshold = uint8(*opts.Shamir.Threshold) // #nosec G115 -- validated to be at most 255
+	cont.SetHeader(header)
+
+	metadata := cont.GetMetadata()
+	metadata.UpdatedAt = time.Now()
+	metadata.Keys = &container.KeyBlock{
+		Holders:  holderRecords,
+		Feldman:  feldman,
+		KeyCheck: container.NewKeyCheck(masterKey),
+		Recovery: metadata.Keys.RecoveryWrap(),
+		Escrow:   metadata.Keys.GetEscrow(),
+	}
+	cont.SetMetadata(metadata)
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// As in reseal, the container is replaced first and the tokens written
+	// only once it is in place.
+	if err = rewriteContainerAtomic(cont, *opts.Container.CurrentPath); err != nil {
+		return err
+	}
+
+	if err = writeTokensAtomic(opts.TokenWriter, tokenBuf.Bytes()); err != nil {
+		return err
+	}
+
+	lib.WarningFormatted(opts.LogWriter, lib.Warning{
+		Operation: "reshare",
+		Message:   "old shares are still valid",
+		Details:   "the master key is kept, so a threshold of the old shares still opens the container; run reseal -rotate-key to revoke them",
+	})
+
+	return nil
+}
+
+// rewriteContainerAtomic - writes cont with its current header and metadata to
+// a temporary file next to targetPath and atomically renames it over
+// targetPath, leaving the payload bytes untouched.
+func rewriteContainerAtomic(cont container.Container, targetPath string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(targetPath), ".tvault-container-*.tmp")
+	if err != nil {
+		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealWriteContainerError, lib.ErrMessageResealWriteContainerError, "", err)
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	// Rewrite fsyncs the temp file before returning, so the data is durable
+	// before the rename below.
+	if err = cont.Rewrite(tmpPath); err != nil {
+		return lib.InternalErr(lib.CategoryReseal, lib.ErrCodeResealWriteContainerError, lib.ErrMessageResealWriteContainerError, "", err)
+	}
+
+	if err = os.Rename(tmpPath, targetPath); err != nil {
+		return lib.IOErr(lib.CategoryReseal, lib.ErrCodeResealWriteContainerError, lib.ErrMessageResealWriteContainerError, "", err)
+	}
+	committed = true
+
+	return fsyncDir(filepath.Dir(targetPath))
+}
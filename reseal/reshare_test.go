@@ -0,0 +1,156 @@
+package reseal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/seal"
+	"github.com/namelesscorp/tvault-core/shamir"
+	"github.com/namelesscorp/tvault-core/token"
+	"github.com/namelesscorp/tvault-core/unseal"
+)
+
+func TestReshare(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		targetPath = filepath.Join(dir, "vault.tvlt")
+		oldTokens  = filepath.Join(dir, "old.json")
+		newTokens  = filepath.Join(dir, "new.json")
+		logPath    = filepath.Join(dir, "log.json")
+		payload    = []byte("payload that reshare must not touch")
+	)
+
+	header, err := container.NewHeader(0, integrity.TypeNone, token.TypeShare, 5, 3)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+
+	cont := container.NewContainer(targetPath, nil, container.Metadata{Tags: []string{}}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(payload), []byte("passphrase")); err != nil {
+		t.Fatalf("WriteEncrypted() error = %v", err)
+	}
+
+	shares, err := shamir.Split(cont.GetMasterKey(), 5, 3, integrity.NewNoneProvider())
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+
+	var tokenBuf bytes.Buffer
+	if err = seal.SaveShareTokens(shares[:3], nil, nil, nil, token.Window{}, 0, lib.WriterFormatJSON, &tokenBuf); err != nil {
+		t.Fatalf("SaveShareTokens() error = %v", err)
+	}
+	if err = os.WriteFile(oldTokens, tokenBuf.Bytes(), 0o600); err != nil {
+		t.Fatalf("write tokens: %v", err)
+	}
+
+	before := readPayload(t, targetPath)
+
+	opts := ReshareOptions{
+		Container:         &lib.Container{CurrentPath: lib.StringPtr(targetPath)},
+		IntegrityProvider: &lib.IntegrityProvider{CurrentPassphrase: lib.StringPtr(""), NewPassphrase: lib.StringPtr("")},
+		Shamir:            &lib.Shamir{Shares: lib.IntPtr(7), Threshold: lib.IntPtr(4)},
+		TokenReader: &lib.Reader{
+			Type:   lib.StringPtr(lib.ReaderTypeFile),
+			Path:   lib.StringPtr(oldTokens),
+			Flag:   lib.StringPtr(""),
+			Format: lib.StringPtr(lib.ReaderFormatJSON),
+		},
+		TokenWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(newTokens),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(logPath),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Holders: &lib.Holders{Path: lib.StringPtr(""), NewPath: lib.StringPtr("")},
+	}
+	if err = opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err = Reshare(context.Background(), opts); err != nil {
+		t.Fatalf("Reshare() error = %v", err)
+	}
+
+	reshared := container.NewContainer(targetPath, nil, container.Metadata{}, container.Header{})
+	if err = reshared.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if reshared.GetHeader().Shares != 7 || reshared.GetHeader().Threshold != 4 {
+		t.Fatalf("header = %d of %d, want 4 of 7", reshared.GetHeader().Threshold, reshared.GetHeader().Shares)
+	}
+	if !bytes.Equal(readPayload(t, targetPath), before) {
+		t.Fatal("encrypted payload changed")
+	}
+
+	tokenString, err := os.ReadFile(newTokens)
+	if err != nil {
+		t.Fatalf("read new tokens: %v", err)
+	}
+	_, newShares, err := unseal.ParseTokens(token.TypeShare, 0, string(tokenString), lib.ReaderFormatJSON, nil, nil)
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	if len(newShares) != 7 {
+		t.Fatalf("got %d new shares, want 7", len(newShares))
+	}
+
+	masterKey, err := unseal.RestoreMasterKey(newShares[3:], nil, nil)
+	if err != nil {
+		t.Fatalf("RestoreMasterKey() error = %v", err)
+	}
+
+	var decrypted bytes.Buffer
+	if err = reshared.DecryptTo(context.Background(), &decrypted, masterKey); err != nil {
+		t.Fatalf("DecryptTo() error = %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), payload) {
+		t.Fatalf("decrypted %q, want %q", decrypted.Bytes(), payload)
+	}
+	if n := countTempFiles(t, dir); n != 0 {
+		t.Fatalf("leftover temp files: %d", n)
+	}
+
+	// The old shares still combine to the key, and the log says so.
+	oldKey, err := unseal.RestoreMasterKey(shares[:3], nil, nil)
+	if err != nil {
+		t.Fatalf("RestoreMasterKey() of the old shares error = %v", err)
+	}
+	if !bytes.Equal(oldKey, masterKey) {
+		t.Fatal("old shares no longer recover the master key")
+	}
+
+	log, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if !bytes.Contains(log, []byte("old shares are still valid")) || !bytes.Contains(log, []byte("reseal -rotate-key")) {
+		t.Errorf("log = %s, want a warning that the old shares stay valid", log)
+	}
+}
+
+// readPayload - returns the container file without its header and metadata.
+func readPayload(t *testing.T, path string) []byte {
+	t.Helper()
+
+	cont := container.NewContainer(path, nil, container.Metadata{}, container.Header{})
+	if err := cont.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read container: %v", err)
+	}
+
+	return data[binary.Size(container.Header{})+int(cont.GetHeader().MetadataSize):]
+}
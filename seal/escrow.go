@@ -0,0 +1,42 @@
+package seal
+
+import (
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+// DecodeEscrowPublicKey - decodes a hex escrow public key; empty means no
+// escrow. The returned error is an unwrapped sentinel for the caller to report
+// under its own category.
+func DecodeEscrowPublicKey(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	publicKey, err := token.DecodeX25519Key(value)
+	if err != nil || lib.CheckX25519PublicKey(publicKey) != nil {
+		return nil, lib.ErrEscrowPublicKeyInvalid
+	}
+
+	return publicKey, nil
+}
+
+// WrapEscrow - wraps masterKey to the hex escrow public key, or returns nil
+// when none is configured.
+func WrapEscrow(category lib.ErrorCategory, publicKeyHex string, masterKey []byte, salt [16]byte) (*container.Escrow, error) {
+	publicKey, err := DecodeEscrowPublicKey(publicKeyHex)
+	if err != nil {
+		return nil, lib.ValidationErr(category, err)
+	}
+	if publicKey == nil {
+		return nil, nil
+	}
+
+	escrow, err := container.NewEscrow(masterKey, publicKey, salt)
+	if err != nil {
+		return nil, lib.CryptoErr(category, lib.ErrCodeEscrowWrapError, lib.ErrMessageEscrowWrapError, "", err)
+	}
+
+	return escrow, nil
+}
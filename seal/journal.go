@@ -0,0 +1,220 @@
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+	"github.com/namelesscorp/tvault-core/shamir"
+)
+
+// checkpointBytes - payload bytes written between two checkpoints of a seal.
+// Each checkpoint syncs the container, so a smaller interval resumes closer to
+// the interruption at the cost of more syncs.
+const checkpointBytes = 256 << 20
+
+// journalInfo - HKDF info binding the journal key to its purpose.
+const journalInfo = "tvault-core seal journal v1"
+
+// journal - the checkpoint journal of a seal, at the container path plus
+// lib.JournalSuffix. It names the temporary container being written and the
+// last checkpoint of its payload, and holds the shares and recovery code,
+// which are issued only once the container is complete, sealed under a key
+// derived from the master key. It is replaced atomically at each checkpoint.
+type journal struct {
+	path string
+
+	TempPath   string               `json:"temp_path"`
+	Checkpoint container.Checkpoint `json:"checkpoint"`
+	Secrets    string               `json:"secrets"`
+
+	// saved is set once the journal is on disk, from then on the temporary
+	// container is kept when the seal fails.
+	saved bool
+}
+
+// journalSecrets - what a resumed seal needs to issue the tokens and recovery
+// code of the container it completes.
+type journalSecrets struct {
+	Shares       []shamir.Share `json:"shares,omitempty"`
+	RecoveryCode string         `json:"recovery_code,omitempty"`
+}
+
+// newJournal - returns the journal of a seal to containerPath through tmpPath,
+// with secrets sealed under masterKey. It is written by the first checkpoint.
+func newJournal(containerPath, tmpPath string, masterKey []byte, salt [16]byte, secrets journalSecrets) (*journal, error) {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, lib.InternalErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+	defer secret.Wipe(plain)
+
+	aesGCM, err := journalAEAD(masterKey, salt)
+	if err != nil {
+		return nil, lib.CryptoErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize(), aesGCM.NonceSize()+len(plain)+aesGCM.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, lib.CryptoErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	return &journal{
+		path:     containerPath + lib.JournalSuffix,
+		TempPath: tmpPath,
+		Secrets:  hex.EncodeToString(aesGCM.Seal(nonce, nonce, plain, []byte(tmpPath))),
+	}, nil
+}
+
+// loadJournal - reads the journal an interrupted seal to containerPath left.
+func loadJournal(containerPath string) (*journal, error) {
+	path := containerPath + lib.JournalSuffix
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalNotFound)
+	case err != nil:
+		return nil, lib.IOErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	j := &journal{path: path, saved: true}
+	if err = json.Unmarshal(data, j); err != nil || !isTempContainerPath(j.TempPath, containerPath) {
+		return nil, lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalInvalid)
+	}
+
+	return j, nil
+}
+
+// isTempContainerPath - reports whether tmpPath is a temporary container
+// newTempContainerPath could have made for containerPath, so a journal never
+// points a seal at another file.
+func isTempContainerPath(tmpPath, containerPath string) bool {
+	name := filepath.Base(tmpPath)
+
+	return filepath.Dir(tmpPath) == filepath.Dir(containerPath) &&
+		strings.HasPrefix(name, ".tvault-container-") && strings.HasSuffix(name, ".tmp")
+}
+
+// removeStaleJournal - removes the journal and temporary container an
+// interrupted seal to containerPath left, before a fresh seal starts over.
+func removeStaleJournal(containerPath string) {
+	path := containerPath + lib.JournalSuffix
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return
+	}
+
+	var j journal
+	if json.Unmarshal(data, &j) == nil && isTempContainerPath(j.TempPath, containerPath) {
+		_ = os.Remove(j.TempPath)
+	}
+	_ = os.Remove(path)
+}
+
+// open - unseals the secrets with masterKey. It fails when masterKey is not
+// the key of the interrupted seal, i.e. the passphrase differs.
+func (j *journal) open(masterKey []byte, salt [16]byte) (journalSecrets, error) {
+	var secrets journalSecrets
+
+	sealed, err := hex.DecodeString(j.Secrets)
+	if err != nil {
+		return secrets, lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalInvalid)
+	}
+
+	aesGCM, err := journalAEAD(masterKey, salt)
+	if err != nil {
+		return secrets, lib.CryptoErr(lib.CategorySeal, lib.ErrCodeJournalOpenError, lib.ErrMessageJournalOpenError, "", err)
+	}
+	if len(sealed) < aesGCM.NonceSize()+aesGCM.Overhead() {
+		return secrets, lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalInvalid)
+	}
+
+	plain, err := aesGCM.Open(nil, sealed[:aesGCM.NonceSize()], sealed[aesGCM.NonceSize():], []byte(j.TempPath))
+	if err != nil {
+		return secrets, lib.CryptoErr(
+			lib.CategorySeal,
+			lib.ErrCodeJournalOpenError,
+			lib.ErrMessageJournalOpenError,
+			lib.SuggestionJournalOpen,
+			err,
+		)
+	}
+	defer secret.Wipe(plain)
+
+	if err = json.Unmarshal(plain, &secrets); err != nil {
+		return secrets, lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalInvalid)
+	}
+
+	return secrets, nil
+}
+
+// save - records cp and writes the journal through a temporary file and a
+// rename, so an interruption leaves the previous journal or this one. It is
+// the container.CheckpointFunc of the seal.
+func (j *journal) save(cp container.Checkpoint) error {
+	j.Checkpoint = cp
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return lib.InternalErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".tvault-journal-*.tmp")
+	if err != nil {
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.path)
+	}
+	if err == nil {
+		err = lib.SyncDir(filepath.Dir(j.path))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+	j.saved = true
+
+	return nil
+}
+
+func (j *journal) remove() {
+	_ = os.Remove(j.path)
+}
+
+func journalAEAD(masterKey []byte, salt [16]byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, masterKey, salt[:], journalInfo, lib.KeyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Wipe(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,81 @@
+package seal
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/shamir"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vault.tvlt")
+	if _, err := loadJournal(targetPath); !errors.Is(err, lib.ErrResumeJournalNotFound) {
+		t.Fatalf("loadJournal() without a journal error = %v, want %v", err, lib.ErrResumeJournalNotFound)
+	}
+
+	tmpPath, err := newTempContainerPath(targetPath)
+	if err != nil {
+		t.Fatalf("newTempContainerPath() error = %v", err)
+	}
+
+	var (
+		masterKey = bytes.Repeat([]byte{7}, lib.KeyLen)
+		salt      = [16]byte{1, 2, 3}
+		secrets   = journalSecrets{
+			Shares:       []shamir.Share{{ID: 1, Value: []byte{0xAA, 0xBB}, Signature: []byte{0xCC}}},
+			RecoveryCode: "ABCD-EFGH",
+		}
+		cp = container.Checkpoint{Chunks: 3, PlainSize: 3000, Offset: 4200}
+	)
+
+	jrn, err := newJournal(targetPath, tmpPath, masterKey, salt, secrets)
+	if err != nil {
+		t.Fatalf("newJournal() error = %v", err)
+	}
+	if err = jrn.save(cp); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	loaded, err := loadJournal(targetPath)
+	if err != nil {
+		t.Fatalf("loadJournal() error = %v", err)
+	}
+	if loaded.TempPath != tmpPath || loaded.Checkpoint != cp {
+		t.Errorf("loaded journal = %+v, want temp %s and checkpoint %+v", loaded, tmpPath, cp)
+	}
+
+	got, err := loaded.open(masterKey, salt)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if got.RecoveryCode != secrets.RecoveryCode || len(got.Shares) != 1 || !bytes.Equal(got.Shares[0].Value, secrets.Shares[0].Value) {
+		t.Errorf("open() = %+v, want %+v", got, secrets)
+	}
+
+	if _, err = loaded.open(bytes.Repeat([]byte{8}, lib.KeyLen), salt); !lib.IsCryptoError(err) {
+		t.Errorf("open() with another key error = %v, want a crypto error", err)
+	}
+
+	removeStaleJournal(targetPath)
+	if _, err = loadJournal(targetPath); !errors.Is(err, lib.ErrResumeJournalNotFound) {
+		t.Errorf("loadJournal() after removeStaleJournal error = %v, want %v", err, lib.ErrResumeJournalNotFound)
+	}
+}
+
+func TestLoadJournalRejectsForeignTempPath(t *testing.T) {
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "vault.tvlt")
+
+	jrn := &journal{path: targetPath + lib.JournalSuffix, TempPath: filepath.Join(dir, "other.tvlt")}
+	if err := jrn.save(container.Checkpoint{}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	if _, err := loadJournal(targetPath); !errors.Is(err, lib.ErrResumeJournalInvalid) {
+		t.Errorf("loadJournal() error = %v, want %v", err, lib.ErrResumeJournalInvalid)
+	}
+}
@@ -0,0 +1,62 @@
+package seal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/kms"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// NewKMSClient - returns a transit client for the engine at mount, reached as
+// opts describes.
+func NewKMSClient(category lib.ErrorCategory, opts *lib.KMS, mount string) (*kms.Transit, error) {
+	if *opts.Address == "" {
+		return nil, lib.ValidationErr(category, lib.ErrKMSAddressRequired)
+	}
+
+	vaultToken, err := kms.ReadToken(*opts.TokenPath)
+	if errors.Is(err, kms.ErrTokenRequired) {
+		return nil, lib.ValidationErr(category, lib.ErrKMSTokenRequired)
+	}
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKMSConfigError, lib.ErrMessageKMSConfigError, lib.SuggestionKMSTokenRequired, err)
+	}
+
+	transit, err := kms.NewTransit(kms.Config{
+		Address:    *opts.Address,
+		Mount:      mount,
+		Token:      vaultToken,
+		CACert:     *opts.CACert,
+		ClientCert: *opts.ClientCert,
+		ClientKey:  *opts.ClientKey,
+		Timeout:    *opts.Timeout,
+	})
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKMSConfigError, lib.ErrMessageKMSConfigError, lib.SuggestionKMSConfig, err)
+	}
+
+	return transit, nil
+}
+
+// WrapKMS - has the transit key at mount/key encrypt masterKey.
+func WrapKMS(ctx context.Context, category lib.ErrorCategory, opts *lib.KMS, mount, key string, masterKey []byte) (*container.KMSWrap, error) {
+	transit, err := NewKMSClient(category, opts, mount)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, version, err := transit.Encrypt(ctx, key, masterKey)
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKMSWrapError, lib.ErrMessageKMSWrapError, lib.SuggestionKMS, err)
+	}
+
+	return &container.KMSWrap{
+		Type:       container.KMSTypeVaultTransit,
+		Mount:      transit.Mount(),
+		Key:        key,
+		Version:    version,
+		Ciphertext: ciphertext,
+	}, nil
+}
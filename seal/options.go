@@ -3,6 +3,7 @@ package seal
 import (
 	"github.com/namelesscorp/tvault-core/compression"
 	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/keyprovider"
 	"github.com/namelesscorp/tvault-core/lib"
 	"github.com/namelesscorp/tvault-core/token"
 )
@@ -14,7 +15,10 @@ type Options struct {
 	IntegrityProvider *lib.IntegrityProvider
 	Shamir            *lib.Shamir
 	TokenWriter       *lib.Writer
+	RecoveryWriter    *lib.Writer
 	LogWriter         *lib.Writer
+	Holders           *lib.Holders
+	KMS               *lib.KMS
 }
 
 func (o *Options) Validate() error {
@@ -38,10 +42,22 @@ func (o *Options) Validate() error {
 		return err
 	}
 
+	if err := o.validateHolders(); err != nil {
+		return err
+	}
+
+	if err := o.validateTokenWindow(); err != nil {
+		return err
+	}
+
 	if err := o.validateTokenWriter(); err != nil {
 		return err
 	}
 
+	if err := o.validateRecoveryWriter(); err != nil {
+		return err
+	}
+
 	return o.validateLogWriter()
 }
 
@@ -50,6 +66,26 @@ func (o *Options) ValidateToken() error {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrTokenTypeInvalid)
 	}
 
+	if *o.Token.Type == token.TypeNamePlugin && !keyprovider.ValidName(*o.Token.Plugin) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrKeyProviderNameInvalid)
+	}
+
+	if *o.Token.Type == token.TypeNameKMS {
+		return o.validateKMS()
+	}
+
+	return nil
+}
+
+func (o *Options) validateKMS() error {
+	if *o.KMS.Address == "" {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrKMSAddressRequired)
+	}
+
+	if *o.KMS.Key == "" {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrKMSKeyRequired)
+	}
+
 	return nil
 }
 
@@ -57,28 +93,80 @@ func (o *Options) validateContainer() error {
 	switch {
 	case *o.Container.NewPath == "":
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerNewPathRequired)
-	case *o.Container.FolderPath == "":
-		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerFolderPathRequired)
 	case *o.Container.Passphrase == "":
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerPassphraseRequired)
+	}
+
+	if err := o.validateStream(); err != nil {
+		return err
+	}
+
+	if _, err := DecodeEscrowPublicKey(*o.Container.EscrowPublicKey); err != nil {
+		return lib.ValidationErr(lib.CategorySeal, err)
+	}
+
+	switch *o.Container.Overwrite {
+	case lib.OverwriteNever, lib.OverwriteAlways, lib.OverwriteBackup:
 	default:
-		return nil
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerOverwriteInvalid)
+	}
+
+	switch *o.Container.Preserve {
+	case lib.PreserveNone, lib.PreserveAll:
+	default:
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerPreserveInvalid)
 	}
+
+	return nil
+}
+
+// validateStream - checks the sources of the seal: stdin, which is sealed
+// alone, or the folder and sources. Neither stdin nor a container streamed to
+// stdout can be resumed.
+func (o *Options) validateStream() error {
+	if *o.Container.Resume && (*o.Container.Stdin || *o.Container.NewPath == lib.StdioPath) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdioResume)
+	}
+
+	if !*o.Container.Stdin {
+		_, err := containerSources(o.Container)
+		return err
+	}
+
+	if *o.Container.FolderPath != "" || len(*o.Container.Sources) > 0 {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdinConflict)
+	}
+
+	return nil
 }
 
 func (o *Options) validateCompression() error {
 	if _, ok := compression.Types[*o.Compression.Type]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrCompressionTypeInvalid)
 	}
+
+	if (*o.Compression.Type == compression.TypeNameRaw) != *o.Container.Stdin {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdinRequiresRaw)
+	}
+
 	return nil
 }
 
+// stdoutInUse - whether writer would mix its output into a container
+// streamed to stdout.
+func (o *Options) stdoutInUse(writer *lib.Writer) bool {
+	return *o.Container.NewPath == lib.StdioPath && *writer.Type == lib.WriterTypeStdout
+}
+
 func (o *Options) validateIntegrity() error {
 	if _, ok := integrity.Types[*o.IntegrityProvider.Type]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrIntegrityProviderTypeInvalid)
 	}
 
-	if *o.Token.Type == token.TypeNameNone && *o.IntegrityProvider.Type != integrity.TypeNameNone {
+	// Only tokens are signed, and none, plugin and kms containers have none.
+	noTokens := *o.Token.Type == token.TypeNameNone || *o.Token.Type == token.TypeNamePlugin ||
+		*o.Token.Type == token.TypeNameKMS
+	if noTokens && *o.IntegrityProvider.Type != integrity.TypeNameNone {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrIntegrityProviderTypeNotNone)
 	}
 
@@ -86,6 +174,16 @@ func (o *Options) validateIntegrity() error {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrIntegrityProviderNewPassphraseRequired)
 	}
 
+	if *o.IntegrityProvider.Type == integrity.TypeNameFeldman {
+		if *o.Token.Type != token.TypeNameShare {
+			return lib.ValidationErr(lib.CategorySeal, lib.ErrFeldmanRequiresShareToken)
+		}
+
+		if o.Shamir.PolicyPath != nil && *o.Shamir.PolicyPath != "" {
+			return lib.ValidationErr(lib.CategorySeal, lib.ErrFeldmanPolicyUnsupported)
+		}
+	}
+
 	return nil
 }
 
@@ -98,6 +196,16 @@ func (o *Options) validateShamir() error {
 		return nil
 	}
 
+	// A policy carries its own thresholds; seal.LoadPolicy validates it and
+	// derives the share count and top threshold from it.
+	if o.Shamir.PolicyPath != nil && *o.Shamir.PolicyPath != "" {
+		if *o.Token.Type != token.TypeNameShare {
+			return lib.ValidationErr(lib.CategorySeal, lib.ErrShamirPolicyRequiresShareToken)
+		}
+
+		return nil
+	}
+
 	if *o.Shamir.Shares == 0 {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrShamirSharesEqual0)
 	}
@@ -129,6 +237,38 @@ func (o *Options) validateShamir() error {
 	return nil
 }
 
+func (o *Options) validateHolders() error {
+	if o.Holders == nil || *o.Holders.Path == "" {
+		return nil
+	}
+
+	if *o.Token.Type != token.TypeNameShare || !*o.Shamir.IsEnabled {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrHoldersRequireShareToken)
+	}
+
+	return nil
+}
+
+// validateTokenWindow - a validity window is only authenticated inside an
+// encrypted token: one sealed with the HMAC integrity passphrase or to a holder.
+func (o *Options) validateTokenWindow() error {
+	window, err := token.ParseWindow(*o.Token.NotBefore, *o.Token.ExpiresAt)
+	if err != nil {
+		return lib.ValidationErr(lib.CategorySeal, err)
+	}
+
+	if window.IsZero() {
+		return nil
+	}
+
+	hasHolders := o.Holders != nil && *o.Holders.Path != ""
+	if *o.IntegrityProvider.Type != integrity.TypeNameHMAC && !hasHolders {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrTokenWindowRequiresEncryption)
+	}
+
+	return nil
+}
+
 func (o *Options) validateTokenWriter() error {
 	if _, ok := lib.WriterTypes[*o.TokenWriter.Type]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrTokenWriterTypeInvalid)
@@ -138,6 +278,10 @@ func (o *Options) validateTokenWriter() error {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrTokenWriterPathRequired)
 	}
 
+	if o.stdoutInUse(o.TokenWriter) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdoutInUse)
+	}
+
 	if _, ok := lib.WriterFormats[*o.TokenWriter.Format]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrTokenWriterFormatInvalid)
 	}
@@ -145,6 +289,30 @@ func (o *Options) validateTokenWriter() error {
 	return nil
 }
 
+func (o *Options) validateRecoveryWriter() error {
+	if !*o.Container.Recovery {
+		return nil
+	}
+
+	if _, ok := lib.WriterTypes[*o.RecoveryWriter.Type]; !ok {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrRecoveryWriterTypeInvalid)
+	}
+
+	if *o.RecoveryWriter.Type == lib.WriterTypeFile && *o.RecoveryWriter.Path == "" {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrRecoveryWriterPathRequired)
+	}
+
+	if o.stdoutInUse(o.RecoveryWriter) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdoutInUse)
+	}
+
+	if _, ok := lib.WriterFormats[*o.RecoveryWriter.Format]; !ok {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrRecoveryWriterFormatInvalid)
+	}
+
+	return nil
+}
+
 func (o *Options) validateLogWriter() error {
 	if _, ok := lib.WriterTypes[*o.LogWriter.Type]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrLogWriterTypeInvalid)
@@ -154,6 +322,10 @@ func (o *Options) validateLogWriter() error {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrLogWriterPathRequired)
 	}
 
+	if o.stdoutInUse(o.LogWriter) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrStdoutInUse)
+	}
+
 	if _, ok := lib.WriterFormats[*o.LogWriter.Format]; !ok {
 		return lib.ValidationErr(lib.CategorySeal, lib.ErrLogWriterFormatInvalid)
 	}
@@ -0,0 +1,20 @@
+package seal
+
+import (
+	"context"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/keyprovider"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// WrapPlugin - has the key provider name wrap masterKey for the container
+// containerID.
+func WrapPlugin(ctx context.Context, category lib.ErrorCategory, name, containerID string, masterKey []byte) (*container.PluginWrap, error) {
+	wrapped, err := keyprovider.Wrap(ctx, name, containerID, masterKey)
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKeyProviderWrapError, lib.ErrMessageKeyProviderWrapError, lib.SuggestionKeyProvider, err)
+	}
+
+	return &container.PluginWrap{Name: name, Key: wrapped}, nil
+}
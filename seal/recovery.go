@@ -0,0 +1,70 @@
+package seal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// RecoveryCode - JSON output of the recovery writer.
+type RecoveryCode struct {
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// newRecovery - generates a recovery code and wraps masterKey to it. The code
+// is returned for SaveRecoveryCode and never stored.
+func newRecovery(masterKey []byte, salt [16]byte) (code, wrapped string, err error) {
+	code, secret, err := lib.GenerateRecoveryCode()
+	if err == nil {
+		wrapped, err = container.NewRecoveryWrap(masterKey, secret, salt)
+	}
+	if err != nil {
+		return "", "", lib.CryptoErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealRecoveryCodeError,
+			lib.ErrMessageSealRecoveryCodeError,
+			"",
+			err,
+		)
+	}
+
+	return code, wrapped, nil
+}
+
+// SaveRecoveryCode - writes the recovery code to the recovery writer. This is
+// the only time the code is shown.
+func SaveRecoveryCode(code string, writerOpts *lib.Writer) error {
+	writer, closer, err := lib.NewWriter(writerOpts)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any
+	switch *writerOpts.Format {
+	case lib.WriterFormatPlaintext:
+		msg = fmt.Sprintf("recovery code:\n%s\n", code)
+	case lib.WriterFormatJSON:
+		msg = RecoveryCode{RecoveryCode: code}
+	default:
+		return lib.ErrUnknownWriterFormat
+	}
+
+	if _, err = lib.WriteFormatted(writer, *writerOpts.Format, msg); err != nil {
+		return lib.IOErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealWriteRecoveryCodeError,
+			lib.ErrMessageSealWriteRecoveryCodeError,
+			"",
+			err,
+		)
+	}
+
+	return nil
+}
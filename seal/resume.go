@@ -0,0 +1,126 @@
+package seal
+
+import (
+	"context"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+	"github.com/namelesscorp/tvault-core/shamir"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+// resumeSeal - completes the seal an interrupted run left at the new path
+// - load the journal and read the temporary container it names
+// - check the header against the options
+// - derive master key and open the journal secrets with it
+// - check the sources against the metadata
+// - verify the written payload against the packed sources and append the rest
+// - move the container into place
+// - issue the recovery code and tokens of the interrupted run
+// The header and key block are kept as the interrupted run wrote them, so the
+// escrow, key provider and KMS are not called again.
+func resumeSeal(ctx context.Context, options Options) error {
+	jrn, err := loadJournal(*options.Container.NewPath)
+	if err != nil {
+		return err
+	}
+
+	comp, err := newCompressor(*options.Compression.Type)
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealCompressFolderError,
+			lib.ErrMessageSealCompressFolderError,
+			"",
+			err,
+		)
+	}
+
+	policy, err := LoadPolicy(options.Shamir)
+	if err != nil {
+		return err
+	}
+
+	holders, _, err := LoadHolders(options.Holders, options.Shamir, policy)
+	if err != nil {
+		return err
+	}
+
+	window, err := token.ParseWindow(*options.Token.NotBefore, *options.Token.ExpiresAt)
+	if err != nil {
+		return lib.ValidationErr(lib.CategorySeal, err)
+	}
+
+	cont := container.NewContainer(jrn.TempPath, nil, container.Metadata{}, container.Header{})
+	if err = cont.Read(); err != nil {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrResumeJournalInvalid)
+	}
+
+	header := cont.GetHeader()
+	if header.CompressionType != comp.ID() ||
+		header.IntegrityProviderType != integrity.ConvertNameToID(*options.IntegrityProvider.Type) ||
+		header.TokenType != token.ConvertNameToID(*options.Token.Type) ||
+		int(header.Shares) != *options.Shamir.Shares ||
+		int(header.Threshold) != *options.Shamir.Threshold ||
+		cont.GetMetadata().Keys.HasRecovery() != *options.Container.Recovery {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrResumeMismatch)
+	}
+
+	passphrase := []byte(*options.Container.Passphrase)
+	key := secret.From(lib.PBKDF2Key(passphrase, header.Salt[:], header.Iterations, lib.KeyLen))
+	defer key.Wipe()
+	secret.Wipe(passphrase)
+	masterKey := key.Bytes()
+
+	secrets, err := jrn.open(masterKey, header.Salt)
+	if err != nil {
+		return err
+	}
+	defer shamir.WipeShares(secrets.Shares)
+
+	sources, err := containerSources(options.Container)
+	if err != nil {
+		return err
+	}
+
+	walker := newWalker(options.Container)
+	entries, uncompressedSize, fileCount, excludedCount, _, err := walkSources(walker, sources, *options.Container.Ignore)
+	if err != nil {
+		return err
+	}
+	if metadata := cont.GetMetadata(); metadata.UncompressedSize != uncompressedSize ||
+		metadata.FileCount != fileCount ||
+		metadata.ExcludedCount != excludedCount ||
+		metadata.SkippedCount != int64(len(walker.Skipped)) ||
+		metadata.Preserve != metadataPreserve(walker) {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrResumeMismatch)
+	}
+
+	// The written part of the payload is packed again to verify it, so the
+	// progress covers all the sources as for a fresh seal.
+	progress := lib.NewProgressReporter()
+	packPhase := progress.Phase(0, 100, uncompressedSize)
+	if p, ok := comp.(interface{ SetProgress(func(int64)) }); ok {
+		p.SetProgress(packPhase.Add)
+	}
+
+	cont.SetMasterKey(masterKey)
+	if err = writeContainer(
+		ctx,
+		comp,
+		cont,
+		jrn,
+		entries,
+		sources,
+		options.Container,
+		&jrn.Checkpoint,
+	); err != nil {
+		return createContainerErr(err)
+	}
+
+	progress.Finish()
+
+	return issueTokens(options, masterKey, header.Salt, holders, policy, window, secrets.Shares, secrets.RecoveryCode)
+}
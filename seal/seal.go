@@ -1,10 +1,12 @@
 package seal
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"strings"
 	"time"
@@ -15,52 +17,106 @@ import (
 	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/integrity/hmac"
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
 	"github.com/namelesscorp/tvault-core/security"
 	"github.com/namelesscorp/tvault-core/shamir"
 	"github.com/namelesscorp/tvault-core/token"
 )
 
 // Seal - seal container by options
+// - check the container path against the overwrite policy
 // - select compressor
+// - load sharing policy and holders
+// - create container header and derive master key
+// - select and create integrity provider
+// - split master key into shares (share tokens)
+// - wrap master key with the key provider plugin or the KMS
 // - create container
-// - select token type
 // create master token or share tokens
-// - select and create integrity provider
 // - derive passphrase
-func Seal(options Options) error {
-	comp, err := newCompressor(*options.Compression.Type)
+//
+// With container -stdin the payload is stdin, sealed as is with compression
+// type raw, and with container -new-path=- the container is written to stdout.
+// With container -resume it completes an interrupted seal instead (see
+// resumeSeal). Cancelling ctx stops packing and encryption and returns an
+// ErrCodeCanceled error; the partial container and its journal are kept for
+// container -resume.
+func Seal(ctx context.Context, options Options) (err error) {
+	defer func() {
+		err = lib.Canceled(ctx, lib.CategorySeal, err)
+	}()
+
+	if err = checkNewPath(options.Container); err != nil {
+		return err
+	}
+
+	if *options.Container.Resume {
+		return resumeSeal(ctx, options)
+	}
+
+	// A raw payload is read from stdin as is: there is nothing to walk or pack.
+	var (
+		sources       []zip.Source
+		comp          compression.Compression
+		compressionID = compression.TypeRaw
+	)
+	if !*options.Container.Stdin {
+		if sources, err = containerSources(options.Container); err != nil {
+			return err
+		}
+
+		if comp, err = newCompressor(*options.Compression.Type); err != nil {
+			return lib.InternalErr(
+				lib.CategorySeal,
+				lib.ErrCodeSealCompressFolderError,
+				lib.ErrMessageSealCompressFolderError,
+				"",
+				err,
+			)
+		}
+		compressionID = comp.ID()
+	}
+
+	policy, err := LoadPolicy(options.Shamir)
 	if err != nil {
-		return lib.InternalErr(
-			lib.CategorySeal,
-			lib.ErrCodeSealCompressFolderError,
-			lib.ErrMessageSealCompressFolderError,
-			"",
-			err,
-		)
+		return err
 	}
 
-	masterKey, containerSalt, err := CreateContainer(
-		comp,
+	holders, holderRecords, err := LoadHolders(options.Holders, options.Shamir, policy)
+	if err != nil {
+		return err
+	}
+
+	window, err := token.ParseWindow(*options.Token.NotBefore, *options.Token.ExpiresAt)
+	if err != nil {
+		return lib.ValidationErr(lib.CategorySeal, err)
+	}
+
+	header, err := container.NewHeader(
+		compressionID,
 		integrity.ConvertNameToID(*options.IntegrityProvider.Type),
 		token.ConvertNameToID(*options.Token.Type),
-		options.Container,
-		options.Shamir,
-		*options.IntegrityProvider.NewPassphrase,
-		*options.Container.FolderPath,
+		uint8(*options.Shamir.Shares),    // #nosec G115
+		uint8(*options.Shamir.Threshold), // #nosec G115
 	)
 	if err != nil {
-		return lib.InternalErr(
+		return lib.CryptoErr(
 			lib.CategorySeal,
-			lib.ErrCodeSealCreateContainerError,
-			lib.ErrMessageSealCreateContainerError,
+			lib.ErrCodeSealCreateContainerHeaderError,
+			lib.ErrMessageSealCreateContainerHeaderError,
 			"",
 			err,
 		)
 	}
 
-	if *options.Token.Type == token.TypeNameNone {
-		return nil
-	}
+	// The master key is derived before the container is written, so the shares
+	// are split up front and a Feldman split can record its commitments in the
+	// container metadata. It lives in locked memory, wiped when seal returns.
+	passphrase := []byte(*options.Container.Passphrase)
+	key := secret.From(lib.PBKDF2Key(passphrase, header.Salt[:], header.Iterations, lib.KeyLen))
+	defer key.Wipe()
+	secret.Wipe(passphrase)
+	masterKey := key.Bytes()
 
 	integrityProvider, err := CreateIntegrityProviderWithNewPassphrase(options.IntegrityProvider)
 	if err != nil {
@@ -73,7 +129,113 @@ func Seal(options Options) error {
 		)
 	}
 
-	integrityProviderPassphrase, err := DeriveIntegrityProviderNewPassphrase(options.IntegrityProvider, containerSalt)
+	var (
+		shares  []shamir.Share
+		feldman *shamir.Feldman
+	)
+	if *options.Token.Type == token.TypeNameShare && *options.Shamir.IsEnabled {
+		if shares, feldman, err = SplitMasterKey(masterKey, options.Shamir, policy, integrityProvider); err != nil {
+			return err
+		}
+	}
+	defer shamir.WipeShares(shares)
+
+	// A key check value lets token verify and unseal confirm a recovered key
+	// without decrypting the payload; containers without tokens need none.
+	var keyCheck string
+	if *options.Token.Type != token.TypeNameNone {
+		keyCheck = container.NewKeyCheck(masterKey)
+	}
+
+	var recoveryCode, recovery string
+	if *options.Container.Recovery {
+		if recoveryCode, recovery, err = newRecovery(masterKey, header.Salt); err != nil {
+			return err
+		}
+	}
+
+	escrow, err := WrapEscrow(lib.CategorySeal, *options.Container.EscrowPublicKey, masterKey, header.Salt)
+	if err != nil {
+		return err
+	}
+
+	// A plugin container has no tokens: its key provider holds the key.
+	var plugin *container.PluginWrap
+	if *options.Token.Type == token.TypeNamePlugin {
+		if plugin, err = WrapPlugin(ctx, lib.CategorySeal, *options.Token.Plugin, header.ID(), masterKey); err != nil {
+			return err
+		}
+	}
+
+	// So does a kms container: the transit key encrypts it.
+	var kmsWrap *container.KMSWrap
+	if *options.Token.Type == token.TypeNameKMS {
+		if kmsWrap, err = WrapKMS(ctx, lib.CategorySeal, options.KMS, *options.KMS.Mount, *options.KMS.Key, masterKey); err != nil {
+			return err
+		}
+	}
+
+	if err = CreateContainer(
+		ctx,
+		comp,
+		header,
+		masterKey,
+		options.Container,
+		options.LogWriter,
+		options.Shamir,
+		*options.IntegrityProvider.NewPassphrase,
+		sources,
+		newKeyBlock(holderRecords, policy, feldman, keyCheck, recovery, escrow, plugin, kmsWrap),
+		shares,
+		recoveryCode,
+	); err != nil {
+		return createContainerErr(err)
+	}
+
+	return issueTokens(options, masterKey, header.Salt, holders, policy, window, shares, recoveryCode)
+}
+
+// createContainerErr - wraps an error of CreateContainer, passing validation
+// errors such as an existing container or a failed resume through unchanged.
+func createContainerErr(err error) error {
+	if lib.IsValidationError(err) {
+		return err
+	}
+
+	return lib.InternalErr(
+		lib.CategorySeal,
+		lib.ErrCodeSealCreateContainerError,
+		lib.ErrMessageSealCreateContainerError,
+		"",
+		err,
+	)
+}
+
+// issueTokens - writes the recovery code, if any, and the master token or share
+// tokens of a container sealed with masterKey.
+func issueTokens(
+	options Options,
+	masterKey []byte,
+	salt [16]byte,
+	holders []token.Holder,
+	policy *shamir.Policy,
+	window token.Window,
+	shares []shamir.Share,
+	recoveryCode string,
+) error {
+	var err error
+	if recoveryCode != "" {
+		if err = SaveRecoveryCode(recoveryCode, options.RecoveryWriter); err != nil {
+			return err
+		}
+	}
+
+	if *options.Token.Type == token.TypeNameNone || *options.Token.Type == token.TypeNamePlugin ||
+		*options.Token.Type == token.TypeNameKMS {
+		return nil
+	}
+
+	integrityProviderPassphrase, err := DeriveIntegrityProviderNewPassphrase(options.IntegrityProvider, salt[:])
 	if err != nil {
 		return lib.InternalErr(
 			lib.CategorySeal,
@@ -83,8 +245,20 @@ func Seal(options Options) error {
 			err,
 		)
 	}
+	defer secret.Wipe(integrityProviderPassphrase)
 
-	if err = GenerateAndSaveTokens(options, integrityProviderPassphrase, masterKey, integrityProvider); err != nil {
+	holderKeys, err := HolderSealKeys(holders, salt[:])
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealLoadHoldersError,
+			lib.ErrMessageSealLoadHoldersError,
+			"",
+			err,
+		)
+	}
+
+	if err = GenerateAndSaveTokens(options, integrityProviderPassphrase, masterKey, shares, holderKeys, policy, window); err != nil {
 		return lib.InternalErr(
 			lib.CategorySeal,
 			lib.ErrCodeSealGenerateAndSaveTokensError,
@@ -97,11 +271,168 @@ func Seal(options Options) error {
 	return nil
 }
 
+// LoadPolicy - reads the sharing policy, if one is set, and sizes the Shamir
+// options from it so the header records its share count and top threshold.
+func LoadPolicy(shamirOpts *lib.Shamir) (*shamir.Policy, error) {
+	if shamirOpts.PolicyPath == nil || *shamirOpts.PolicyPath == "" {
+		return nil, nil
+	}
+
+	policy, err := shamir.LoadPolicy(*shamirOpts.PolicyPath)
+	if err != nil {
+		return nil, lib.FormatErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealLoadPolicyError,
+			lib.ErrMessageSealLoadPolicyError,
+			"",
+			err,
+		)
+	}
+
+	shares, threshold := policy.Shares(), policy.Threshold
+	shamirOpts.Shares = &shares
+	shamirOpts.Threshold = &threshold
+
+	return policy, nil
+}
+
+// newKeyBlock - returns the key block for the given records, or nil when there
+// is nothing to record.
+func newKeyBlock(
+	holderRecords []container.HolderRecord,
+	policy *shamir.Policy,
+	feldman *shamir.Feldman,
+	keyCheck string,
+	recovery string,
+	escrow *container.Escrow,
+	plugin *container.PluginWrap,
+	kmsWrap *container.KMSWrap,
+) *container.KeyBlock {
+	if len(holderRecords) == 0 && policy == nil && feldman == nil && keyCheck == "" && recovery == "" && escrow == nil &&
+		plugin == nil && kmsWrap == nil {
+		return nil
+	}
+
+	return &container.KeyBlock{
+		Holders:  holderRecords,
+		Policy:   policy,
+		Feldman:  feldman,
+		KeyCheck: keyCheck,
+		Recovery: recovery,
+		Escrow:   escrow,
+		Plugin:   plugin,
+		KMS:      kmsWrap,
+	}
+}
+
+// LoadHolders - reads the holders file, if one is set, and returns the holder of
+// each share in share order with the records to keep in the key block. Without
+// a policy share i+1 goes to the i-th holder in the file; with a policy every
+// share goes to the holder named like its member.
+func LoadHolders(
+	holdersOpts *lib.Holders,
+	shamirOpts *lib.Shamir,
+	policy *shamir.Policy,
+) ([]token.Holder, []container.HolderRecord, error) {
+	if holdersOpts == nil || *holdersOpts.Path == "" {
+		return nil, nil, nil
+	}
+
+	holders, err := token.LoadHolders(*holdersOpts.Path)
+	if err != nil {
+		return nil, nil, lib.IOErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealLoadHoldersError,
+			lib.ErrMessageSealLoadHoldersError,
+			"",
+			err,
+		)
+	}
+
+	if policy != nil {
+		if holders, err = holdersForPolicy(holders, policy); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(holders) != *shamirOpts.Shares {
+		return nil, nil, lib.ValidationErr(lib.CategorySeal, lib.ErrHoldersCountMismatch)
+	}
+
+	records := make([]container.HolderRecord, 0, len(holders))
+	for i, holder := range holders {
+		record := container.HolderRecord{
+			ID:   i + 1,
+			Name: holder.Name,
+			Kind: token.ConvertHolderKindToName(holder.Kind()),
+		}
+
+		if holder.Kind() == token.HolderKindX25519 {
+			var publicKey []byte
+			if publicKey, err = holder.PublicKeyBytes(); err != nil {
+				return nil, nil, lib.FormatErr(
+					lib.CategorySeal,
+					lib.ErrCodeSealLoadHoldersError,
+					lib.ErrMessageSealLoadHoldersError,
+					"",
+					err,
+				)
+			}
+			record.PublicKey = hex.EncodeToString(publicKey)
+		}
+
+		records = append(records, record)
+	}
+
+	return holders, records, nil
+}
+
+// holdersForPolicy - expands the holders file to one holder per policy share,
+// matching holders to members by name.
+func holdersForPolicy(holders []token.Holder, policy *shamir.Policy) ([]token.Holder, error) {
+	expanded := make([]token.Holder, 0, policy.Shares())
+	for _, slot := range policy.Slots() {
+		holder, ok := token.FindHolder(holders, slot.Member)
+		if !ok {
+			return nil, lib.FormatErr(
+				lib.CategorySeal,
+				lib.ErrCodeSealLoadHoldersError,
+				lib.ErrMessageSealLoadHoldersError,
+				"no holder for member "+slot.Member,
+				lib.ErrHoldersCountMismatch,
+			)
+		}
+
+		expanded = append(expanded, holder)
+	}
+
+	return expanded, nil
+}
+
+// HolderSealKeys - returns the key each holder's share is sealed to, in share order.
+func HolderSealKeys(holders []token.Holder, salt []byte) ([]token.HolderKey, error) {
+	if len(holders) == 0 {
+		return nil, nil
+	}
+
+	holderKeys := make([]token.HolderKey, 0, len(holders))
+	for _, holder := range holders {
+		holderKey, err := holder.SealKey(salt)
+		if err != nil {
+			return nil, err
+		}
+
+		holderKeys = append(holderKeys, holderKey)
+	}
+
+	return holderKeys, nil
+}
+
 // entriesPacker is implemented by compressors that can pack a pre-walked entry
 // list, letting the caller reuse a single filesystem walk for both stats and
 // packing instead of walking twice.
 type entriesPacker interface {
-	PackEntriesTo(entries []zip.Entry, w io.Writer) error
+	PackEntriesTo(ctx context.Context, entries []zip.Entry, w io.Writer) error
 }
 
 // newCompressor - select compressor instance by type
@@ -118,41 +449,41 @@ func newCompressor(compressionType string) (compression.Compression, error) {
 	}
 }
 
-// CreateContainer - create container file and return master key
-// - init container header
+// CreateContainer - create container file encrypted with masterKey
 // - select container name
 // - get encrypted folder stats
 // - create security score instance
 // - calculate security score
 // - create container instance with metadata
-// - pack files to container
+// - pack files to a temporary container next to the new path
+// - move it into place as the overwrite policy allows
+// keyBlock may be nil when no key-management records are needed. An existing
+// container at the new path is untouched until the new one is complete. comp
+// is nil for a raw payload from stdin, and a new path of lib.StdioPath streams
+// the container to stdout instead (see streamContainer).
+// shares and recoveryCode, issued by the caller once the container is in
+// place, are sealed into the checkpoint journal for container -resume.
+// Special files the walk skipped are counted in the metadata and named in a
+// warning on logWriter.
 func CreateContainer(
+	ctx context.Context,
 	comp compression.Compression,
-	integrityProviderID, tokenID byte,
+	header container.Header,
+	masterKey []byte,
 	containerOpts *lib.Container,
+	logWriter *lib.Writer,
 	shamir *lib.Shamir,
 	integrityProviderPassphrase string,
-	folderPath string,
-) ([]byte, []byte, error) {
-	header, err := container.NewHeader(
-		comp.ID(),
-		integrityProviderID,
-		tokenID,
-		uint8(*shamir.Shares),    // #nosec G115
-		uint8(*shamir.Threshold), // #nosec G115
+	sources []zip.Source,
+	keyBlock *container.KeyBlock,
+	shares []shamir.Share,
+	recoveryCode string,
+) error {
+	var (
+		stream        = *containerOpts.NewPath == lib.StdioPath
+		containerName = *containerOpts.Name
 	)
-	if err != nil {
-		return nil, nil, lib.CryptoErr(
-			lib.CategorySeal,
-			lib.ErrCodeSealCreateContainerHeaderError,
-			lib.ErrMessageSealCreateContainerHeaderError,
-			"",
-			err,
-		)
-	}
-
-	var containerName = *containerOpts.Name
-	if containerName == "" {
+	if containerName == "" && !stream {
 		containerName = path.Base(*containerOpts.NewPath)
 
 		var pathList = strings.Split(containerName, ".")
@@ -161,34 +492,49 @@ func CreateContainer(
 		}
 	}
 
-	// Walk the folder once: the returned stats populate the metadata/security
+	// Walk the sources once: the returned stats populate the metadata/security
 	// score (written before the payload), and the returned entries are handed to
 	// the packer below so the tree is not walked a second time to compress it.
-	entries, uncompressedSize, fileCount, fileNameList, err := zip.WalkFolder(folderPath)
-	if err != nil {
-		return nil, nil, lib.IOErr(
-			lib.CategorySeal,
-			lib.ErrCodeSealCompressionPackError,
-			lib.ErrMessageSealCompressionPackError,
-			"",
-			err,
-		)
+	// Excluded paths are left out of both, so the score sees included names only.
+	// A raw payload is one unnamed stream whose size the container records
+	// once it is written.
+	// With container -preserve=all the walk also records directories,
+	// ownership, extended attributes and hard links.
+	var (
+		walker                          = newWalker(containerOpts)
+		entries                         []zip.Entry
+		uncompressedSize, excludedCount int64
+		fileCount                       int64 = 1
+		fileNameList                    []string
+		err                             error
+	)
+	if !*containerOpts.Stdin {
+		entries, uncompressedSize, fileCount, excludedCount, fileNameList, err = walkSources(walker, sources, *containerOpts.Ignore)
+		if err != nil {
+			return err
+		}
+		WarnSkipped(logWriter, "seal", walker.Skipped)
 	}
 
 	// Report "PROGRESS <pct>" off the uncompressed input: WalkFolder already
 	// gave the total, and the packer reports each source byte as it is read
 	// (via SetProgress), so progress advances while the archive streams through
 	// the pipe into WriteEncrypted below. Finish is emitted on success only.
-	progress := lib.NewProgressReporter()
+	// Nothing is reported while stdout carries the container, and a raw
+	// payload of unknown size reports Finish alone.
+	var progress *lib.ProgressReporter
+	if !stream {
+		progress = lib.NewProgressReporter()
+	}
 	packPhase := progress.Phase(0, 100, uncompressedSize)
 	if p, ok := comp.(interface{ SetProgress(func(int64)) }); ok {
 		p.SetProgress(packPhase.Add)
 	}
 
 	secScore := security.New(security.Params{
-		TokenType:                   token.ConvertIDToName(tokenID),
-		IntegrityProviderType:       integrity.ConvertIDToName(integrityProviderID),
-		CompressionType:             compression.ConvertIDToName(comp.ID()),
+		TokenType:                   token.ConvertIDToName(header.TokenType),
+		IntegrityProviderType:       integrity.ConvertIDToName(header.IntegrityProviderType),
+		CompressionType:             compression.ConvertIDToName(header.CompressionType),
 		NumberOfShares:              *shamir.Shares,
 		NumberOfThreshold:           *shamir.Threshold,
 		ContainerPassphrase:         *containerOpts.Passphrase,
@@ -196,54 +542,228 @@ func CreateContainer(
 		FileNameList:                fileNameList,
 	})
 
-	cont := container.NewContainer(
+	metadata := container.Metadata{
+		Name:      containerName,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Comment:   *containerOpts.Comment,
+		Tags:      lib.ParseTags(*containerOpts.Tags),
+		// CompressedSize is filled in by WriteEncrypted once the payload is
+		// streamed (it equals the number of compressed bytes consumed).
+		UncompressedSize: uncompressedSize,
+		FileCount:        fileCount,
+		ExcludedCount:    excludedCount,
+		SkippedCount:     int64(len(walker.Skipped)),
+		Preserve:         metadataPreserve(walker),
+		SecurityScore:    secScore.Calculate(),
+		Keys:             keyBlock,
+	}
+
+	if stream {
+		return streamContainer(ctx, comp, container.NewContainer("", masterKey, metadata, header), entries, sources, containerOpts)
+	}
+
+	// A seal that starts over drops what an interrupted one left behind.
+	removeStaleJournal(*containerOpts.NewPath)
+
+	tmpPath, err := newTempContainerPath(*containerOpts.NewPath)
+	if err != nil {
+		return err
+	}
+
+	jrn, err := newJournal(
 		*containerOpts.NewPath,
-		nil,
-		container.Metadata{
-			Name:      containerName,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Comment:   *containerOpts.Comment,
-			Tags:      lib.ParseTags(*containerOpts.Tags),
-			// CompressedSize is filled in by WriteEncrypted once the payload is
-			// streamed (it equals the number of compressed bytes consumed).
-			UncompressedSize: uncompressedSize,
-			FileCount:        fileCount,
-			SecurityScore:    secScore.Calculate(),
-		},
-		header,
+		tmpPath,
+		masterKey,
+		header.Salt,
+		journalSecrets{Shares: shares, RecoveryCode: recoveryCode},
 	)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
 
-	pr, pw := io.Pipe()
-	packErrCh := make(chan error, 1)
+	cont := container.NewContainer(tmpPath, masterKey, metadata, header)
 
-	go func() {
-		defer func() { _ = pw.Close() }()
+	if err = writeContainer(ctx, comp, cont, jrn, entries, sources, containerOpts, nil); err != nil {
+		return err
+	}
 
-		// Reuse the entries already collected by WalkFolder above; fall back to a
-		// fresh walk only if the compressor cannot pack pre-walked entries.
-		var packErr error
-		if packer, ok := comp.(entriesPacker); ok {
-			packErr = packer.PackEntriesTo(entries, pw)
-		} else {
-			packErr = comp.PackTo(folderPath, pw)
+	progress.Finish()
+
+	return nil
+}
+
+// containerSources - the sources of a seal: FolderPath, when given, packed at
+// the archive root, followed by Sources.
+func containerSources(containerOpts *lib.Container) ([]zip.Source, error) {
+	var sources []zip.Source
+	if *containerOpts.FolderPath != "" {
+		sources = append(sources, zip.Source{Path: *containerOpts.FolderPath})
+	}
+
+	for _, spec := range *containerOpts.Sources {
+		src, err := zip.ParseSource(spec)
+		if err != nil {
+			return nil, lib.ValidationErr(lib.CategorySeal, err)
 		}
+		sources = append(sources, src)
+	}
 
-		if packErr != nil {
-			_ = pw.CloseWithError(packErr)
-			packErrCh <- packErr
+	if len(sources) == 0 {
+		return nil, lib.ValidationErr(lib.CategorySeal, lib.ErrContainerSourceRequired)
+	}
 
-			return
+	return sources, nil
+}
+
+// newWalker - the walker of the sources, preserving what container -preserve
+// asks for.
+func newWalker(containerOpts *lib.Container) *zip.Walker {
+	return &zip.Walker{Preserve: *containerOpts.Preserve == lib.PreserveAll}
+}
+
+// metadataPreserve - the preserve mode recorded in the metadata: PreserveAll,
+// or empty for the default.
+func metadataPreserve(walker *zip.Walker) string {
+	if walker.Preserve {
+		return lib.PreserveAll
+	}
+
+	return ""
+}
+
+// WarnSkipped - names the special files a walk skipped in a warning on
+// logWriter, if there are any.
+func WarnSkipped(logWriter *lib.Writer, operation string, skipped []string) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	lib.WarningFormatted(logWriter, lib.Warning{
+		Operation: operation,
+		Message:   "special files were skipped",
+		Details:   "FIFOs, sockets and devices are not packed: " + strings.Join(skipped, ", "),
+	})
+}
+
+// walkSources - walks the sources for packing with walker, leaving out the
+// paths their .tvaultignore and the ignore rules exclude. A collision of
+// archive paths is passed through as a validation error.
+func walkSources(walker *zip.Walker, sources []zip.Source, ignore []string) (
+	entries []zip.Entry,
+	uncompressedSize, fileCount, excludedCount int64,
+	fileNameList []string,
+	err error,
+) {
+	entries, uncompressedSize, fileCount, excludedCount, fileNameList, err = walker.WalkSources(sources, ignore)
+	if lib.IsValidationError(err) {
+		return nil, 0, 0, 0, nil, err
+	}
+	if err != nil {
+		return nil, 0, 0, 0, nil, lib.IOErr(
+			lib.CategorySeal,
+			lib.ErrCodeSealCompressionPackError,
+			lib.ErrMessageSealCompressionPackError,
+			"",
+			err,
+		)
+	}
+
+	return entries, uncompressedSize, fileCount, excludedCount, fileNameList, nil
+}
+
+// writeContainer - packs entries into cont, the temporary container of jrn,
+// saving a checkpoint to jrn every checkpointBytes, and moves it to the new
+// path. With from set the payload is resumed from that checkpoint instead of
+// written from the start. Once the first checkpoint is saved a failure keeps
+// the temporary container and journal for container -resume; the journal is
+// removed when the container is in place.
+func writeContainer(
+	ctx context.Context,
+	comp compression.Compression,
+	cont container.Container,
+	jrn *journal,
+	entries []zip.Entry,
+	sources []zip.Source,
+	containerOpts *lib.Container,
+	from *container.Checkpoint,
+) (err error) {
+	defer func() {
+		if err != nil && !jrn.saved {
+			_ = os.Remove(jrn.TempPath)
 		}
+	}()
+	cont.SetCheckpoint(checkpointBytes, jrn.save)
 
+	err = encryptPayload(ctx, comp, entries, sources, containerOpts, func(r io.Reader) error {
+		if from == nil {
+			return cont.WriteEncrypted(ctx, r, []byte(*containerOpts.Passphrase))
+		}
+
+		return cont.ResumeEncrypted(ctx, r, *from)
+	})
+	if err != nil {
+		return err
+	}
+
+	// WriteEncrypted fsyncs the temporary file before returning, so the data
+	// is durable before the rename.
+	if err = commitContainer(jrn.TempPath, *containerOpts.NewPath, *containerOpts.Overwrite); err != nil {
+		return err
+	}
+	jrn.remove()
+
+	return nil
+}
+
+// streamContainer - writes cont to stdout as it is sealed. Nothing is staged
+// on disk, so there is no temporary file, journal or overwrite policy, and a
+// failure leaves a truncated container the reader rejects.
+func streamContainer(
+	ctx context.Context,
+	comp compression.Compression,
+	cont container.Container,
+	entries []zip.Entry,
+	sources []zip.Source,
+	containerOpts *lib.Container,
+) error {
+	return encryptPayload(ctx, comp, entries, sources, containerOpts, func(r io.Reader) error {
+		return cont.WriteEncryptedTo(ctx, r, os.Stdout, []byte(*containerOpts.Passphrase))
+	})
+}
+
+// encryptPayload - hands the payload to encrypt, which writes it to the
+// container: stdin for container -stdin, or else the entries packed through a
+// pipe, so the archive is never staged on disk.
+func encryptPayload(
+	ctx context.Context,
+	comp compression.Compression,
+	entries []zip.Entry,
+	sources []zip.Source,
+	containerOpts *lib.Container,
+	encrypt func(r io.Reader) error,
+) error {
+	var (
+		payload   io.ReadCloser
+		packErrCh = make(chan error, 1)
+	)
+	if *containerOpts.Stdin {
+		payload = io.NopCloser(os.Stdin)
 		packErrCh <- nil
-	}()
+	} else {
+		payload = packPayload(ctx, comp, entries, sources, packErrCh)
+	}
 
-	if err = cont.WriteEncrypted(pr, []byte(*containerOpts.Passphrase)); err != nil {
-		_ = pr.Close()
+	if err := encrypt(payload); err != nil {
+		_ = payload.Close()
 		<-packErrCh
 
-		return nil, nil, lib.CryptoErr(
+		if lib.IsValidationError(err) {
+			return err
+		}
+
+		return lib.CryptoErr(
 			lib.CategorySeal,
 			lib.ErrCodeSealEncryptContainerError,
 			lib.ErrMessageSealEncryptContainerError,
@@ -253,7 +773,7 @@ func CreateContainer(
 	}
 
 	if packErr := <-packErrCh; packErr != nil {
-		return nil, nil, lib.IOErr(
+		return lib.IOErr(
 			lib.CategorySeal,
 			lib.ErrCodeSealCompressionPackError,
 			lib.ErrMessageSealCompressionPackError,
@@ -262,10 +782,47 @@ func CreateContainer(
 		)
 	}
 
-	progress.Finish()
+	return nil
+}
+
+// packPayload - packs entries into a pipe from a goroutine and returns its
+// read end; the packing error is sent to packErrCh once the pipe is closed.
+func packPayload(
+	ctx context.Context,
+	comp compression.Compression,
+	entries []zip.Entry,
+	sources []zip.Source,
+	packErrCh chan<- error,
+) io.ReadCloser {
+	pr, pw := io.Pipe()
 
-	var containerHeaderSalt = cont.GetHeader().Salt
-	return cont.GetMasterKey(), containerHeaderSalt[:], nil
+	go func() {
+		defer func() { _ = pw.Close() }()
+
+		// Reuse the entries already collected by WalkSources above; fall back to
+		// a fresh walk only if the compressor cannot pack pre-walked entries,
+		// which describes a single folder at the archive root alone.
+		var packErr error
+		switch packer, ok := comp.(entriesPacker); {
+		case ok:
+			packErr = packer.PackEntriesTo(ctx, entries, pw)
+		case len(sources) == 1 && sources[0].Prefix == "":
+			packErr = comp.PackTo(ctx, sources[0].Path, pw)
+		default:
+			packErr = lib.ErrUnknownCompressionType
+		}
+
+		if packErr != nil {
+			_ = pw.CloseWithError(packErr)
+			packErrCh <- packErr
+
+			return
+		}
+
+		packErrCh <- nil
+	}()
+
+	return pr
 }
 
 // CreateIntegrityProviderWithNewPassphrase - creates a new integrity provider based on the specified type and new passphrase.
@@ -275,6 +832,8 @@ func CreateIntegrityProviderWithNewPassphrase(integrityProvider *lib.IntegrityPr
 		return integrity.NewNoneProvider(), nil
 	case integrity.TypeNameHMAC:
 		return hmac.New([]byte(*integrityProvider.NewPassphrase)), nil
+	case integrity.TypeNameFeldman:
+		return integrity.NewFeldmanProvider(), nil
 	case integrity.TypeNameEd25519:
 		return nil, lib.ErrEd25519Unimplemented
 	default:
@@ -303,7 +862,10 @@ func GenerateAndSaveTokens(
 	options Options,
 	integrityProviderPassphrase []byte,
 	masterKey []byte,
-	integrityProvider integrity.Provider,
+	shares []shamir.Share,
+	holderKeys []token.HolderKey,
+	policy *shamir.Policy,
+	window token.Window,
 ) error {
 	tokenWriter, closer, err := lib.NewWriter(options.TokenWriter)
 	if err != nil {
@@ -317,10 +879,12 @@ func GenerateAndSaveTokens(
 
 	if *options.Shamir.IsEnabled {
 		return SaveShareTokens(
-			options.Shamir,
+			shares,
+			policy,
 			integrityProviderPassphrase,
-			masterKey,
-			integrityProvider,
+			holderKeys,
+			window,
+			0, // a new container starts at key epoch 0
 			*options.TokenWriter.Format,
 			tokenWriter,
 		)
@@ -329,27 +893,37 @@ func GenerateAndSaveTokens(
 	return SaveMasterToken(
 		integrityProviderPassphrase,
 		masterKey,
+		window,
+		0,
 		*options.TokenWriter.Format,
 		tokenWriter,
 	)
 }
 
-func SaveShareTokens(
-	shamirOpts *lib.Shamir,
-	additionalPassword []byte,
+// SplitMasterKey - splits the master key: by the policy when one is set, over
+// P-256 with public commitments for the feldman provider, and byte-wise over
+// GF(256) otherwise. The Feldman data is nil for the other schemes.
+func SplitMasterKey(
 	masterKey []byte,
+	shamirOpts *lib.Shamir,
+	policy *shamir.Policy,
 	integrityProvider integrity.Provider,
-	tokenWriterFormat string,
-	writer io.Writer,
-) error {
-	shares, err := shamir.Split(
-		masterKey,
-		*shamirOpts.Shares,
-		*shamirOpts.Threshold,
-		integrityProvider,
+) ([]shamir.Share, *shamir.Feldman, error) {
+	var (
+		shares  []shamir.Share
+		feldman *shamir.Feldman
+		err     error
 	)
+	switch {
+	case policy != nil:
+		shares, err = shamir.SplitPolicy(masterKey, policy, integrityProvider)
+	case integrityProvider.ID() == integrity.TypeFeldman:
+		shares, feldman, err = shamir.SplitFeldman(masterKey, *shamirOpts.Shares, *shamirOpts.Threshold)
+	default:
+		shares, err = shamir.Split(masterKey, *shamirOpts.Shares, *shamirOpts.Threshold, integrityProvider)
+	}
 	if err != nil {
-		return lib.CryptoErr(
+		return nil, nil, lib.CryptoErr(
 			lib.CategorySeal,
 			lib.ErrCodeSealShamirSplitError,
 			lib.ErrMessageSealShamirSplitError,
@@ -358,17 +932,52 @@ func SaveShareTokens(
 		)
 	}
 
+	return shares, feldman, nil
+}
+
+// SaveShareTokens - writes one token per share. With holderKeys set, the share
+// with ID n is sealed to holderKeys[n-1] instead of being encrypted with
+// additionalPassword, which then only keys the integrity signature. With a
+// policy each token records its group and member; every token carries window
+// and the container key epoch. shares may be a subset of the split, as when
+// reseal re-issues only the shares it was given.
+func SaveShareTokens(
+	shares []shamir.Share,
+	policy *shamir.Policy,
+	additionalPassword []byte,
+	holderKeys []token.HolderKey,
+	window token.Window,
+	epoch uint32,
+	tokenWriterFormat string,
+	writer io.Writer,
+) error {
+	var (
+		slots []shamir.Slot
+		err   error
+	)
+	if policy != nil {
+		slots = policy.Slots()
+	}
+
 	switch tokenWriterFormat {
 	case lib.WriterFormatPlaintext:
 		var b strings.Builder
 		b.WriteString("tokens:\n")
 
 		for _, share := range shares {
+			i := int(share.ID) - 1
+
 			var shareToken []byte
-			if shareToken, err = buildShareToken(&share, additionalPassword); err != nil {
+			if shareToken, err = buildShareToken(&share, slotAt(slots, i), additionalPassword, holderKeyAt(holderKeys, i), window, epoch); err != nil {
 				return err
 			}
 
+			if holderKey := holderKeyAt(holderKeys, i); holderKey != nil {
+				b.WriteString(fmt.Sprintf("holder: %s\n", holderKey.Name))
+			}
+			if slot := slotAt(slots, i); slot != nil {
+				b.WriteString(fmt.Sprintf("member: %s (%s)\n", slot.Member, slot.Group))
+			}
 			b.WriteString(base64.StdEncoding.EncodeToString(shareToken))
 			b.WriteString("\n---\n")
 		}
@@ -385,12 +994,20 @@ func SaveShareTokens(
 	case lib.WriterFormatJSON:
 		list := token.List{TokenList: make([]string, 0, len(shares))}
 		for _, share := range shares {
+			i := int(share.ID) - 1
+
 			var shareToken []byte
-			if shareToken, err = buildShareToken(&share, additionalPassword); err != nil {
+			if shareToken, err = buildShareToken(&share, slotAt(slots, i), additionalPassword, holderKeyAt(holderKeys, i), window, epoch); err != nil {
 				return err
 			}
 
 			list.TokenList = append(list.TokenList, base64.StdEncoding.EncodeToString(shareToken))
+			if holderKey := holderKeyAt(holderKeys, i); holderKey != nil {
+				list.Holders = append(list.Holders, holderKey.Name)
+			}
+			if slot := slotAt(slots, i); slot != nil {
+				list.Members = append(list.Members, fmt.Sprintf("%s (%s)", slot.Member, slot.Group))
+			}
 		}
 
 		if _, err = lib.WriteFormatted(writer, tokenWriterFormat, list); err != nil {
@@ -409,16 +1026,55 @@ func SaveShareTokens(
 	return nil
 }
 
-func buildShareToken(share *shamir.Share, additionalPassword []byte) ([]byte, error) {
-	shareToken, err := token.Build(
-		token.Token{
-			Version:   token.Version,
-			ID:        int(share.ID),
-			Value:     hex.EncodeToString(share.Value),
-			Signature: hex.EncodeToString(share.Signature),
-		},
-		additionalPassword,
+// holderKeyAt - returns the holder key for share index i, or nil when shares
+// are not sealed per holder.
+func holderKeyAt(holderKeys []token.HolderKey, i int) *token.HolderKey {
+	if i < 0 || i >= len(holderKeys) {
+		return nil
+	}
+
+	return &holderKeys[i]
+}
+
+// slotAt - returns the policy slot for share index i, or nil without a policy.
+func slotAt(slots []shamir.Slot, i int) *shamir.Slot {
+	if i < 0 || i >= len(slots) {
+		return nil
+	}
+
+	return &slots[i]
+}
+
+func buildShareToken(
+	share *shamir.Share,
+	slot *shamir.Slot,
+	additionalPassword []byte,
+	holderKey *token.HolderKey,
+	window token.Window,
+	epoch uint32,
+) ([]byte, error) {
+	tok := token.Token{
+		Version:   token.Version,
+		ID:        int(share.ID),
+		Value:     hex.EncodeToString(share.Value),
+		Signature: hex.EncodeToString(share.Signature),
+		Epoch:     epoch,
+	}
+	if slot != nil {
+		tok.Group = slot.Group
+		tok.Member = slot.Member
+	}
+	window.Apply(&tok)
+
+	var (
+		shareToken []byte
+		err        error
 	)
+	if holderKey != nil {
+		shareToken, err = token.BuildForHolder(tok, *holderKey)
+	} else {
+		shareToken, err = token.Build(tok, additionalPassword)
+	}
 	if err != nil {
 		return nil, lib.CryptoErr(
 			lib.CategorySeal,
@@ -434,10 +1090,12 @@ func buildShareToken(share *shamir.Share, additionalPassword []byte) ([]byte, er
 
 func SaveMasterToken(
 	additionalPassword, masterKey []byte,
+	window token.Window,
+	epoch uint32,
 	writerFormat string,
 	w io.Writer,
 ) error {
-	encodedToken, err := buildMasterToken(additionalPassword, masterKey)
+	encodedToken, err := buildMasterToken(additionalPassword, masterKey, window, epoch)
 	if err != nil {
 		return err
 	}
@@ -465,14 +1123,15 @@ func SaveMasterToken(
 	return nil
 }
 
-func buildMasterToken(pwd, masterKey []byte) (string, error) {
-	raw, err := token.Build(
-		token.Token{
-			Version: token.Version,
-			Value:   hex.EncodeToString(masterKey),
-		},
-		pwd,
-	)
+func buildMasterToken(pwd, masterKey []byte, window token.Window, epoch uint32) (string, error) {
+	tok := token.Token{
+		Version: token.Version,
+		Value:   hex.EncodeToString(masterKey),
+		Epoch:   epoch,
+	}
+	window.Apply(&tok)
+
+	raw, err := token.Build(tok, pwd)
 	if err != nil {
 		return "", lib.CryptoErr(
 			lib.CategorySeal,
@@ -0,0 +1,121 @@
+package seal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// checkNewPath - fails before any key is wrapped or file packed when the
+// container path exists and the overwrite policy keeps it. A container
+// streamed to stdout has no path to check.
+func checkNewPath(containerOpts *lib.Container) error {
+	if *containerOpts.Overwrite != lib.OverwriteNever || *containerOpts.NewPath == lib.StdioPath {
+		return nil
+	}
+
+	if _, err := os.Lstat(*containerOpts.NewPath); err == nil {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerExists)
+	}
+
+	return nil
+}
+
+// newTempContainerPath - creates the empty temporary file a container is
+// written to, next to targetPath so the final rename stays on one file system.
+func newTempContainerPath(targetPath string) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(targetPath), ".tvault-container-*.tmp")
+	if err != nil {
+		return "", lib.IOErr(lib.CategorySeal, lib.ErrCodeSealWriteContainerError, lib.ErrMessageSealWriteContainerError, "", err)
+	}
+	_ = tmp.Close()
+
+	return tmp.Name(), nil
+}
+
+// commitContainer - moves the finished temporary container to targetPath as
+// the overwrite policy allows, then syncs the directory so the rename survives
+// a power failure. tmpPath is left in place when the commit fails.
+func commitContainer(tmpPath, targetPath, overwrite string) error {
+	var err error
+	switch overwrite {
+	case lib.OverwriteAlways:
+		err = renameContainer(tmpPath, targetPath)
+	case lib.OverwriteBackup:
+		err = renameWithBackup(tmpPath, targetPath)
+	default:
+		err = renameNoClobber(tmpPath, targetPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = lib.SyncDir(filepath.Dir(targetPath)); err != nil {
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeSealSyncDirError, lib.ErrMessageSealSyncDirError, "", err)
+	}
+
+	return nil
+}
+
+// renameNoClobber - moves tmpPath to targetPath unless targetPath exists. A
+// hard link fails atomically on an existing target, so a container created
+// while this one was sealed is never replaced; file systems without hard links
+// fall back to a check and a rename.
+func renameNoClobber(tmpPath, targetPath string) error {
+	err := os.Link(tmpPath, targetPath)
+	switch {
+	case err == nil:
+		_ = os.Remove(tmpPath)
+		return nil
+	case errors.Is(err, fs.ErrExist):
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerExists)
+	}
+
+	if _, err = os.Lstat(targetPath); err == nil {
+		return lib.ValidationErr(lib.CategorySeal, lib.ErrContainerExists)
+	}
+
+	return renameContainer(tmpPath, targetPath)
+}
+
+// renameWithBackup - keeps an existing container as targetPath+BackupSuffix,
+// replacing an older backup, and moves tmpPath over targetPath. The backup is a
+// hard link where possible, so targetPath holds the old container until the
+// rename replaces it; otherwise the old container is renamed aside and put
+// back if the second rename fails.
+func renameWithBackup(tmpPath, targetPath string) error {
+	if _, err := os.Lstat(targetPath); err != nil {
+		return renameContainer(tmpPath, targetPath)
+	}
+
+	backupPath := targetPath + lib.BackupSuffix
+	if err := os.Remove(backupPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeSealBackupContainerError, lib.ErrMessageSealBackupContainerError, "", err)
+	}
+
+	if err := os.Link(targetPath, backupPath); err == nil {
+		return renameContainer(tmpPath, targetPath)
+	}
+
+	if err := os.Rename(targetPath, backupPath); err != nil {
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeSealBackupContainerError, lib.ErrMessageSealBackupContainerError, "", err)
+	}
+
+	if err := renameContainer(tmpPath, targetPath); err != nil {
+		_ = os.Rename(backupPath, targetPath)
+		return err
+	}
+
+	return nil
+}
+
+func renameContainer(tmpPath, targetPath string) error {
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		return lib.IOErr(lib.CategorySeal, lib.ErrCodeSealWriteContainerError, lib.ErrMessageSealWriteContainerError, "", err)
+	}
+
+	return nil
+}
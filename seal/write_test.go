@@ -0,0 +1,112 @@
+package seal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestCommitContainer(t *testing.T) {
+	tests := []struct {
+		name       string
+		overwrite  string
+		existing   bool
+		oldBackup  bool
+		wantErr    error
+		wantTarget string
+		wantBackup string
+	}{
+		{name: "never creates", overwrite: lib.OverwriteNever, wantTarget: "new"},
+		{name: "never keeps existing", overwrite: lib.OverwriteNever, existing: true, wantErr: lib.ErrContainerExists, wantTarget: "old"},
+		{name: "always creates", overwrite: lib.OverwriteAlways, wantTarget: "new"},
+		{name: "always replaces", overwrite: lib.OverwriteAlways, existing: true, wantTarget: "new"},
+		{name: "backup creates without backup", overwrite: lib.OverwriteBackup, wantTarget: "new"},
+		{name: "backup keeps old container", overwrite: lib.OverwriteBackup, existing: true, wantTarget: "new", wantBackup: "old"},
+		{name: "backup replaces older backup", overwrite: lib.OverwriteBackup, existing: true, oldBackup: true, wantTarget: "new", wantBackup: "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			targetPath := filepath.Join(dir, "vault.tvlt")
+			backupPath := targetPath + lib.BackupSuffix
+
+			if tt.existing {
+				writeFile(t, targetPath, "old")
+			}
+			if tt.oldBackup {
+				writeFile(t, backupPath, "older")
+			}
+
+			tmpPath, err := newTempContainerPath(targetPath)
+			if err != nil {
+				t.Fatalf("newTempContainerPath() error = %v", err)
+			}
+			writeFile(t, tmpPath, "new")
+
+			err = commitContainer(tmpPath, targetPath, tt.overwrite)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("commitContainer() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := readFile(t, targetPath); got != tt.wantTarget {
+				t.Errorf("target = %q, want %q", got, tt.wantTarget)
+			}
+
+			if tt.wantBackup != "" {
+				if got := readFile(t, backupPath); got != tt.wantBackup {
+					t.Errorf("backup = %q, want %q", got, tt.wantBackup)
+				}
+			} else if _, err = os.Stat(backupPath); !os.IsNotExist(err) {
+				t.Errorf("unexpected backup %s: %v", backupPath, err)
+			}
+
+			// A successful commit consumes the temporary file; a refused one
+			// leaves it for CreateContainer to remove.
+			if _, err = os.Stat(tmpPath); (err == nil) != (tt.wantErr != nil) {
+				t.Errorf("temporary file present = %v, want %v", err == nil, tt.wantErr != nil)
+			}
+		})
+	}
+}
+
+func TestCheckNewPath(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "vault.tvlt")
+	opts := &lib.Container{NewPath: lib.StringPtr(targetPath), Overwrite: lib.StringPtr(lib.OverwriteNever)}
+
+	if err := checkNewPath(opts); err != nil {
+		t.Fatalf("checkNewPath() on a new path error = %v", err)
+	}
+
+	writeFile(t, targetPath, "old")
+	if err := checkNewPath(opts); !errors.Is(err, lib.ErrContainerExists) {
+		t.Errorf("checkNewPath() error = %v, want %v", err, lib.ErrContainerExists)
+	}
+
+	opts.Overwrite = lib.StringPtr(lib.OverwriteBackup)
+	if err := checkNewPath(opts); err != nil {
+		t.Errorf("checkNewPath() with overwrite backup error = %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+
+	return string(data)
+}
@@ -166,7 +166,7 @@ func (s score) scoreSensitiveFiles() float64 {
 // scoreIntegrityProvider - scores the integrity provider choice.
 func (s score) scoreIntegrityProvider() float64 {
 	switch s.params.IntegrityProviderType {
-	case integrity.TypeNameEd25519:
+	case integrity.TypeNameEd25519, integrity.TypeNameFeldman:
 		return 1.0
 	case integrity.TypeNameHMAC:
 		return 0.7
@@ -182,7 +182,7 @@ func (s score) scoreToken() float64 {
 	switch s.params.TokenType {
 	case token.TypeNameShare:
 		return 1.0
-	case token.TypeNameMaster:
+	case token.TypeNameMaster, token.TypeNamePlugin, token.TypeNameKMS:
 		return 0.5
 	case token.TypeNameNone:
 		return 0.0
@@ -0,0 +1,322 @@
+package shamir
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+)
+
+const (
+	// feldmanScalarLen - length of a P-256 scalar, and so of a Feldman share value.
+	feldmanScalarLen = 32
+
+	// feldmanKeyInfo - HKDF info string binding the shared scalar to the sealed secret.
+	feldmanKeyInfo = "tvault-core feldman secret v1"
+)
+
+// Feldman - public data of a verifiable split. A random scalar s is shared
+// with Shamir over the P-256 group order and the secret is sealed under a key
+// derived from s. Commitments are the compressed points a_j·G of the sharing
+// polynomial's coefficients, so anyone can check a share without a shared
+// secret and without learning s.
+type Feldman struct {
+	Commitments  [][]byte `json:"commitments"`
+	SealedSecret []byte   `json:"sealed_secret"`
+}
+
+// Threshold - number of shares needed to recover the secret.
+func (f *Feldman) Threshold() int {
+	if f == nil {
+		return 0
+	}
+
+	return len(f.Commitments)
+}
+
+// SplitFeldman - splits input into n verifiable shares with threshold t. The
+// shares carry no signature: they are checked against the returned Feldman
+// commitments with VerifyFeldman.
+//
+//nolint:staticcheck // SA1019: crypto/elliptic is the only stdlib API for raw P-256 point arithmetic
+func SplitFeldman(input []byte, n, t int) ([]Share, *Feldman, error) {
+	if t < 2 || t > 255 || n < t || n > 255 {
+		return nil, nil, lib.InternalErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirInvalidThresholdOrShares,
+			lib.ErrMessageShamirInvalidThresholdOrShares,
+			"",
+			errors.New(lib.ErrMessageShamirInvalidThresholdOrShares),
+		)
+	}
+
+	var (
+		curve  = elliptic.P256()
+		order  = curve.Params().N
+		coeffs = make([]*big.Int, t)
+		err    error
+	)
+	defer wipeScalars(coeffs)
+
+	for j := range coeffs {
+		if coeffs[j], err = randomScalar(order); err != nil {
+			return nil, nil, lib.IOErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirIOReadFullError,
+				lib.ErrMessageShamirIOReadFullError,
+				"",
+				err,
+			)
+		}
+	}
+
+	sealedSecret, err := sealFeldmanSecret(coeffs[0], input)
+	if err != nil {
+		return nil, nil, lib.CryptoErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirFeldmanSealError,
+			lib.ErrMessageShamirFeldmanSealError,
+			"",
+			err,
+		)
+	}
+
+	feldman := &Feldman{
+		Commitments:  make([][]byte, t),
+		SealedSecret: sealedSecret,
+	}
+	for j, coeff := range coeffs {
+		x, y := curve.ScalarBaseMult(scalarBytes(coeff))
+		feldman.Commitments[j] = elliptic.MarshalCompressed(curve, x, y)
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		id := byte(i + 1) // #nosec G115 -- n is capped at 255 above
+		value := evalPolyMod(coeffs, big.NewInt(int64(id)), order)
+		shares[i] = Share{
+			ID:         id,
+			Value:      scalarBytes(value),
+			ProviderID: integrity.TypeFeldman,
+		}
+		wipeScalars([]*big.Int{value})
+	}
+
+	return shares, feldman, nil
+}
+
+// VerifyFeldman - reports whether share lies on the committed polynomial:
+// value·G == Σ id^j·C_j.
+//
+//nolint:staticcheck // SA1019: crypto/elliptic is the only stdlib API for raw P-256 point arithmetic
+func VerifyFeldman(share Share, feldman *Feldman) bool {
+	curve := elliptic.P256()
+	order := curve.Params().N
+
+	if feldman.Threshold() == 0 || share.ID == 0 || len(share.Value) != feldmanScalarLen {
+		return false
+	}
+
+	value := new(big.Int).SetBytes(share.Value)
+	if value.Cmp(order) >= 0 {
+		return false
+	}
+
+	var (
+		x, y  *big.Int
+		id    = big.NewInt(int64(share.ID))
+		power = big.NewInt(1)
+	)
+	for _, commitment := range feldman.Commitments {
+		cx, cy := elliptic.UnmarshalCompressed(curve, commitment)
+		if cx == nil {
+			return false
+		}
+
+		tx, ty := curve.ScalarMult(cx, cy, scalarBytes(power))
+		if x == nil {
+			x, y = tx, ty
+		} else {
+			x, y = curve.Add(x, y, tx, ty)
+		}
+
+		power.Mul(power, id).Mod(power, order)
+	}
+
+	vx, vy := curve.ScalarBaseMult(share.Value)
+
+	return vx.Cmp(x) == 0 && vy.Cmp(y) == 0
+}
+
+// CombineFeldman - recovers the secret from verifiable shares. Shares failing
+// VerifyFeldman are left out and their ids returned; the rest are combined as
+// long as at least the threshold of them are valid.
+func CombineFeldman(shares []Share, feldman *Feldman) ([]byte, []byte, error) {
+	if err := checkShareIDs(shares); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		valid   = make([]Share, 0, len(shares))
+		invalid []byte
+	)
+	for _, sh := range shares {
+		if VerifyFeldman(sh, feldman) {
+			valid = append(valid, sh)
+		} else {
+			invalid = append(invalid, sh.ID)
+		}
+	}
+	slices.Sort(invalid)
+
+	threshold := feldman.Threshold()
+	if threshold == 0 || len(valid) < threshold {
+		return nil, invalid, lib.CryptoErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirFeldmanInsufficientShares,
+			lib.ErrMessageShamirFeldmanInsufficient,
+			strconv.Itoa(len(valid))+" of "+strconv.Itoa(threshold)+" required shares are valid; invalid shares: "+
+				FormatIDs(invalid),
+			lib.ErrShamirFeldmanInsufficientShares,
+		)
+	}
+
+	s := interpolateScalar(valid[:threshold])
+	defer wipeScalars([]*big.Int{s})
+
+	key, err := openFeldmanSecret(s, feldman.SealedSecret)
+	if err != nil {
+		return nil, invalid, lib.CryptoErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirFeldmanOpenError,
+			lib.ErrMessageShamirFeldmanOpenError,
+			"",
+			err,
+		)
+	}
+
+	return key, invalid, nil
+}
+
+// interpolateScalar - Lagrange interpolation at 0 over the P-256 group order.
+func interpolateScalar(shares []Share) *big.Int {
+	var (
+		order  = elliptic.P256().Params().N
+		result = new(big.Int)
+	)
+	for i, si := range shares {
+		var (
+			num = big.NewInt(1)
+			den = big.NewInt(1)
+			xi  = big.NewInt(int64(si.ID))
+		)
+		for j, sj := range shares {
+			if i == j {
+				continue
+			}
+
+			xj := big.NewInt(int64(sj.ID))
+			num.Mul(num, xj).Mod(num, order)
+			den.Mul(den, new(big.Int).Sub(xj, xi)).Mod(den, order)
+		}
+
+		term := new(big.Int).SetBytes(si.Value)
+		term.Mul(term, num).Mul(term, den.ModInverse(den, order)).Mod(term, order)
+		result.Add(result, term).Mod(result, order)
+	}
+
+	return result
+}
+
+// evalPolyMod - evaluates the polynomial with coefficients coeffs at x modulo order.
+func evalPolyMod(coeffs []*big.Int, x, order *big.Int) *big.Int {
+	result := new(big.Int)
+	for j := len(coeffs) - 1; j >= 0; j-- {
+		result.Mul(result, x).Add(result, coeffs[j]).Mod(result, order)
+	}
+
+	return result
+}
+
+// randomScalar - returns a uniformly random scalar in [1, order-1].
+func randomScalar(order *big.Int) (*big.Int, error) {
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(order, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+// wipeScalars - zeroes the words of secret scalars, such as the polynomial
+// coefficients. Temporaries of big.Int arithmetic are not reached.
+func wipeScalars(scalars []*big.Int) {
+	for _, k := range scalars {
+		if k != nil {
+			clear(k.Bits())
+			k.SetInt64(0)
+		}
+	}
+}
+
+func scalarBytes(k *big.Int) []byte {
+	return k.FillBytes(make([]byte, feldmanScalarLen))
+}
+
+// sealFeldmanSecret - AES-256-GCM under HKDF-SHA256(s); returns nonce || ciphertext+tag.
+func sealFeldmanSecret(s *big.Int, secret []byte) ([]byte, error) {
+	aesGCM, err := newFeldmanGCM(s)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize(), aesGCM.NonceSize()+len(secret)+aesGCM.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nonce, nonce, secret, nil), nil
+}
+
+// openFeldmanSecret - reverses sealFeldmanSecret.
+func openFeldmanSecret(s *big.Int, sealed []byte) ([]byte, error) {
+	aesGCM, err := newFeldmanGCM(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aesGCM.NonceSize()+aesGCM.Overhead() {
+		return nil, lib.ErrShamirFeldmanSealedTooShort
+	}
+
+	return aesGCM.Open(nil, sealed[:aesGCM.NonceSize()], sealed[aesGCM.NonceSize():], nil)
+}
+
+func newFeldmanGCM(s *big.Int) (cipher.AEAD, error) {
+	ikm := scalarBytes(s)
+	defer secret.Wipe(ikm)
+
+	key, err := hkdf.Key(sha256.New, ikm, nil, feldmanKeyInfo, lib.KeyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Wipe(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
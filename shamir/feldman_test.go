@@ -0,0 +1,94 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestSplitCombineFeldman(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	shares, feldman, err := SplitFeldman(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("SplitFeldman() error = %v", err)
+	}
+	if feldman.Threshold() != 3 {
+		t.Fatalf("Threshold() = %d, want 3", feldman.Threshold())
+	}
+
+	for _, sh := range shares {
+		if !VerifyFeldman(sh, feldman) {
+			t.Errorf("VerifyFeldman() = false for share %d", sh.ID)
+		}
+	}
+
+	tampered := slices.Clone(shares)
+	tampered[1].Value = slices.Clone(tampered[1].Value)
+	tampered[1].Value[31] ^= 0x01
+
+	tests := []struct {
+		name        string
+		shares      []Share
+		wantInvalid []byte
+		wantErr     error
+	}{
+		{
+			name:   "threshold_shares",
+			shares: shares[2:],
+		},
+		{
+			name:        "tampered_share_excluded",
+			shares:      tampered[:4],
+			wantInvalid: []byte{2},
+		},
+		{
+			name:        "too_few_valid_shares",
+			shares:      tampered[:3],
+			wantInvalid: []byte{2},
+			wantErr:     lib.ErrShamirFeldmanInsufficientShares,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, invalid, err := CombineFeldman(tt.shares, feldman)
+			if !slices.Equal(invalid, tt.wantInvalid) {
+				t.Errorf("CombineFeldman() invalid = %v, want %v", invalid, tt.wantInvalid)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("CombineFeldman() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CombineFeldman() error = %v", err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("CombineFeldman() = %x, want %x", got, secret)
+			}
+		})
+	}
+}
+
+func TestVerifyFeldmanForeignCommitments(t *testing.T) {
+	shares, _, err := SplitFeldman([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatalf("SplitFeldman() error = %v", err)
+	}
+
+	_, other, err := SplitFeldman([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatalf("SplitFeldman() error = %v", err)
+	}
+
+	if VerifyFeldman(shares[0], other) {
+		t.Error("VerifyFeldman() = true against commitments of another split")
+	}
+}
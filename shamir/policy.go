@@ -0,0 +1,481 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+)
+
+// Policy - a two-level access structure. The secret is split into one share per
+// unit of group weight with the top-level Threshold; each group's shares are
+// then split again among its members with the group's own Threshold, a member
+// of weight w receiving w shares. "2 of 3 executives OR 1 executive plus 3 of 5
+// engineers" is threshold 2 over: executives (2 of 3, weight 2), executive
+// (1 of 3, weight 1) and engineers (3 of 5, weight 1).
+type (
+	Policy struct {
+		Threshold int     `json:"threshold"`
+		Groups    []Group `json:"groups"`
+	}
+
+	Group struct {
+		Name      string   `json:"name"`
+		Threshold int      `json:"threshold"`
+		Weight    int      `json:"weight,omitempty"`
+		Members   []Member `json:"members"`
+	}
+
+	Member struct {
+		Name   string `json:"name"`
+		Weight int    `json:"weight,omitempty"`
+	}
+
+	// Slot - one share of a policy split: its global share id (the x-coordinate
+	// inside its group, unique across the policy) and who it belongs to.
+	Slot struct {
+		ID     byte
+		Group  string
+		Member string
+	}
+)
+
+// LoadPolicy - reads and validates a policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err = json.Unmarshal(content, &policy); err != nil {
+		return nil, err
+	}
+
+	if err = policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// Validate - checks the thresholds, weights, names and the 255-share limit.
+func (p *Policy) Validate() error {
+	if len(p.Groups) == 0 {
+		return lib.ErrShamirPolicyNoGroups
+	}
+
+	if p.Threshold < 1 || p.Threshold > p.topShares() {
+		return lib.ErrShamirPolicyThresholdInvalid
+	}
+
+	groupNames := make(map[string]struct{}, len(p.Groups))
+	for _, group := range p.Groups {
+		if group.Name == "" {
+			return lib.ErrShamirPolicyNameRequired
+		}
+		if _, ok := groupNames[group.Name]; ok {
+			return lib.ErrShamirPolicyNameDuplicated
+		}
+		groupNames[group.Name] = struct{}{}
+
+		if group.Weight < 0 || len(group.Members) == 0 {
+			return fmt.Errorf("%w; group %s", lib.ErrShamirPolicyWeightInvalid, group.Name)
+		}
+
+		memberNames := make(map[string]struct{}, len(group.Members))
+		for _, member := range group.Members {
+			if member.Name == "" {
+				return lib.ErrShamirPolicyNameRequired
+			}
+			if _, ok := memberNames[member.Name]; ok {
+				return lib.ErrShamirPolicyNameDuplicated
+			}
+			memberNames[member.Name] = struct{}{}
+
+			if member.Weight < 0 {
+				return fmt.Errorf("%w; member %s", lib.ErrShamirPolicyWeightInvalid, member.Name)
+			}
+		}
+
+		if group.Threshold < 1 || group.Threshold > group.shares() {
+			return fmt.Errorf("%w; group %s", lib.ErrShamirPolicyThresholdInvalid, group.Name)
+		}
+	}
+
+	if p.Shares() > 255 || p.topShares() > 255 {
+		return lib.ErrShamirSharesGreaterThan255
+	}
+
+	return nil
+}
+
+// Shares - total number of member shares (tokens) the policy produces.
+func (p *Policy) Shares() int {
+	var total int
+	for _, group := range p.Groups {
+		total += group.shares()
+	}
+
+	return total
+}
+
+// Slots - enumerates the member shares in token order: groups in order, members
+// in order, weight times each. Share ids run from 1 to Shares().
+func (p *Policy) Slots() []Slot {
+	slots := make([]Slot, 0, p.Shares())
+	for _, group := range p.Groups {
+		for _, member := range group.Members {
+			for w := 0; w < weightOf(member.Weight); w++ {
+				slots = append(slots, Slot{
+					ID:     byte(len(slots) + 1), // #nosec G115 -- Validate caps Shares() at 255
+					Group:  group.Name,
+					Member: member.Name,
+				})
+			}
+		}
+	}
+
+	return slots
+}
+
+// Members - distinct member names across all groups, in first-seen order.
+func (p *Policy) Members() []string {
+	var (
+		seen  = make(map[string]struct{})
+		names []string
+	)
+	for _, group := range p.Groups {
+		for _, member := range group.Members {
+			if _, ok := seen[member.Name]; !ok {
+				seen[member.Name] = struct{}{}
+				names = append(names, member.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+func (p *Policy) topShares() int {
+	var total int
+	for _, group := range p.Groups {
+		total += weightOf(group.Weight)
+	}
+
+	return total
+}
+
+func (g Group) shares() int {
+	var total int
+	for _, member := range g.Members {
+		total += weightOf(member.Weight)
+	}
+
+	return total
+}
+
+// weightOf - an omitted (zero) weight counts as 1.
+func weightOf(weight int) int {
+	if weight == 0 {
+		return 1
+	}
+
+	return weight
+}
+
+// SplitPolicy - splits input according to policy and signs every member share
+// with provider. Shares are returned in Slots() order.
+func SplitPolicy(input []byte, policy *Policy, provider integrity.Provider) ([]Share, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, lib.InternalErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirInvalidThresholdOrShares,
+			lib.ErrMessageShamirInvalidThresholdOrShares,
+			"",
+			err,
+		)
+	}
+
+	topValues, err := splitValues(input, sequentialIDs(1, policy.topShares()), policy.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		slots  = policy.Slots()
+		shares = make([]Share, 0, len(slots))
+		topIdx int
+		next   int
+	)
+	for _, group := range policy.Groups {
+		// The group secret is the concatenation of the group's top-level shares.
+		var groupSecret []byte
+		for w := 0; w < weightOf(group.Weight); w++ {
+			groupSecret = append(groupSecret, topValues[topIdx]...)
+			topIdx++
+		}
+
+		groupSlots := slots[next : next+group.shares()]
+		next += group.shares()
+
+		ids := make([]byte, len(groupSlots))
+		for i, slot := range groupSlots {
+			ids[i] = slot.ID
+		}
+
+		values, err := splitValues(groupSecret, ids, group.Threshold)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, id := range ids {
+			signature, err := provider.Sign(id, values[i])
+			if err != nil {
+				return nil, lib.CryptoErr(
+					lib.CategoryShamir,
+					lib.ErrCodeShamirSignShareError,
+					lib.ErrMessageShamirSignShareError,
+					"",
+					err,
+				)
+			}
+
+			shares = append(shares, Share{
+				ID:         id,
+				Value:      values[i],
+				ProviderID: provider.ID(),
+				Signature:  signature,
+			})
+		}
+	}
+
+	return shares, nil
+}
+
+// CombinePolicy - reconstructs the secret from member shares split with
+// SplitPolicy. Signatures are verified first. When the shares do not satisfy
+// the policy, the error details list which groups are short and by how much.
+func CombinePolicy(shares []Share, policy *Policy, provider integrity.Provider) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, lib.ErrEmptyShares
+	}
+
+	if err := verifyShares(shares, provider); err != nil {
+		return nil, err
+	}
+
+	var (
+		slots = policy.Slots()
+		byID  = make(map[byte]Share, len(shares))
+	)
+	for _, share := range shares {
+		if int(share.ID) > len(slots) {
+			return nil, lib.FormatErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirDuplicateShareID,
+				lib.ErrMessageShamirDuplicateShareID,
+				"",
+				nil,
+			)
+		}
+		byID[share.ID] = share
+	}
+
+	var (
+		topIDs    []byte
+		topValues [][]byte
+		topID     = 1
+		next      int
+		missing   []string
+	)
+	for _, group := range policy.Groups {
+		weight := weightOf(group.Weight)
+		groupSlots := slots[next : next+group.shares()]
+		next += group.shares()
+
+		var (
+			ids       []byte
+			values    [][]byte
+			absent    []string
+			seenNames = make(map[string]struct{})
+		)
+		for _, slot := range groupSlots {
+			share, ok := byID[slot.ID]
+			if !ok {
+				if _, dup := seenNames[slot.Member]; !dup {
+					absent = append(absent, slot.Member)
+					seenNames[slot.Member] = struct{}{}
+				}
+				continue
+			}
+			ids = append(ids, share.ID)
+			values = append(values, share.Value)
+		}
+
+		if len(ids) < group.Threshold {
+			missing = append(missing, fmt.Sprintf(
+				"group %s has %d of %d required shares (not supplied: %s)",
+				group.Name, len(ids), group.Threshold, strings.Join(absent, ", "),
+			))
+			topID += weight
+
+			continue
+		}
+
+		for _, value := range values {
+			if len(value) != len(values[0]) || len(value)%weight != 0 {
+				return nil, lib.FormatErr(
+					lib.CategoryShamir,
+					lib.ErrCodeShamirShareLengthMismatch,
+					lib.ErrMessageShamirShareLengthMismatch,
+					"",
+					nil,
+				)
+			}
+		}
+
+		// Each group secret is `weight` top-level shares laid end to end.
+		groupSecret := interpolateValues(ids[:group.Threshold], values[:group.Threshold])
+		topLen := len(groupSecret) / weight
+		for w := 0; w < weight; w++ {
+			topIDs = append(topIDs, byte(topID)) // #nosec G115 -- Validate caps top shares at 255
+			topValues = append(topValues, groupSecret[w*topLen:(w+1)*topLen])
+			topID++
+		}
+	}
+
+	if len(topIDs) < policy.Threshold {
+		missing = append(missing, fmt.Sprintf(
+			"policy needs %d group shares, the satisfied groups provide %d",
+			policy.Threshold, len(topIDs),
+		))
+
+		return nil, lib.CryptoErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirPolicyUnsatisfied,
+			lib.ErrMessageShamirPolicyUnsatisfied,
+			strings.Join(missing, "; "),
+			lib.ErrShamirPolicyUnsatisfied,
+		)
+	}
+
+	return interpolateValues(topIDs[:policy.Threshold], topValues[:policy.Threshold]), nil
+}
+
+// verifyShares - rejects zero or duplicate share ids (see Combine) and checks
+// every signature with provider. Value lengths differ between policy groups,
+// so they are checked per group by the caller.
+func verifyShares(shares []Share, provider integrity.Provider) error {
+	seen := make(map[byte]struct{}, len(shares))
+	for _, sh := range shares {
+		if _, dup := seen[sh.ID]; dup || sh.ID == 0 {
+			return lib.FormatErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirDuplicateShareID,
+				lib.ErrMessageShamirDuplicateShareID,
+				"",
+				nil,
+			)
+		}
+		seen[sh.ID] = struct{}{}
+
+		isVerify, err := provider.IsVerify(sh.ID, sh.Value, sh.Signature)
+		if err != nil {
+			return lib.CryptoErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirVerifySignatureError,
+				lib.ErrMessageShamirVerifySignatureError,
+				"",
+				err,
+			)
+		}
+
+		if !isVerify {
+			return lib.CryptoErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirVerifySignatureFailed,
+				lib.ErrMessageShamirVerifySignatureFailed,
+				"",
+				nil,
+			)
+		}
+	}
+
+	return nil
+}
+
+// splitValues - evaluates a random degree t-1 polynomial per input byte at each
+// x in ids. Unlike Split it accepts t == 1 (every share equals the input),
+// which policies need for "any one member of the group".
+func splitValues(input, ids []byte, t int) ([][]byte, error) {
+	if t < 1 || t > len(ids) {
+		return nil, lib.InternalErr(
+			lib.CategoryShamir,
+			lib.ErrCodeShamirInvalidThresholdOrShares,
+			lib.ErrMessageShamirInvalidThresholdOrShares,
+			"",
+			errors.New(lib.ErrMessageShamirInvalidThresholdOrShares),
+		)
+	}
+
+	values := make([][]byte, len(ids))
+	for i := range values {
+		values[i] = make([]byte, len(input))
+	}
+
+	cfs := make([]byte, t)
+	defer secret.Wipe(cfs)
+
+	for i, b := range input {
+		cfs[0] = b
+		if _, err := io.ReadFull(rand.Reader, cfs[1:]); err != nil {
+			wipeValues(values)
+			return nil, lib.IOErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirIOReadFullError,
+				lib.ErrMessageShamirIOReadFullError,
+				"",
+				err,
+			)
+		}
+
+		for j, x := range ids {
+			values[j][i] = evalPoly(cfs, x)
+		}
+	}
+
+	return values, nil
+}
+
+// interpolateValues - recovers the polynomial constant term byte by byte.
+func interpolateValues(ids []byte, values [][]byte) []byte {
+	res := make([]byte, len(values[0]))
+	yVals := make([]byte, len(ids))
+	defer secret.Wipe(yVals)
+
+	for i := range res {
+		for j := range values {
+			yVals[j] = values[j][i]
+		}
+		res[i] = lagrangeInterpolate(0, ids, yVals)
+	}
+
+	return res
+}
+
+func sequentialIDs(from, n int) []byte {
+	ids := make([]byte, n)
+	for i := range ids {
+		ids[i] = byte(from + i) // #nosec G115 -- callers stay within 1..255
+	}
+
+	return ids
+}
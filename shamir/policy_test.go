@@ -0,0 +1,193 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/integrity/hmac"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// executivesOrEngineers - "2 of 3 executives OR 1 executive plus 3 of 5 engineers".
+func executivesOrEngineers() *Policy {
+	executives := []Member{{Name: "ceo"}, {Name: "cfo"}, {Name: "cto"}}
+
+	return &Policy{
+		Threshold: 2,
+		Groups: []Group{
+			{Name: "executives", Threshold: 2, Weight: 2, Members: executives},
+			{Name: "executive", Threshold: 1, Members: executives},
+			{Name: "engineers", Threshold: 3, Members: []Member{
+				{Name: "e1"}, {Name: "e2"}, {Name: "e3"}, {Name: "e4"}, {Name: "e5"},
+			}},
+		},
+	}
+}
+
+func pick(shares []Share, slots []Slot, want map[string][]string) []Share {
+	var picked []Share
+	for i, slot := range slots {
+		for _, member := range want[slot.Group] {
+			if slot.Member == member {
+				picked = append(picked, shares[i])
+			}
+		}
+	}
+
+	return picked
+}
+
+func TestSplitCombinePolicy(t *testing.T) {
+	var (
+		secret   = []byte("0123456789abcdef0123456789abcdef")
+		policy   = executivesOrEngineers()
+		provider = hmac.New([]byte("integrity"))
+	)
+
+	shares, err := SplitPolicy(secret, policy, provider)
+	if err != nil {
+		t.Fatalf("SplitPolicy() error = %v", err)
+	}
+	if len(shares) != policy.Shares() || len(shares) != 11 {
+		t.Fatalf("SplitPolicy() returned %d shares, want %d", len(shares), policy.Shares())
+	}
+
+	slots := policy.Slots()
+
+	tests := []struct {
+		name    string
+		want    map[string][]string
+		wantErr bool
+		details string
+	}{
+		{
+			name: "two_executives",
+			want: map[string][]string{"executives": {"ceo", "cto"}},
+		},
+		{
+			name: "one_executive_three_engineers",
+			want: map[string][]string{"executive": {"cfo"}, "engineers": {"e1", "e3", "e5"}},
+		},
+		{
+			name:    "one_executive_only",
+			want:    map[string][]string{"executive": {"cfo"}, "executives": {"cfo"}},
+			wantErr: true,
+			details: "group executives has 1 of 2 required shares",
+		},
+		{
+			name:    "two_engineers_and_executive",
+			want:    map[string][]string{"executive": {"ceo"}, "engineers": {"e1", "e2"}},
+			wantErr: true,
+			details: "not supplied: e3, e4, e5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CombinePolicy(pick(shares, slots, tt.want), policy, provider)
+			if tt.wantErr {
+				if !errors.Is(err, lib.ErrShamirPolicyUnsatisfied) {
+					t.Fatalf("CombinePolicy() error = %v, want %v", err, lib.ErrShamirPolicyUnsatisfied)
+				}
+
+				e, _ := lib.AsError(err)
+				if !strings.Contains(e.Details, tt.details) {
+					t.Errorf("CombinePolicy() details = %q, want it to contain %q", e.Details, tt.details)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CombinePolicy() error = %v", err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("CombinePolicy() = %x, want %x", got, secret)
+			}
+		})
+	}
+}
+
+func TestPolicyWeights(t *testing.T) {
+	var (
+		secret   = []byte("weighted secret")
+		provider = hmac.New([]byte("integrity"))
+		policy   = &Policy{
+			Threshold: 1,
+			Groups: []Group{{Name: "board", Threshold: 3, Members: []Member{
+				{Name: "chair", Weight: 2}, {Name: "a"}, {Name: "b"},
+			}}},
+		}
+	)
+
+	shares, err := SplitPolicy(secret, policy, provider)
+	if err != nil {
+		t.Fatalf("SplitPolicy() error = %v", err)
+	}
+
+	got, err := CombinePolicy(pick(shares, policy.Slots(), map[string][]string{"board": {"chair", "b"}}), policy, provider)
+	if err != nil {
+		t.Fatalf("CombinePolicy() error = %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("CombinePolicy() = %q, want %q", got, secret)
+	}
+
+	if _, err = CombinePolicy(pick(shares, policy.Slots(), map[string][]string{"board": {"a", "b"}}), policy, provider); err == nil {
+		t.Error("CombinePolicy() expected error for two weight-1 members")
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr error
+	}{
+		{
+			name:    "no_groups",
+			policy:  Policy{Threshold: 1},
+			wantErr: lib.ErrShamirPolicyNoGroups,
+		},
+		{
+			name:    "threshold_above_group_weights",
+			policy:  Policy{Threshold: 2, Groups: []Group{{Name: "g", Threshold: 1, Members: []Member{{Name: "a"}}}}},
+			wantErr: lib.ErrShamirPolicyThresholdInvalid,
+		},
+		{
+			name:    "group_threshold_above_members",
+			policy:  Policy{Threshold: 1, Groups: []Group{{Name: "g", Threshold: 2, Members: []Member{{Name: "a"}}}}},
+			wantErr: lib.ErrShamirPolicyThresholdInvalid,
+		},
+		{
+			name: "duplicated_group",
+			policy: Policy{Threshold: 1, Groups: []Group{
+				{Name: "g", Threshold: 1, Members: []Member{{Name: "a"}}},
+				{Name: "g", Threshold: 1, Members: []Member{{Name: "b"}}},
+			}},
+			wantErr: lib.ErrShamirPolicyNameDuplicated,
+		},
+		{
+			name:    "negative_weight",
+			policy:  Policy{Threshold: 1, Groups: []Group{{Name: "g", Threshold: 1, Members: []Member{{Name: "a", Weight: -1}}}}},
+			wantErr: lib.ErrShamirPolicyWeightInvalid,
+		},
+		{
+			name:   "valid",
+			policy: *executivesOrEngineers(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package shamir
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+)
+
+// maxSubsetTrials - upper bound on the threshold-sized subsets tried per
+// search, so a large share set cannot turn into an exponential search.
+const maxSubsetTrials = 4096
+
+// KeyCheck - reports whether a candidate secret is the right one, e.g. by
+// authenticating the first payload chunk of a container with it.
+type KeyCheck func(secret []byte) bool
+
+// CombineConsistent - recovers the secret from a share set that may contain
+// corrupted shares. Shares failing their signature or the common value length
+// are dropped; threshold-sized subsets of the rest are combined until check
+// accepts the result. Returns the secret and the ids of every supplied share
+// that does not lie on the recovered polynomial.
+func CombineConsistent(
+	shares []Share,
+	threshold int,
+	provider integrity.Provider,
+	check KeyCheck,
+) ([]byte, []byte, error) {
+	if len(shares) < 2 || threshold < 2 {
+		return nil, nil, lib.ValidationErr(
+			lib.CategoryShamir,
+			lib.ErrShamirSharesLessThan2,
+		)
+	}
+
+	if err := checkShareIDs(shares); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		length     = commonLength(shares)
+		corrupted  []byte
+		candidates = make([]Share, 0, len(shares))
+	)
+	for _, sh := range shares {
+		if len(sh.Value) != length {
+			corrupted = append(corrupted, sh.ID)
+			continue
+		}
+
+		isVerify, err := provider.IsVerify(sh.ID, sh.Value, sh.Signature)
+		if err != nil {
+			return nil, nil, lib.CryptoErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirVerifySignatureError,
+				lib.ErrMessageShamirVerifySignatureError,
+				"",
+				err,
+			)
+		}
+		if !isVerify {
+			corrupted = append(corrupted, sh.ID)
+			continue
+		}
+
+		candidates = append(candidates, sh)
+	}
+
+	if len(candidates) >= threshold {
+		var recovered []byte
+		forEachSubset(len(candidates), threshold, func(subset []int) bool {
+			candidate := interpolateAt(0, subsetShares(candidates, subset))
+			if check(candidate) {
+				recovered = candidate
+			} else {
+				secret.Wipe(candidate)
+			}
+
+			return recovered != nil
+		})
+
+		if recovered != nil {
+			corrupted = append(corrupted, inconsistentIDs(candidates, threshold, recovered)...)
+			slices.Sort(corrupted)
+
+			return recovered, corrupted, nil
+		}
+	}
+
+	return nil, nil, lib.CryptoErr(
+		lib.CategoryShamir,
+		lib.ErrCodeShamirNoConsistentSubset,
+		lib.ErrMessageShamirNoConsistentSubset,
+		"no "+strconv.Itoa(threshold)+" of the supplied shares recover the key; shares failing verification: "+
+			FormatIDs(corrupted),
+		lib.ErrShamirNoConsistentSubset,
+	)
+}
+
+// FormatIDs - renders share ids as "2, 5", or "none".
+func FormatIDs(ids []byte) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(int(id))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// inconsistentIDs - returns the ids of candidates off the polynomial that goes
+// through key and agrees with the most candidates. Agreement decides rather
+// than the first subset yielding key, since shares corrupted alike can still
+// interpolate to the right key.
+func inconsistentIDs(candidates []Share, threshold int, key []byte) []byte {
+	var best []byte
+	forEachSubset(len(candidates), threshold, func(subset []int) bool {
+		picked := subsetShares(candidates, subset)
+		if !matchesAt(0, picked, key) {
+			return false
+		}
+
+		off := make([]byte, 0)
+		for idx, sh := range candidates {
+			if !slices.Contains(subset, idx) && !matchesAt(sh.ID, picked, sh.Value) {
+				off = append(off, sh.ID)
+			}
+		}
+
+		if best == nil || len(off) < len(best) {
+			best = off
+		}
+
+		return len(best) == 0
+	})
+
+	return best
+}
+
+// checkShareIDs - rejects a zero or repeated share id, as Combine does.
+func checkShareIDs(shares []Share) error {
+	seen := make(map[byte]struct{}, len(shares))
+	for _, sh := range shares {
+		if _, dup := seen[sh.ID]; dup || sh.ID == 0 {
+			return lib.FormatErr(
+				lib.CategoryShamir,
+				lib.ErrCodeShamirDuplicateShareID,
+				lib.ErrMessageShamirDuplicateShareID,
+				"",
+				nil,
+			)
+		}
+		seen[sh.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+// commonLength - the most frequent share value length; a share of any other
+// length cannot belong to the split.
+func commonLength(shares []Share) int {
+	var (
+		counts = make(map[int]int, 1)
+		best   int
+	)
+	for _, sh := range shares {
+		counts[len(sh.Value)]++
+		if counts[len(sh.Value)] > counts[best] {
+			best = len(sh.Value)
+		}
+	}
+
+	return best
+}
+
+// matchesAt - reports whether the polynomial through shares is want at x,
+// wiping the value it interpolates.
+func matchesAt(x byte, shares []Share, want []byte) bool {
+	got := interpolateAt(x, shares)
+	defer secret.Wipe(got)
+
+	return slices.Equal(got, want)
+}
+
+// interpolateAt - evaluates the polynomial through shares at x, byte by byte.
+func interpolateAt(x byte, shares []Share) []byte {
+	var (
+		xVals = make([]byte, len(shares))
+		yVals = make([]byte, len(shares))
+		res   = make([]byte, len(shares[0].Value))
+	)
+	defer secret.Wipe(yVals)
+
+	for i, sh := range shares {
+		xVals[i] = sh.ID
+	}
+
+	for i := range res {
+		for j, sh := range shares {
+			yVals[j] = sh.Value[i]
+		}
+
+		res[i] = lagrangeInterpolate(x, xVals, yVals)
+	}
+
+	return res
+}
+
+// forEachSubset - calls fn with every k-sized subset of indices into n items,
+// in lexicographic order, until fn returns true or maxSubsetTrials is reached.
+func forEachSubset(n, k int, fn func(subset []int) bool) {
+	subset := make([]int, k)
+	for i := range subset {
+		subset[i] = i
+	}
+
+	for trial := 0; trial < maxSubsetTrials; trial++ {
+		if fn(subset) || !nextSubset(subset, n) {
+			return
+		}
+	}
+}
+
+// nextSubset - advances subset, a sorted list of indices into n items, to the
+// next combination in lexicographic order; false when it was the last one.
+func nextSubset(subset []int, n int) bool {
+	k := len(subset)
+	for i := k - 1; i >= 0; i-- {
+		if subset[i] < n-k+i {
+			subset[i]++
+			for j := i + 1; j < k; j++ {
+				subset[j] = subset[j-1] + 1
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func subsetShares(shares []Share, subset []int) []Share {
+	picked := make([]Share, len(subset))
+	for i, idx := range subset {
+		picked[i] = shares[idx]
+	}
+
+	return picked
+}
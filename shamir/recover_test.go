@@ -0,0 +1,73 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/integrity/hmac"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestCombineConsistent(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	check := func(candidate []byte) bool { return bytes.Equal(candidate, secret) }
+
+	tests := []struct {
+		name          string
+		provider      integrity.Provider
+		threshold     int
+		corrupt       []int
+		expectCorrupt []byte
+	}{
+		{name: "all shares good", provider: integrity.NewNoneProvider(), threshold: 2},
+		{name: "one corrupted share", provider: integrity.NewNoneProvider(), threshold: 2, corrupt: []int{0}, expectCorrupt: []byte{1}},
+		{name: "two corrupted shares", provider: integrity.NewNoneProvider(), threshold: 2, corrupt: []int{1, 3}, expectCorrupt: []byte{2, 4}},
+		// Shares 1 and 2 corrupted alike still interpolate the secret with share 3.
+		{name: "shares corrupted alike", provider: integrity.NewNoneProvider(), threshold: 3, corrupt: []int{0, 1}, expectCorrupt: []byte{1, 2}},
+		{name: "signature failure", provider: hmac.New([]byte("pass")), threshold: 2, corrupt: []int{4}, expectCorrupt: []byte{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, err := Split(secret, 6, tt.threshold, tt.provider)
+			if err != nil {
+				t.Fatalf("Split error: %v", err)
+			}
+			for _, i := range tt.corrupt {
+				shares[i].Value[0] ^= 0xFF
+			}
+
+			got, corrupted, err := CombineConsistent(shares, tt.threshold, tt.provider, check)
+			if err != nil {
+				t.Fatalf("CombineConsistent error: %v", err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("secret = %x, want %x", got, secret)
+			}
+			if !bytes.Equal(corrupted, tt.expectCorrupt) {
+				t.Errorf("corrupted = %v, want %v", corrupted, tt.expectCorrupt)
+			}
+		})
+	}
+}
+
+func TestCombineConsistentNoGoodSubset(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	provider := integrity.NewNoneProvider()
+
+	shares, err := Split(secret, 4, 3, provider)
+	if err != nil {
+		t.Fatalf("Split error: %v", err)
+	}
+	shares[0].Value[0] ^= 0x01
+	shares[1].Value[0] ^= 0x02
+
+	_, _, err = CombineConsistent(shares, 3, provider, func(candidate []byte) bool {
+		return bytes.Equal(candidate, secret)
+	})
+	if !errors.Is(err, lib.ErrShamirNoConsistentSubset) {
+		t.Fatalf("expected ErrShamirNoConsistentSubset, got %v", err)
+	}
+}
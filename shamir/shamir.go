@@ -7,6 +7,7 @@ import (
 
 	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
 )
 
 type Share struct {
@@ -16,6 +17,18 @@ type Share struct {
 	Signature  []byte
 }
 
+// Wipe - zeroes the share value once it is no longer needed.
+func (s Share) Wipe() {
+	secret.Wipe(s.Value)
+}
+
+// WipeShares - zeroes the value of every share.
+func WipeShares(shares []Share) {
+	for _, sh := range shares {
+		sh.Wipe()
+	}
+}
+
 // Split - divides a secret into n shares with a recovery threshold t using Shamir's Secret Sharing scheme.
 // It signs each share with the provided integrity provider for authenticity and returns the shares.
 func Split(input []byte, n, t int, provider integrity.Provider) ([]Share, error) {
@@ -34,11 +47,15 @@ func Split(input []byte, n, t int, provider integrity.Provider) ([]Share, error)
 		shareData[i] = make([]byte, len(input))
 	}
 
+	// The coefficients hold the secret byte; they are wiped once split.
+	cfs := make([]byte, t)
+	defer secret.Wipe(cfs)
+
 	for i, b := range input {
-		cfs := make([]byte, t)
 		cfs[0] = b
 
 		if _, err := io.ReadFull(rand.Reader, cfs[1:]); err != nil {
+			wipeValues(shareData)
 			return nil, lib.IOErr(
 				lib.CategoryShamir,
 				lib.ErrCodeShamirIOReadFullError,
@@ -63,6 +80,7 @@ func Split(input []byte, n, t int, provider integrity.Provider) ([]Share, error)
 
 		signature, err := provider.Sign(id, val)
 		if err != nil {
+			wipeValues(shareData)
 			return nil, lib.CryptoErr(
 				lib.CategoryShamir,
 				lib.ErrCodeShamirSignShareError,
@@ -163,11 +181,18 @@ func Combine(shares []Share, provider integrity.Provider) ([]byte, error) {
 		}
 
 		res[i] = lagrangeInterpolate(0, xVals, yVals)
+		secret.Wipe(yVals)
 	}
 
 	return res, nil
 }
 
+func wipeValues(values [][]byte) {
+	for _, value := range values {
+		secret.Wipe(value)
+	}
+}
+
 // lagrangeInterpolate - computes the Lagrange interpolation over a finite field GF(256).
 // x is the x-coordinate for interpolation. xVals and yVals must have the same length.
 // Returns the interpolated y-value at x.
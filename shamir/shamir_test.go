@@ -244,3 +244,21 @@ func TestCombineRejectsMalformedShares(t *testing.T) {
 		}
 	})
 }
+
+func TestWipeShares(t *testing.T) {
+	input := []byte("secret")
+	shares, err := Split(input, 3, 2, &mock.Provider{IsVerifySign: true, Signature: []byte("abcd")})
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+
+	WipeShares(shares)
+	for _, sh := range shares {
+		if !bytes.Equal(sh.Value, make([]byte, len(input))) {
+			t.Errorf("share %d value = %x after WipeShares, want zeroes", sh.ID, sh.Value)
+		}
+	}
+	if !bytes.Equal(input, []byte("secret")) {
+		t.Errorf("WipeShares() changed the input to %q", input)
+	}
+}
@@ -0,0 +1,351 @@
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+)
+
+const (
+	HolderKindPassphrase byte = 0x01
+	HolderKindX25519     byte = 0x02
+
+	HolderKindNamePassphrase string = "passphrase"
+	HolderKindNameX25519     string = "x25519"
+
+	// encFormatHolder - first byte of a per-holder share envelope:
+	// encFormatHolder || share id || holder kind || body.
+	// The 3-byte prefix is authenticated as additional data, so a share cannot
+	// be re-labelled to another holder without failing authentication.
+	encFormatHolder byte = 0x02
+
+	holderPrefixLen = 3
+
+	// holderKeyInfo - HKDF info string binding X25519 holder keys to share tokens.
+	holderKeyInfo = "tvault-core holder share token v1"
+)
+
+type (
+	// Holder - one entry of a holders file. A holder either has a passphrase or an
+	// X25519 key: seal needs the public key, unseal needs the private key.
+	Holder struct {
+		Name       string `json:"name"`
+		Passphrase string `json:"passphrase,omitempty"`
+		PublicKey  string `json:"public_key,omitempty"`
+		PrivateKey string `json:"private_key,omitempty"`
+	}
+
+	// HolderList - on-disk layout of a holders file.
+	HolderList struct {
+		Holders []Holder `json:"holders"`
+	}
+
+	// HolderKey - the key a share token is sealed to: a derived passphrase key for
+	// HolderKindPassphrase or a raw X25519 public key for HolderKindX25519.
+	HolderKey struct {
+		Name string
+		Kind byte
+		Key  []byte
+	}
+
+	// HolderKeyFunc - resolves the opening key for a holder envelope: the derived
+	// passphrase key or the raw X25519 private key of the holder of share id.
+	HolderKeyFunc func(id int, kind byte) ([]byte, error)
+)
+
+// LoadHolders - reads and validates a holders file.
+func LoadHolders(path string) ([]Holder, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	var list HolderList
+	if err = json.Unmarshal(content, &list); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(list.Holders))
+	for _, holder := range list.Holders {
+		if holder.Name == "" {
+			return nil, lib.ErrHolderNameRequired
+		}
+		if _, ok := seen[holder.Name]; ok {
+			return nil, lib.ErrHolderNameDuplicated
+		}
+		seen[holder.Name] = struct{}{}
+	}
+
+	return list.Holders, nil
+}
+
+// FindHolder - returns the holder with the given name.
+func FindHolder(holders []Holder, name string) (Holder, bool) {
+	for _, holder := range holders {
+		if holder.Name == name {
+			return holder, true
+		}
+	}
+
+	return Holder{}, false
+}
+
+// Kind - X25519 when the holder lists any key material, passphrase otherwise.
+func (h Holder) Kind() byte {
+	if h.PublicKey != "" || h.PrivateKey != "" {
+		return HolderKindX25519
+	}
+
+	return HolderKindPassphrase
+}
+
+// PublicKeyBytes - returns the holder's X25519 public key, derived from the
+// private key when only that is listed.
+func (h Holder) PublicKeyBytes() ([]byte, error) {
+	if h.PublicKey != "" {
+		return DecodeX25519Key(h.PublicKey)
+	}
+
+	if h.PrivateKey == "" {
+		return nil, lib.ErrHolderSecretMissing
+	}
+
+	privateKey, err := DecodeX25519Key(h.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return lib.X25519PublicKey(privateKey)
+}
+
+// SealKey - returns the key that seal encrypts this holder's share to.
+func (h Holder) SealKey(salt []byte) (HolderKey, error) {
+	if h.Kind() == HolderKindX25519 {
+		publicKey, err := h.PublicKeyBytes()
+		if err != nil {
+			return HolderKey{}, err
+		}
+
+		return HolderKey{Name: h.Name, Kind: HolderKindX25519, Key: publicKey}, nil
+	}
+
+	if h.Passphrase == "" {
+		return HolderKey{}, lib.ErrHolderSecretMissing
+	}
+
+	return HolderKey{Name: h.Name, Kind: HolderKindPassphrase, Key: DeriveHolderKey(h.Passphrase, salt, h.Name)}, nil
+}
+
+// OpenKey - returns the key that opens this holder's share, or
+// lib.ErrHolderSecretMissing when the holders file does not carry it.
+func (h Holder) OpenKey(kind byte, salt []byte) ([]byte, error) {
+	switch kind {
+	case HolderKindPassphrase:
+		if h.Passphrase == "" {
+			return nil, lib.ErrHolderSecretMissing
+		}
+
+		return DeriveHolderKey(h.Passphrase, salt, h.Name), nil
+	case HolderKindX25519:
+		if h.PrivateKey == "" {
+			return nil, lib.ErrHolderSecretMissing
+		}
+
+		return DecodeX25519Key(h.PrivateKey)
+	default:
+		return nil, lib.ErrUnknownHolderKind
+	}
+}
+
+// DeriveHolderKey - derives a holder's AES-256 key from their passphrase. The
+// holder name is mixed into the salt, so two holders choosing the same
+// passphrase still get different keys.
+func DeriveHolderKey(passphrase string, salt []byte, name string) []byte {
+	holderSalt := make([]byte, 0, len(salt)+len(name))
+	holderSalt = append(holderSalt, salt...)
+	holderSalt = append(holderSalt, name...)
+
+	return lib.PBKDF2Key([]byte(passphrase), holderSalt, lib.Iterations, lib.KeyLen)
+}
+
+// DecodeX25519Key - decodes a hex-encoded raw X25519 key.
+func DecodeX25519Key(value string) ([]byte, error) {
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) != lib.X25519KeyLen {
+		return nil, lib.ErrX25519InvalidKey
+	}
+
+	return key, nil
+}
+
+// ConvertHolderKindToName - returns the name of a holder kind.
+func ConvertHolderKindToName(kind byte) string {
+	switch kind {
+	case HolderKindX25519:
+		return HolderKindNameX25519
+	default:
+		return HolderKindNamePassphrase
+	}
+}
+
+// ConvertHolderNameToKind - returns the holder kind for a name.
+func ConvertHolderNameToKind(name string) byte {
+	switch name {
+	case HolderKindNameX25519:
+		return HolderKindX25519
+	default:
+		return HolderKindPassphrase
+	}
+}
+
+// BuildForHolder - serializes a share Token and seals it to a single holder,
+// producing encFormatHolder || id || kind || body. The share id is kept in the
+// clear so unseal can tell which holder's secret to ask for.
+func BuildForHolder(token Token, holderKey HolderKey) ([]byte, error) {
+	if token.ID < 0 || token.ID > math.MaxUint8 {
+		return nil, lib.ErrTokenIDOutOfRange
+	}
+
+	tokenBytes, err := Build(token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Wipe(tokenBytes)
+
+	prefix := []byte{encFormatHolder, byte(token.ID), holderKey.Kind}
+
+	var body []byte
+	switch holderKey.Kind {
+	case HolderKindPassphrase:
+		body, err = sealHolderPassphrase(holderKey.Key, tokenBytes, prefix)
+	case HolderKindX25519:
+		body, err = lib.X25519Seal(holderKey.Key, tokenBytes, prefix, holderKeyInfo)
+	default:
+		err = lib.ErrUnknownHolderKind
+	}
+	if err != nil {
+		return nil, lib.CryptoErr(
+			lib.CategoryToken,
+			lib.ErrCodeTokenHolderSealError,
+			lib.ErrMessageTokenHolderSealError,
+			"",
+			err,
+		)
+	}
+
+	return append(prefix, body...), nil
+}
+
+// PeekHolder - reports whether a base64 token is a holder envelope and, if so,
+// the share id and holder kind it was sealed for.
+func PeekHolder(tokenBytes []byte) (id int, kind byte, ok bool) {
+	decoded, err := base64.StdEncoding.DecodeString(string(tokenBytes))
+	if err != nil || len(decoded) < holderPrefixLen || decoded[0] != encFormatHolder {
+		return 0, 0, false
+	}
+
+	return int(decoded[1]), decoded[2], true
+}
+
+// ParseForHolder - opens a base64 holder envelope with the holder's key (see
+// HolderKeyFunc) and returns the share Token inside it.
+func ParseForHolder(tokenBytes, key []byte) (Token, error) {
+	decoded, err := decodeBase64(tokenBytes)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if len(decoded) < holderPrefixLen || decoded[0] != encFormatHolder {
+		return Token{}, lib.ErrHolderEnvelopeRequired
+	}
+
+	prefix, body := decoded[:holderPrefixLen], decoded[holderPrefixLen:]
+
+	var tokenBytesPlain []byte
+	switch prefix[2] {
+	case HolderKindPassphrase:
+		tokenBytesPlain, err = openHolderPassphrase(key, body, prefix)
+	case HolderKindX25519:
+		tokenBytesPlain, err = lib.X25519Open(key, body, prefix, holderKeyInfo)
+	default:
+		err = lib.ErrUnknownHolderKind
+	}
+	if err != nil {
+		return Token{}, lib.CryptoErr(
+			lib.CategoryToken,
+			lib.ErrCodeTokenHolderOpenError,
+			lib.ErrMessageTokenHolderOpenError,
+			"",
+			err,
+		)
+	}
+	defer secret.Wipe(tokenBytesPlain)
+
+	var result Token
+	if err = json.Unmarshal(tokenBytesPlain, &result); err != nil {
+		return Token{}, lib.FormatErr(
+			lib.CategoryToken,
+			lib.ErrCodeTokenUnmarshalJSONError,
+			lib.ErrMessageTokenUnmarshalJSONError,
+			"",
+			err,
+		)
+	}
+
+	if result.Version != Version {
+		return Token{}, lib.ErrInvalidTokenVersion
+	}
+
+	if result.ID != int(prefix[1]) {
+		return Token{}, lib.ErrHolderIDMismatch
+	}
+
+	return result, nil
+}
+
+// sealHolderPassphrase - AES-256-GCM with the derived holder key; returns nonce || ciphertext+tag.
+func sealHolderPassphrase(key, data, aad []byte) ([]byte, error) {
+	aesGCM, err := newHolderGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aesGCM.NonceSize(), aesGCM.NonceSize()+len(data)+aesGCM.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aesGCM.Seal(nonce, nonce, data, aad), nil
+}
+
+// openHolderPassphrase - reverses sealHolderPassphrase.
+func openHolderPassphrase(key, body, aad []byte) ([]byte, error) {
+	aesGCM, err := newHolderGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < aesGCM.NonceSize()+aesGCM.Overhead() {
+		return nil, lib.ErrTokenCiphertextTooShort
+	}
+
+	return aesGCM.Open(nil, body[:aesGCM.NonceSize()], body[aesGCM.NonceSize():], aad)
+}
+
+func newHolderGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
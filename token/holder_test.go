@@ -0,0 +1,175 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestBuildForHolder(t *testing.T) {
+	privateKey, publicKey, err := lib.GenerateX25519Key()
+	if err != nil {
+		t.Fatalf("GenerateX25519Key() error = %v", err)
+	}
+
+	var (
+		salt = []byte("0123456789abcdef")
+		tok  = Token{Version: Version, ID: 3, Value: "aabb", Signature: "ccdd"}
+	)
+
+	tests := []struct {
+		name     string
+		sealHold Holder
+		openHold Holder
+		kind     byte
+		wantErr  bool
+	}{
+		{
+			name:     "passphrase",
+			sealHold: Holder{Name: "alice", Passphrase: "alice-secret"},
+			openHold: Holder{Name: "alice", Passphrase: "alice-secret"},
+			kind:     HolderKindPassphrase,
+		},
+		{
+			name:     "passphrase_wrong_secret",
+			sealHold: Holder{Name: "alice", Passphrase: "alice-secret"},
+			openHold: Holder{Name: "alice", Passphrase: "bob-secret"},
+			kind:     HolderKindPassphrase,
+			wantErr:  true,
+		},
+		{
+			name:     "passphrase_other_holder_same_secret",
+			sealHold: Holder{Name: "alice", Passphrase: "shared"},
+			openHold: Holder{Name: "bob", Passphrase: "shared"},
+			kind:     HolderKindPassphrase,
+			wantErr:  true,
+		},
+		{
+			name:     "x25519",
+			sealHold: Holder{Name: "carol", PublicKey: hex.EncodeToString(publicKey)},
+			openHold: Holder{Name: "carol", PrivateKey: hex.EncodeToString(privateKey)},
+			kind:     HolderKindX25519,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holderKey, err := tt.sealHold.SealKey(salt)
+			if err != nil {
+				t.Fatalf("SealKey() error = %v", err)
+			}
+
+			built, err := BuildForHolder(tok, holderKey)
+			if err != nil {
+				t.Fatalf("BuildForHolder() error = %v", err)
+			}
+			encoded := []byte(base64.StdEncoding.EncodeToString(built))
+
+			id, kind, ok := PeekHolder(encoded)
+			if !ok || id != tok.ID || kind != tt.kind {
+				t.Fatalf("PeekHolder() = %d, %d, %v; want %d, %d, true", id, kind, ok, tok.ID, tt.kind)
+			}
+
+			openKey, err := tt.openHold.OpenKey(kind, salt)
+			if err != nil {
+				t.Fatalf("OpenKey() error = %v", err)
+			}
+
+			parsed, err := ParseForHolder(encoded, openKey)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseForHolder() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseForHolder() error = %v", err)
+			}
+			if parsed != tok {
+				t.Errorf("ParseForHolder() = %+v, want %+v", parsed, tok)
+			}
+		})
+	}
+}
+
+func TestParseForHolderRejectsRelabelledShare(t *testing.T) {
+	holderKey, err := Holder{Name: "alice", Passphrase: "secret"}.SealKey([]byte("salt"))
+	if err != nil {
+		t.Fatalf("SealKey() error = %v", err)
+	}
+
+	built, err := BuildForHolder(Token{Version: Version, ID: 1, Value: "aa"}, holderKey)
+	if err != nil {
+		t.Fatalf("BuildForHolder() error = %v", err)
+	}
+
+	// Moving the envelope to another share id must fail authentication.
+	built[1] = 2
+	if _, err = ParseForHolder([]byte(base64.StdEncoding.EncodeToString(built)), holderKey.Key); err == nil {
+		t.Error("ParseForHolder() expected error for re-labelled share")
+	}
+}
+
+func TestPeekHolderPlainToken(t *testing.T) {
+	built, err := Build(Token{Version: Version, ID: 1, Value: "aa"}, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if _, _, ok := PeekHolder([]byte(base64.StdEncoding.EncodeToString(built))); ok {
+		t.Error("PeekHolder() reported a holder envelope for a plain token")
+	}
+}
+
+func TestLoadHolders(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			content: `{"holders":[{"name":"alice","passphrase":"a"},{"name":"bob","public_key":"00"}]}`,
+			want:    2,
+		},
+		{
+			name:    "missing_name",
+			content: `{"holders":[{"passphrase":"a"}]}`,
+			wantErr: lib.ErrHolderNameRequired,
+		},
+		{
+			name:    "duplicated_name",
+			content: `{"holders":[{"name":"alice"},{"name":"alice"}]}`,
+			wantErr: lib.ErrHolderNameDuplicated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "holders.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			holders, err := LoadHolders(path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("LoadHolders() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadHolders() error = %v", err)
+			}
+			if len(holders) != tt.want {
+				t.Errorf("LoadHolders() len = %d, want %d", len(holders), tt.want)
+			}
+		})
+	}
+}
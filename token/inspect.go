@@ -0,0 +1,90 @@
+package token
+
+import (
+	"encoding/json"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+const (
+	EnvelopeNone       string = "none"
+	EnvelopePassphrase string = "passphrase"
+	EnvelopeHolder     string = "holder"
+)
+
+// Info - what a token tells about itself without its value: the envelope it is
+// wrapped in and, once opened, its version, share id and whether it is signed.
+// Fields past Opened are only set when Opened is true, except ID, which holder
+// envelopes carry in the clear.
+type Info struct {
+	Envelope  string `json:"envelope"`
+	Holder    string `json:"holder,omitempty"`
+	Encrypted bool   `json:"encrypted"`
+	Opened    bool   `json:"opened"`
+	Version   int    `json:"version,omitempty"`
+	ID        int    `json:"id,omitempty"`
+	Signed    bool   `json:"signed"`
+	Group     string `json:"group,omitempty"`
+	Member    string `json:"member,omitempty"`
+	NotBefore string `json:"not_before,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Epoch     uint32 `json:"epoch,omitempty"`
+}
+
+// Inspect - decodes a base64 token and reports its envelope. A plain token is
+// read in full; an encrypted one is left closed (see Info.Open).
+func Inspect(tokenBytes []byte) (Info, error) {
+	decoded, err := decodeBase64(tokenBytes)
+	if err != nil {
+		return Info{}, err
+	}
+
+	if id, kind, ok := PeekHolder(tokenBytes); ok {
+		return Info{
+			Envelope:  EnvelopeHolder,
+			Holder:    ConvertHolderKindToName(kind),
+			Encrypted: true,
+			ID:        id,
+		}, nil
+	}
+
+	if len(decoded) > 0 && decoded[0] == encFormatGCM {
+		return Info{Envelope: EnvelopePassphrase, Encrypted: true}, nil
+	}
+
+	var tok Token
+	if err = json.Unmarshal(decoded, &tok); err != nil {
+		return Info{}, lib.FormatErr(
+			lib.CategoryToken,
+			lib.ErrCodeTokenUnmarshalJSONError,
+			lib.ErrMessageTokenUnmarshalJSONError,
+			"",
+			err,
+		)
+	}
+
+	info := Info{Envelope: EnvelopeNone}
+	info.Open(tok)
+
+	return info, nil
+}
+
+// Open - fills in the fields read from the opened token. The value is never
+// copied.
+func (i *Info) Open(tok Token) {
+	i.Opened = true
+	i.Version = tok.Version
+	i.ID = tok.ID
+	i.Signed = tok.Signature != ""
+	i.Group = tok.Group
+	i.Member = tok.Member
+	i.Epoch = tok.Epoch
+
+	if tok.NotBefore != 0 {
+		i.NotBefore = formatUnix(tok.NotBefore)
+	}
+
+	if tok.ExpiresAt != 0 {
+		i.ExpiresAt = formatUnix(tok.ExpiresAt)
+	}
+}
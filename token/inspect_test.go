@@ -0,0 +1,81 @@
+package token
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	var (
+		tok  = Token{Version: Version, ID: 2, Value: "aabb", Signature: "ccdd", Group: "ops", Member: "alice"}
+		salt = []byte("0123456789abcdef")
+	)
+
+	plain, err := Build(tok, nil)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	encrypted, err := Build(tok, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	holderKey, err := Holder{Name: "alice", Passphrase: "alice-secret"}.SealKey(salt)
+	if err != nil {
+		t.Fatalf("SealKey() error = %v", err)
+	}
+
+	sealed, err := BuildForHolder(tok, holderKey)
+	if err != nil {
+		t.Fatalf("BuildForHolder() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   []byte
+		want    Info
+		wantErr bool
+	}{
+		{
+			name:  "plain",
+			token: plain,
+			want: Info{
+				Envelope: EnvelopeNone,
+				Opened:   true,
+				Version:  Version,
+				ID:       2,
+				Signed:   true,
+				Group:    "ops",
+				Member:   "alice",
+			},
+		},
+		{
+			name:  "passphrase_envelope",
+			token: encrypted,
+			want:  Info{Envelope: EnvelopePassphrase, Encrypted: true},
+		},
+		{
+			name:  "holder_envelope",
+			token: sealed,
+			want:  Info{Envelope: EnvelopeHolder, Holder: HolderKindNamePassphrase, Encrypted: true, ID: 2},
+		},
+		{
+			name:    "not_a_token",
+			token:   []byte("garbage"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect([]byte(base64.StdEncoding.EncodeToString(tt.token)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Inspect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Inspect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
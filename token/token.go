@@ -6,9 +6,11 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
 )
 
 const (
@@ -18,10 +20,14 @@ const (
 	TypeNone   byte = 0x00
 	TypeShare  byte = 0x01
 	TypeMaster byte = 0x02
+	TypePlugin byte = 0x03
+	TypeKMS    byte = 0x04
 
 	TypeNameNone   string = "none"
 	TypeNameShare  string = "share"
 	TypeNameMaster string = "master"
+	TypeNamePlugin string = "plugin"
+	TypeNameKMS    string = "kms"
 
 	// encFormatGCM - first byte of an encrypted token envelope, identifying the
 	// AES-GCM (AEAD) format: encFormatGCM || nonce || ciphertext+tag.
@@ -34,6 +40,8 @@ var Types = map[string]struct{}{
 	TypeNameNone:   {},
 	TypeNameShare:  {},
 	TypeNameMaster: {},
+	TypeNamePlugin: {},
+	TypeNameKMS:    {},
 }
 
 // Token - represents a data structure for handling token information with properties like version, ID, type.
@@ -43,12 +51,43 @@ type (
 		ID        int    `json:"id,omitempty"`
 		Value     string `json:"vl"`
 		Signature string `json:"s,omitempty"`
+		// Group and Member - set on shares split by a sharing policy.
+		Group  string `json:"g,omitempty"`
+		Member string `json:"m,omitempty"`
+		// NotBefore and ExpiresAt - validity window in Unix seconds, 0 when
+		// open. They sit inside the envelope, so AEAD covers them.
+		NotBefore int64 `json:"nbf,omitempty"`
+		ExpiresAt int64 `json:"exp,omitempty"`
+		// Epoch - key epoch of the container when the token was issued.
+		Epoch uint32 `json:"ep,omitempty"`
 	}
 	List struct {
 		TokenList []string `json:"token_list"`
+		// Holders - holder names in token order, set when shares are sealed per holder.
+		Holders []string `json:"holders,omitempty"`
+		// Members - "member (group)" in token order, set when a sharing policy is used.
+		Members []string `json:"members,omitempty"`
 	}
 )
 
+// CheckEpoch - rejects a token issued before the container's master key was
+// last rotated, which would otherwise only fail to decrypt.
+func (t Token) CheckEpoch(epoch uint32) error {
+	if t.Epoch >= epoch {
+		return nil
+	}
+
+	return lib.NewError(
+		lib.ErrorTypeValidation,
+		lib.CategoryToken,
+		lib.ErrCodeTokenRevoked,
+		fmt.Sprintf(lib.ErrMessageTokenRevoked, t.Epoch),
+		fmt.Sprintf("container key epoch is %d", epoch),
+		lib.SuggestionTokenRevoked,
+		lib.ErrTokenRevoked,
+	)
+}
+
 // Build - serializes a Token into a JSON byte slice and encrypts it if a key is provided.
 func Build(token Token, key []byte) ([]byte, error) {
 	tokenBytes, err := json.Marshal(&token)
@@ -65,6 +104,7 @@ func Build(token Token, key []byte) ([]byte, error) {
 	if key == nil {
 		return tokenBytes, nil
 	}
+	defer secret.Wipe(tokenBytes)
 
 	encrypted, err := encrypt(tokenBytes, key)
 	if err != nil {
@@ -82,6 +122,7 @@ func Parse(tokenBytes, key []byte) (Token, error) {
 	if err != nil {
 		return Token{}, err
 	}
+	defer secret.Wipe(decoded)
 
 	var decrypted []byte
 	if len(key) > 0 {
@@ -89,6 +130,7 @@ func Parse(tokenBytes, key []byte) (Token, error) {
 		if err != nil {
 			return Token{}, err
 		}
+		defer secret.Wipe(decrypted)
 	} else {
 		decrypted = decoded
 	}
@@ -250,6 +292,10 @@ func ConvertNameToID(name string) byte {
 		return TypeShare
 	case TypeNameMaster:
 		return TypeMaster
+	case TypeNamePlugin:
+		return TypePlugin
+	case TypeNameKMS:
+		return TypeKMS
 	default:
 		return TypeNone
 	}
@@ -263,6 +309,10 @@ func ConvertIDToName(id byte) string {
 		return TypeNameShare
 	case TypeMaster:
 		return TypeNameMaster
+	case TypePlugin:
+		return TypeNamePlugin
+	case TypeKMS:
+		return TypeNameKMS
 	default:
 		return TypeNameNone
 	}
@@ -3,7 +3,10 @@ package token
 import (
 	"crypto/aes"
 	"encoding/base64"
+	"errors"
 	"testing"
+
+	"github.com/namelesscorp/tvault-core/lib"
 )
 
 func TestBuild(t *testing.T) {
@@ -219,3 +222,32 @@ func TestBuildAndParseIntegration(t *testing.T) {
 		t.Errorf("Integration test failed: got %v, want %v", decodedToken, validToken)
 	}
 }
+
+func TestCheckEpoch(t *testing.T) {
+	tests := []struct {
+		name       string
+		tokenEpoch uint32
+		epoch      uint32
+		wantErr    error
+		wantMsg    string
+	}{
+		{name: "unrotated", tokenEpoch: 0, epoch: 0},
+		{name: "current", tokenEpoch: 2, epoch: 2},
+		{name: "revoked", tokenEpoch: 1, epoch: 2, wantErr: lib.ErrTokenRevoked, wantMsg: "revoked token (epoch 1)"},
+		{name: "issued_before_rotation", tokenEpoch: 0, epoch: 1, wantErr: lib.ErrTokenRevoked, wantMsg: "revoked token (epoch 0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Token{Version: Version, Epoch: tt.tokenEpoch}.CheckEpoch(tt.epoch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckEpoch() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var libErr *lib.Error
+			if errors.As(err, &libErr) && libErr.Message != tt.wantMsg {
+				t.Errorf("CheckEpoch() message = %q, want %q", libErr.Message, tt.wantMsg)
+			}
+		})
+	}
+}
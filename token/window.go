@@ -0,0 +1,101 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// Window - validity window of issued tokens. A zero time leaves that side of
+// the window open.
+type Window struct {
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// ParseWindow - parses RFC 3339 not-before and expires-at times; an empty
+// string leaves that side open. The returned errors are unwrapped sentinels
+// for the caller to report under its own category.
+func ParseWindow(notBefore, expiresAt string) (Window, error) {
+	var (
+		window Window
+		err    error
+	)
+	if notBefore != "" {
+		if window.NotBefore, err = time.Parse(time.RFC3339, notBefore); err != nil {
+			return Window{}, lib.ErrTokenNotBeforeInvalid
+		}
+	}
+
+	if expiresAt != "" {
+		if window.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+			return Window{}, lib.ErrTokenExpiresAtInvalid
+		}
+	}
+
+	if !window.NotBefore.IsZero() && !window.ExpiresAt.IsZero() && !window.NotBefore.Before(window.ExpiresAt) {
+		return Window{}, lib.ErrTokenWindowEmpty
+	}
+
+	return window, nil
+}
+
+// IsZero - reports whether the window is open on both sides.
+func (w Window) IsZero() bool {
+	return w.NotBefore.IsZero() && w.ExpiresAt.IsZero()
+}
+
+// Apply - records the window in tok as Unix seconds.
+func (w Window) Apply(tok *Token) {
+	if !w.NotBefore.IsZero() {
+		tok.NotBefore = w.NotBefore.Unix()
+	}
+
+	if !w.ExpiresAt.IsZero() {
+		tok.ExpiresAt = w.ExpiresAt.Unix()
+	}
+}
+
+// CheckWindow - rejects a token used before its not-before or at or after its
+// expiry time. The window is only as trustworthy as the envelope around the
+// token, so seal refuses to set one on tokens that are not encrypted.
+func (t Token) CheckWindow(now time.Time) error {
+	if t.NotBefore != 0 && now.Before(time.Unix(t.NotBefore, 0)) {
+		return lib.NewError(
+			lib.ErrorTypeValidation,
+			lib.CategoryToken,
+			lib.ErrCodeTokenNotYetValid,
+			lib.ErrMessageTokenNotYetValid,
+			"valid from "+formatUnix(t.NotBefore),
+			lib.SuggestionTokenNotYetValid,
+			lib.ErrTokenNotYetValid,
+		)
+	}
+
+	if t.ExpiresAt != 0 && !now.Before(time.Unix(t.ExpiresAt, 0)) {
+		return lib.NewError(
+			lib.ErrorTypeValidation,
+			lib.CategoryToken,
+			lib.ErrCodeTokenExpired,
+			lib.ErrMessageTokenExpired,
+			"expired at "+formatUnix(t.ExpiresAt),
+			lib.SuggestionTokenExpired,
+			lib.ErrTokenExpired,
+		)
+	}
+
+	return nil
+}
+
+// IsValidityError - reports whether err is a rejection by CheckWindow or
+// CheckEpoch, which callers pass on as is rather than as a parse error.
+func IsValidityError(err error) bool {
+	return errors.Is(err, lib.ErrTokenNotYetValid) ||
+		errors.Is(err, lib.ErrTokenExpired) ||
+		errors.Is(err, lib.ErrTokenRevoked)
+}
+
+func formatUnix(sec int64) string {
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
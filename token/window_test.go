@@ -0,0 +1,97 @@
+package token
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		notBefore string
+		expiresAt string
+		wantErr   error
+	}{
+		{name: "open"},
+		{name: "expires_only", expiresAt: "2026-12-31T18:00:00Z"},
+		{name: "both", notBefore: "2026-01-01T00:00:00Z", expiresAt: "2026-12-31T18:00:00+02:00"},
+		{name: "bad_not_before", notBefore: "2026-01-01", wantErr: lib.ErrTokenNotBeforeInvalid},
+		{name: "bad_expires_at", expiresAt: "tomorrow", wantErr: lib.ErrTokenExpiresAtInvalid},
+		{
+			name:      "empty_window",
+			notBefore: "2026-12-31T18:00:00Z",
+			expiresAt: "2026-01-01T00:00:00Z",
+			wantErr:   lib.ErrTokenWindowEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := ParseWindow(tt.notBefore, tt.expiresAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseWindow() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && window.IsZero() != (tt.notBefore == "" && tt.expiresAt == "") {
+				t.Errorf("IsZero() = %v", window.IsZero())
+			}
+		})
+	}
+}
+
+func TestCheckWindow(t *testing.T) {
+	var (
+		now    = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+		window = Window{
+			NotBefore: now.Add(-time.Hour),
+			ExpiresAt: now.Add(time.Hour),
+		}
+		tok = Token{Version: Version, Value: "aabb"}
+	)
+	window.Apply(&tok)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr error
+	}{
+		{name: "inside", now: now},
+		{name: "before", now: now.Add(-2 * time.Hour), wantErr: lib.ErrTokenNotYetValid},
+		{name: "at_expiry", now: now.Add(time.Hour), wantErr: lib.ErrTokenExpired},
+		{name: "after", now: now.Add(2 * time.Hour), wantErr: lib.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tok.CheckWindow(tt.now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckWindow() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (Token{Version: Version}).CheckWindow(now); err != nil {
+		t.Errorf("CheckWindow() on a token without a window error = %v", err)
+	}
+}
+
+func TestWindowSurvivesEnvelope(t *testing.T) {
+	key := make([]byte, 32)
+	tok := Token{Version: Version, Value: "aabb"}
+	Window{ExpiresAt: time.Unix(1798740000, 0)}.Apply(&tok)
+
+	built, err := Build(tok, key)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	parsed, err := Parse([]byte(base64.StdEncoding.EncodeToString(built)), key)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.ExpiresAt != tok.ExpiresAt {
+		t.Errorf("ExpiresAt = %d, want %d", parsed.ExpiresAt, tok.ExpiresAt)
+	}
+}
@@ -0,0 +1,32 @@
+package unseal
+
+import (
+	"github.com/namelesscorp/tvault-core/agent"
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// CachedMasterKey - returns the master key of cont cached by the agent, if an
+// agent runs and holds a key that opens cont.
+func CachedMasterKey(opts *lib.Agent, cont container.Container) ([]byte, bool) {
+	if opts == nil {
+		return nil, false
+	}
+
+	masterKey, ok := agent.NewClient(*opts.Socket).Get(cont.GetHeader().ID())
+	if !ok || !CheckMasterKey(cont, masterKey) {
+		return nil, false
+	}
+
+	return masterKey, true
+}
+
+// CacheMasterKey - hands the master key of cont to the agent. It is best
+// effort: without a running agent nothing is cached.
+func CacheMasterKey(opts *lib.Agent, cont container.Container, masterKey []byte) {
+	if opts == nil {
+		return
+	}
+
+	_ = agent.NewClient(*opts.Socket).Add(cont.GetHeader().ID(), masterKey)
+}
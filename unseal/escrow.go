@@ -0,0 +1,51 @@
+package unseal
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+// ReadEscrowPrivateKey - reads the hex escrow private key from path.
+func ReadEscrowPrivateKey(category lib.ErrorCategory, path string) ([]byte, error) {
+	content, err := os.ReadFile(path) // #nosec G304 -- the caller names the key file
+	if err == nil {
+		var privateKey []byte
+		if privateKey, err = token.DecodeX25519Key(strings.TrimSpace(string(content))); err == nil {
+			return privateKey, nil
+		}
+	}
+
+	return nil, lib.IOErr(
+		category,
+		lib.ErrCodeEscrowReadKeyError,
+		lib.ErrMessageEscrowReadKeyError,
+		"the key file holds the escrow X25519 private key as 64 hex characters",
+		err,
+	)
+}
+
+// OpenEscrow - unwraps the master key of cont with the escrow private key.
+func OpenEscrow(category lib.ErrorCategory, cont container.Container, privateKey []byte) ([]byte, error) {
+	masterKey, err := cont.GetMetadata().Keys.OpenEscrow(privateKey, cont.GetHeader().Salt)
+	if errors.Is(err, lib.ErrEscrowNotAvailable) || errors.Is(err, lib.ErrEscrowKeyMismatch) {
+		return nil, lib.ValidationErr(category, err)
+	}
+	if err != nil {
+		return nil, lib.NewError(
+			lib.ErrorTypeCrypto,
+			category,
+			lib.ErrCodeEscrowOpenError,
+			lib.ErrMessageEscrowOpenError,
+			"",
+			lib.SuggestionEscrowOpenError,
+			err,
+		)
+	}
+
+	return masterKey, nil
+}
@@ -0,0 +1,58 @@
+package unseal
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+func TestHolderKeyResolverPromptsOncePerHolder(t *testing.T) {
+	header, err := container.NewHeader(0, integrity.TypeNone, token.TypeShare, 3, 2)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+
+	// alice holds two shares, as a weighted member of a sharing policy.
+	cont := container.NewContainer("", nil, container.Metadata{Keys: &container.KeyBlock{Holders: []container.HolderRecord{
+		{ID: 1, Name: "alice", Kind: token.HolderKindNamePassphrase},
+		{ID: 2, Name: "alice", Kind: token.HolderKindNamePassphrase},
+		{ID: 3, Name: "bob", Kind: token.HolderKindNamePassphrase},
+	}}}, header)
+
+	var prompts []string
+	saved := promptSecret
+	promptSecret = func(label string) (string, error) {
+		prompts = append(prompts, label)
+		return "secret-" + label, nil
+	}
+	t.Cleanup(func() { promptSecret = saved })
+
+	resolve, err := NewHolderKeyResolver(cont, &lib.Holders{Path: lib.StringPtr("")})
+	if err != nil {
+		t.Fatalf("NewHolderKeyResolver() error = %v", err)
+	}
+
+	keys := make(map[int][]byte)
+	for _, id := range []int{1, 2, 3, 1} {
+		if keys[id], err = resolve(id, token.HolderKindPassphrase); err != nil {
+			t.Fatalf("resolve(%d) error = %v", id, err)
+		}
+	}
+
+	if want := []string{"share 1 (alice)", "share 3 (bob)"}; !slices.Equal(prompts, want) {
+		t.Errorf("prompts = %q, want %q", prompts, want)
+	}
+	if !bytes.Equal(keys[1], keys[2]) || bytes.Equal(keys[1], keys[3]) {
+		t.Error("alice's shares do not share one key distinct from bob's")
+	}
+
+	salt := header.Salt
+	if want := token.DeriveHolderKey("secret-share 1 (alice)", salt[:], "alice"); !bytes.Equal(keys[1], want) {
+		t.Errorf("alice's key = %x, want %x", keys[1], want)
+	}
+}
@@ -0,0 +1,181 @@
+package unseal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// journal - the checkpoint journal of an unseal: one JSON line naming the
+// container, then one line per extracted entry with the SHA-256 of its
+// content. It is the zip.ExtractLog of the extraction. Lines are appended
+// without a sync: an entry whose line is lost is extracted again, and a torn
+// last line is dropped when the journal is opened to resume.
+type journal struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	sums map[string][]byte
+}
+
+// journalLine - a line of the journal: the first sets Container, every other
+// sets Name and SHA256.
+type journalLine struct {
+	Container string `json:"container,omitempty"`
+	Name      string `json:"name,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// journalPath - the journal of an unseal to folderPath sits next to the folder.
+func journalPath(folderPath string) (string, error) {
+	abs, err := filepath.Abs(folderPath)
+	if err != nil {
+		return "", err
+	}
+
+	return abs + lib.JournalSuffix, nil
+}
+
+// createJournal - starts the journal of a fresh unseal of the container
+// containerID, replacing the journal of an earlier interrupted one.
+func createJournal(folderPath, containerID string) (*journal, error) {
+	path, err := journalPath(folderPath)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o750)
+	}
+	if err != nil {
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	j := &journal{path: path, f: f, sums: make(map[string][]byte)}
+	if err = j.append(journalLine{Container: containerID}); err != nil {
+		j.remove()
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	return j, nil
+}
+
+// openJournal - loads the journal an interrupted unseal of the container
+// containerID to folderPath left, to continue it.
+func openJournal(folderPath, containerID string) (*journal, error) {
+	path, err := journalPath(folderPath)
+	if err != nil {
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, lib.ValidationErr(lib.CategoryUnseal, lib.ErrResumeJournalNotFound)
+	case err != nil:
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	var (
+		sums  = make(map[string][]byte)
+		valid int64
+		head  = true
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		var line journalLine
+		if err = json.Unmarshal(scanner.Bytes(), &line); err != nil || int(valid)+len(scanner.Bytes()) >= len(data) {
+			// Only a line cut short by the interruption lacks its newline.
+			break
+		}
+
+		if head {
+			if line.Container != containerID {
+				return nil, lib.ValidationErr(lib.CategoryUnseal, lib.ErrResumeJournalInvalid)
+			}
+			head = false
+		} else {
+			sum, errDecode := hex.DecodeString(line.SHA256)
+			if errDecode != nil || line.Name == "" {
+				break
+			}
+			sums[line.Name] = sum
+		}
+
+		valid += int64(len(scanner.Bytes())) + 1
+	}
+	if head {
+		return nil, lib.ValidationErr(lib.CategoryUnseal, lib.ErrResumeJournalInvalid)
+	}
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY, 0o600)
+	if err == nil {
+		err = f.Truncate(valid)
+		if err == nil {
+			_, err = f.Seek(valid, io.SeekStart)
+		}
+		if err != nil {
+			_ = f.Close()
+		}
+	}
+	if err != nil {
+		return nil, lib.IOErr(lib.CategoryUnseal, lib.ErrCodeJournalWriteError, lib.ErrMessageJournalWriteError, "", err)
+	}
+
+	return &journal{path: path, f: f, sums: sums}, nil
+}
+
+// Done - implements zip.ExtractLog.
+func (j *journal) Done(name string) ([]byte, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	sum, ok := j.sums[name]
+	return sum, ok
+}
+
+// Extracted - implements zip.ExtractLog.
+func (j *journal) Extracted(name string, sum []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.sums[name] = sum
+	return j.append(journalLine{Name: name, SHA256: hex.EncodeToString(sum)})
+}
+
+func (j *journal) append(line journalLine) error {
+	b, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.f.Write(append(b, '\n'))
+	return err
+}
+
+// finish - closes the journal, deleting it once the unseal succeeded and
+// keeping it for container -resume otherwise.
+func (j *journal) finish(err error) {
+	if err == nil {
+		j.remove()
+		return
+	}
+
+	_ = j.f.Close()
+}
+
+func (j *journal) remove() {
+	_ = j.f.Close()
+	_ = os.Remove(j.path)
+}
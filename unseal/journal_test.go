@@ -0,0 +1,221 @@
+package unseal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/namelesscorp/tvault-core/compression"
+	"github.com/namelesscorp/tvault-core/compression/zip"
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+func TestOpenJournalDropsTornLine(t *testing.T) {
+	folderPath := filepath.Join(t.TempDir(), "folder")
+	if _, err := openJournal(folderPath, "id"); !errors.Is(err, lib.ErrResumeJournalNotFound) {
+		t.Fatalf("openJournal() without a journal error = %v, want %v", err, lib.ErrResumeJournalNotFound)
+	}
+
+	jrn, err := createJournal(folderPath, "id")
+	if err != nil {
+		t.Fatalf("createJournal() error = %v", err)
+	}
+	if err = jrn.Extracted("a.txt", []byte{1}); err != nil {
+		t.Fatalf("Extracted() error = %v", err)
+	}
+	jrn.finish(errors.New("interrupted"))
+
+	st, err := os.Stat(jrn.path)
+	if err != nil {
+		t.Fatalf("stat journal: %v", err)
+	}
+	valid := st.Size()
+
+	// The interruption cut the line of b.txt short.
+	appendFile(t, jrn.path, `{"name":"b.txt","sha256":"0`)
+
+	if _, err = openJournal(folderPath, "other"); !errors.Is(err, lib.ErrResumeJournalInvalid) {
+		t.Fatalf("openJournal() of another container error = %v, want %v", err, lib.ErrResumeJournalInvalid)
+	}
+
+	jrn, err = openJournal(folderPath, "id")
+	if err != nil {
+		t.Fatalf("openJournal() error = %v", err)
+	}
+	if sum, ok := jrn.Done("a.txt"); !ok || !bytes.Equal(sum, []byte{1}) {
+		t.Errorf("Done(a.txt) = %x, %v, want 01, true", sum, ok)
+	}
+	if _, ok := jrn.Done("b.txt"); ok {
+		t.Error("Done(b.txt) = true for a torn line")
+	}
+	if st, err = os.Stat(jrn.path); err != nil || st.Size() != valid {
+		t.Fatalf("journal size = %d (%v), want it truncated to %d", st.Size(), err, valid)
+	}
+
+	// Lines appended after the resume follow the last valid one.
+	if err = jrn.Extracted("b.txt", []byte{2}); err != nil {
+		t.Fatalf("Extracted() error = %v", err)
+	}
+	jrn.finish(errors.New("interrupted"))
+
+	if jrn, err = openJournal(folderPath, "id"); err != nil {
+		t.Fatalf("openJournal() after append error = %v", err)
+	}
+	if sum, ok := jrn.Done("b.txt"); !ok || !bytes.Equal(sum, []byte{2}) {
+		t.Errorf("Done(b.txt) = %x, %v, want 02, true", sum, ok)
+	}
+
+	jrn.finish(nil)
+	if _, err = os.Stat(jrn.path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal after a successful unseal: stat error = %v, want it removed", err)
+	}
+}
+
+func TestUnsealResumeSkipsCheckedFiles(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		sourcePath = filepath.Join(dir, "source")
+		targetPath = filepath.Join(dir, "vault.tvlt")
+		folderPath = filepath.Join(dir, "folder")
+		files      = map[string]string{"a.txt": "alpha", "b.txt": "bravo", "c.txt": "charlie"}
+	)
+
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(sourcePath, name), content)
+	}
+
+	payload, err := zip.New().Pack(sourcePath)
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+
+	header, err := container.NewHeader(compression.TypeZip, integrity.TypeNone, token.TypeNone, 0, 0)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+	cont := container.NewContainer(targetPath, nil, container.Metadata{Tags: []string{}}, header)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader(payload), []byte("passphrase")); err != nil {
+		t.Fatalf("WriteEncrypted() error = %v", err)
+	}
+
+	// The interrupted unseal checked a.txt and b.txt by hash and never got to
+	// c.txt; b.txt was changed since.
+	jrn, err := createJournal(folderPath, header.ID())
+	if err != nil {
+		t.Fatalf("createJournal() error = %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		writeTestFile(t, filepath.Join(folderPath, name), files[name])
+		sum := sha256.Sum256([]byte(files[name]))
+		if err = jrn.Extracted(name, sum[:]); err != nil {
+			t.Fatalf("Extracted() error = %v", err)
+		}
+	}
+	jrn.finish(errors.New("interrupted"))
+	writeTestFile(t, filepath.Join(folderPath, "b.txt"), "changed")
+
+	checked := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err = os.Chtimes(filepath.Join(folderPath, "a.txt"), checked, checked); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	opts := Options{
+		Container: &lib.Container{
+			CurrentPath:   lib.StringPtr(targetPath),
+			FolderPath:    lib.StringPtr(folderPath),
+			Passphrase:    lib.StringPtr("passphrase"),
+			RecoveryCode:  lib.StringPtr(""),
+			EscrowKeyPath: lib.StringPtr(""),
+			Resume:        lib.BoolPtr(true),
+			Stdout:        lib.BoolPtr(false),
+			OnConflict:    lib.StringPtr(lib.ConflictFail),
+			DryRun:        lib.BoolPtr(false),
+			Symlinks:      lib.StringPtr(lib.SymlinksReject),
+			Preserve:      lib.StringPtr(lib.PreserveNone),
+		},
+		InfoWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(filepath.Join(dir, "info.json")),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		LogWriter: &lib.Writer{
+			Type:   lib.StringPtr(lib.WriterTypeFile),
+			Path:   lib.StringPtr(filepath.Join(dir, "log.json")),
+			Format: lib.StringPtr(lib.WriterFormatJSON),
+		},
+		Limits: &lib.Limits{
+			MaxFiles: lib.Int64Ptr(0),
+			MaxBytes: lib.Int64Ptr(0),
+			MaxRatio: lib.Int64Ptr(0),
+			MaxDepth: lib.IntPtr(0),
+		},
+	}
+	if err = opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if err = Unseal(context.Background(), opts); err != nil {
+		t.Fatalf("Unseal() error = %v", err)
+	}
+
+	for name, content := range files {
+		if got := readTestFile(t, filepath.Join(folderPath, name)); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+
+	st, err := os.Stat(filepath.Join(folderPath, "a.txt"))
+	if err != nil {
+		t.Fatalf("stat a.txt: %v", err)
+	}
+	if !st.ModTime().Equal(checked) {
+		t.Errorf("a.txt modified at %v, want it left as checked at %v", st.ModTime(), checked)
+	}
+
+	if _, err = os.Stat(jrn.path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal after the resumed unseal: stat error = %v, want it removed", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+
+	return string(b)
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatalf("append to %s: %v", path, err)
+	}
+}
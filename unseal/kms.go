@@ -0,0 +1,44 @@
+package unseal
+
+import (
+	"context"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/seal"
+)
+
+// OpenKMS - has the transit key recorded in cont decrypt its master key, and
+// checks that the key opens cont. opts gives the server and credentials.
+func OpenKMS(ctx context.Context, category lib.ErrorCategory, opts *lib.KMS, cont container.Container) ([]byte, error) {
+	wrap := cont.GetMetadata().Keys.GetKMS()
+	if wrap == nil {
+		return nil, lib.ValidationErr(category, lib.ErrKMSNotAvailable)
+	}
+
+	transit, err := seal.NewKMSClient(category, opts, KMSMount(opts, wrap))
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, err := transit.Decrypt(ctx, wrap.Key, wrap.Ciphertext)
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKMSUnwrapError, lib.ErrMessageKMSUnwrapError, lib.SuggestionKMS, err)
+	}
+
+	if !CheckMasterKey(cont, masterKey) {
+		return nil, lib.CryptoErr(category, lib.ErrCodeKMSUnwrapError, lib.ErrMessageKMSUnwrapError, "", lib.ErrKMSKeyMismatch)
+	}
+
+	return masterKey, nil
+}
+
+// KMSMount - the mount given in opts, which the user chose, or else the one
+// recorded in the container metadata.
+func KMSMount(opts *lib.KMS, wrap *container.KMSWrap) string {
+	if opts.Mount != nil && *opts.Mount != "" {
+		return *opts.Mount
+	}
+
+	return wrap.Mount
+}
@@ -6,7 +6,12 @@ type Options struct {
 	Container         *lib.Container
 	IntegrityProvider *lib.IntegrityProvider
 	TokenReader       *lib.Reader
+	InfoWriter        *lib.Writer
 	LogWriter         *lib.Writer
+	Holders           *lib.Holders
+	Agent             *lib.Agent
+	KMS               *lib.KMS
+	Limits            *lib.Limits
 }
 
 func (o *Options) Validate() error {
@@ -14,7 +19,13 @@ func (o *Options) Validate() error {
 		return err
 	}
 
-	if err := o.validateTokenReader(); err != nil {
+	if err := o.validateLimits(); err != nil {
+		return err
+	}
+
+	// The token reader is checked by Unseal, once it is known that neither the
+	// agent, a recovery code nor the escrow key replaces the tokens.
+	if err := o.validateInfoWriter(); err != nil {
 		return err
 	}
 
@@ -28,14 +39,62 @@ func (o *Options) validateContainer() error {
 			lib.CategoryUnseal,
 			lib.ErrContainerCurrentPathRequired,
 		)
-	case *o.Container.FolderPath == "":
+	case *o.Container.Stdout && *o.Container.Resume:
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrStdioResume,
+		)
+	case *o.Container.FolderPath == "" && !*o.Container.Stdout:
 		return lib.ValidationErr(
 			lib.CategoryUnseal,
 			lib.ErrContainerFolderPathRequired,
 		)
+	case *o.Container.DryRun && (*o.Container.Resume || *o.Container.Stdout):
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrDryRunConflict,
+		)
+	}
+
+	switch *o.Container.OnConflict {
+	case lib.ConflictFail, lib.ConflictSkip, lib.ConflictOverwrite, lib.ConflictRename, lib.ConflictNewer:
+	default:
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrContainerOnConflictInvalid,
+		)
+	}
+
+	switch *o.Container.Preserve {
+	case lib.PreserveNone, lib.PreserveAll:
 	default:
-		return nil
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrContainerPreserveInvalid,
+		)
 	}
+
+	switch *o.Container.Symlinks {
+	case lib.SymlinksReject, lib.SymlinksSkip, lib.SymlinksContain, lib.SymlinksAllow:
+	default:
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrContainerSymlinksInvalid,
+		)
+	}
+
+	return nil
+}
+
+func (o *Options) validateLimits() error {
+	if *o.Limits.MaxFiles < 0 || *o.Limits.MaxBytes < 0 || *o.Limits.MaxRatio < 0 || *o.Limits.MaxDepth < 0 {
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrLimitsInvalid,
+		)
+	}
+
+	return nil
 }
 
 func (o *Options) validateTokenReader() error {
@@ -73,6 +132,31 @@ func (o *Options) validateTokenReader() error {
 	return nil
 }
 
+func (o *Options) validateInfoWriter() error {
+	if _, ok := lib.WriterTypes[*o.InfoWriter.Type]; !ok {
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrInfoWriterTypeInvalid,
+		)
+	}
+
+	if *o.InfoWriter.Type == lib.WriterTypeFile && *o.InfoWriter.Path == "" {
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrInfoWriterPathRequired,
+		)
+	}
+
+	if _, ok := lib.WriterFormats[*o.InfoWriter.Format]; !ok {
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrInfoWriterFormatInvalid,
+		)
+	}
+
+	return nil
+}
+
 func (o *Options) validateLogWriter() error {
 	if _, ok := lib.WriterTypes[*o.LogWriter.Type]; !ok {
 		return lib.ValidationErr(
@@ -88,6 +172,13 @@ func (o *Options) validateLogWriter() error {
 		)
 	}
 
+	if *o.Container.Stdout && *o.LogWriter.Type == lib.WriterTypeStdout {
+		return lib.ValidationErr(
+			lib.CategoryUnseal,
+			lib.ErrStdoutInUse,
+		)
+	}
+
 	if _, ok := lib.WriterFormats[*o.LogWriter.Format]; !ok {
 		return lib.ValidationErr(
 			lib.CategoryUnseal,
@@ -0,0 +1,29 @@
+package unseal
+
+import (
+	"context"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/keyprovider"
+	"github.com/namelesscorp/tvault-core/lib"
+)
+
+// OpenPlugin - has the key provider recorded in cont unwrap its master key,
+// and checks that the key opens cont.
+func OpenPlugin(ctx context.Context, category lib.ErrorCategory, cont container.Container) ([]byte, error) {
+	plugin := cont.GetMetadata().Keys.GetPlugin()
+	if plugin == nil {
+		return nil, lib.ValidationErr(category, lib.ErrKeyProviderNotAvailable)
+	}
+
+	masterKey, err := keyprovider.Unwrap(ctx, plugin.Name, cont.GetHeader().ID(), plugin.Key)
+	if err != nil {
+		return nil, lib.IOErr(category, lib.ErrCodeKeyProviderUnwrapError, lib.ErrMessageKeyProviderUnwrapError, lib.SuggestionKeyProvider, err)
+	}
+
+	if !CheckMasterKey(cont, masterKey) {
+		return nil, lib.CryptoErr(category, lib.ErrCodeKeyProviderUnwrapError, lib.ErrMessageKeyProviderUnwrapError, "", lib.ErrKeyProviderKeyMismatch)
+	}
+
+	return masterKey, nil
+}
@@ -1,24 +1,61 @@
 package unseal
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"math"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/namelesscorp/tvault-core/compression"
 	"github.com/namelesscorp/tvault-core/compression/zip"
 	"github.com/namelesscorp/tvault-core/container"
 	"github.com/namelesscorp/tvault-core/integrity"
 	"github.com/namelesscorp/tvault-core/integrity/hmac"
 	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
 	"github.com/namelesscorp/tvault-core/shamir"
 	"github.com/namelesscorp/tvault-core/token"
 )
 
+// Result - outcome of an unseal to a folder, or with container -dry-run what
+// it would write; paths are those in the container.
+type Result struct {
+	DryRun          bool           `json:"dry_run"`
+	OnConflict      string         `json:"on_conflict"`
+	Symlinks        string         `json:"symlinks"`
+	Created         []string       `json:"created"`
+	Conflicts       []zip.Conflict `json:"conflicts"`
+	SkippedSymlinks []string       `json:"skipped_symlinks"`
+}
+
 // Unseal - decrypts a container, restores its data, and unpacks its content to the specified folder using given options.
-func Unseal(opts Options) error {
+// Extracted entries are recorded in a journal next to the folder, removed once
+// the unseal succeeds. With container -resume an interrupted unseal continues
+// from its journal: entries whose files still have the recorded hash are kept,
+// the rest are extracted again.
+// Cancelling ctx stops decryption or extraction, removes the files it had not
+// finished and returns an ErrCodeCanceled error; finished files and the
+// journal are kept for container -resume.
+// With container -stdout a container sealed from stdin is decrypted to stdout
+// instead; an archive must be extracted and a raw payload must go to stdout.
+// Entries whose path already exists in the folder are handled by container
+// -on-conflict, and with -dry-run nothing is written, not even the journal.
+// The files created and the conflicts met are written to the info writer.
+// An archive beyond unseal limits fails with ErrExtractLimitExceeded before
+// anything is written, on its recorded totals before the key is recovered.
+// Symlink entries follow container -symlinks, which by default refuses those
+// leading out of the folder and any entry written through a symlink.
+func Unseal(ctx context.Context, opts Options) (err error) {
+	defer func() {
+		err = lib.Canceled(ctx, lib.CategoryUnseal, err)
+	}()
+
 	cont := container.NewContainer(
 		*opts.Container.CurrentPath,
 		nil,
@@ -35,13 +72,75 @@ func Unseal(opts Options) error {
 		)
 	}
 
-	var masterKey []byte
-	switch cont.GetHeader().TokenType {
-	case token.TypeMaster, token.TypeShare:
+	raw := cont.GetHeader().CompressionType == compression.TypeRaw
+	switch {
+	case *opts.Container.Stdout && !raw:
+		return lib.ValidationErr(lib.CategoryUnseal, lib.ErrRawPayloadRequired)
+	case !*opts.Container.Stdout && raw:
+		return lib.ValidationErr(lib.CategoryUnseal, lib.ErrRawPayloadNotStdout)
+	}
+
+	// The recorded totals of an archive are checked before the key is
+	// recovered; the archive itself is checked again before extraction.
+	limits := zipLimits(opts.Limits)
+	if !raw {
+		metadata := cont.GetMetadata()
+		if err := limits.CheckTotals(metadata.FileCount, metadata.UncompressedSize); err != nil {
+			return err
+		}
+	}
+
+	// A resumed unseal opens its journal first, so a missing one fails before
+	// the key is recovered.
+	var jrn *journal
+	if *opts.Container.Resume {
+		if jrn, err = openJournal(*opts.Container.FolderPath, cont.GetHeader().ID()); err != nil {
+			return err
+		}
+		defer func() { jrn.finish(err) }()
+	}
+
+	masterKey, cached := CachedMasterKey(opts.Agent, cont)
+	tokenType := cont.GetHeader().TokenType
+	switch {
+	case cached:
+		// The agent holds the key from an earlier unseal.
+	case *opts.Container.RecoveryCode != "":
+		// The recovery code opens the container whatever its token type.
+		var err error
+		if masterKey, err = openRecovery(cont, *opts.Container.RecoveryCode); err != nil {
+			return err
+		}
+	case *opts.Container.EscrowKeyPath != "":
+		// So does the escrow private key, used by escrow unseal.
+		privateKey, err := ReadEscrowPrivateKey(lib.CategoryUnseal, *opts.Container.EscrowKeyPath)
+		if err != nil {
+			return err
+		}
+
+		if masterKey, err = OpenEscrow(lib.CategoryUnseal, cont, privateKey); err != nil {
+			return err
+		}
+	case tokenType == token.TypePlugin:
+		var err error
+		if masterKey, err = OpenPlugin(ctx, lib.CategoryUnseal, cont); err != nil {
+			return err
+		}
+	case tokenType == token.TypeKMS:
+		var err error
+		if masterKey, err = OpenKMS(ctx, lib.CategoryUnseal, opts.KMS, cont); err != nil {
+			return err
+		}
+	case tokenType == token.TypeMaster || tokenType == token.TypeShare:
+		if err := opts.validateTokenReader(); err != nil {
+			return err
+		}
+
 		derivedPassphrase := DeriveIntegrityProviderPassphrase(
 			*opts.IntegrityProvider.CurrentPassphrase,
 			cont.GetHeader().Salt,
 		)
+		defer secret.Wipe(derivedPassphrase)
 
 		var tokenString string
 		tokenString, err := GetTokenString(opts.TokenReader)
@@ -55,13 +154,24 @@ func Unseal(opts Options) error {
 			)
 		}
 
+		resolveHolderKey, err := NewHolderKeyResolver(cont, opts.Holders)
+		if err != nil {
+			return err
+		}
+
 		var shares []shamir.Share
 		masterKey, shares, err = ParseTokens(
 			cont.GetHeader().TokenType,
+			cont.GetHeader().KeyEpoch,
 			tokenString,
 			*opts.TokenReader.Format,
 			derivedPassphrase,
+			resolveHolderKey,
 		)
+		defer shamir.WipeShares(shares)
+		if token.IsValidityError(err) {
+			return err
+		}
 		if err != nil {
 			return lib.InternalErr(
 				lib.CategoryUnseal,
@@ -73,25 +183,59 @@ func Unseal(opts Options) error {
 		}
 
 		if len(masterKey) == 0 {
-			masterKey, err = RestoreMasterKey(shares, derivedPassphrase)
+			var corrupted []byte
+			masterKey, corrupted, err = RecoverMasterKey(cont, shares, derivedPassphrase)
 			if err != nil {
 				return lib.InternalErr(
 					lib.CategoryUnseal,
 					lib.ErrCodeUnsealRestoreMasterKeyError,
 					lib.ErrMessageUnsealRestoreMasterKeyError,
-					"",
+					lib.ErrDetails(err),
 					err,
 				)
 			}
+
+			if len(corrupted) > 0 {
+				lib.WarningFormatted(opts.LogWriter, lib.Warning{
+					Operation: "unseal",
+					Message:   "corrupted shares were excluded",
+					Details:   "share ids inconsistent with the recovered key: " + shamir.FormatIDs(corrupted),
+				})
+			}
 		}
-	case token.TypeNone:
-		var salt = cont.GetHeader().Salt
+	case tokenType == token.TypeNone:
+		var (
+			salt       = cont.GetHeader().Salt
+			passphrase = []byte(*opts.Container.Passphrase)
+		)
 		masterKey = lib.PBKDF2Key(
-			[]byte(*opts.Container.Passphrase),
+			passphrase,
 			salt[:],
 			cont.GetHeader().Iterations,
 			lib.KeyLen,
 		)
+		secret.Wipe(passphrase)
+	}
+
+	// The key is moved into locked memory, wiped when unseal returns.
+	key := secret.From(masterKey)
+	defer key.Wipe()
+	masterKey = key.Bytes()
+
+	// A raw payload has nothing to extract: it goes to stdout as it is
+	// decrypted, with no temporary file, journal or progress. Each chunk is
+	// authenticated before it is written, but a failure in a later chunk
+	// leaves the reader with a prefix, so the exit status must be checked.
+	if raw {
+		if err := cont.DecryptTo(ctx, os.Stdout, masterKey); err != nil {
+			return lib.InternalErr(lib.CategoryUnseal, lib.ErrCodeUnsealContainerError, lib.ErrMessageUnsealContainerError, "", err)
+		}
+
+		if !cached {
+			CacheMasterKey(opts.Agent, cont, masterKey)
+		}
+
+		return nil
 	}
 
 	tmp, err := os.CreateTemp("", "tvault-unseal-*.zip")
@@ -111,7 +255,7 @@ func Unseal(opts Options) error {
 	progress := lib.NewProgressReporter()
 
 	decryptPhase := progress.Phase(0, 50, cont.GetMetadata().CompressedSize)
-	decryptErr := cont.DecryptTo(decryptPhase.WrapWriter(tmp), masterKey)
+	decryptErr := cont.DecryptTo(ctx, decryptPhase.WrapWriter(tmp), masterKey)
 	if decryptErr != nil {
 		return lib.InternalErr(lib.CategoryUnseal, lib.ErrCodeUnsealContainerError, lib.ErrMessageUnsealContainerError, "", decryptErr)
 	}
@@ -140,15 +284,133 @@ func Unseal(opts Options) error {
 		p.SetProgress(extractPhase.Add)
 	}
 
-	if err := unpacker.UnpackFrom(zf, st.Size(), *opts.Container.FolderPath); err != nil {
+	dryRun := *opts.Container.DryRun
+	if jrn == nil && !dryRun {
+		if jrn, err = createJournal(*opts.Container.FolderPath, cont.GetHeader().ID()); err != nil {
+			return err
+		}
+		defer func() { jrn.finish(err) }()
+	}
+	if l, ok := unpacker.(interface{ SetExtractLog(zip.ExtractLog) }); ok && jrn != nil {
+		l.SetExtractLog(jrn)
+	}
+
+	report := zip.ExtractReport{Created: []string{}, Conflicts: []zip.Conflict{}, SkippedSymlinks: []string{}}
+	if c, ok := unpacker.(interface{ SetConflictPolicy(string, bool) }); ok {
+		c.SetConflictPolicy(*opts.Container.OnConflict, dryRun)
+	}
+	if r, ok := unpacker.(interface{ SetExtractReport(*zip.ExtractReport) }); ok {
+		r.SetExtractReport(&report)
+	}
+	if l, ok := unpacker.(interface{ SetLimits(zip.Limits) }); ok {
+		l.SetLimits(limits)
+	}
+	if s, ok := unpacker.(interface{ SetSymlinkPolicy(string) }); ok {
+		s.SetSymlinkPolicy(*opts.Container.Symlinks)
+	}
+	if p, ok := unpacker.(interface{ SetPreserve(bool) }); ok {
+		p.SetPreserve(*opts.Container.Preserve == lib.PreserveAll)
+	}
+
+	if err := unpacker.UnpackFrom(ctx, zf, st.Size(), *opts.Container.FolderPath); err != nil {
+		if lib.IsValidationError(err) {
+			return err
+		}
+
 		return lib.IOErr(lib.CategoryUnseal, lib.ErrCodeUnsealCompressionUnpackError, lib.ErrMessageUnsealCompressionUnpackError, "", err)
 	}
 
+	if !cached {
+		CacheMasterKey(opts.Agent, cont, masterKey)
+	}
+
 	progress.Finish()
 
+	return writeResult(opts.InfoWriter, Result{
+		DryRun:          dryRun,
+		OnConflict:      *opts.Container.OnConflict,
+		Symlinks:        *opts.Container.Symlinks,
+		Created:         report.Created,
+		Conflicts:       report.Conflicts,
+		SkippedSymlinks: report.SkippedSymlinks,
+	})
+}
+
+// zipLimits - the extraction limits of unseal limits.
+func zipLimits(opts *lib.Limits) zip.Limits {
+	return zip.Limits{
+		MaxFiles: *opts.MaxFiles,
+		MaxBytes: *opts.MaxBytes,
+		MaxRatio: *opts.MaxRatio,
+		MaxDepth: *opts.MaxDepth,
+	}
+}
+
+// writeResult - writes the outcome of an unseal to a folder to the info
+// writer.
+func writeResult(opts *lib.Writer, result Result) error {
+	writer, closer, err := lib.NewWriter(opts)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any = result
+	if *opts.Format == lib.WriterFormatPlaintext {
+		var b strings.Builder
+		b.WriteString("[unseal]\n")
+		_, _ = fmt.Fprintf(&b, "Dry Run: %t\n", result.DryRun)
+		_, _ = fmt.Fprintf(&b, "On Conflict: %s\n", result.OnConflict)
+		_, _ = fmt.Fprintf(&b, "Symlinks: %s\n", result.Symlinks)
+		_, _ = fmt.Fprintf(&b, "Created: %s\n", strings.Join(result.Created, ","))
+		_, _ = fmt.Fprintf(&b, "Skipped Symlinks: %s\n", strings.Join(result.SkippedSymlinks, ","))
+		for _, conflict := range result.Conflicts {
+			if conflict.RenamedTo != "" {
+				_, _ = fmt.Fprintf(&b, "Conflict: %s: %s to %s\n", conflict.Path, conflict.Action, conflict.RenamedTo)
+				continue
+			}
+			_, _ = fmt.Fprintf(&b, "Conflict: %s: %s\n", conflict.Path, conflict.Action)
+		}
+		msg = b.String()
+	}
+
+	if _, err = lib.WriteFormatted(writer, *opts.Format, msg); err != nil {
+		return lib.IOErr(lib.CategoryUnseal, lib.ErrCodeUnsealUnpackContentError, lib.ErrMessageUnsealUnpackContentError, "", err)
+	}
+
 	return nil
 }
 
+// openRecovery - unwraps the master key of cont with a recovery code.
+func openRecovery(cont container.Container, code string) ([]byte, error) {
+	secret, err := lib.ParseRecoveryCode(code)
+	if err != nil {
+		return nil, lib.ValidationErr(lib.CategoryUnseal, err)
+	}
+
+	masterKey, err := cont.GetMetadata().Keys.OpenRecovery(secret, cont.GetHeader().Salt)
+	if errors.Is(err, lib.ErrRecoveryNotAvailable) {
+		return nil, lib.ValidationErr(lib.CategoryUnseal, err)
+	}
+	if err != nil {
+		return nil, lib.NewError(
+			lib.ErrorTypeCrypto,
+			lib.CategoryUnseal,
+			lib.ErrCodeRecoveryCodeMismatch,
+			lib.ErrMessageRecoveryCodeMismatch,
+			"",
+			lib.SuggestionRecoveryCodeMismatch,
+			err,
+		)
+	}
+
+	return masterKey, nil
+}
+
 func DeriveIntegrityProviderPassphrase(passphrase string, salt [16]byte) []byte {
 	if passphrase == "" {
 		return nil
@@ -189,10 +451,93 @@ func GetTokenString(tokenReader *lib.Reader) (string, error) {
 	return string(content), nil
 }
 
+// promptSecret - asks for a holder secret; replaced in tests.
+var promptSecret = lib.Prompt
+
+// holderKeyID - a holder, whose key opens every share they hold.
+type holderKeyID struct {
+	name string
+	kind byte
+}
+
+// NewHolderKeyResolver - returns a token.HolderKeyFunc for shares sealed per
+// holder. The holder of each share comes from the container key block; their
+// secret is taken from the holders file when it lists one, otherwise it is
+// prompted for as "share <id> (<name>)". Resolved keys are cached by holder,
+// so a holder of several shares under a sharing policy, or a reseal that
+// re-issues shares, asks for each secret once.
+func NewHolderKeyResolver(cont container.Container, holdersOpts *lib.Holders) (token.HolderKeyFunc, error) {
+	var holders []token.Holder
+	if holdersOpts != nil && *holdersOpts.Path != "" {
+		var err error
+		if holders, err = token.LoadHolders(*holdersOpts.Path); err != nil {
+			return nil, lib.IOErr(
+				lib.CategoryUnseal,
+				lib.ErrCodeUnsealResolveHolderKeyError,
+				lib.ErrMessageUnsealResolveHolderKeyError,
+				"",
+				err,
+			)
+		}
+	}
+
+	var (
+		salt     = cont.GetHeader().Salt
+		keyBlock = cont.GetMetadata().Keys
+		cache    = make(map[holderKeyID][]byte)
+	)
+	return func(id int, kind byte) ([]byte, error) {
+		record, ok := keyBlock.Holder(id)
+		if !ok {
+			return nil, lib.ErrHolderNotFound
+		}
+
+		if token.ConvertHolderNameToKind(record.Kind) != kind {
+			return nil, lib.ErrUnknownHolderKind
+		}
+
+		cacheID := holderKeyID{name: record.Name, kind: kind}
+		if key, ok := cache[cacheID]; ok {
+			return key, nil
+		}
+
+		holder, _ := token.FindHolder(holders, record.Name)
+		holder.Name = record.Name
+
+		key, err := holder.OpenKey(kind, salt[:])
+		if errors.Is(err, lib.ErrHolderSecretMissing) {
+			var secret string
+			if secret, err = promptSecret(fmt.Sprintf("share %d (%s)", id, record.Name)); err != nil {
+				return nil, err
+			}
+
+			switch kind {
+			case token.HolderKindX25519:
+				holder.PrivateKey = secret
+			default:
+				holder.Passphrase = secret
+			}
+			key, err = holder.OpenKey(kind, salt[:])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cache[cacheID] = key
+
+		return key, nil
+	}, nil
+}
+
+// ParseTokens - parses the token list read from the token reader. Shares sealed
+// per holder are opened with resolveHolderKey; all other tokens with addPwd.
+// Tokens issued before key epoch epoch are rejected as revoked.
 func ParseTokens(
 	tokenType byte,
+	epoch uint32,
 	tokenString, tokenFormat string,
 	addPwd []byte,
+	resolveHolderKey token.HolderKeyFunc,
 ) (masterKey []byte, shares []shamir.Share, err error) {
 	switch tokenFormat {
 	case lib.ReaderFormatPlaintext:
@@ -207,7 +552,7 @@ func ParseTokens(
 			)
 		}
 
-		return parseTokenList(tokenType, tokenList, addPwd)
+		return parseTokenList(tokenType, epoch, tokenList, addPwd, resolveHolderKey)
 	case lib.ReaderFormatJSON:
 		var list token.List
 		if err = json.Unmarshal([]byte(tokenString), &list); err != nil {
@@ -220,20 +565,40 @@ func ParseTokens(
 			)
 		}
 
-		return parseTokenList(tokenType, list.TokenList, addPwd)
+		return parseTokenList(tokenType, epoch, list.TokenList, addPwd, resolveHolderKey)
 	default:
 		return nil, nil, lib.ErrUnknownReaderType
 	}
 }
 
+// SplitTokens - splits the token string read from the token reader into the
+// individual raw (still encrypted) tokens.
+func SplitTokens(tokenString, readerFormat string) ([]string, error) {
+	switch readerFormat {
+	case lib.ReaderFormatPlaintext:
+		return strings.Split(tokenString, "|"), nil
+	case lib.ReaderFormatJSON:
+		var list token.List
+		if err := json.Unmarshal([]byte(tokenString), &list); err != nil {
+			return nil, err
+		}
+
+		return list.TokenList, nil
+	default:
+		return nil, lib.ErrUnknownReaderType
+	}
+}
+
 func parseTokenList(
 	tokenType byte,
+	epoch uint32,
 	tokenList []string,
 	addPwd []byte,
+	resolveHolderKey token.HolderKeyFunc,
 ) (masterKey []byte, shares []shamir.Share, err error) {
 	for _, raw := range tokenList {
 		var tok token.Token
-		if tok, err = token.Parse([]byte(raw), addPwd); err != nil {
+		if tok, err = ParseToken(raw, addPwd, resolveHolderKey); err != nil {
 			return nil, nil, lib.FormatErr(
 				lib.CategoryUnseal,
 				lib.ErrCodeUnsealParseTokenError,
@@ -243,6 +608,16 @@ func parseTokenList(
 			)
 		}
 
+		// The window was read from inside the authenticated envelope, so it
+		// is checked only once the token has been opened.
+		if err = tok.CheckWindow(time.Now()); err != nil {
+			return nil, nil, err
+		}
+
+		if err = tok.CheckEpoch(epoch); err != nil {
+			return nil, nil, err
+		}
+
 		switch tokenType {
 		case token.TypeMaster:
 			if masterKey, err = hex.DecodeString(tok.Value); err != nil {
@@ -279,6 +654,32 @@ func parseTokenList(
 	return masterKey, shares, nil
 }
 
+// ParseToken - parses one raw token, opening it with its holder's key when it
+// was sealed per holder.
+func ParseToken(raw string, addPwd []byte, resolveHolderKey token.HolderKeyFunc) (token.Token, error) {
+	id, kind, ok := token.PeekHolder([]byte(raw))
+	if !ok {
+		return token.Parse([]byte(raw), addPwd)
+	}
+
+	if resolveHolderKey == nil {
+		return token.Token{}, lib.ErrHolderNotFound
+	}
+
+	key, err := resolveHolderKey(id, kind)
+	if err != nil {
+		return token.Token{}, lib.InternalErr(
+			lib.CategoryUnseal,
+			lib.ErrCodeUnsealResolveHolderKeyError,
+			lib.ErrMessageUnsealResolveHolderKeyError,
+			fmt.Sprintf("share %d", id),
+			err,
+		)
+	}
+
+	return token.ParseForHolder([]byte(raw), key)
+}
+
 func createShareFromToken(item token.Token) (shamir.Share, error) {
 	val, err := hex.DecodeString(item.Value)
 	if err != nil {
@@ -313,7 +714,44 @@ func createShareFromToken(item token.Token) (shamir.Share, error) {
 	}, nil
 }
 
-func RestoreMasterKey(shares []shamir.Share, addPwd []byte) ([]byte, error) {
+// RecoverMasterKey - restores the master key of cont from shares. When more
+// shares than the threshold are supplied, threshold-sized subsets are checked
+// against the container so corrupted shares are excluded; their ids are
+// returned alongside the key. Feldman shares are checked against the
+// container's commitments instead, so no subset search is needed.
+func RecoverMasterKey(cont container.Container, shares []shamir.Share, addPwd []byte) ([]byte, []byte, error) {
+	if feldman := cont.GetMetadata().Keys.GetFeldman(); feldman != nil {
+		return shamir.CombineFeldman(shares, feldman)
+	}
+
+	policy := cont.GetMetadata().Keys.GetPolicy()
+	if policy != nil || len(shares) <= int(cont.GetHeader().Threshold) {
+		masterKey, err := RestoreMasterKey(shares, addPwd, policy)
+		return masterKey, nil, err
+	}
+
+	integrityProvider, err := createIntegrityProvider(shares[0].ProviderID, addPwd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return shamir.CombineConsistent(
+		shares,
+		int(cont.GetHeader().Threshold),
+		integrityProvider,
+		func(secret []byte) bool { return CheckMasterKey(cont, secret) },
+	)
+}
+
+// CheckMasterKey - reports whether masterKey opens cont, by Container.CheckKey.
+// A key that cannot be verified is not reported as opening it.
+func CheckMasterKey(cont container.Container, masterKey []byte) bool {
+	return cont.CheckKey(masterKey) == nil
+}
+
+// RestoreMasterKey - combines the shares back into the master key, following the
+// container's sharing policy when it has one.
+func RestoreMasterKey(shares []shamir.Share, addPwd []byte, policy *shamir.Policy) ([]byte, error) {
 	if len(shares) == 0 {
 		return nil, lib.ErrEmptyShares
 	}
@@ -323,6 +761,10 @@ func RestoreMasterKey(shares []shamir.Share, addPwd []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	if policy != nil {
+		return shamir.CombinePolicy(shares, policy, integrityProvider)
+	}
+
 	return shamir.Combine(shares, integrityProvider)
 }
 
@@ -332,6 +774,8 @@ func createIntegrityProvider(providerID byte, addPwd []byte) (integrity.Provider
 		return integrity.NewNoneProvider(), nil
 	case integrity.TypeHMAC:
 		return hmac.New(addPwd), nil
+	case integrity.TypeFeldman:
+		return integrity.NewFeldmanProvider(), nil
 	case integrity.TypeEd25519:
 		return nil, lib.ErrEd25519Unimplemented
 	default:
@@ -0,0 +1,167 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/token"
+	"github.com/namelesscorp/tvault-core/unseal"
+)
+
+type (
+	// TokenInfo - what token inspect reports for one token.
+	TokenInfo struct {
+		token.Info
+		Error string `json:"error,omitempty"`
+	}
+
+	// InspectResult - outcome of token inspect, in token order.
+	InspectResult struct {
+		Tokens []TokenInfo `json:"tokens"`
+	}
+)
+
+// Inspect - decodes tokens and reports their envelope, version, share id and
+// whether they are signed, never their value. Encrypted tokens are opened only
+// when the container is given together with the integrity passphrase (for
+// passphrase envelopes) or a holders file (for holder envelopes); otherwise
+// only what is in the clear is reported.
+func Inspect(opts InspectOptions) error {
+	tokenString, err := unseal.GetTokenString(opts.TokenReader)
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyGetTokenStringError,
+			lib.ErrMessageVerifyGetTokenStringError,
+			"",
+			err,
+		)
+	}
+
+	rawTokens, err := unseal.SplitTokens(tokenString, *opts.TokenReader.Format)
+	if err != nil {
+		return lib.FormatErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyParseTokensError,
+			lib.ErrMessageVerifyParseTokensError,
+			"",
+			err,
+		)
+	}
+
+	var (
+		addPwd           []byte
+		resolveHolderKey token.HolderKeyFunc
+	)
+	if *opts.Container.CurrentPath != "" {
+		cont := container.NewContainer(
+			*opts.Container.CurrentPath,
+			nil,
+			container.Metadata{Tags: make([]string, 0)},
+			container.Header{},
+		)
+		if err = cont.Read(); err != nil {
+			return lib.IOErr(
+				lib.CategoryToken,
+				lib.ErrCodeVerifyOpenContainerError,
+				lib.ErrMessageVerifyOpenContainerError,
+				"",
+				err,
+			)
+		}
+
+		addPwd = unseal.DeriveIntegrityProviderPassphrase(*opts.IntegrityProvider.CurrentPassphrase, cont.GetHeader().Salt)
+
+		if *opts.Holders.Path != "" {
+			if resolveHolderKey, err = unseal.NewHolderKeyResolver(cont, opts.Holders); err != nil {
+				return err
+			}
+		}
+	}
+
+	result := InspectResult{Tokens: make([]TokenInfo, 0, len(rawTokens))}
+	for _, raw := range rawTokens {
+		result.Tokens = append(result.Tokens, inspectToken(raw, addPwd, resolveHolderKey))
+	}
+
+	return write(opts.InfoWriter, result, func() string { return formatInspectResult(result) })
+}
+
+// inspectToken - inspects one raw token, opening it when a key for its
+// envelope is available.
+func inspectToken(raw string, addPwd []byte, resolveHolderKey token.HolderKeyFunc) TokenInfo {
+	info, err := token.Inspect([]byte(raw))
+	if err != nil {
+		return TokenInfo{Error: err.Error()}
+	}
+
+	canOpen := (info.Envelope == token.EnvelopePassphrase && len(addPwd) > 0) ||
+		(info.Envelope == token.EnvelopeHolder && resolveHolderKey != nil)
+	if info.Opened || !canOpen {
+		return TokenInfo{Info: info}
+	}
+
+	tok, err := unseal.ParseToken(raw, addPwd, resolveHolderKey)
+	if err != nil {
+		return TokenInfo{Info: info, Error: err.Error()}
+	}
+
+	info.Open(tok)
+
+	return TokenInfo{Info: info}
+}
+
+func formatInspectResult(result InspectResult) string {
+	var b strings.Builder
+	b.WriteString("[token inspection]\n")
+
+	for i, item := range result.Tokens {
+		envelope := item.Envelope
+		if item.Holder != "" {
+			envelope += " (" + item.Holder + ")"
+		}
+
+		_, _ = fmt.Fprintf(&b, "Token %d:\n", i+1)
+		if item.Envelope != "" {
+			_, _ = fmt.Fprintf(&b, "  Envelope: %s\n", envelope)
+			_, _ = fmt.Fprintf(&b, "  Encrypted: %s\n", formatBool(item.Encrypted))
+			_, _ = fmt.Fprintf(&b, "  Opened: %s\n", formatBool(item.Opened))
+		}
+		if item.Opened {
+			_, _ = fmt.Fprintf(&b, "  Version: %d\n", item.Version)
+		}
+		if item.ID != 0 {
+			_, _ = fmt.Fprintf(&b, "  Share ID: %d\n", item.ID)
+		}
+		if item.Opened {
+			_, _ = fmt.Fprintf(&b, "  Signature: %s\n", formatBool(item.Signed))
+		}
+		if item.Member != "" {
+			_, _ = fmt.Fprintf(&b, "  Member: %s (%s)\n", item.Member, item.Group)
+		}
+		if item.NotBefore != "" {
+			_, _ = fmt.Fprintf(&b, "  Not before: %s\n", item.NotBefore)
+		}
+		if item.ExpiresAt != "" {
+			_, _ = fmt.Fprintf(&b, "  Expires at: %s\n", item.ExpiresAt)
+		}
+		if item.Epoch != 0 {
+			_, _ = fmt.Fprintf(&b, "  Key epoch: %d\n", item.Epoch)
+		}
+		if item.Error != "" {
+			_, _ = fmt.Fprintf(&b, "  Error: %s\n", item.Error)
+		}
+	}
+
+	return b.String()
+}
+
+func formatBool(v bool) string {
+	if v {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,118 @@
+package verify
+
+import "github.com/namelesscorp/tvault-core/lib"
+
+type (
+	Options struct {
+		Container         *lib.Container
+		IntegrityProvider *lib.IntegrityProvider
+		TokenReader       *lib.Reader
+		Holders           *lib.Holders
+		InfoWriter        *lib.Writer
+		LogWriter         *lib.Writer
+	}
+
+	// InspectOptions - options of token inspect. Container is optional: it is
+	// only needed to open encrypted tokens.
+	InspectOptions struct {
+		Container         *lib.Container
+		IntegrityProvider *lib.IntegrityProvider
+		TokenReader       *lib.Reader
+		Holders           *lib.Holders
+		InfoWriter        *lib.Writer
+		LogWriter         *lib.Writer
+	}
+)
+
+func (o *Options) Validate() error {
+	if *o.Container.CurrentPath == "" {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrVerifyContainerPathRequired)
+	}
+
+	if err := o.validateTokenReader(); err != nil {
+		return err
+	}
+
+	if err := o.validateInfoWriter(); err != nil {
+		return err
+	}
+
+	return o.validateLogWriter()
+}
+
+func (o *Options) validateTokenReader() error {
+	if _, ok := lib.ReaderTypes[*o.TokenReader.Type]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrTokenReaderTypeInvalid)
+	}
+
+	switch *o.TokenReader.Type {
+	case lib.ReaderTypeFlag:
+		if *o.TokenReader.Flag == "" {
+			return lib.ValidationErr(lib.CategoryToken, lib.ErrTokenReaderFlagRequired)
+		}
+	case lib.ReaderTypeFile:
+		if *o.TokenReader.Path == "" {
+			return lib.ValidationErr(lib.CategoryToken, lib.ErrTokenReaderPathRequired)
+		}
+	}
+
+	if _, ok := lib.ReaderFormats[*o.TokenReader.Format]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrTokenReaderFormatInvalid)
+	}
+
+	return nil
+}
+
+func (o *Options) validateInfoWriter() error {
+	if _, ok := lib.WriterTypes[*o.InfoWriter.Type]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrInfoWriterTypeInvalid)
+	}
+
+	if *o.InfoWriter.Type == lib.WriterTypeFile && *o.InfoWriter.Path == "" {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrInfoWriterPathRequired)
+	}
+
+	if _, ok := lib.WriterFormats[*o.InfoWriter.Format]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrInfoWriterFormatInvalid)
+	}
+
+	return nil
+}
+
+func (o *Options) validateLogWriter() error {
+	if _, ok := lib.WriterTypes[*o.LogWriter.Type]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrLogWriterTypeInvalid)
+	}
+
+	if *o.LogWriter.Type == lib.WriterTypeFile && *o.LogWriter.Path == "" {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrLogWriterPathRequired)
+	}
+
+	if _, ok := lib.WriterFormats[*o.LogWriter.Format]; !ok {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrLogWriterFormatInvalid)
+	}
+
+	return nil
+}
+
+func (o *InspectOptions) Validate() error {
+	if *o.Container.CurrentPath == "" &&
+		(*o.IntegrityProvider.CurrentPassphrase != "" || *o.Holders.Path != "") {
+		return lib.ValidationErr(lib.CategoryToken, lib.ErrInspectContainerPathRequired)
+	}
+
+	opts := Options{
+		TokenReader: o.TokenReader,
+		InfoWriter:  o.InfoWriter,
+		LogWriter:   o.LogWriter,
+	}
+	if err := opts.validateTokenReader(); err != nil {
+		return err
+	}
+
+	if err := opts.validateInfoWriter(); err != nil {
+		return err
+	}
+
+	return opts.validateLogWriter()
+}
@@ -0,0 +1,289 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/integrity/hmac"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/lib/secret"
+	"github.com/namelesscorp/tvault-core/shamir"
+	"github.com/namelesscorp/tvault-core/token"
+	"github.com/namelesscorp/tvault-core/unseal"
+)
+
+const (
+	StatusValid        = "valid"
+	StatusInvalid      = "invalid"
+	StatusInsufficient = "insufficient"
+
+	resultMessage = "[token verification]\nStatus: %s\nValid shares: %s\nInvalid shares: %s\nUnreadable tokens: %s\nThreshold: %d\n"
+)
+
+// Result - outcome of checking share tokens against a container.
+type Result struct {
+	Status string `json:"status"`
+	Valid  []int  `json:"valid"`
+	// Invalid - IDs of shares that failed their check, and of shares whose ID
+	// an earlier share already had.
+	Invalid []int `json:"invalid"`
+	// Unreadable - positions (1-based) of tokens that could not be opened.
+	Unreadable []int `json:"unreadable"`
+	Threshold  int   `json:"threshold"`
+}
+
+// Verify - checks share tokens against a container without decrypting its
+// payload. Each share is checked against the Feldman commitments or, with the
+// integrity passphrase, its HMAC signature; a quorum of shares is combined and
+// checked against the key check value in the key block.
+func Verify(opts Options) error {
+	cont := container.NewContainer(
+		*opts.Container.CurrentPath,
+		nil,
+		container.Metadata{Tags: make([]string, 0)},
+		container.Header{},
+	)
+	if err := cont.Read(); err != nil {
+		return lib.IOErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyOpenContainerError,
+			lib.ErrMessageVerifyOpenContainerError,
+			"",
+			err,
+		)
+	}
+
+	var (
+		passphrase = *opts.IntegrityProvider.CurrentPassphrase
+		addPwd     = unseal.DeriveIntegrityProviderPassphrase(passphrase, cont.GetHeader().Salt)
+		signer     integrity.Provider
+	)
+	// Shares are signed with the passphrase itself, as seal does, while tokens
+	// are encrypted with the key derived from it.
+	if cont.GetHeader().IntegrityProviderType == integrity.TypeHMAC && passphrase != "" {
+		signer = hmac.New([]byte(passphrase))
+	}
+
+	if cont.GetHeader().TokenType != token.TypeShare || !canVerify(cont, signer) {
+		return lib.InternalErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyUnsupportedContainer,
+			lib.ErrMessageVerifyUnsupportedContainer,
+			"integrity provider: "+integrity.ConvertIDToName(cont.GetHeader().IntegrityProviderType),
+			lib.ErrVerifyUnsupportedContainer,
+		)
+	}
+
+	tokenString, err := unseal.GetTokenString(opts.TokenReader)
+	if err != nil {
+		return lib.InternalErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyGetTokenStringError,
+			lib.ErrMessageVerifyGetTokenStringError,
+			"",
+			err,
+		)
+	}
+
+	rawTokens, err := unseal.SplitTokens(tokenString, *opts.TokenReader.Format)
+	if err != nil {
+		return lib.FormatErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyParseTokensError,
+			lib.ErrMessageVerifyParseTokensError,
+			"",
+			err,
+		)
+	}
+
+	resolveHolderKey, err := unseal.NewHolderKeyResolver(cont, opts.Holders)
+	if err != nil {
+		return err
+	}
+
+	// Tokens are opened one at a time so that one bad token is reported
+	// instead of failing the whole set.
+	var (
+		shares     = make([]shamir.Share, 0, len(rawTokens))
+		unreadable = make([]int, 0)
+	)
+	for i, raw := range rawTokens {
+		_, parsed, parseErr := unseal.ParseTokens(
+			token.TypeShare,
+			cont.GetHeader().KeyEpoch,
+			raw,
+			lib.ReaderFormatPlaintext,
+			addPwd,
+			resolveHolderKey,
+		)
+		if parseErr != nil {
+			unreadable = append(unreadable, i+1)
+			continue
+		}
+
+		shares = append(shares, parsed...)
+	}
+
+	result := check(cont, shares, addPwd, signer)
+	if len(unreadable) > 0 {
+		result.Unreadable = unreadable
+		result.Status = StatusInvalid
+	}
+
+	return writeResult(opts.InfoWriter, result)
+}
+
+// canVerify - reports whether cont has anything to check shares against: the
+// Feldman commitments, HMAC signatures (given a signer) or a key check value.
+func canVerify(cont container.Container, signer integrity.Provider) bool {
+	keyBlock := cont.GetMetadata().Keys
+
+	return keyBlock.GetFeldman() != nil || keyBlock.HasKeyCheck() || signer != nil
+}
+
+// check - sorts shares into valid and invalid and, when none is invalid,
+// combines them and checks the result against the container. A share repeating
+// the ID of an earlier one is invalid, so it is not counted twice towards the
+// threshold.
+func check(cont container.Container, shares []shamir.Share, addPwd []byte, signer integrity.Provider) Result {
+	var (
+		keyBlock = cont.GetMetadata().Keys
+		feldman  = keyBlock.GetFeldman()
+		result   = Result{
+			Status:     StatusValid,
+			Valid:      make([]int, 0, len(shares)),
+			Invalid:    make([]int, 0),
+			Unreadable: make([]int, 0),
+			Threshold:  int(cont.GetHeader().Threshold),
+		}
+		seen = make(map[byte]bool, len(shares))
+	)
+	for _, sh := range shares {
+		valid := true
+		switch {
+		case seen[sh.ID]:
+			valid = false
+		case feldman != nil:
+			valid = shamir.VerifyFeldman(sh, feldman)
+		case signer != nil:
+			valid, _ = signer.IsVerify(sh.ID, sh.Value, sh.Signature)
+		}
+		seen[sh.ID] = true
+
+		if valid {
+			result.Valid = append(result.Valid, int(sh.ID))
+		} else {
+			result.Invalid = append(result.Invalid, int(sh.ID))
+		}
+	}
+
+	switch {
+	case len(result.Invalid) > 0:
+		result.Status = StatusInvalid
+	case keyBlock.GetPolicy() != nil:
+		result.Status = checkPolicy(cont, shares, addPwd)
+	case len(result.Valid) < result.Threshold:
+		result.Status = fmt.Sprintf("%s (%d of %d)", StatusInsufficient, len(result.Valid), result.Threshold)
+	case keyBlock.HasKeyCheck():
+		masterKey, _, err := unseal.RecoverMasterKey(cont, shares, addPwd)
+		if err != nil || !keyBlock.MatchesKey(masterKey) {
+			result.Status = StatusInvalid
+		}
+		secret.Wipe(masterKey)
+	}
+
+	return result
+}
+
+// checkPolicy - combines shares under the container's sharing policy. An
+// unsatisfied policy is reported as insufficient with the groups still short.
+func checkPolicy(cont container.Container, shares []shamir.Share, addPwd []byte) string {
+	masterKey, err := unseal.RestoreMasterKey(shares, addPwd, cont.GetMetadata().Keys.GetPolicy())
+	defer secret.Wipe(masterKey)
+
+	if errors.Is(err, lib.ErrShamirPolicyUnsatisfied) {
+		var libErr *lib.Error
+		if errors.As(err, &libErr) && libErr.Details != "" {
+			return fmt.Sprintf("%s (%s)", StatusInsufficient, libErr.Details)
+		}
+
+		return StatusInsufficient
+	}
+
+	if err != nil {
+		return StatusInvalid
+	}
+
+	if keyBlock := cont.GetMetadata().Keys; keyBlock.HasKeyCheck() && !keyBlock.MatchesKey(masterKey) {
+		return StatusInvalid
+	}
+
+	return StatusValid
+}
+
+func writeResult(opts *lib.Writer, result Result) error {
+	return write(opts, result, func() string {
+		return fmt.Sprintf(
+			resultMessage,
+			result.Status,
+			formatIDs(result.Valid),
+			formatIDs(result.Invalid),
+			formatIDs(result.Unreadable),
+			result.Threshold,
+		)
+	})
+}
+
+// write - writes result to the info writer, as JSON or as the text returned by
+// plaintext.
+func write(opts *lib.Writer, result any, plaintext func() string) error {
+	writer, closer, err := lib.NewWriter(opts)
+	if err != nil {
+		return err
+	}
+
+	if closer != nil {
+		defer func(closer io.Closer) {
+			_ = closer.Close()
+		}(closer)
+	}
+
+	var msg any
+	switch *opts.Format {
+	case lib.WriterFormatPlaintext:
+		msg = plaintext()
+	case lib.WriterFormatJSON:
+		msg = result
+	}
+
+	if _, err = lib.WriteFormatted(writer, *opts.Format, msg); err != nil {
+		return lib.IOErr(
+			lib.CategoryToken,
+			lib.ErrCodeVerifyWriteResultError,
+			lib.ErrMessageVerifyWriteResultError,
+			"",
+			err,
+		)
+	}
+
+	return nil
+}
+
+// formatIDs - renders share ids or token positions as "2, 5", or "none".
+func formatIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,172 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/namelesscorp/tvault-core/container"
+	"github.com/namelesscorp/tvault-core/integrity"
+	"github.com/namelesscorp/tvault-core/lib"
+	"github.com/namelesscorp/tvault-core/seal"
+	"github.com/namelesscorp/tvault-core/shamir"
+	"github.com/namelesscorp/tvault-core/token"
+)
+
+func TestVerifyKeyCheck(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		contPath  = filepath.Join(dir, "vault.tvlt")
+		infoPath  = filepath.Join(dir, "info.json")
+		masterKey = bytes.Repeat([]byte{0x42}, lib.KeyLen)
+	)
+
+	header, err := container.NewHeader(0, integrity.TypeNone, token.TypeShare, 3, 3)
+	if err != nil {
+		t.Fatalf("NewHeader() error = %v", err)
+	}
+
+	cont := container.NewContainer(contPath, nil, container.Metadata{
+		Tags: []string{},
+		Keys: &container.KeyBlock{KeyCheck: container.NewKeyCheck(masterKey)},
+	}, header)
+	cont.SetMasterKey(masterKey)
+	if err = cont.WriteEncrypted(context.Background(), bytes.NewReader([]byte("payload")), nil); err != nil {
+		t.Fatalf("WriteEncrypted() error = %v", err)
+	}
+
+	shares, err := shamir.Split(masterKey, 3, 3, integrity.NewNoneProvider())
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+
+	foreign, err := shamir.Split(bytes.Repeat([]byte{0x43}, lib.KeyLen), 3, 3, integrity.NewNoneProvider())
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		shares      []shamir.Share
+		extra       string
+		wantStatus  string
+		wantValid   []int
+		wantInvalid []int
+		wantUnread  []int
+	}{
+		{
+			name:        "quorum",
+			shares:      shares,
+			wantStatus:  StatusValid,
+			wantValid:   []int{1, 2, 3},
+			wantInvalid: []int{},
+			wantUnread:  []int{},
+		},
+		{
+			name:        "insufficient",
+			shares:      shares[:2],
+			wantStatus:  "insufficient (2 of 3)",
+			wantValid:   []int{1, 2},
+			wantInvalid: []int{},
+			wantUnread:  []int{},
+		},
+		{
+			name:        "foreign_shares",
+			shares:      foreign,
+			wantStatus:  StatusInvalid,
+			wantValid:   []int{1, 2, 3},
+			wantInvalid: []int{},
+			wantUnread:  []int{},
+		},
+		{
+			name:        "unreadable_token",
+			shares:      shares[:2],
+			extra:       "not-a-token",
+			wantStatus:  StatusInvalid,
+			wantValid:   []int{1, 2},
+			wantInvalid: []int{},
+			wantUnread:  []int{3},
+		},
+		{
+			name:        "duplicate_share",
+			shares:      []shamir.Share{shares[0], shares[1], shares[0]},
+			wantStatus:  StatusInvalid,
+			wantValid:   []int{1, 2},
+			wantInvalid: []int{1},
+			wantUnread:  []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokenBuf bytes.Buffer
+			if err = seal.SaveShareTokens(tt.shares, nil, nil, nil, token.Window{}, 0, lib.WriterFormatJSON, &tokenBuf); err != nil {
+				t.Fatalf("SaveShareTokens() error = %v", err)
+			}
+
+			var list token.List
+			if err = json.Unmarshal(tokenBuf.Bytes(), &list); err != nil {
+				t.Fatalf("unmarshal tokens: %v", err)
+			}
+			if tt.extra != "" {
+				list.TokenList = append(list.TokenList, tt.extra)
+			}
+			tokenString, _ := json.Marshal(list)
+
+			opts := Options{
+				Container:         &lib.Container{CurrentPath: lib.StringPtr(contPath)},
+				IntegrityProvider: &lib.IntegrityProvider{CurrentPassphrase: lib.StringPtr("")},
+				TokenReader: &lib.Reader{
+					Type:   lib.StringPtr(lib.ReaderTypeFlag),
+					Path:   lib.StringPtr(""),
+					Flag:   lib.StringPtr(string(tokenString)),
+					Format: lib.StringPtr(lib.ReaderFormatJSON),
+				},
+				Holders: &lib.Holders{Path: lib.StringPtr("")},
+				InfoWriter: &lib.Writer{
+					Type:   lib.StringPtr(lib.WriterTypeFile),
+					Path:   lib.StringPtr(infoPath),
+					Format: lib.StringPtr(lib.WriterFormatJSON),
+				},
+				LogWriter: &lib.Writer{
+					Type:   lib.StringPtr(lib.WriterTypeStdout),
+					Path:   lib.StringPtr(""),
+					Format: lib.StringPtr(lib.WriterFormatJSON),
+				},
+			}
+			if err = opts.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if err = Verify(opts); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			content, err := os.ReadFile(infoPath)
+			if err != nil {
+				t.Fatalf("read result: %v", err)
+			}
+
+			var got Result
+			if err = json.Unmarshal(content, &got); err != nil {
+				t.Fatalf("unmarshal result: %v", err)
+			}
+
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(got.Valid, tt.wantValid) {
+				t.Errorf("Valid = %v, want %v", got.Valid, tt.wantValid)
+			}
+			if !reflect.DeepEqual(got.Invalid, tt.wantInvalid) {
+				t.Errorf("Invalid = %v, want %v", got.Invalid, tt.wantInvalid)
+			}
+			if !reflect.DeepEqual(got.Unreadable, tt.wantUnread) {
+				t.Errorf("Unreadable = %v, want %v", got.Unreadable, tt.wantUnread)
+			}
+		})
+	}
+}